@@ -16,6 +16,11 @@
 package backend
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
 	"github.com/consensys/gnark/backend/hint"
 	"github.com/consensys/gnark/logger"
 	"github.com/rs/zerolog"
@@ -57,18 +62,35 @@ type ProverOption func(*ProverConfig) error
 
 // ProverConfig is the configuration for the prover with the options applied.
 type ProverConfig struct {
-	Force         bool                      // defaults to false
-	HintFunctions map[hint.ID]hint.Function // defaults to all built-in hint functions
-	CircuitLogger zerolog.Logger            // defaults to gnark.Logger
+	Force           bool                                   // defaults to false
+	HintFunctions   map[hint.ID]hint.Function              // defaults to all built-in hint functions
+	HintProvider    hint.Provider                          // defaults to nil
+	HintTranscript  *hint.Transcript                       // defaults to nil
+	CircuitLogger   zerolog.Logger                         // defaults to gnark.Logger
+	CircuitLogSink  CircuitLogSink                         // defaults to nil (logs to CircuitLogger)
+	Ctx             context.Context                        // defaults to context.Background()
+	Progress        func(ProgressEvent)                    // defaults to nil
+	WorkerPool      *WorkerPool                            // defaults to DefaultWorkerPool()
+	SolverTaskSize  int                                    // defaults to DefaultSolverTaskSize
+	SolverKeepGoing bool                                   // defaults to false
+	SolverHook      func(cID int, state SolverState) error // defaults to nil
+
+	timer *stageTimer // set by WithMetrics and WithMetricsHook
 }
 
 // NewProverConfig returns a default ProverConfig with given prover options opts
 // applied.
 func NewProverConfig(opts ...ProverOption) (ProverConfig, error) {
 	log := logger.Logger()
-	opt := ProverConfig{CircuitLogger: log, HintFunctions: make(map[hint.ID]hint.Function)}
+	opt := ProverConfig{
+		CircuitLogger:  log,
+		HintFunctions:  make(map[hint.ID]hint.Function),
+		Ctx:            context.Background(),
+		WorkerPool:     DefaultWorkerPool(),
+		SolverTaskSize: DefaultSolverTaskSize,
+	}
 	for _, v := range hint.GetRegistered() {
-		opt.HintFunctions[hint.UUID(v)] = v
+		addHint(opt.HintFunctions, v)
 	}
 	for _, option := range opts {
 		if err := option(&opt); err != nil {
@@ -97,19 +119,148 @@ func WithHints(hintFunctions ...hint.Function) ProverOption {
 		// it is an error to register hint function several times, but as the
 		// prover already checks it then omit here.
 		for _, h := range hintFunctions {
-			uuid := hint.UUID(h)
-			if _, ok := opt.HintFunctions[uuid]; ok {
-				log.Warn().Int("hintID", int(uuid)).Str("name", hint.Name(h)).Msg("duplicate hint function")
-			} else {
-				opt.HintFunctions[uuid] = h
+			if !addHint(opt.HintFunctions, h) {
+				log.Warn().Int("hintID", int(hint.UUID(h))).Str("name", hint.Name(h)).Msg("duplicate hint function")
 			}
 		}
 		return nil
 	}
 }
 
+// addHint adds h to the hint functions and returns false if it was already present. A named
+// hint (see hint.RegisterNamed) is also added under its legacy ID, such that the constraint
+// systems compiled before it was named can still be solved.
+func addHint(hintFunctions map[hint.ID]hint.Function, h hint.Function) bool {
+	uuid := hint.UUID(h)
+	if _, ok := hintFunctions[uuid]; ok {
+		return false
+	}
+	hintFunctions[uuid] = h
+	if legacy := hint.LegacyUUID(h); legacy != uuid {
+		if _, ok := hintFunctions[legacy]; !ok {
+			hintFunctions[legacy] = h
+		}
+	}
+	return true
+}
+
+// WithHintProvider is a prover option that specifies a provider computing, by name, the hints
+// whose functions are not given with WithHints (see hint.Provider).
+func WithHintProvider(p hint.Provider) ProverOption {
+	return func(opt *ProverConfig) error {
+		opt.HintProvider = p
+		return nil
+	}
+}
+
+// WithHintTranscript is a prover option that records the results of the hint calls in t. The
+// hint calls already recorded in t are not executed, their outputs are replayed from t. As
+// such, solving again with the same transcript gives the same solution, even if the hints are
+// not deterministic or not available anymore.
+func WithHintTranscript(t *hint.Transcript) ProverOption {
+	return func(opt *ProverConfig) error {
+		opt.HintTranscript = t
+		return nil
+	}
+}
+
+// ResolveHints returns the hint functions used by the solver for a constraint system with the
+// given hint dependencies (hint ID to name). The hints without a function are called with the
+// hint provider, and the hint calls are recorded in the hint transcript, if any.
+//
+// The excluded hints are neither provided nor recorded. This is the case of the commitment hint
+// of Groth16, which sets the commitment of the proof: replaying its outputs would skip it.
+func (cfg *ProverConfig) ResolveHints(dependencies map[hint.ID]string, excluded ...hint.ID) map[hint.ID]hint.Function {
+	if cfg.HintProvider == nil && cfg.HintTranscript == nil {
+		return cfg.HintFunctions
+	}
+	res := make(map[hint.ID]hint.Function, len(cfg.HintFunctions)+len(dependencies))
+	for id, f := range cfg.HintFunctions {
+		res[id] = f
+	}
+	skip := make(map[hint.ID]struct{}, len(excluded))
+	for _, id := range excluded {
+		skip[id] = struct{}{}
+	}
+	for id, name := range dependencies {
+		if _, ok := skip[id]; ok {
+			continue
+		}
+		f, ok := res[id]
+		if !ok && cfg.HintProvider != nil {
+			f = hint.Provided(cfg.HintProvider, name)
+		}
+		if cfg.HintTranscript != nil {
+			f = cfg.HintTranscript.Wrap(name, f)
+		}
+		if f != nil {
+			res[id] = f
+		}
+	}
+	return res
+}
+
+// DefaultSolverTaskSize is the default minimum number of constraints of a level solved by a
+// worker. The levels with fewer constraints are solved sequentially.
+const DefaultSolverTaskSize = 50
+
+// WithWorkerPool is a prover option that sets the pool of workers used by the solver and the
+// prover. For example, WithWorkerPool(NewWorkerPool(2)) runs the solver and the MSMs of the
+// prove call on 2 CPUs, and a pool shared by several prove calls limits their total concurrency.
+// The FFTs are not bounded by the pool (see WorkerPool).
+func WithWorkerPool(p *WorkerPool) ProverOption {
+	return func(opt *ProverConfig) error {
+		if p == nil {
+			return errors.New("nil worker pool")
+		}
+		opt.WorkerPool = p
+		return nil
+	}
+}
+
+// WithSolverTaskSize is a prover option that sets the minimum number of constraints of a level
+// solved by a worker of the solver (DefaultSolverTaskSize by default). Smaller tasks solve the
+// levels with more parallelism, at the cost of more synchronization.
+func WithSolverTaskSize(n int) ProverOption {
+	return func(opt *ProverConfig) error {
+		if n < 1 {
+			return fmt.Errorf("invalid solver task size %d", n)
+		}
+		opt.SolverTaskSize = n
+		return nil
+	}
+}
+
+// WithSolverKeepGoing is a prover option that lets the solver keep going after an unsatisfied
+// constraint, and return all the unsatisfied constraints in a
+// *constraint.UnsatisfiedConstraintsError. The wires solved by an unsatisfied constraint may
+// have wrong values (0 if they could not be solved), such that the constraints depending on
+// them may not be satisfied either.
+func WithSolverKeepGoing() ProverOption {
+	return func(opt *ProverConfig) error {
+		opt.SolverKeepGoing = true
+		return nil
+	}
+}
+
+// SolverState gives access to the values of the wires while the solver runs, see WithSolverHook
+type SolverState interface {
+	// Value returns the value of the wire, or false if the wire is not solved yet
+	Value(wire int) (*big.Int, bool)
+}
+
+// WithSolverHook is a prover option that makes the solver solve the constraints sequentially, in
+// level order, and call hook before solving each constraint. If hook returns an error, the
+// solver stops and returns it. See package constraint/debugger.
+func WithSolverHook(hook func(cID int, state SolverState) error) ProverOption {
+	return func(opt *ProverConfig) error {
+		opt.SolverHook = hook
+		return nil
+	}
+}
+
 // WithCircuitLogger is a prover option that specifies zerolog.Logger as a destination for the
-// logs printed by api.Println(). By default, uses gnark/logger.
+// logs printed by api.Println(), api.Printf() and api.PrintlnIf(). By default, uses gnark/logger.
 // zerolog.Nop() will disable logging
 func WithCircuitLogger(l zerolog.Logger) ProverOption {
 	return func(opt *ProverConfig) error {
@@ -117,3 +268,95 @@ func WithCircuitLogger(l zerolog.Logger) ProverOption {
 		return nil
 	}
 }
+
+// CircuitLogSink receives the logs printed by the circuit (api.Println, api.Printf and
+// api.PrintlnIf), with the location of the call (file.go:line)
+type CircuitLogSink func(caller, msg string)
+
+// WithCircuitLogSink is a prover option that routes the logs printed by the circuit to sink,
+// instead of the circuit logger (see WithCircuitLogger). The test engine also routes the logs to
+// the sink (see test.WithBackendProverOptions).
+func WithCircuitLogSink(sink CircuitLogSink) ProverOption {
+	return func(opt *ProverConfig) error {
+		if sink == nil {
+			return errors.New("nil circuit log sink")
+		}
+		opt.CircuitLogSink = sink
+		return nil
+	}
+}
+
+// WithContext is a prover option that sets the context of the prover. The prover checks the
+// context between its stages (see ProverStage) and returns the context error once it is done.
+func WithContext(ctx context.Context) ProverOption {
+	return func(opt *ProverConfig) error {
+		opt.Ctx = ctx
+		return nil
+	}
+}
+
+// WithProgress is a prover option that registers a callback notified when each stage of the
+// prover starts and completes. Stages may run concurrently, so the callback may be called
+// from several goroutines at once.
+func WithProgress(progress func(ProgressEvent)) ProverOption {
+	return func(opt *ProverConfig) error {
+		opt.Progress = progress
+		return nil
+	}
+}
+
+// ProverStage identifies a stage of a prover, reported to the WithProgress callback
+type ProverStage string
+
+const (
+	StageSolve ProverStage = "solve" // constraint system solver
+
+	// groth16
+	StageFFT   ProverStage = "fft"    // quotient polynomial, computeH
+	StageMSMA  ProverStage = "msm A"  // [A]1 multi-exponentiation
+	StageMSMB1 ProverStage = "msm B1" // [B]1 multi-exponentiation
+	StageMSMB2 ProverStage = "msm B2" // [B]2 multi-exponentiation
+	StageMSMK  ProverStage = "msm K"  // [K]1 multi-exponentiation
+	StageMSMZ  ProverStage = "msm Z"  // [Z]1 multi-exponentiation
+
+	// plonk and plonkFRI
+	StageRound1 ProverStage = "round 1" // commitments to l, r, o
+	StageRound2 ProverStage = "round 2" // permutation polynomial z
+	StageRound3 ProverStage = "round 3" // quotient polynomial h
+	StageRound4 ProverStage = "round 4" // evaluations at ζ, linearized polynomial
+	StageRound5 ProverStage = "round 5" // opening proofs
+)
+
+// ProgressEvent is sent to the WithProgress callback when a stage of the prover starts
+// and when it completes
+type ProgressEvent struct {
+	Stage ProverStage
+	Done  bool // false when the stage starts, true when it completes
+}
+
+// BeginStage notifies the progress callback that stage starts. It returns the context
+// error if the context is done, in which case the prover must not start the stage.
+func (cfg *ProverConfig) BeginStage(stage ProverStage) error {
+	if cfg.Ctx != nil {
+		if err := cfg.Ctx.Err(); err != nil {
+			return err
+		}
+	}
+	if cfg.Progress != nil {
+		cfg.Progress(ProgressEvent{Stage: stage})
+	}
+	if cfg.timer != nil {
+		cfg.timer.begin(stage)
+	}
+	return nil
+}
+
+// EndStage notifies the progress callback that stage completed
+func (cfg *ProverConfig) EndStage(stage ProverStage) {
+	if cfg.timer != nil {
+		cfg.timer.end(stage)
+	}
+	if cfg.Progress != nil {
+		cfg.Progress(ProgressEvent{Stage: stage, Done: true})
+	}
+}
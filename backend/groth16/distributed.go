@@ -0,0 +1,229 @@
+// Copyright 2020 ConsenSys AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package groth16
+
+import (
+	"errors"
+	"net/rpc"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/constraint"
+	cs_bls12377 "github.com/consensys/gnark/constraint/bls12-377"
+	cs_bls12381 "github.com/consensys/gnark/constraint/bls12-381"
+	cs_bls24315 "github.com/consensys/gnark/constraint/bls24-315"
+	cs_bls24317 "github.com/consensys/gnark/constraint/bls24-317"
+	cs_bn254 "github.com/consensys/gnark/constraint/bn254"
+	cs_bw6633 "github.com/consensys/gnark/constraint/bw6-633"
+	cs_bw6761 "github.com/consensys/gnark/constraint/bw6-761"
+
+	fr_bls12377 "github.com/consensys/gnark-crypto/ecc/bls12-377/fr"
+	fr_bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
+	fr_bls24315 "github.com/consensys/gnark-crypto/ecc/bls24-315/fr"
+	fr_bls24317 "github.com/consensys/gnark-crypto/ecc/bls24-317/fr"
+	fr_bn254 "github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	fr_bw6633 "github.com/consensys/gnark-crypto/ecc/bw6-633/fr"
+	fr_bw6761 "github.com/consensys/gnark-crypto/ecc/bw6-761/fr"
+
+	groth16_bls12377 "github.com/consensys/gnark/internal/backend/bls12-377/groth16"
+	groth16_bls12381 "github.com/consensys/gnark/internal/backend/bls12-381/groth16"
+	groth16_bls24315 "github.com/consensys/gnark/internal/backend/bls24-315/groth16"
+	groth16_bls24317 "github.com/consensys/gnark/internal/backend/bls24-317/groth16"
+	groth16_bn254 "github.com/consensys/gnark/internal/backend/bn254/groth16"
+	groth16_bw6633 "github.com/consensys/gnark/internal/backend/bw6-633/groth16"
+	groth16_bw6761 "github.com/consensys/gnark/internal/backend/bw6-761/groth16"
+)
+
+// Worker computes the FFTs and multi-exponentiations of ProveDistributed.
+//
+// it's underlying implementation is strongly typed with the curve (see gnark/internal/backend)
+type Worker interface {
+	CurveID() ecc.ID
+}
+
+// ErrWorkerCurveMismatch is returned by ProveDistributed when a worker is not defined on the curve of the circuit
+var ErrWorkerCurveMismatch = errors.New("worker curve doesn't match the circuit curve")
+
+// NewLocalWorker returns a Worker computing work units in process, with pk.
+func NewLocalWorker(pk ProvingKey) Worker {
+	switch _pk := pk.(type) {
+	case *groth16_bls12377.ProvingKey:
+		return groth16_bls12377.NewLocalWorker(_pk)
+	case *groth16_bls12381.ProvingKey:
+		return groth16_bls12381.NewLocalWorker(_pk)
+	case *groth16_bn254.ProvingKey:
+		return groth16_bn254.NewLocalWorker(_pk)
+	case *groth16_bw6761.ProvingKey:
+		return groth16_bw6761.NewLocalWorker(_pk)
+	case *groth16_bls24317.ProvingKey:
+		return groth16_bls24317.NewLocalWorker(_pk)
+	case *groth16_bls24315.ProvingKey:
+		return groth16_bls24315.NewLocalWorker(_pk)
+	case *groth16_bw6633.ProvingKey:
+		return groth16_bw6633.NewLocalWorker(_pk)
+	default:
+		panic("unrecognized ProvingKey curve type")
+	}
+}
+
+// RegisterWorker registers on server a worker computing work units with pk. The worker
+// can then be reached from another process with NewRPCWorker.
+func RegisterWorker(server *rpc.Server, pk ProvingKey) error {
+	switch _pk := pk.(type) {
+	case *groth16_bls12377.ProvingKey:
+		return groth16_bls12377.RegisterWorker(server, _pk)
+	case *groth16_bls12381.ProvingKey:
+		return groth16_bls12381.RegisterWorker(server, _pk)
+	case *groth16_bn254.ProvingKey:
+		return groth16_bn254.RegisterWorker(server, _pk)
+	case *groth16_bw6761.ProvingKey:
+		return groth16_bw6761.RegisterWorker(server, _pk)
+	case *groth16_bls24317.ProvingKey:
+		return groth16_bls24317.RegisterWorker(server, _pk)
+	case *groth16_bls24315.ProvingKey:
+		return groth16_bls24315.RegisterWorker(server, _pk)
+	case *groth16_bw6633.ProvingKey:
+		return groth16_bw6633.RegisterWorker(server, _pk)
+	default:
+		panic("unrecognized ProvingKey curve type")
+	}
+}
+
+// NewRPCWorker returns a Worker forwarding work units through client to a worker registered
+// with RegisterWorker.
+func NewRPCWorker(curveID ecc.ID, client *rpc.Client) Worker {
+	switch curveID {
+	case ecc.BLS12_377:
+		return groth16_bls12377.NewRPCWorker(client)
+	case ecc.BLS12_381:
+		return groth16_bls12381.NewRPCWorker(client)
+	case ecc.BN254:
+		return groth16_bn254.NewRPCWorker(client)
+	case ecc.BW6_761:
+		return groth16_bw6761.NewRPCWorker(client)
+	case ecc.BLS24_317:
+		return groth16_bls24317.NewRPCWorker(client)
+	case ecc.BLS24_315:
+		return groth16_bls24315.NewRPCWorker(client)
+	case ecc.BW6_633:
+		return groth16_bw6633.NewRPCWorker(client)
+	default:
+		panic("not implemented")
+	}
+}
+
+// ProveDistributed generates a proof like Prove, but splits the FFTs and the multi-exponentiations
+// in work units computed by the workers. The workers must hold the same proving key as pk; the
+// point vectors of pk are not accessed by ProveDistributed itself.
+//
+// Note that the workers learn the solution of the constraint system.
+func ProveDistributed(r1cs constraint.ConstraintSystem, pk ProvingKey, fullWitness witness.Witness, workers []Worker, opts ...backend.ProverOption) (Proof, error) {
+
+	// apply options
+	opt, err := backend.NewProverConfig(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	switch _r1cs := r1cs.(type) {
+	case *cs_bls12377.R1CS:
+		w, ok := fullWitness.Vector().(fr_bls12377.Vector)
+		if !ok {
+			return nil, witness.ErrInvalidWitness
+		}
+		_workers := make([]groth16_bls12377.Worker, len(workers))
+		for i := range workers {
+			if _workers[i], ok = workers[i].(groth16_bls12377.Worker); !ok {
+				return nil, ErrWorkerCurveMismatch
+			}
+		}
+		return groth16_bls12377.ProveDistributed(_r1cs, pk.(*groth16_bls12377.ProvingKey), w, opt, _workers)
+	case *cs_bls12381.R1CS:
+		w, ok := fullWitness.Vector().(fr_bls12381.Vector)
+		if !ok {
+			return nil, witness.ErrInvalidWitness
+		}
+		_workers := make([]groth16_bls12381.Worker, len(workers))
+		for i := range workers {
+			if _workers[i], ok = workers[i].(groth16_bls12381.Worker); !ok {
+				return nil, ErrWorkerCurveMismatch
+			}
+		}
+		return groth16_bls12381.ProveDistributed(_r1cs, pk.(*groth16_bls12381.ProvingKey), w, opt, _workers)
+	case *cs_bn254.R1CS:
+		w, ok := fullWitness.Vector().(fr_bn254.Vector)
+		if !ok {
+			return nil, witness.ErrInvalidWitness
+		}
+		_workers := make([]groth16_bn254.Worker, len(workers))
+		for i := range workers {
+			if _workers[i], ok = workers[i].(groth16_bn254.Worker); !ok {
+				return nil, ErrWorkerCurveMismatch
+			}
+		}
+		return groth16_bn254.ProveDistributed(_r1cs, pk.(*groth16_bn254.ProvingKey), w, opt, _workers)
+	case *cs_bw6761.R1CS:
+		w, ok := fullWitness.Vector().(fr_bw6761.Vector)
+		if !ok {
+			return nil, witness.ErrInvalidWitness
+		}
+		_workers := make([]groth16_bw6761.Worker, len(workers))
+		for i := range workers {
+			if _workers[i], ok = workers[i].(groth16_bw6761.Worker); !ok {
+				return nil, ErrWorkerCurveMismatch
+			}
+		}
+		return groth16_bw6761.ProveDistributed(_r1cs, pk.(*groth16_bw6761.ProvingKey), w, opt, _workers)
+	case *cs_bls24317.R1CS:
+		w, ok := fullWitness.Vector().(fr_bls24317.Vector)
+		if !ok {
+			return nil, witness.ErrInvalidWitness
+		}
+		_workers := make([]groth16_bls24317.Worker, len(workers))
+		for i := range workers {
+			if _workers[i], ok = workers[i].(groth16_bls24317.Worker); !ok {
+				return nil, ErrWorkerCurveMismatch
+			}
+		}
+		return groth16_bls24317.ProveDistributed(_r1cs, pk.(*groth16_bls24317.ProvingKey), w, opt, _workers)
+	case *cs_bls24315.R1CS:
+		w, ok := fullWitness.Vector().(fr_bls24315.Vector)
+		if !ok {
+			return nil, witness.ErrInvalidWitness
+		}
+		_workers := make([]groth16_bls24315.Worker, len(workers))
+		for i := range workers {
+			if _workers[i], ok = workers[i].(groth16_bls24315.Worker); !ok {
+				return nil, ErrWorkerCurveMismatch
+			}
+		}
+		return groth16_bls24315.ProveDistributed(_r1cs, pk.(*groth16_bls24315.ProvingKey), w, opt, _workers)
+	case *cs_bw6633.R1CS:
+		w, ok := fullWitness.Vector().(fr_bw6633.Vector)
+		if !ok {
+			return nil, witness.ErrInvalidWitness
+		}
+		_workers := make([]groth16_bw6633.Worker, len(workers))
+		for i := range workers {
+			if _workers[i], ok = workers[i].(groth16_bw6633.Worker); !ok {
+				return nil, ErrWorkerCurveMismatch
+			}
+		}
+		return groth16_bw6633.ProveDistributed(_r1cs, pk.(*groth16_bw6633.ProvingKey), w, opt, _workers)
+	default:
+		panic("unrecognized R1CS curve type")
+	}
+}
@@ -20,6 +20,7 @@
 package groth16
 
 import (
+	"errors"
 	"io"
 
 	"github.com/consensys/gnark-crypto/ecc"
@@ -33,6 +34,7 @@ import (
 	cs_bn254 "github.com/consensys/gnark/constraint/bn254"
 	cs_bw6633 "github.com/consensys/gnark/constraint/bw6-633"
 	cs_bw6761 "github.com/consensys/gnark/constraint/bw6-761"
+	"github.com/consensys/gnark/internal/mmap"
 
 	fr_bls12377 "github.com/consensys/gnark-crypto/ecc/bls12-377/fr"
 	fr_bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
@@ -80,6 +82,16 @@ type ProvingKey interface {
 	// NbG2 returns the number of G2 elements in the ProvingKey
 	NbG2() int
 
+	// WriteDump writes the ProvingKey in a format that can be memory mapped, see OpenProvingKeyDump
+	WriteDump(w io.Writer) (int64, error)
+
+	// ReadDump reads a ProvingKey written by WriteDump, without checking the points
+	ReadDump(r io.Reader) (int64, error)
+
+	// UnsafeMapDump sets the ProvingKey from a dump written by WriteDump, without copying
+	// its point vectors; data must outlive the ProvingKey
+	UnsafeMapDump(data []byte) error
+
 	IsDifferent(interface{}) bool
 }
 
@@ -159,6 +171,20 @@ func Verify(proof Proof, vk VerifyingKey, publicWitness witness.Witness) error {
 	}
 }
 
+// SolidityCalldata returns the ABI encoded call to the verifyProof method of the
+// contract generated by vk.ExportSolidity. Only BN254 is supported.
+func SolidityCalldata(proof Proof, vk VerifyingKey, publicWitness witness.Witness) ([]byte, error) {
+	_proof, ok := proof.(*groth16_bn254.Proof)
+	if !ok {
+		return nil, errors.New("solidity calldata is only supported on BN254")
+	}
+	w, ok := publicWitness.Vector().(fr_bn254.Vector)
+	if !ok {
+		return nil, witness.ErrInvalidWitness
+	}
+	return groth16_bn254.SolidityCalldata(_proof, vk.(*groth16_bn254.VerifyingKey), w)
+}
+
 // Prove runs the groth16.Prove algorithm.
 //
 // if the force flag is set:
@@ -363,6 +389,25 @@ func NewProvingKey(curveID ecc.ID) ProvingKey {
 	return pk
 }
 
+// OpenProvingKeyDump memory maps a ProvingKey written by ProvingKey.WriteDump.
+//
+// The point vectors of the key are not loaded in memory: the pages holding the points used
+// by a multi-exponentiation are read from the file by the OS when Prove needs them, and can be
+// evicted afterwards. The returned io.Closer releases the mapping, it must be called once the key
+// is no longer used. Points are not checked to be on the curve or in the correct subgroup.
+func OpenProvingKeyDump(curveID ecc.ID, path string) (ProvingKey, io.Closer, error) {
+	f, err := mmap.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	pk := NewProvingKey(curveID)
+	if err := pk.UnsafeMapDump(f.Data); err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return pk, f, nil
+}
+
 // NewVerifyingKey instantiates a curve-typed VerifyingKey and returns an interface
 // This function exists for serialization purposes
 func NewVerifyingKey(curveID ecc.ID) VerifyingKey {
@@ -1,17 +1,202 @@
 package groth16_test
 
 import (
+	"context"
 	"math/big"
+	"net"
+	"net/rpc"
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
 
 	"github.com/consensys/gnark"
 	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend"
 	"github.com/consensys/gnark/backend/groth16"
 	"github.com/consensys/gnark/constraint"
 	"github.com/consensys/gnark/frontend"
 	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/stretchr/testify/require"
 )
 
+func TestProvingKeyDump(t *testing.T) {
+	for _, curve := range getCurves() {
+		t.Run(curve.String(), func(t *testing.T) {
+			assert := require.New(t)
+
+			ccs, err := frontend.Compile(curve.ScalarField(), r1cs.NewBuilder, &refCircuit{nbConstraints: 10})
+			assert.NoError(err)
+			pk, vk, err := groth16.Setup(ccs)
+			assert.NoError(err)
+
+			path := filepath.Join(t.TempDir(), "pk.dump")
+			f, err := os.Create(path)
+			assert.NoError(err)
+			_, err = pk.WriteDump(f)
+			assert.NoError(err)
+			assert.NoError(f.Close())
+
+			mappedPk, closer, err := groth16.OpenProvingKeyDump(curve, path)
+			assert.NoError(err)
+			defer closer.Close()
+			assert.False(pk.IsDifferent(mappedPk))
+
+			y := new(big.Int).Exp(big.NewInt(2), new(big.Int).Lsh(big.NewInt(1), 10), curve.ScalarField())
+			fullWitness, err := frontend.NewWitness(&refCircuit{X: 2, Y: y}, curve.ScalarField())
+			assert.NoError(err)
+			publicWitness, err := fullWitness.Public()
+			assert.NoError(err)
+
+			proof, err := groth16.Prove(ccs, mappedPk, fullWitness)
+			assert.NoError(err)
+			assert.NoError(groth16.Verify(proof, vk, publicWitness))
+		})
+	}
+}
+
+func TestProveDistributed(t *testing.T) {
+	assert := require.New(t)
+
+	ccs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &refCircuit{nbConstraints: 10})
+	assert.NoError(err)
+	pk, vk, err := groth16.Setup(ccs)
+	assert.NoError(err)
+
+	server := rpc.NewServer()
+	assert.NoError(groth16.RegisterWorker(server, pk))
+	serverConn, clientConn := net.Pipe()
+	go server.ServeConn(serverConn)
+	client := rpc.NewClient(clientConn)
+	defer client.Close()
+
+	y := new(big.Int).Exp(big.NewInt(2), new(big.Int).Lsh(big.NewInt(1), 10), ecc.BN254.ScalarField())
+	fullWitness, err := frontend.NewWitness(&refCircuit{X: 2, Y: y}, ecc.BN254.ScalarField())
+	assert.NoError(err)
+	publicWitness, err := fullWitness.Public()
+	assert.NoError(err)
+
+	workers := []groth16.Worker{groth16.NewRPCWorker(ecc.BN254, client), groth16.NewLocalWorker(pk)}
+	proof, err := groth16.ProveDistributed(ccs, pk, fullWitness, workers)
+	assert.NoError(err)
+	assert.NoError(groth16.Verify(proof, vk, publicWitness))
+
+	// workers must be defined on the circuit curve
+	workers = []groth16.Worker{groth16.NewRPCWorker(ecc.BLS12_381, client)}
+	_, err = groth16.ProveDistributed(ccs, pk, fullWitness, workers)
+	assert.ErrorIs(err, groth16.ErrWorkerCurveMismatch)
+}
+
+func TestProveProgress(t *testing.T) {
+	assert := require.New(t)
+
+	ccs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &refCircuit{nbConstraints: 10})
+	assert.NoError(err)
+	pk, vk, err := groth16.Setup(ccs)
+	assert.NoError(err)
+
+	y := new(big.Int).Exp(big.NewInt(2), new(big.Int).Lsh(big.NewInt(1), 10), ecc.BN254.ScalarField())
+	fullWitness, err := frontend.NewWitness(&refCircuit{X: 2, Y: y}, ecc.BN254.ScalarField())
+	assert.NoError(err)
+	publicWitness, err := fullWitness.Public()
+	assert.NoError(err)
+
+	var lock sync.Mutex
+	done := make(map[backend.ProverStage]int)
+	progress := backend.WithProgress(func(e backend.ProgressEvent) {
+		lock.Lock()
+		defer lock.Unlock()
+		if e.Done {
+			done[e.Stage]++
+		}
+	})
+	proof, err := groth16.Prove(ccs, pk, fullWitness, progress)
+	assert.NoError(err)
+	assert.NoError(groth16.Verify(proof, vk, publicWitness))
+	for _, stage := range []backend.ProverStage{
+		backend.StageSolve, backend.StageFFT,
+		backend.StageMSMA, backend.StageMSMB1, backend.StageMSMB2, backend.StageMSMK, backend.StageMSMZ,
+	} {
+		assert.Equal(1, done[stage], stage)
+	}
+
+	// a cancelled prover stops before solving
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = groth16.Prove(ccs, pk, fullWitness, backend.WithContext(ctx))
+	assert.ErrorIs(err, context.Canceled)
+}
+
+func TestProveMetrics(t *testing.T) {
+	assert := require.New(t)
+
+	ccs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &refCircuit{nbConstraints: 10})
+	assert.NoError(err)
+	pk, _, err := groth16.Setup(ccs)
+	assert.NoError(err)
+
+	y := new(big.Int).Exp(big.NewInt(2), new(big.Int).Lsh(big.NewInt(1), 10), ecc.BN254.ScalarField())
+	fullWitness, err := frontend.NewWitness(&refCircuit{X: 2, Y: y}, ecc.BN254.ScalarField())
+	assert.NoError(err)
+
+	var metrics backend.ProverMetrics
+	var collector backend.MemoryCollector
+	_, err = groth16.Prove(ccs, pk, fullWitness, backend.WithMetrics(&metrics), backend.WithMetricsHook(&collector))
+	assert.NoError(err)
+
+	assert.Len(metrics.Stages, 7)
+	assert.Equal(metrics.Stages, collector.Stages())
+	for _, m := range metrics.Stages {
+		assert.LessOrEqual(m.Duration, metrics.Total, m.Stage)
+	}
+	assert.NotZero(metrics.Duration(backend.StageSolve))
+	assert.NotZero(metrics.Duration(backend.StageMSMK))
+
+	// metrics are reset when the option is reused
+	_, err = groth16.Prove(ccs, pk, fullWitness, backend.WithMetrics(&metrics))
+	assert.NoError(err)
+	assert.Len(metrics.Stages, 7)
+}
+
+func TestProveWorkerPool(t *testing.T) {
+	assert := require.New(t)
+
+	ccs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &refCircuit{nbConstraints: 10})
+	assert.NoError(err)
+	pk, vk, err := groth16.Setup(ccs)
+	assert.NoError(err)
+
+	y := new(big.Int).Exp(big.NewInt(2), new(big.Int).Lsh(big.NewInt(1), 10), ecc.BN254.ScalarField())
+	fullWitness, err := frontend.NewWitness(&refCircuit{X: 2, Y: y}, ecc.BN254.ScalarField())
+	assert.NoError(err)
+	publicWitness, err := fullWitness.Public()
+	assert.NoError(err)
+
+	// concurrent provers share a pool of 2 workers
+	pool := backend.NewWorkerPool(2)
+	var wg sync.WaitGroup
+	proofs := make([]groth16.Proof, 4)
+	errs := make([]error, len(proofs))
+	for i := range proofs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			proofs[i], errs[i] = groth16.Prove(ccs, pk, fullWitness, backend.WithWorkerPool(pool), backend.WithSolverTaskSize(1))
+		}(i)
+	}
+	wg.Wait()
+	for i := range proofs {
+		assert.NoError(errs[i])
+		assert.NoError(groth16.Verify(proofs[i], vk, publicWitness))
+	}
+
+	// all the workers are released
+	assert.Equal(2, pool.Acquire(3))
+
+	_, err = groth16.Prove(ccs, pk, fullWitness, backend.WithSolverTaskSize(0))
+	assert.Error(err)
+}
+
 //--------------------//
 //     benches		  //
 //--------------------//
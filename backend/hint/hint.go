@@ -61,6 +61,31 @@ proving option.
 
 In the init() method of the gadget, call the method Register(hintFn) method on
 the hint function hintFn to register a hint function in the package registry.
+
+# Hint identifiers
+
+Compiled constraint systems refer to hint functions by their ID (see UUID). By default, the
+ID is derived from the Go function name, which changes when the function is renamed or moved,
+and for anonymous functions, when other anonymous functions are added to the package; the
+constraint systems serialized before the change can't be solved anymore. To avoid it, bind the
+hint function to an explicit, versioned identifier when registering it:
+
+	func init() {
+		hint.RegisterNamed("mygadget/v1/div", divHint)
+	}
+
+The name is stored in the constraint system and the function is resolved by it at solving
+time. Constraint systems compiled before the hint was named keep referring to its previous ID
+(see LegacyUUID), which the prover resolves as well.
+
+# Hint providers
+
+The hint functions which are not linked in the prover binary, for example hints calling
+external oracles, can be computed by a Provider, which the solver calls with the name of the
+hint (see backend.WithHintProvider). This package implements providers calling in-process
+functions (Map), a remote server (RPCProvider) or a subprocess over its standard input and
+output (Subprocess). A Transcript records the results of the hint calls, such that the
+solution can be reproduced without calling the hints again (see backend.WithHintTranscript).
 */
 package hint
 
@@ -84,19 +109,45 @@ type ID uint32
 //	b[0] and b[1].
 type Function func(field *big.Int, inputs []*big.Int, outputs []*big.Int) error
 
-// UUID is a reference function for computing the hint ID based on a function name
+// UUID is a reference function for computing the hint ID based on the name of the function
+// (see Name).
+//
+// Hints registered with RegisterNamed have an ID derived from their explicit name, which doesn't
+// change when the code moves. Otherwise, the ID is derived from the Go function name; if fn is an
+// anonymous function, its name is package.glob..funcN, and N may change when new anonymous
+// functions are added in the package, so will the UUID.
 func UUID(fn Function) ID {
-	hf := fnv.New32a()
-	name := Name(fn)
-
-	// TODO relying on name to derive UUID is risky; if fn is an anonymous func, wil be package.glob..funcN
-	// and if new anonymous functions are added in the package, N may change, so will UUID.
-	hf.Write([]byte(name)) // #nosec G104 -- does not err
+	return NamedUUID(Name(fn))
+}
 
-	return ID(hf.Sum32())
+// LegacyUUID returns the hint ID derived from the Go function name of fn, which is the ID
+// given to fn by constraint systems compiled before it was registered with RegisterNamed.
+func LegacyUUID(fn Function) ID {
+	return NamedUUID(goName(fn))
 }
 
+// Name returns the name given to fn with RegisterNamed, or its Go function name.
 func Name(fn Function) string {
+	registryM.RLock()
+	defer registryM.RUnlock()
+	return nameOf(fn)
+}
+
+func nameOf(fn Function) string {
+	if n, ok := names[reflect.ValueOf(fn).Pointer()]; ok {
+		return n
+	}
+	return goName(fn)
+}
+
+func goName(fn Function) string {
 	fnptr := reflect.ValueOf(fn).Pointer()
 	return runtime.FuncForPC(fnptr).Name()
 }
+
+// NamedUUID returns the hint ID derived from the given name.
+func NamedUUID(name string) ID {
+	hf := fnv.New32a()
+	hf.Write([]byte(name)) // #nosec G104 -- does not err
+	return ID(hf.Sum32())
+}
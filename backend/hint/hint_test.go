@@ -0,0 +1,67 @@
+package hint_test
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/hint"
+	cs "github.com/consensys/gnark/constraint/bn254"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/stretchr/testify/require"
+)
+
+func double(_ *big.Int, inputs []*big.Int, outputs []*big.Int) error {
+	outputs[0].Lsh(inputs[0], 1)
+	return nil
+}
+
+type doubleCircuit struct {
+	X, Y frontend.Variable
+}
+
+func (c *doubleCircuit) Define(api frontend.API) error {
+	res, err := api.Compiler().NewHint(double, 1, c.X)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(res[0], c.Y)
+	api.AssertIsEqual(api.Add(c.X, c.X), c.Y)
+	return nil
+}
+
+func TestRegisterNamed(t *testing.T) {
+	assert := require.New(t)
+	const name = "hint_test/v1/double"
+
+	witness, err := frontend.NewWitness(&doubleCircuit{X: 3, Y: 6}, ecc.BN254.ScalarField())
+	assert.NoError(err)
+
+	// a constraint system compiled before the hint is named
+	hint.Register(double)
+	legacy, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &doubleCircuit{})
+	assert.NoError(err)
+	assert.Equal(hint.LegacyUUID(double), hint.UUID(double))
+	var buf bytes.Buffer
+	_, err = legacy.WriteTo(&buf)
+	assert.NoError(err)
+
+	hint.RegisterNamed(name, double)
+	assert.Equal(name, hint.Name(double))
+	assert.NotEqual(hint.LegacyUUID(double), hint.UUID(double))
+	assert.Panics(func() { hint.RegisterNamed("hint_test/v2/double", double) })
+	assert.Panics(func() { hint.RegisterNamed(name, hint.InvZero) })
+
+	ccs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &doubleCircuit{})
+	assert.NoError(err)
+	assert.Equal(map[hint.ID]string{hint.UUID(double): name}, ccs.(*cs.R1CS).MHintsDependencies)
+	assert.NoError(ccs.IsSolved(witness))
+
+	// the previously serialized constraint system is still solved
+	reloaded := cs.NewR1CS(0)
+	_, err = reloaded.ReadFrom(&buf)
+	assert.NoError(err)
+	assert.NoError(reloaded.IsSolved(witness))
+}
@@ -0,0 +1,50 @@
+package hint
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// ErrUnknownHint is returned by a Provider which can't compute the requested hint.
+var ErrUnknownHint = errors.New("unknown hint")
+
+// Provider computes hints by name, for the hint functions which are not linked in the prover
+// binary (e.g. hints calling external oracles). The name of a hint is the one recorded in the
+// constraint system, see Name.
+//
+// The solver calls the provider for the hints whose functions were not given with
+// backend.WithHints, possibly concurrently.
+type Provider interface {
+	// Call computes the outputs of the hint with the given name. The outputs are initialized
+	// to zero.
+	Call(name string, field *big.Int, inputs []*big.Int, outputs []*big.Int) error
+}
+
+// Map is a Provider calling in-process hint functions by name.
+type Map map[string]Function
+
+// NewMap returns a Map of the given hint functions by their name (see Name).
+func NewMap(hintFns ...Function) Map {
+	m := make(Map, len(hintFns))
+	for _, fn := range hintFns {
+		m[Name(fn)] = fn
+	}
+	return m
+}
+
+// Call implements Provider
+func (m Map) Call(name string, field *big.Int, inputs []*big.Int, outputs []*big.Int) error {
+	fn, ok := m[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownHint, name)
+	}
+	return fn(field, inputs, outputs)
+}
+
+// Provided returns a hint function calling the provider p for the hint with the given name.
+func Provided(p Provider, name string) Function {
+	return func(field *big.Int, inputs []*big.Int, outputs []*big.Int) error {
+		return p.Call(name, field, inputs, outputs)
+	}
+}
@@ -0,0 +1,167 @@
+package hint_test
+
+import (
+	"bytes"
+	"math/big"
+	"net"
+	"net/rpc/jsonrpc"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/backend/hint"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/frontend/cs/scs"
+	"github.com/stretchr/testify/require"
+)
+
+// price is the value returned by the oracle
+var price = big.NewInt(7)
+
+func oracle(_ *big.Int, inputs []*big.Int, outputs []*big.Int) error {
+	outputs[0].Set(price)
+	return nil
+}
+
+type oracleCircuit struct {
+	X frontend.Variable
+	Y frontend.Variable `gnark:",public"`
+}
+
+func (c *oracleCircuit) Define(api frontend.API) error {
+	res, err := api.Compiler().NewHint(oracle, 1, c.X)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(api.Add(res[0], c.X), c.Y)
+	return nil
+}
+
+func TestMain(m *testing.M) {
+	// the test binary serves the hints when started by TestProviders
+	if os.Getenv("GNARK_HINT_SERVER") == "1" {
+		if err := hint.ServeStdio(hint.NewMap(oracle)); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func compileOracle(t *testing.T) ([]constraint.ConstraintSystem, witness.Witness) {
+	var res []constraint.ConstraintSystem
+	for _, builder := range []frontend.NewBuilder{r1cs.NewBuilder, scs.NewBuilder} {
+		ccs, err := frontend.Compile(ecc.BN254.ScalarField(), builder, &oracleCircuit{})
+		require.NoError(t, err)
+		res = append(res, ccs)
+	}
+	w, err := frontend.NewWitness(&oracleCircuit{X: 3, Y: 10}, ecc.BN254.ScalarField())
+	require.NoError(t, err)
+	return res, w
+}
+
+func TestProviders(t *testing.T) {
+	assert := require.New(t)
+	ccss, w := compileOracle(t)
+
+	// in-process
+	m := hint.NewMap(oracle)
+	assert.Contains(m, hint.Name(oracle))
+
+	// RPC
+	client, server := net.Pipe()
+	go func() {
+		_ = hint.ServeRPC(m, server)
+	}()
+	remote := hint.NewRPCProvider(jsonrpc.NewClient(client))
+	defer remote.Close()
+
+	// subprocess
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	cmd.Env = append(os.Environ(), "GNARK_HINT_SERVER=1")
+	subprocess, err := hint.StartSubprocess(cmd)
+	assert.NoError(err)
+
+	for _, ccs := range ccss {
+		assert.Error(ccs.IsSolved(w), "the oracle is not registered")
+		for _, p := range []hint.Provider{m, remote, subprocess} {
+			assert.NoError(ccs.IsSolved(w, backend.WithHintProvider(p)))
+		}
+		assert.Error(ccs.IsSolved(w, backend.WithHintProvider(hint.Map{})))
+	}
+	assert.NoError(subprocess.Close())
+
+	// unknown hints are reported
+	outputs := []*big.Int{new(big.Int)}
+	assert.ErrorIs(hint.Map{}.Call("unknown", ecc.BN254.ScalarField(), nil, outputs), hint.ErrUnknownHint)
+	assert.Error(remote.Call("unknown", ecc.BN254.ScalarField(), nil, outputs))
+}
+
+func TestTranscript(t *testing.T) {
+	assert := require.New(t)
+	ccss, w := compileOracle(t)
+	defer price.SetUint64(7)
+
+	for _, ccs := range ccss {
+		price.SetUint64(7)
+		transcript := hint.NewTranscript()
+		p := backend.WithHintProvider(hint.NewMap(oracle))
+		assert.NoError(ccs.IsSolved(w, p, backend.WithHintTranscript(transcript)))
+		assert.Equal(1, transcript.Len())
+
+		var buf bytes.Buffer
+		_, err := transcript.WriteTo(&buf)
+		assert.NoError(err)
+		transcript, err = hint.ReadTranscript(&buf)
+		assert.NoError(err)
+		assert.Equal([]hint.TranscriptEntry{{
+			Name:      hint.Name(oracle),
+			Field:     ecc.BN254.ScalarField(),
+			Inputs:    []*big.Int{big.NewInt(3)},
+			NbOutputs: 1,
+			Outputs:   []*big.Int{big.NewInt(7)},
+		}}, transcript.Entries())
+
+		// the oracle changed, the transcript reproduces the previous solution
+		price.SetUint64(8)
+		assert.Error(ccs.IsSolved(w, p))
+		assert.NoError(ccs.IsSolved(w, p, backend.WithHintTranscript(transcript)))
+		assert.NoError(ccs.IsSolved(w, backend.WithHintTranscript(transcript)))
+
+		// calls which are not recorded can't be replayed without the oracle
+		other, err := frontend.NewWitness(&oracleCircuit{X: 4, Y: 11}, ecc.BN254.ScalarField())
+		assert.NoError(err)
+		assert.Error(ccs.IsSolved(other, backend.WithHintTranscript(transcript)))
+	}
+}
+
+type toBinaryCircuit struct {
+	X frontend.Variable
+}
+
+func (c *toBinaryCircuit) Define(api frontend.API) error {
+	// the same hint is called on the same input with different numbers of outputs
+	api.ToBinary(c.X, 8)
+	api.ToBinary(c.X, 16)
+	return nil
+}
+
+func TestTranscriptNbOutputs(t *testing.T) {
+	assert := require.New(t)
+	for _, builder := range []frontend.NewBuilder{r1cs.NewBuilder, scs.NewBuilder} {
+		ccs, err := frontend.Compile(ecc.BN254.ScalarField(), builder, &toBinaryCircuit{})
+		assert.NoError(err)
+		w, err := frontend.NewWitness(&toBinaryCircuit{X: 5}, ecc.BN254.ScalarField())
+		assert.NoError(err)
+
+		transcript := hint.NewTranscript()
+		assert.NoError(ccs.IsSolved(w, backend.WithHintTranscript(transcript)))
+		assert.Equal(2, transcript.Len())
+		assert.NoError(ccs.IsSolved(w, backend.WithHintTranscript(transcript)))
+	}
+}
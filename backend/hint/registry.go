@@ -1,12 +1,15 @@
 package hint
 
 import (
+	"fmt"
+	"reflect"
 	"sync"
 
 	"github.com/consensys/gnark/logger"
 )
 
 var registry = make(map[ID]Function)
+var names = make(map[uintptr]string) // explicit names of the hint functions, see RegisterNamed
 var registryM sync.RWMutex
 
 // Register registers a hint function in the global registry.
@@ -14,8 +17,8 @@ func Register(hintFns ...Function) {
 	registryM.Lock()
 	defer registryM.Unlock()
 	for _, hintFn := range hintFns {
-		key := UUID(hintFn)
-		name := Name(hintFn)
+		name := nameOf(hintFn)
+		key := NamedUUID(name)
 		if _, ok := registry[key]; ok {
 			log := logger.Logger()
 			log.Warn().Str("name", name).Msg("function registered multiple times")
@@ -25,6 +28,43 @@ func Register(hintFns ...Function) {
 	}
 }
 
+// RegisterNamed registers a hint function in the global registry, bound to the given
+// identifier. The identifier is used instead of the Go function name to derive the hint ID (see
+// UUID), such that the compiled constraint systems don't depend on the location of the function
+// in the code. It should be versioned (e.g. "mygadget/v1/div") and changed when the semantics of
+// the function change.
+//
+// RegisterNamed must be called before compiling the circuits using fn, typically in an init()
+// method. It panics if the name is empty, or if the name or the function are already bound.
+func RegisterNamed(name string, fn Function) {
+	if name == "" {
+		panic("hint: empty name")
+	}
+	registryM.Lock()
+	defer registryM.Unlock()
+
+	ptr := reflect.ValueOf(fn).Pointer()
+	if n, ok := names[ptr]; ok {
+		if n == name {
+			log := logger.Logger()
+			log.Warn().Str("name", name).Msg("function registered multiple times")
+			return
+		}
+		panic(fmt.Sprintf("hint: %s already registered as %q", goName(fn), n))
+	}
+	key := NamedUUID(name)
+	if other, ok := registry[key]; ok {
+		panic(fmt.Sprintf("hint: %q already registered for %s", name, goName(other)))
+	}
+
+	// the function may have been registered under its Go function name
+	if f, ok := registry[NamedUUID(goName(fn))]; ok && reflect.ValueOf(f).Pointer() == ptr {
+		delete(registry, NamedUUID(goName(fn)))
+	}
+	names[ptr] = name
+	registry[key] = fn
+}
+
 // GetRegistered returns all registered hint functions.
 func GetRegistered() []Function {
 	registryM.RLock()
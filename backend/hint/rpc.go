@@ -0,0 +1,175 @@
+package hint
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"os/exec"
+)
+
+// RPCService is the name of the net/rpc service exposing a Provider (see RegisterRPC). Its
+// only method is Call, which takes CallArgs and returns CallReply.
+//
+// With the JSON-RPC codec used by ServeRPC, DialRPC and StartSubprocess, a request is
+//
+//	{"method": "Hints.Call", "params": [{"Name": "...", "Field": 21888..., "Inputs": [1, 2], "NbOutputs": 1}], "id": 0}
+//
+// and its response
+//
+//	{"id": 0, "result": {"Outputs": [3]}, "error": null}
+//
+// such that hint servers can be implemented in any language.
+const RPCService = "Hints"
+
+// CallArgs are the arguments of the RPC method computing a hint.
+type CallArgs struct {
+	Name      string
+	Field     *big.Int
+	Inputs    []*big.Int
+	NbOutputs int
+}
+
+// CallReply is the reply of the RPC method computing a hint.
+type CallReply struct {
+	Outputs []*big.Int
+}
+
+type rpcService struct {
+	p Provider
+}
+
+func (s *rpcService) Call(args CallArgs, reply *CallReply) error {
+	if args.NbOutputs < 0 {
+		return fmt.Errorf("invalid number of outputs %d", args.NbOutputs)
+	}
+	reply.Outputs = make([]*big.Int, args.NbOutputs)
+	for i := range reply.Outputs {
+		reply.Outputs[i] = new(big.Int)
+	}
+	return s.p.Call(args.Name, args.Field, args.Inputs, reply.Outputs)
+}
+
+// RegisterRPC registers p as the RPCService of the server.
+func RegisterRPC(server *rpc.Server, p Provider) error {
+	return server.RegisterName(RPCService, &rpcService{p: p})
+}
+
+// ServeRPC serves the hints of p on the connection with the JSON-RPC codec, and blocks until
+// the client hangs up.
+func ServeRPC(p Provider, conn io.ReadWriteCloser) error {
+	server := rpc.NewServer()
+	if err := RegisterRPC(server, p); err != nil {
+		return err
+	}
+	server.ServeCodec(jsonrpc.NewServerCodec(conn))
+	return nil
+}
+
+// ServeStdio serves the hints of p on the standard input and output, for the provider
+// started by StartSubprocess. It blocks until the standard input is closed.
+func ServeStdio(p Provider) error {
+	return ServeRPC(p, &pipe{r: os.Stdin, w: os.Stdout})
+}
+
+// RPCProvider is a Provider calling a remote RPCService.
+type RPCProvider struct {
+	client *rpc.Client
+}
+
+// NewRPCProvider returns a Provider calling the RPCService of the client.
+func NewRPCProvider(client *rpc.Client) *RPCProvider {
+	return &RPCProvider{client: client}
+}
+
+// DialRPC connects to a hint server at the given address with the JSON-RPC codec (see
+// net.Dial for the network and address formats).
+func DialRPC(network, address string) (*RPCProvider, error) {
+	client, err := jsonrpc.Dial(network, address)
+	if err != nil {
+		return nil, err
+	}
+	return NewRPCProvider(client), nil
+}
+
+// Call implements Provider
+func (p *RPCProvider) Call(name string, field *big.Int, inputs []*big.Int, outputs []*big.Int) error {
+	var reply CallReply
+	args := CallArgs{Name: name, Field: field, Inputs: inputs, NbOutputs: len(outputs)}
+	if err := p.client.Call(RPCService+".Call", args, &reply); err != nil {
+		return fmt.Errorf("hint %s: %w", name, err)
+	}
+	if len(reply.Outputs) != len(outputs) {
+		return fmt.Errorf("hint %s: expected %d outputs, got %d", name, len(outputs), len(reply.Outputs))
+	}
+	for i := range outputs {
+		if reply.Outputs[i] == nil {
+			return fmt.Errorf("hint %s: output %d missing", name, i)
+		}
+		outputs[i].Set(reply.Outputs[i])
+	}
+	return nil
+}
+
+// Close closes the connection to the server.
+func (p *RPCProvider) Close() error {
+	return p.client.Close()
+}
+
+// Subprocess is a Provider calling a subprocess over its standard input and output, with the
+// JSON-RPC codec. The subprocess is typically a Go program calling ServeStdio.
+type Subprocess struct {
+	*RPCProvider
+	cmd *exec.Cmd
+}
+
+// StartSubprocess starts the command and returns a Provider calling it. The standard input and
+// output of the command must not be set.
+func StartSubprocess(cmd *exec.Cmd) (*Subprocess, error) {
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	client := jsonrpc.NewClient(&pipe{r: stdout, w: stdin})
+	return &Subprocess{RPCProvider: NewRPCProvider(client), cmd: cmd}, nil
+}
+
+// Close closes the standard input of the subprocess and waits for it to exit.
+func (s *Subprocess) Close() error {
+	err := s.RPCProvider.Close()
+	if werr := s.cmd.Wait(); werr != nil {
+		return werr
+	}
+	return err
+}
+
+// pipe is a connection made of a reader and a writer
+type pipe struct {
+	r io.ReadCloser
+	w io.WriteCloser
+}
+
+func (p *pipe) Read(b []byte) (int, error) {
+	return p.r.Read(b)
+}
+
+func (p *pipe) Write(b []byte) (int, error) {
+	return p.w.Write(b)
+}
+
+func (p *pipe) Close() error {
+	werr := p.w.Close()
+	if err := p.r.Close(); err != nil {
+		return err
+	}
+	return werr
+}
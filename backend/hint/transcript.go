@@ -0,0 +1,155 @@
+package hint
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Transcript records the results of hint calls, such that the solver can be run again without
+// calling the hint functions or providers, and obtain the same solution. This makes the proofs
+// which rely on non-deterministic hints (e.g. hints calling external oracles) reproducible.
+//
+// A Transcript is also a cache: a hint called twice over the same field with the same inputs
+// and number of outputs returns the recorded outputs. See backend.WithHintTranscript.
+type Transcript struct {
+	mu      sync.Mutex
+	entries map[string]TranscriptEntry
+}
+
+// TranscriptEntry is a hint call recorded in a Transcript
+type TranscriptEntry struct {
+	Name      string
+	Field     *big.Int // modulus of the field the hint is called over
+	Inputs    []*big.Int
+	NbOutputs int
+	Outputs   []*big.Int
+}
+
+// NewTranscript returns a Transcript with the given hint calls
+func NewTranscript(entries ...TranscriptEntry) *Transcript {
+	t := &Transcript{entries: make(map[string]TranscriptEntry, len(entries))}
+	for _, e := range entries {
+		t.entries[transcriptKey(e.Name, e.Field, e.Inputs, e.NbOutputs)] = e
+	}
+	return t
+}
+
+// ReadTranscript reads a Transcript written by Transcript.WriteTo
+func ReadTranscript(r io.Reader) (*Transcript, error) {
+	var entries []TranscriptEntry
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if e.Field == nil {
+			return nil, fmt.Errorf("hint %s: transcript entry without field", e.Name)
+		}
+		if len(e.Outputs) != e.NbOutputs {
+			return nil, fmt.Errorf("hint %s: transcript has %d outputs, expected %d", e.Name, len(e.Outputs), e.NbOutputs)
+		}
+	}
+	return NewTranscript(entries...), nil
+}
+
+// Wrap returns a hint function which returns the outputs recorded for the hint with the given
+// name if any, and otherwise calls fn and records its outputs. If fn is nil, the hint can only
+// be replayed from the transcript.
+func (t *Transcript) Wrap(name string, fn Function) Function {
+	return func(field *big.Int, inputs []*big.Int, outputs []*big.Int) error {
+		key := transcriptKey(name, field, inputs, len(outputs))
+		t.mu.Lock()
+		e, ok := t.entries[key]
+		t.mu.Unlock()
+		if ok {
+			if len(e.Outputs) != len(outputs) {
+				return fmt.Errorf("hint %s: transcript has %d outputs, expected %d", name, len(e.Outputs), len(outputs))
+			}
+			for i := range outputs {
+				outputs[i].Set(e.Outputs[i])
+			}
+			return nil
+		}
+		if fn == nil {
+			return fmt.Errorf("hint %s: call not found in transcript", name)
+		}
+
+		if err := fn(field, inputs, outputs); err != nil {
+			return err
+		}
+		e = TranscriptEntry{
+			Name:      name,
+			Field:     new(big.Int).Set(field),
+			Inputs:    make([]*big.Int, len(inputs)),
+			NbOutputs: len(outputs),
+			Outputs:   make([]*big.Int, len(outputs)),
+		}
+		for i := range inputs {
+			e.Inputs[i] = new(big.Int).Set(inputs[i])
+		}
+		for i := range outputs {
+			e.Outputs[i] = new(big.Int).Set(outputs[i])
+		}
+		t.mu.Lock()
+		t.entries[key] = e
+		t.mu.Unlock()
+		return nil
+	}
+}
+
+// Len returns the number of recorded hint calls
+func (t *Transcript) Len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.entries)
+}
+
+// Entries returns the recorded hint calls, sorted by name and inputs
+func (t *Transcript) Entries() []TranscriptEntry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	keys := make([]string, 0, len(t.entries))
+	for k := range t.entries {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	entries := make([]TranscriptEntry, len(keys))
+	for i, k := range keys {
+		entries[i] = t.entries[k]
+	}
+	return entries
+}
+
+// WriteTo writes the recorded hint calls in JSON, in a deterministic order
+func (t *Transcript) WriteTo(w io.Writer) (int64, error) {
+	data, err := json.MarshalIndent(t.Entries(), "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
+// transcriptKey identifies a hint call: the same inputs can be given to a hint over another
+// field, or with another number of outputs (e.g. bits.ToBinary), with other results
+func transcriptKey(name string, field *big.Int, inputs []*big.Int, nbOutputs int) string {
+	var sbb strings.Builder
+	sbb.WriteString(name)
+	sbb.WriteByte(';')
+	if field != nil {
+		sbb.WriteString(field.Text(16))
+	}
+	sbb.WriteByte(';')
+	sbb.WriteString(strconv.Itoa(nbOutputs))
+	sbb.WriteByte(';')
+	for _, in := range inputs {
+		sbb.WriteByte(',')
+		sbb.WriteString(in.Text(16))
+	}
+	return sbb.String()
+}
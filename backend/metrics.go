@@ -0,0 +1,178 @@
+// Copyright 2020 ConsenSys AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+// StageMetrics holds the measurements of a completed prover stage.
+//
+// Stages of the groth16 prover run concurrently; Alloc accounts for the allocations
+// of all goroutines while the stage ran, not only the ones of the stage.
+type StageMetrics struct {
+	Stage     ProverStage
+	Start     time.Time
+	Duration  time.Duration
+	Alloc     uint64 // bytes allocated on the heap while the stage ran
+	HeapInuse uint64 // bytes in in-use heap spans when the stage completed
+}
+
+// ProverMetrics is filled by the prover when set with WithMetrics
+type ProverMetrics struct {
+	Stages []StageMetrics // in completion order
+	Total  time.Duration  // from the start of the first stage to the end of the last one
+}
+
+// Duration returns the duration of the given stage, or 0 if it didn't run
+func (m *ProverMetrics) Duration(stage ProverStage) time.Duration {
+	var d time.Duration
+	for i := range m.Stages {
+		if m.Stages[i].Stage == stage {
+			d += m.Stages[i].Duration
+		}
+	}
+	return d
+}
+
+// MetricsHook receives the metrics of each prover stage when it completes. It is the
+// extension point to export the prover metrics to a collector, for example by observing
+// StageMetrics.Duration in a histogram labeled by StageMetrics.Stage.
+//
+// ObserveStage may be called from several goroutines at once.
+type MetricsHook interface {
+	ObserveStage(StageMetrics)
+}
+
+// MetricsHookFunc is an adapter to use a function as a MetricsHook
+type MetricsHookFunc func(StageMetrics)
+
+// ObserveStage calls f(m)
+func (f MetricsHookFunc) ObserveStage(m StageMetrics) {
+	f(m)
+}
+
+// WithMetrics is a prover option that fills m with the measurements of the prover stages.
+// m is reset by the prover and must not be read before it returns.
+func WithMetrics(m *ProverMetrics) ProverOption {
+	return func(opt *ProverConfig) error {
+		*m = ProverMetrics{}
+		opt.stageTimer().metrics = m
+		return nil
+	}
+}
+
+// WithMetricsHook is a prover option that registers a hook receiving the measurements of
+// each prover stage. It can be set several times to register several hooks.
+func WithMetricsHook(hook MetricsHook) ProverOption {
+	return func(opt *ProverConfig) error {
+		t := opt.stageTimer()
+		t.hooks = append(t.hooks, hook)
+		return nil
+	}
+}
+
+func (cfg *ProverConfig) stageTimer() *stageTimer {
+	if cfg.timer == nil {
+		cfg.timer = &stageTimer{starts: make(map[ProverStage]stageStart)}
+	}
+	return cfg.timer
+}
+
+// MemoryCollector is a MetricsHook keeping the metrics in memory. The zero value is
+// ready to use.
+type MemoryCollector struct {
+	lock   sync.Mutex
+	stages []StageMetrics
+}
+
+// ObserveStage implements MetricsHook
+func (c *MemoryCollector) ObserveStage(m StageMetrics) {
+	c.lock.Lock()
+	c.stages = append(c.stages, m)
+	c.lock.Unlock()
+}
+
+// Stages returns a copy of the collected metrics, in completion order
+func (c *MemoryCollector) Stages() []StageMetrics {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return append([]StageMetrics(nil), c.stages...)
+}
+
+// Reset discards the collected metrics
+func (c *MemoryCollector) Reset() {
+	c.lock.Lock()
+	c.stages = nil
+	c.lock.Unlock()
+}
+
+// stageTimer measures the prover stages for WithMetrics and the metrics hooks
+type stageTimer struct {
+	lock    sync.Mutex
+	starts  map[ProverStage]stageStart
+	first   time.Time // start of the first stage
+	metrics *ProverMetrics
+	hooks   []MetricsHook
+}
+
+type stageStart struct {
+	time       time.Time
+	totalAlloc uint64
+}
+
+func (t *stageTimer) begin(stage ProverStage) {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	now := time.Now()
+	t.lock.Lock()
+	if t.first.IsZero() {
+		t.first = now
+	}
+	t.starts[stage] = stageStart{time: now, totalAlloc: ms.TotalAlloc}
+	t.lock.Unlock()
+}
+
+func (t *stageTimer) end(stage ProverStage) {
+	end := time.Now()
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	t.lock.Lock()
+	start, ok := t.starts[stage]
+	if !ok {
+		t.lock.Unlock()
+		return
+	}
+	delete(t.starts, stage)
+	m := StageMetrics{
+		Stage:     stage,
+		Start:     start.time,
+		Duration:  end.Sub(start.time),
+		Alloc:     ms.TotalAlloc - start.totalAlloc,
+		HeapInuse: ms.HeapInuse,
+	}
+	if t.metrics != nil {
+		t.metrics.Stages = append(t.metrics.Stages, m)
+		t.metrics.Total = end.Sub(t.first)
+	}
+	t.lock.Unlock()
+
+	for _, hook := range t.hooks {
+		hook.ObserveStage(m)
+	}
+}
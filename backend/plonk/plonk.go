@@ -20,6 +20,7 @@
 package plonk
 
 import (
+	"errors"
 	"io"
 
 	"github.com/consensys/gnark-crypto/ecc"
@@ -245,6 +246,20 @@ func Verify(proof Proof, vk VerifyingKey, publicWitness witness.Witness) error {
 	}
 }
 
+// SolidityCalldata returns the ABI encoded call to the verify_serialized_proof method of the
+// contract generated by vk.ExportSolidity. Only BN254 is supported.
+func SolidityCalldata(proof Proof, vk VerifyingKey, publicWitness witness.Witness) ([]byte, error) {
+	_proof, ok := proof.(*plonk_bn254.Proof)
+	if !ok {
+		return nil, errors.New("solidity calldata is only supported on BN254")
+	}
+	w, ok := publicWitness.Vector().(fr_bn254.Vector)
+	if !ok {
+		return nil, witness.ErrInvalidWitness
+	}
+	return plonk_bn254.SolidityCalldata(_proof, vk.(*plonk_bn254.VerifyingKey), w)
+}
+
 // NewCS instantiate a concrete curved-typed SparseR1CS and return a ConstraintSystem interface
 // This method exists for (de)serialization purposes
 func NewCS(curveID ecc.ID) constraint.ConstraintSystem {
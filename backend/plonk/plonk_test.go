@@ -2,12 +2,15 @@ package plonk_test
 
 import (
 	"bytes"
+	"context"
 	"math/big"
+	"sync"
 	"testing"
 
 	"github.com/consensys/gnark"
 	"github.com/consensys/gnark-crypto/ecc"
 	"github.com/consensys/gnark-crypto/kzg"
+	"github.com/consensys/gnark/backend"
 	"github.com/consensys/gnark/backend/plonk"
 	"github.com/consensys/gnark/constraint"
 	"github.com/consensys/gnark/frontend"
@@ -58,6 +61,46 @@ func TestProver(t *testing.T) {
 	}
 }
 
+func TestProveProgress(t *testing.T) {
+	assert := require.New(t)
+
+	ccs, err := frontend.Compile(ecc.BN254.ScalarField(), scs.NewBuilder, &refCircuit{nbConstraints: 10})
+	assert.NoError(err)
+	srs, err := test.NewKZGSRS(ccs)
+	assert.NoError(err)
+	pk, vk, err := plonk.Setup(ccs, srs)
+	assert.NoError(err)
+
+	y := new(big.Int).Exp(big.NewInt(2), new(big.Int).Lsh(big.NewInt(1), 10), ecc.BN254.ScalarField())
+	fullWitness, err := frontend.NewWitness(&refCircuit{X: 2, Y: y}, ecc.BN254.ScalarField())
+	assert.NoError(err)
+	publicWitness, err := fullWitness.Public()
+	assert.NoError(err)
+
+	var lock sync.Mutex
+	var stages []backend.ProverStage
+	progress := backend.WithProgress(func(e backend.ProgressEvent) {
+		lock.Lock()
+		defer lock.Unlock()
+		if e.Done {
+			stages = append(stages, e.Stage)
+		}
+	})
+	proof, err := plonk.Prove(ccs, pk, fullWitness, progress)
+	assert.NoError(err)
+	assert.NoError(plonk.Verify(proof, vk, publicWitness))
+	assert.Equal([]backend.ProverStage{
+		backend.StageSolve,
+		backend.StageRound1, backend.StageRound2, backend.StageRound3, backend.StageRound4, backend.StageRound5,
+	}, stages)
+
+	// a cancelled prover stops before solving
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = plonk.Prove(ccs, pk, fullWitness, backend.WithContext(ctx))
+	assert.ErrorIs(err, context.Canceled)
+}
+
 func BenchmarkSetup(b *testing.B) {
 	for _, curve := range getCurves() {
 		b.Run(curve.String(), func(b *testing.B) {
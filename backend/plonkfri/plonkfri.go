@@ -13,10 +13,23 @@
 // limitations under the License.
 
 // Package plonkfri implements PLONK Zero Knowledge Proof system, with FRI as commitment scheme.
-
+//
+// The FRI parameters (blowup factor, number of queries, folding arity and proof of work
+// bits) are set with the prover and setup options. The backend runs over the scalar fields
+// of the supported curves.
+//
+// The support of 64-bit fields such as Goldilocks or BabyBear is a separate follow-up: over
+// such a field, the challenges and the FRI folding must be done in an extension field for
+// the proofs to be sound, and the field needs its own constraint system, witness, FFT domain
+// and hash. Until then, a constraint system over another field is rejected by Setup.
 package plonkfri
 
 import (
+	"fmt"
+	"io"
+	"math/bits"
+
+	"github.com/consensys/gnark-crypto/ecc"
 	"github.com/consensys/gnark/backend"
 	"github.com/consensys/gnark/constraint"
 
@@ -45,22 +58,26 @@ import (
 	fr_bw6761 "github.com/consensys/gnark-crypto/ecc/bw6-761/fr"
 
 	plonk_bls24317 "github.com/consensys/gnark/internal/backend/bls24-317/plonkfri"
+
+	gnarkio "github.com/consensys/gnark/io"
 )
 
 // Proof represents a Plonk proof generated by plonk.Prove
 //
 // it's underlying implementation is curve specific (see gnark/internal/backend)
 type Proof interface {
-	// io.WriterTo
-	// io.ReaderFrom
+	io.WriterTo
+	io.ReaderFrom
+	gnarkio.WriterRawTo
 }
 
 // ProvingKey represents a plonk ProvingKey
 //
 // it's underlying implementation is strongly typed with the curve (see gnark/internal/backend)
 type ProvingKey interface {
-	// io.WriterTo
-	// io.ReaderFrom
+	io.WriterTo
+	io.ReaderFrom
+	gnarkio.WriterRawTo
 	VerifyingKey() interface{}
 }
 
@@ -68,32 +85,149 @@ type ProvingKey interface {
 //
 // it's underlying implementation is strongly typed with the curve (see gnark/internal/backend)
 type VerifyingKey interface {
-	// io.WriterTo
-	// io.ReaderFrom
-	// InitKZG(srs kzg.SRS) error
+	io.WriterTo
+	io.ReaderFrom
+	gnarkio.WriterRawTo
 	NbPublicWitness() int // number of elements expected in the public witness
+	SecurityLevel() int   // conjectured security level in bits of the FRI commitment scheme
+}
+
+// SetupConfig holds the parameters of the FRI commitment scheme instantiated by Setup.
+// They are part of the verifying key; proofs are built and verified with the parameters
+// the keys were generated with.
+type SetupConfig struct {
+	// BlowupFactor is the ratio between the size of the evaluation domain and the degree
+	// bound of the committed polynomials, a power of 2 ⩾ 2. Defaults to 8.
+	BlowupFactor int
+
+	// NbQueries is the number of FRI queries checked by the verifier. Defaults to 34.
+	NbQueries int
+
+	// FoldingArity is the number of evaluations folded into one at each FRI step, a power
+	// of 2 in [2, 256]. Defaults to 2.
+	FoldingArity int
+
+	// GrindingBits is the number of bits of proof of work asked to the prover before the
+	// queries are derived, in [0, 32]. Defaults to 0.
+	GrindingBits int
+
+	// MiMC selects MiMC instead of SHA256 for the merkle trees and the Fiat Shamir
+	// transcripts, so that the proofs can be verified in a circuit (see
+	// std/recursion/plonkfri). Defaults to false.
+	MiMC bool
+}
+
+// SecurityLevel returns the conjectured security level in bits of FRI instantiated with
+// these parameters, NbQueries·log₂(BlowupFactor) + GrindingBits, not accounting for the
+// bound set by the size of the field.
+func (cfg SetupConfig) SecurityLevel() int {
+	return cfg.NbQueries*bits.TrailingZeros(uint(cfg.BlowupFactor)) + cfg.GrindingBits
+}
+
+// SetupOption defines option for altering the behaviour of Setup
+type SetupOption func(*SetupConfig) error
+
+// WithBlowupFactor sets the FRI blowup factor
+func WithBlowupFactor(blowupFactor int) SetupOption {
+	return func(cfg *SetupConfig) error {
+		cfg.BlowupFactor = blowupFactor
+		return nil
+	}
+}
+
+// WithNbQueries sets the number of FRI queries
+func WithNbQueries(nbQueries int) SetupOption {
+	return func(cfg *SetupConfig) error {
+		cfg.NbQueries = nbQueries
+		return nil
+	}
+}
+
+// WithFoldingArity sets the FRI folding arity
+func WithFoldingArity(arity int) SetupOption {
+	return func(cfg *SetupConfig) error {
+		cfg.FoldingArity = arity
+		return nil
+	}
+}
+
+// WithGrindingBits sets the number of bits of proof of work of FRI
+func WithGrindingBits(grindingBits int) SetupOption {
+	return func(cfg *SetupConfig) error {
+		cfg.GrindingBits = grindingBits
+		return nil
+	}
+}
+
+// WithMiMC sets MiMC as the hash function of FRI and of the Fiat Shamir transcripts,
+// which is needed to verify the proofs in a circuit
+func WithMiMC() SetupOption {
+	return func(cfg *SetupConfig) error {
+		cfg.MiMC = true
+		return nil
+	}
+}
+
+// WithSecurityLevel sets the number of FRI queries to the smallest one reaching the
+// given conjectured security level with the blowup factor and grinding bits set by
+// the previous options.
+func WithSecurityLevel(level int) SetupOption {
+	return func(cfg *SetupConfig) error {
+		logBlowup := bits.TrailingZeros(uint(cfg.BlowupFactor))
+		if logBlowup == 0 {
+			return fmt.Errorf("invalid blowup factor %d", cfg.BlowupFactor)
+		}
+		cfg.NbQueries = 1
+		if remaining := level - cfg.GrindingBits; remaining > 0 {
+			cfg.NbQueries = (remaining + logBlowup - 1) / logBlowup
+		}
+		return nil
+	}
+}
+
+// NewSetupConfig returns a default SetupConfig with given setup options applied
+func NewSetupConfig(opts ...SetupOption) (SetupConfig, error) {
+	cfg := SetupConfig{
+		BlowupFactor: 8,
+		NbQueries:    34,
+		FoldingArity: 2,
+		GrindingBits: 0,
+	}
+	for _, option := range opts {
+		if err := option(&cfg); err != nil {
+			return SetupConfig{}, err
+		}
+	}
+	return cfg, nil
 }
 
 // Setup prepares the public data associated to a circuit + public inputs.
-func Setup(ccs constraint.ConstraintSystem) (ProvingKey, VerifyingKey, error) {
+//
+// The parameters of the FRI commitment scheme are set with the SetupOption.
+func Setup(ccs constraint.ConstraintSystem, opts ...SetupOption) (ProvingKey, VerifyingKey, error) {
+
+	cfg, err := NewSetupConfig(opts...)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	switch tccs := ccs.(type) {
 	case *cs_bn254.SparseR1CS:
-		return plonk_bn254.Setup(tccs)
+		return plonk_bn254.Setup(tccs, plonk_bn254.FRIParams(cfg))
 	case *cs_bls12381.SparseR1CS:
-		return plonk_bls12381.Setup(tccs)
+		return plonk_bls12381.Setup(tccs, plonk_bls12381.FRIParams(cfg))
 	case *cs_bls12377.SparseR1CS:
-		return plonk_bls12377.Setup(tccs)
+		return plonk_bls12377.Setup(tccs, plonk_bls12377.FRIParams(cfg))
 	case *cs_bw6761.SparseR1CS:
-		return plonk_bw6761.Setup(tccs)
+		return plonk_bw6761.Setup(tccs, plonk_bw6761.FRIParams(cfg))
 	case *cs_bls24315.SparseR1CS:
-		return plonk_bls24315.Setup(tccs)
+		return plonk_bls24315.Setup(tccs, plonk_bls24315.FRIParams(cfg))
 	case *cs_bw6633.SparseR1CS:
-		return plonk_bw6633.Setup(tccs)
+		return plonk_bw6633.Setup(tccs, plonk_bw6633.FRIParams(cfg))
 	case *cs_bls24317.SparseR1CS:
-		return plonk_bls24317.Setup(tccs)
+		return plonk_bls24317.Setup(tccs, plonk_bls24317.FRIParams(cfg))
 	default:
-		panic("unrecognized SparseR1CS curve type")
+		return nil, nil, fmt.Errorf("unsupported constraint system %T: the backend runs over the scalar fields of the supported curves only", ccs)
 	}
 
 }
@@ -222,3 +356,81 @@ func Verify(proof Proof, vk VerifyingKey, publicWitness witness.Witness) error {
 		panic("unrecognized proof type")
 	}
 }
+
+// NewProvingKey instantiates a curve-typed ProvingKey and returns an interface
+// This function exists for serialization purposes
+func NewProvingKey(curveID ecc.ID) ProvingKey {
+	var pk ProvingKey
+	switch curveID {
+	case ecc.BN254:
+		pk = &plonk_bn254.ProvingKey{}
+	case ecc.BLS12_377:
+		pk = &plonk_bls12377.ProvingKey{}
+	case ecc.BLS12_381:
+		pk = &plonk_bls12381.ProvingKey{}
+	case ecc.BW6_761:
+		pk = &plonk_bw6761.ProvingKey{}
+	case ecc.BLS24_317:
+		pk = &plonk_bls24317.ProvingKey{}
+	case ecc.BLS24_315:
+		pk = &plonk_bls24315.ProvingKey{}
+	case ecc.BW6_633:
+		pk = &plonk_bw6633.ProvingKey{}
+	default:
+		panic("not implemented")
+	}
+
+	return pk
+}
+
+// NewProof instantiates a curve-typed Proof and returns an interface
+// This function exists for serialization purposes
+func NewProof(curveID ecc.ID) Proof {
+	var proof Proof
+	switch curveID {
+	case ecc.BN254:
+		proof = &plonk_bn254.Proof{}
+	case ecc.BLS12_377:
+		proof = &plonk_bls12377.Proof{}
+	case ecc.BLS12_381:
+		proof = &plonk_bls12381.Proof{}
+	case ecc.BW6_761:
+		proof = &plonk_bw6761.Proof{}
+	case ecc.BLS24_317:
+		proof = &plonk_bls24317.Proof{}
+	case ecc.BLS24_315:
+		proof = &plonk_bls24315.Proof{}
+	case ecc.BW6_633:
+		proof = &plonk_bw6633.Proof{}
+	default:
+		panic("not implemented")
+	}
+
+	return proof
+}
+
+// NewVerifyingKey instantiates a curve-typed VerifyingKey and returns an interface
+// This function exists for serialization purposes
+func NewVerifyingKey(curveID ecc.ID) VerifyingKey {
+	var vk VerifyingKey
+	switch curveID {
+	case ecc.BN254:
+		vk = &plonk_bn254.VerifyingKey{}
+	case ecc.BLS12_377:
+		vk = &plonk_bls12377.VerifyingKey{}
+	case ecc.BLS12_381:
+		vk = &plonk_bls12381.VerifyingKey{}
+	case ecc.BW6_761:
+		vk = &plonk_bw6761.VerifyingKey{}
+	case ecc.BLS24_317:
+		vk = &plonk_bls24317.VerifyingKey{}
+	case ecc.BLS24_315:
+		vk = &plonk_bls24315.VerifyingKey{}
+	case ecc.BW6_633:
+		vk = &plonk_bw6633.VerifyingKey{}
+	default:
+		panic("not implemented")
+	}
+
+	return vk
+}
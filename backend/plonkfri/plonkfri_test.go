@@ -0,0 +1,75 @@
+package plonkfri_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/plonkfri"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/scs"
+	"github.com/consensys/gnark/internal/tinyfield"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetupOptions(t *testing.T) {
+	assert := require.New(t)
+
+	ccs, err := frontend.Compile(ecc.BN254.ScalarField(), scs.NewBuilder, &refCircuit{nbConstraints: 10})
+	assert.NoError(err)
+
+	_, vk, err := plonkfri.Setup(ccs)
+	assert.NoError(err)
+	assert.Equal(34*3, vk.SecurityLevel())
+
+	opts := []plonkfri.SetupOption{
+		plonkfri.WithBlowupFactor(16),
+		plonkfri.WithFoldingArity(4),
+		plonkfri.WithGrindingBits(4),
+		plonkfri.WithSecurityLevel(30),
+	}
+	cfg, err := plonkfri.NewSetupConfig(opts...)
+	assert.NoError(err)
+	assert.Equal(7, cfg.NbQueries)
+	assert.Equal(32, cfg.SecurityLevel())
+
+	_, vk, err = plonkfri.Setup(ccs, opts...)
+	assert.NoError(err)
+	assert.Equal(32, vk.SecurityLevel())
+
+	// the FRI parameters are serialized with the verifying key
+	var buf bytes.Buffer
+	_, err = vk.WriteTo(&buf)
+	assert.NoError(err)
+	reconstructed := plonkfri.NewVerifyingKey(ecc.BN254)
+	_, err = reconstructed.ReadFrom(&buf)
+	assert.NoError(err)
+	assert.Equal(32, reconstructed.SecurityLevel())
+
+	_, _, err = plonkfri.Setup(ccs, plonkfri.WithFoldingArity(3))
+	assert.Error(err)
+	_, _, err = plonkfri.Setup(ccs, plonkfri.WithNbQueries(0))
+	assert.Error(err)
+}
+
+func TestSetupOtherField(t *testing.T) {
+	// the fields which aren't the scalar field of a curve (e.g. 64-bit fields) are rejected
+	ccs, err := frontend.Compile(tinyfield.Modulus(), scs.NewBuilder, &refCircuit{nbConstraints: 10})
+	require.NoError(t, err)
+	_, _, err = plonkfri.Setup(ccs)
+	require.Error(t, err)
+}
+
+type refCircuit struct {
+	nbConstraints int
+	X             frontend.Variable
+	Y             frontend.Variable `gnark:",public"`
+}
+
+func (circuit *refCircuit) Define(api frontend.API) error {
+	for i := 0; i < circuit.nbConstraints; i++ {
+		circuit.X = api.Mul(circuit.X, circuit.X)
+	}
+	api.AssertIsEqual(circuit.X, circuit.Y)
+	return nil
+}
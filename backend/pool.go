@@ -0,0 +1,142 @@
+package backend
+
+import (
+	"runtime"
+	"sync"
+)
+
+// WorkerPool is a budget of workers shared by the constraint solver and the provers (MSM and
+// the loops over the polynomials). A parallel task acquires workers from the pool for its
+// duration, and runs on at most as many goroutines as it acquired.
+//
+// The FFTs are not bounded by the pool: the FFTs of gnark-crypto don't take a number of tasks
+// and run on runtime.NumCPU() goroutines. As such, a pool bounds the CPU usage of the provers
+// everywhere but in their FFTs.
+//
+// By default, all the provers share DefaultWorkerPool. A WorkerPool is safe for concurrent use.
+type WorkerPool struct {
+	nbWorkers int
+	workers   chan struct{}
+}
+
+var (
+	defaultPoolM sync.RWMutex
+	defaultPool  = NewWorkerPool(runtime.NumCPU())
+)
+
+// NewWorkerPool returns a pool of nbWorkers workers (at least 1).
+func NewWorkerPool(nbWorkers int) *WorkerPool {
+	if nbWorkers < 1 {
+		nbWorkers = 1
+	}
+	p := &WorkerPool{nbWorkers: nbWorkers, workers: make(chan struct{}, nbWorkers)}
+	for i := 0; i < nbWorkers; i++ {
+		p.workers <- struct{}{}
+	}
+	return p
+}
+
+// DefaultWorkerPool returns the pool used when no pool is given with WithWorkerPool. Unless set
+// with SetDefaultWorkerPool, it has runtime.NumCPU() workers.
+func DefaultWorkerPool() *WorkerPool {
+	defaultPoolM.RLock()
+	defer defaultPoolM.RUnlock()
+	return defaultPool
+}
+
+// SetDefaultWorkerPool sets the pool used when no pool is given with WithWorkerPool, for
+// example to configure the concurrency of all the provers of the process once.
+func SetDefaultWorkerPool(p *WorkerPool) {
+	if p == nil {
+		panic("nil worker pool")
+	}
+	defaultPoolM.Lock()
+	defaultPool = p
+	defaultPoolM.Unlock()
+}
+
+// Workers returns the worker pool of the configuration, DefaultWorkerPool() if none is set
+func (cfg *ProverConfig) Workers() *WorkerPool {
+	if cfg.WorkerPool == nil {
+		return DefaultWorkerPool()
+	}
+	return cfg.WorkerPool
+}
+
+// NbWorkers returns the number of workers of the pool
+func (p *WorkerPool) NbWorkers() int {
+	return p.nbWorkers
+}
+
+// Acquire waits until a worker is available, and acquires up to n workers (at least 1). It
+// returns the number of acquired workers, which must be released with Release.
+func (p *WorkerPool) Acquire(n int) int {
+	<-p.workers
+	acquired := 1
+	for acquired < n {
+		select {
+		case <-p.workers:
+			acquired++
+		default:
+			return acquired
+		}
+	}
+	return acquired
+}
+
+// Release releases n workers acquired with Acquire
+func (p *WorkerPool) Release(n int) {
+	for i := 0; i < n; i++ {
+		p.workers <- struct{}{}
+	}
+}
+
+// Run acquires up to n workers, calls f with the number of acquired workers, and releases
+// them. f should not run on more goroutines than the number of workers, for example
+//
+//	pool.Run(n, func(nbTasks int) {
+//		p.MultiExp(points, scalars, ecc.MultiExpConfig{NbTasks: nbTasks})
+//	})
+func (p *WorkerPool) Run(n int, f func(nbWorkers int)) {
+	acquired := p.Acquire(n)
+	defer p.Release(acquired)
+	f(acquired)
+}
+
+// Parallelize splits [0, nbIterations) in up to nbIterations/minWork (rounded up) tasks, and
+// runs them on the workers of the pool. It returns once work returned for all the tasks. If there is a
+// single task, it runs on the calling goroutine.
+func (p *WorkerPool) Parallelize(nbIterations, minWork int, work func(start, end int)) {
+	if minWork < 1 {
+		minWork = 1
+	}
+	nbTasks := nbIterations / minWork
+	if nbIterations%minWork != 0 {
+		nbTasks++
+	}
+	if nbTasks <= 1 {
+		work(0, nbIterations)
+		return
+	}
+
+	p.Run(nbTasks, func(nbTasks int) {
+		nbIterationsPerTask := nbIterations / nbTasks
+		extraTasks := nbIterations - nbTasks*nbIterationsPerTask
+
+		var wg sync.WaitGroup
+		wg.Add(nbTasks)
+		start := 0
+		for i := 0; i < nbTasks; i++ {
+			end := start + nbIterationsPerTask
+			if i < extraTasks {
+				end++
+			}
+			go func(start, end int) {
+				work(start, end)
+				wg.Done()
+			}(start, end)
+			start = end
+		}
+		wg.Wait()
+	})
+}
@@ -0,0 +1,104 @@
+package witness
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+
+	"github.com/consensys/gnark/frontend/schema"
+	"github.com/fxamacker/cbor/v2"
+)
+
+// ErrMissingSchema is returned when building a witness from a constraint system which doesn't
+// carry the schema of its inputs.
+var ErrMissingSchema = errors.New("constraint system has no input schema")
+
+var reflectMapStringAny = reflect.TypeOf(map[string]any(nil))
+
+// SchemaProvider is implemented by constraint.ConstraintSystem, it describes the inputs of
+// a compiled circuit.
+type SchemaProvider interface {
+	Field() *big.Int
+	GetSchema() *schema.Schema
+}
+
+// FromJSON builds a witness from a JSON encoded assignment, following the input schema
+// recorded in the constraint system at compile time. The Go circuit structure isn't needed.
+//
+// The assignment is a JSON object with the circuit fields (arrays for slices and arrays),
+// the values are numbers or strings in base 10 or 16 (0x prefix). If any secret value is
+// missing, only the public part of the witness is built.
+func FromJSON(cs SchemaProvider, data []byte) (Witness, error) {
+	s := cs.GetSchema()
+	if s == nil {
+		return nil, ErrMissingSchema
+	}
+	w, err := New(cs.Field())
+	if err != nil {
+		return nil, err
+	}
+	// FromJSON may modify the schema
+	_s := *s
+	if err := w.FromJSON(&_s, data); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// FromCBOR builds a witness from a CBOR encoded assignment, following the input schema
+// recorded in the constraint system at compile time. The Go circuit structure isn't needed.
+//
+// The assignment has the same structure as the JSON one (see FromJSON). The values may
+// also be CBOR integers, big numbers, or byte strings (big endian).
+func FromCBOR(cs SchemaProvider, data []byte) (Witness, error) {
+	dm, err := cbor.DecOptions{DefaultMapType: reflectMapStringAny}.DecMode()
+	if err != nil {
+		return nil, err
+	}
+	var assignment any
+	if err := dm.Unmarshal(data, &assignment); err != nil {
+		return nil, err
+	}
+	assignment, err = cborToJSON(assignment)
+	if err != nil {
+		return nil, err
+	}
+	jsonData, err := json.Marshal(assignment)
+	if err != nil {
+		return nil, err
+	}
+	return FromJSON(cs, jsonData)
+}
+
+// cborToJSON converts the values of a decoded CBOR assignment such that the JSON encoding
+// of the result can be parsed by FromJSON
+func cborToJSON(v any) (any, error) {
+	switch t := v.(type) {
+	case map[string]any:
+		for k := range t {
+			var err error
+			if t[k], err = cborToJSON(t[k]); err != nil {
+				return nil, fmt.Errorf("%s: %w", k, err)
+			}
+		}
+		return t, nil
+	case []any:
+		for i := range t {
+			var err error
+			if t[i], err = cborToJSON(t[i]); err != nil {
+				return nil, err
+			}
+		}
+		return t, nil
+	case big.Int:
+		return t.String(), nil
+	case []byte:
+		return new(big.Int).SetBytes(t).String(), nil
+	case uint64, int64, string, nil:
+		return t, nil
+	default:
+		return nil, fmt.Errorf("unsupported value of type %T", v)
+	}
+}
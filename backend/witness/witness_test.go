@@ -1,14 +1,19 @@
 package witness_test
 
 import (
+	"bytes"
 	"fmt"
+	"math/big"
 	"reflect"
 	"testing"
 
 	"github.com/consensys/gnark-crypto/ecc"
 	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
 	"github.com/consensys/gnark/backend/witness"
+	cs_bn254 "github.com/consensys/gnark/constraint/bn254"
 	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/fxamacker/cbor/v2"
 	"github.com/stretchr/testify/require"
 )
 
@@ -148,3 +153,80 @@ func roundTripMarshalJSON(assert *require.Assertions, assignment circuit, public
 	assert.True(reflect.DeepEqual(rw, w), "witness json round trip serialization")
 
 }
+
+type nestedInputs struct {
+	A frontend.Variable
+	B [2]frontend.Variable `gnark:",public"`
+}
+
+type nestedCircuit struct {
+	X      frontend.Variable `gnark:",public"`
+	Inputs [2]nestedInputs
+	Y      []frontend.Variable `gnark:"y"`
+}
+
+func (c *nestedCircuit) Define(api frontend.API) error {
+	sum := c.X
+	for _, in := range c.Inputs {
+		sum = api.Add(sum, in.A, in.B[0], in.B[1])
+	}
+	api.AssertIsEqual(sum, api.Mul(c.Y[0], c.Y[1]))
+	return nil
+}
+
+func TestFromConstraintSystem(t *testing.T) {
+	assert := require.New(t)
+
+	ccs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &nestedCircuit{Y: make([]frontend.Variable, 2)})
+	assert.NoError(err)
+
+	// the schema is serialized with the constraint system
+	var buf bytes.Buffer
+	_, err = ccs.WriteTo(&buf)
+	assert.NoError(err)
+	reconstructed := cs_bn254.NewR1CS(0)
+	_, err = reconstructed.ReadFrom(&buf)
+	assert.NoError(err)
+
+	assignment := nestedCircuit{
+		X: 1,
+		Inputs: [2]nestedInputs{
+			{A: 2, B: [2]frontend.Variable{3, 4}},
+			{A: 5, B: [2]frontend.Variable{6, 7}},
+		},
+		Y: []frontend.Variable{4, 7},
+	}
+	expected, err := frontend.NewWitness(&assignment, ecc.BN254.ScalarField())
+	assert.NoError(err)
+
+	data := `{"X":1,"Inputs":[{"A":2,"B":[3,4]},{"A":"5","B":[6,"0x7"]}],"y":[4,7]}`
+	w, err := witness.FromJSON(reconstructed, []byte(data))
+	assert.NoError(err)
+	assert.Equal(expected.Vector(), w.Vector())
+	assert.NoError(reconstructed.IsSolved(w))
+
+	cborData, err := cbor.Marshal(map[string]any{
+		"X":      1,
+		"Inputs": []any{map[string]any{"A": 2, "B": []any{3, 4}}, map[string]any{"A": big.NewInt(5), "B": []any{6, []byte{7}}}},
+		"y":      []any{4, 7},
+	})
+	assert.NoError(err)
+	w, err = witness.FromCBOR(reconstructed, cborData)
+	assert.NoError(err)
+	assert.Equal(expected.Vector(), w.Vector())
+
+	// without the secret inputs, only the public part is built
+	expected, err = expected.Public()
+	assert.NoError(err)
+	w, err = witness.FromJSON(reconstructed, []byte(`{"X":1,"Inputs":[{"B":[3,4]},{"B":[6,7]}]}`))
+	assert.NoError(err)
+	assert.Equal(expected.Vector(), w.Vector())
+
+	_, err = witness.FromJSON(reconstructed, []byte(`{"Inputs":[{"B":[3,4]},{"B":[6,7]}]}`))
+	assert.Error(err, "missing public input")
+	_, err = witness.FromJSON(reconstructed, []byte(`{"X":1,"Z":2}`))
+	assert.Error(err, "unknown field")
+
+	_, err = witness.FromJSON(cs_bn254.NewR1CS(0), []byte(data))
+	assert.ErrorIs(err, witness.ErrMissingSchema)
+}
@@ -0,0 +1,102 @@
+// Command csdebug runs the solver of a compiled constraint system step by step in a terminal,
+// with breakpoints on constraints, source locations (file:line) and wires, see
+// constraint/debugger.
+//
+// The constraint system is given as written with WriteTo, and the witness as written with
+// witness.Witness.WriteTo:
+//
+//	csdebug -curve bn254 -backend groth16 circuit.r1cs witness.bin
+//
+// The exit code is 0 on success and 2 on error. Type help at the prompt for the commands.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/plonk"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/constraint/debugger"
+)
+
+func main() {
+	curve := flag.String("curve", "bn254", "curve of the constraint system")
+	backend := flag.String("backend", "groth16", "backend of the constraint system: groth16 (R1CS) or plonk (SparseR1CS)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] constraint-system witness\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	curveID, err := parseCurve(*curve)
+	if err != nil {
+		fatal(err)
+	}
+	var ccs constraint.ConstraintSystem
+	switch *backend {
+	case "groth16":
+		ccs = groth16.NewCS(curveID)
+	case "plonk":
+		ccs = plonk.NewCS(curveID)
+	default:
+		fatal(fmt.Errorf("unknown backend %s", *backend))
+	}
+	if err := readFrom(flag.Arg(0), ccs); err != nil {
+		fatal(err)
+	}
+	w, err := witness.New(curveID.ScalarField())
+	if err != nil {
+		fatal(err)
+	}
+	if err := readFrom(flag.Arg(1), w); err != nil {
+		fatal(err)
+	}
+
+	d, err := debugger.New(ccs)
+	if err != nil {
+		fatal(err)
+	}
+	if err := d.REPL(os.Stdin, os.Stdout, w); err != nil {
+		fatal(err)
+	}
+}
+
+func parseCurve(name string) (ecc.ID, error) {
+	for _, id := range ecc.Implemented() {
+		if strings.EqualFold(id.String(), name) {
+			return id, nil
+		}
+	}
+	return ecc.UNKNOWN, fmt.Errorf("unknown curve %s", name)
+}
+
+type readerFrom interface {
+	ReadFrom(r io.Reader) (int64, error)
+}
+
+func readFrom(path string, v readerFrom) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := v.ReadFrom(f); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(2)
+}
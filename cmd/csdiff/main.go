@@ -0,0 +1,68 @@
+// Command csdiff compares two compiled constraint systems and reports the added, removed and
+// changed constraints (modulo the numbering of the internal wires), the changes in hint usage and
+// in the inputs layout, grouped by the location which produced the constraints.
+//
+// The constraint systems are given as IR files, written with constraint.NewIR and IR.WriteJSON:
+//
+//	csdiff before.json after.json
+//
+// The exit code is 0 if the constraint systems are the same, 1 if they differ and 2 on error.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/consensys/gnark/constraint"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s before.json after.json\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	a, err := readIR(flag.Arg(0))
+	if err != nil {
+		fatal(err)
+	}
+	b, err := readIR(flag.Arg(1))
+	if err != nil {
+		fatal(err)
+	}
+	if a.Kind != b.Kind || a.Field != b.Field {
+		fatal(fmt.Errorf("can't compare a %s over 0x%s with a %s over 0x%s", a.Kind, a.Field, b.Kind, b.Field))
+	}
+
+	d := constraint.DiffIR(a, b)
+	if err := d.WriteText(os.Stdout, a, b); err != nil {
+		fatal(err)
+	}
+	if !d.IsEmpty() {
+		os.Exit(1)
+	}
+}
+
+func readIR(path string) (*constraint.IR, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	ir, err := constraint.ReadIR(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return ir, nil
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(2)
+}
@@ -21,11 +21,12 @@ import (
 	"fmt"
 	"github.com/fxamacker/cbor/v2"
 	"io"
-	"runtime"
+	"math"
 	"sync"
 	"time"
 
 	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/backend/hint"
 	"github.com/consensys/gnark/backend/witness"
 	"github.com/consensys/gnark/constraint"
 	"github.com/consensys/gnark/internal/backend/ioutils"
@@ -33,7 +34,7 @@ import (
 	"github.com/consensys/gnark/profile"
 
 	"github.com/consensys/gnark-crypto/ecc"
-	"math"
+	"math/big"
 
 	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
 )
@@ -73,32 +74,39 @@ func (cs *R1CS) AddConstraint(r1c constraint.R1C, debugInfo ...constraint.DebugI
 	return cID
 }
 
-// Solve sets all the wires and returns the a, b, c vectors.
+// SolveWires sets all the wires and returns the a, b, c vectors.
 // the cs system should have been compiled before. The entries in a, b, c are in Montgomery form.
 // a, b, c vectors: ab-c = hz
 // witness = [publicWires | secretWires] (without the ONE_WIRE !)
 // returns  [publicWires | secretWires | internalWires ]
-func (cs *R1CS) Solve(witness, a, b, c fr.Vector, opt backend.ProverConfig) (fr.Vector, error) {
+func (cs *R1CS) SolveWires(witness, a, b, c fr.Vector, opt backend.ProverConfig) (fr.Vector, error) {
+	solution, err := cs.solve(witness, a, b, c, opt, constraint.SolveTarget{All: true}, nil)
+	return solution.values, err
+}
+
+// solve sets the wires selected by the target, starting from the values of the wires of a
+// partial solution which are not nil (from may be nil).
+func (cs *R1CS) solve(witness, a, b, c fr.Vector, opt backend.ProverConfig, target constraint.SolveTarget, from []*big.Int) (*solution, error) {
 	log := logger.Logger().With().Int("nbConstraints", len(cs.Constraints)).Str("backend", "groth16").Logger()
 
 	nbWires := len(cs.Public) + len(cs.Secret) + cs.NbInternalVariables
-	solution, err := newSolution(nbWires, opt.HintFunctions, cs.MHintsDependencies, cs.MHints, cs.Coefficients, &cs.System.SymbolTable)
+	solution, err := newSolution(nbWires, opt.ResolveHints(cs.MHintsDependencies, cs.CommitmentInfo.HintIDs()...), cs.MHintsDependencies, cs.MHints, cs.Coefficients, &cs.System.SymbolTable)
 	if err != nil {
-		return make(fr.Vector, nbWires), err
+		return &solution, err
 	}
 	start := time.Now()
 
 	if len(witness) != len(cs.Public)-1+len(cs.Secret) { // - 1 for ONE_WIRE
 		err = fmt.Errorf("invalid witness size, got %d, expected %d = %d (public) + %d (secret)", len(witness), int(len(cs.Public)-1+len(cs.Secret)), len(cs.Public)-1, len(cs.Secret))
 		log.Err(err).Send()
-		return solution.values, err
+		return &solution, err
 	}
 
 	// compute the wires and the a, b, c polynomials
 	if len(a) != len(cs.Constraints) || len(b) != len(cs.Constraints) || len(c) != len(cs.Constraints) {
 		err = errors.New("invalid input size: len(a, b, c) == len(Constraints)")
 		log.Err(err).Send()
-		return solution.values, err
+		return &solution, err
 	}
 
 	solution.solved[0] = true // ONE_WIRE
@@ -112,145 +120,142 @@ func (cs *R1CS) Solve(witness, a, b, c fr.Vector, opt backend.ProverConfig) (fr.
 	// we instantiated all wires
 	solution.nbSolved += uint64(len(witness) + 1)
 
-	// now that we know all inputs are set, defer log printing once all solution.values are computed
-	// (or sooner, if a constraint is not satisfied)
-	defer solution.printLogs(opt.CircuitLogger, cs.Logs)
+	// the internal wires solved previously
+	solution.setPartial(len(witness)+1, from)
+
+	levels, err := cs.SolvingPlan(target, func(wire int) bool { return solution.solved[wire] })
+	if err != nil {
+		log.Err(err).Send()
+		return &solution, err
+	}
 
-	if err := cs.parallelSolve(a, b, c, &solution); err != nil {
+	if target.All {
+		// now that we know all inputs are set, defer log printing once all solution.values are computed
+		// (or sooner, if a constraint is not satisfied)
+		defer solution.printLogs(opt, cs.Logs)
+	}
+
+	if err := cs.parallelSolve(a, b, c, &solution, levels, opt); err != nil {
 		if unsatisfiedErr, ok := err.(*UnsatisfiedConstraintError); ok {
 			log.Err(errors.New("unsatisfied constraint")).Int("id", unsatisfiedErr.CID).Send()
+		} else if unsatisfiedErr, ok := err.(*constraint.UnsatisfiedConstraintsError); ok {
+			log.Err(errors.New("unsatisfied constraints")).Int("count", len(unsatisfiedErr.Constraints)).Send()
 		} else {
 			log.Err(err).Send()
 		}
-		return solution.values, err
+		return &solution, err
 	}
 
 	// sanity check; ensure all wires are marked as "instantiated"
-	if !solution.isValid() {
+	if !solution.isSolved(target) {
 		log.Err(errors.New("solver didn't instantiate all wires")).Send()
 		panic("solver didn't instantiate all wires")
 	}
 
 	log.Debug().Dur("took", time.Since(start)).Msg("constraint system solver done")
 
-	return solution.values, nil
+	return &solution, nil
 }
 
-func (cs *R1CS) parallelSolve(a, b, c fr.Vector, solution *solution) error {
-	// minWorkPerCPU is the minimum target number of constraint a task should hold
-	// in other words, if a level has less than minWorkPerCPU, it will not be parallelized and executed
+func (cs *R1CS) parallelSolve(a, b, c fr.Vector, solution *solution, levels [][]int, opt backend.ProverConfig) error {
+	// minWork is the minimum target number of constraint a task should hold
+	// in other words, if a level has less than minWork, it will not be parallelized and executed
 	// sequentially without sync.
-	const minWorkPerCPU = 50.0
+	minWork := opt.SolverTaskSize
+	if minWork <= 0 {
+		minWork = backend.DefaultSolverTaskSize
+	}
+	if opt.SolverHook != nil {
+		// the constraints are solved sequentially, and the hook is called before each of them
+		minWork = math.MaxInt
+	}
 
-	// cs.Levels has a list of levels, where all constraints in a level l(n) are independent
-	// and may only have dependencies on previous levels
+	// levels (cs.Levels or a subset, see SolvingPlan) has a list of levels, where all constraints
+	// in a level l(n) are independent and may only have dependencies on previous levels
 	// for each constraint
 	// we are guaranteed that each R1C contains at most one unsolved wire
 	// first we solve the unsolved wire (if any)
 	// then we check that the constraint is valid
 	// if a[i] * b[i] != c[i]; it means the constraint is not satisfied
 
-	var wg sync.WaitGroup
-	chTasks := make(chan []int, runtime.NumCPU())
-	chError := make(chan *UnsatisfiedConstraintError, runtime.NumCPU())
-
-	// start a worker pool
-	// each worker wait on chTasks
-	// a task is a slice of constraint indexes to be solved
-	for i := 0; i < runtime.NumCPU(); i++ {
-		go func() {
-			for t := range chTasks {
-				for _, i := range t {
-					// for each constraint in the task, solve it.
-					if err := cs.solveConstraint(cs.Constraints[i], solution, &a[i], &b[i], &c[i]); err != nil {
-						var debugInfo *string
-						if dID, ok := cs.MDebug[i]; ok {
-							debugInfo = new(string)
-							*debugInfo = solution.logValue(cs.DebugInfo[dID])
-						}
-						chError <- &UnsatisfiedConstraintError{CID: i, Err: err, DebugInfo: debugInfo}
-						wg.Done()
+	solve := func(i int) *UnsatisfiedConstraintError {
+		if err := cs.solveConstraint(cs.Constraints[i], solution, &a[i], &b[i], &c[i]); err != nil {
+			return cs.unsatisfied(i, solution, err)
+		}
+		return nil
+	}
+
+	// the levels are solved by the workers of the pool, which may be shared with other solvers
+	// and provers
+	pool := opt.Workers()
+	var (
+		errLock     sync.Mutex
+		errLevel    error
+		unsatisfied []*constraint.UnsatisfiedConstraint
+	)
+	for _, level := range levels {
+		nbUnsatisfied := len(unsatisfied)
+		pool.Parallelize(len(level), minWork, func(start, end int) {
+			for _, i := range level[start:end] {
+				if opt.SolverHook != nil {
+					if err := opt.SolverHook(i, solution); err != nil {
+						errLevel = err
 						return
 					}
 				}
-				wg.Done()
-			}
-		}()
-	}
-
-	// clean up pool go routines
-	defer func() {
-		close(chTasks)
-		close(chError)
-	}()
-
-	// for each level, we push the tasks
-	for _, level := range cs.Levels {
-
-		// max CPU to use
-		maxCPU := float64(len(level)) / minWorkPerCPU
-
-		if maxCPU <= 1.0 {
-			// we do it sequentially
-			for _, i := range level {
-				if err := cs.solveConstraint(cs.Constraints[i], solution, &a[i], &b[i], &c[i]); err != nil {
-					var debugInfo *string
-					if dID, ok := cs.MDebug[i]; ok {
-						debugInfo = new(string)
-						*debugInfo = solution.logValue(cs.DebugInfo[dID])
+				if err := solve(i); err != nil {
+					errLock.Lock()
+					if opt.SolverKeepGoing {
+						unsatisfied = append(unsatisfied, err.Details)
+					} else if errLevel == nil {
+						errLevel = err
+					}
+					errLock.Unlock()
+					if opt.SolverKeepGoing {
+						solution.skip(&cs.Constraints[i])
+						continue
 					}
-					return &UnsatisfiedConstraintError{CID: i, Err: err, DebugInfo: debugInfo}
+					return
 				}
 			}
-			continue
-		}
-
-		// number of tasks for this level is set to num cpus
-		// but if we don't have enough work for all our CPUS, it can be lower.
-		nbTasks := runtime.NumCPU()
-		maxTasks := int(math.Ceil(maxCPU))
-		if nbTasks > maxTasks {
-			nbTasks = maxTasks
-		}
-		nbIterationsPerCpus := len(level) / nbTasks
-
-		// more CPUs than tasks: a CPU will work on exactly one iteration
-		// note: this depends on minWorkPerCPU constant
-		if nbIterationsPerCpus < 1 {
-			nbIterationsPerCpus = 1
-			nbTasks = len(level)
-		}
-
-		extraTasks := len(level) - (nbTasks * nbIterationsPerCpus)
-		extraTasksOffset := 0
-
-		for i := 0; i < nbTasks; i++ {
-			wg.Add(1)
-			_start := i*nbIterationsPerCpus + extraTasksOffset
-			_end := _start + nbIterationsPerCpus
-			if extraTasks > 0 {
-				_end++
-				extraTasks--
-				extraTasksOffset++
-			}
-			// since we're never pushing more than num CPU tasks
-			// we will never be blocked here
-			chTasks <- level[_start:_end]
-		}
-
-		// wait for the level to be done
-		wg.Wait()
-
-		if len(chError) > 0 {
-			return <-chError
+		})
+		if errLevel != nil {
+			return errLevel
 		}
+		sortUnsatisfied(unsatisfied[nbUnsatisfied:])
+	}
+	if len(unsatisfied) != 0 {
+		return &constraint.UnsatisfiedConstraintsError{Constraints: unsatisfied}
 	}
 
 	return nil
 }
 
+// unsatisfied returns the error of the unsatisfied constraint i, with the values of its terms
+func (cs *R1CS) unsatisfied(i int, solution *solution, err error) *UnsatisfiedConstraintError {
+	r := &cs.Constraints[i]
+	details := &constraint.UnsatisfiedConstraint{
+		CID: i,
+		Err: err,
+		Expressions: []constraint.ExpressionValue{
+			solution.expressionValue(&cs.System, "L", r.L, false),
+			solution.expressionValue(&cs.System, "R", r.R, false),
+			solution.expressionValue(&cs.System, "O", r.O, false),
+		},
+		Stack: cs.Stack(i),
+	}
+	solution.message(&cs.System, details)
+	var debugInfo *string
+	if dID, ok := cs.MDebug[i]; ok {
+		debugInfo = new(string)
+		*debugInfo, _ = solution.logValue(cs.DebugInfo[dID])
+		details.Debug = *debugInfo
+	}
+	return &UnsatisfiedConstraintError{CID: i, Err: err, DebugInfo: debugInfo, Details: details}
+}
+
 // IsSolved returns nil if given witness solves the R1CS and error otherwise
-// this method wraps cs.Solve() and allocates cs.Solve() inputs
+// this method wraps cs.SolveWires() and allocates cs.SolveWires() inputs
 func (cs *R1CS) IsSolved(witness witness.Witness, opts ...backend.ProverOption) error {
 	opt, err := backend.NewProverConfig(opts...)
 	if err != nil {
@@ -261,10 +266,56 @@ func (cs *R1CS) IsSolved(witness witness.Witness, opts ...backend.ProverOption)
 	b := make(fr.Vector, len(cs.Constraints))
 	c := make(fr.Vector, len(cs.Constraints))
 	v := witness.Vector().(fr.Vector)
-	_, err = cs.Solve(v, a, b, c, opt)
+	_, err = cs.SolveWires(v, a, b, c, opt)
 	return err
 }
 
+// Solve solves the R1CS with the given witness and returns the values of all the wires, with
+// the hint calls of the solver.
+func (cs *R1CS) Solve(witness witness.Witness, opts ...backend.ProverOption) (*constraint.Solution, error) {
+	return cs.SolvePartial(witness, constraint.SolveTarget{All: true}, opts...)
+}
+
+// SolvePartial solves the wires selected by the target, and returns a partial solution where
+// the values of the other internal wires are nil. The solver can be resumed with Resume.
+func (cs *R1CS) SolvePartial(witness witness.Witness, target constraint.SolveTarget, opts ...backend.ProverOption) (*constraint.Solution, error) {
+	return cs.solvePartial(witness.Vector().(fr.Vector), target, nil, opts)
+}
+
+// Resume solves the wires selected by the target, starting from a partial solution returned
+// by SolvePartial or Resume. Unless a hint transcript is given, the hint calls recorded in the
+// partial solution are replayed.
+func (cs *R1CS) Resume(from *constraint.Solution, target constraint.SolveTarget, opts ...backend.ProverOption) (*constraint.Solution, error) {
+	if err := cs.System.CheckSolution(from, cs.Field()); err != nil {
+		return nil, err
+	}
+	witness := make(fr.Vector, len(cs.Public)-1+len(cs.Secret)) // - 1 for ONE_WIRE
+	for i := range witness {
+		witness[i].SetBigInt(from.Values[i+1])
+	}
+	opts = append([]backend.ProverOption{backend.WithHintTranscript(from.Transcript())}, opts...)
+	return cs.solvePartial(witness, target, from.Values, opts)
+}
+
+func (cs *R1CS) solvePartial(witness fr.Vector, target constraint.SolveTarget, from []*big.Int, opts []backend.ProverOption) (*constraint.Solution, error) {
+	opt, err := backend.NewProverConfig(opts...)
+	if err != nil {
+		return nil, err
+	}
+	if opt.HintTranscript == nil {
+		opt.HintTranscript = hint.NewTranscript()
+	}
+
+	a := make(fr.Vector, len(cs.Constraints))
+	b := make(fr.Vector, len(cs.Constraints))
+	c := make(fr.Vector, len(cs.Constraints))
+	solution, err := cs.solve(witness, a, b, c, opt, target, from)
+	if err != nil {
+		return nil, err
+	}
+	return cs.System.NewSolution(cs.Field(), solution.bigInts(), opt.HintTranscript, true), nil
+}
+
 // divByCoeff sets res = res / t.Coeff
 func (cs *R1CS) divByCoeff(res *fr.Element, t constraint.Term) {
 	cID := t.CoeffID()
@@ -19,11 +19,13 @@ package cs
 import (
 	"errors"
 	"fmt"
+	"github.com/consensys/gnark/backend"
 	"github.com/consensys/gnark/backend/hint"
 	"github.com/consensys/gnark/constraint"
 	"github.com/consensys/gnark/debug"
 	"github.com/rs/zerolog"
 	"math/big"
+	"sort"
 	"strconv"
 	"strings"
 	"sync/atomic"
@@ -82,6 +84,38 @@ func (s *solution) isValid() bool {
 	return int(s.nbSolved) == len(s.values)
 }
 
+// Value implements backend.SolverState
+func (s *solution) Value(wire int) (*big.Int, bool) {
+	if wire < 0 || wire >= len(s.values) || !s.solved[wire] {
+		return nil, false
+	}
+	return s.values[wire].BigInt(new(big.Int)), true
+}
+
+// isSolved returns true if the wires selected by the target are solved
+func (s *solution) isSolved(target constraint.SolveTarget) bool {
+	if target.All {
+		return s.isValid()
+	}
+	for _, w := range target.Wires {
+		if !s.solved[w] {
+			return false
+		}
+	}
+	return true
+}
+
+// setPartial sets the wires after the inputs which have a value in the partial solution
+func (s *solution) setPartial(nbInputs int, from []*big.Int) {
+	for i := nbInputs; i < len(from); i++ {
+		if from[i] != nil {
+			s.values[i].SetBigInt(from[i])
+			s.solved[i] = true
+			s.nbSolved++
+		}
+	}
+}
+
 // computeTerm computes coeff*variable
 func (s *solution) computeTerm(t constraint.Term) fr.Element {
 	cID, vID := t.CoeffID(), t.WireID()
@@ -209,29 +243,32 @@ func (s *solution) solveWithHint(vID int, h *constraint.Hint) error {
 	return err
 }
 
-func (s *solution) printLogs(log zerolog.Logger, logs []constraint.LogEntry) {
-	if log.GetLevel() == zerolog.Disabled {
+func (s *solution) printLogs(opt backend.ProverConfig, logs []constraint.LogEntry) {
+	if opt.CircuitLogSink == nil && opt.CircuitLogger.GetLevel() == zerolog.Disabled {
 		return
 	}
 
 	for i := 0; i < len(logs); i++ {
-		logLine := s.logValue(logs[i])
-		log.Debug().Str(zerolog.CallerFieldName, logs[i].Caller).Msg(logLine)
+		logLine, ok := s.logValue(logs[i])
+		if !ok {
+			// the condition of the log is not satisfied
+			continue
+		}
+		if opt.CircuitLogSink != nil {
+			opt.CircuitLogSink(logs[i].Caller, logLine)
+			continue
+		}
+		opt.CircuitLogger.Debug().Str(zerolog.CallerFieldName, logs[i].Caller).Msg(logLine)
 	}
 }
 
-const unsolvedVariable = "<unsolved>"
-
-func (s *solution) logValue(log constraint.LogEntry) string {
-	var toResolve []interface{}
-	var (
-		eval         fr.Element
-		missingValue bool
-	)
+// logValue resolves the log entry (see constraint.LogEntry.Resolve), and returns false if it
+// is conditional and its condition is not satisfied
+func (s *solution) logValue(log constraint.LogEntry) (string, bool) {
+	values := make([]*big.Int, len(log.ToResolve))
+	var eval fr.Element
 	for j := 0; j < len(log.ToResolve); j++ {
-		// before eval le
-
-		missingValue = false
+		missingValue := false
 		eval.SetZero()
 
 		for _, t := range log.ToResolve[j] {
@@ -253,15 +290,15 @@ func (s *solution) logValue(log constraint.LogEntry) string {
 			eval.Add(&eval, &tv)
 		}
 
-		// after
-		if missingValue {
-			toResolve = append(toResolve, unsolvedVariable)
-		} else {
-			// we have to append our accumulator
-			toResolve = append(toResolve, eval.String())
+		if !missingValue {
+			values[j] = eval.BigInt(new(big.Int))
 		}
-
 	}
+	toResolve, ok := log.Resolve(values, fr.Modulus())
+	if !ok {
+		return "", false
+	}
+
 	if len(log.Stack) > 0 {
 		var sbb strings.Builder
 		for _, lID := range log.Stack {
@@ -278,7 +315,7 @@ func (s *solution) logValue(log constraint.LogEntry) string {
 		}
 		toResolve = append(toResolve, sbb.String())
 	}
-	return fmt.Sprintf(log.Format, toResolve...)
+	return fmt.Sprintf(log.Format, toResolve...), true
 }
 
 // UnsatisfiedConstraintError wraps an error with useful metadata on the unsatisfied constraint
@@ -286,11 +323,114 @@ type UnsatisfiedConstraintError struct {
 	Err       error
 	CID       int     // constraint ID
 	DebugInfo *string // optional debug info
+
+	// Details has the values of the terms of the constraint, the hints which solved its wires and
+	// the call stack where it was added.
+	Details *constraint.UnsatisfiedConstraint
 }
 
 func (r *UnsatisfiedConstraintError) Error() string {
+	if r.Details != nil {
+		return r.Details.Error()
+	}
 	if r.DebugInfo != nil {
 		return fmt.Sprintf("constraint #%d is not satisfied: %s", r.CID, *r.DebugInfo)
 	}
 	return fmt.Sprintf("constraint #%d is not satisfied: %s", r.CID, r.Err.Error())
 }
+
+// As makes errors.As find the details of the unsatisfied constraint as a
+// *constraint.UnsatisfiedConstraint, independently of the curve
+func (r *UnsatisfiedConstraintError) As(target interface{}) bool {
+	if t, ok := target.(**constraint.UnsatisfiedConstraint); ok && r.Details != nil {
+		*t = r.Details
+		return true
+	}
+	return false
+}
+
+// message sets the label and the message of the assertion which added the constraint, if any
+// (see frontend.WithMessage)
+func (s *solution) message(system *constraint.System, details *constraint.UnsatisfiedConstraint) {
+	if mID, ok := system.MMessages[details.CID]; ok {
+		details.Label = system.Messages[mID].Label
+		details.Message, _ = s.logValue(system.Messages[mID])
+	}
+}
+
+// expressionValue returns the value of the sum (or product) of the terms, with the values of
+// the terms, for the details of an unsatisfied constraint
+func (s *solution) expressionValue(system *constraint.System, name string, terms []constraint.Term, product bool) constraint.ExpressionValue {
+	e := constraint.ExpressionValue{Name: name, Product: product, Terms: make([]constraint.TermValue, len(terms))}
+	var v fr.Element
+	if product {
+		v.SetOne()
+	}
+	solved := true
+	for i, t := range terms {
+		coeff := s.coefficients[t.CoeffID()].BigInt(new(big.Int))
+		var tv fr.Element
+		switch {
+		case t.IsConstant():
+			e.Terms[i] = constraint.TermValue{Wire: -1, Coeff: coeff}
+			tv = s.coefficients[t.CoeffID()]
+		case !s.solved[t.WireID()]:
+			e.Terms[i] = system.NewTermValue(t.WireID(), coeff, nil)
+			solved = false
+			continue
+		default:
+			e.Terms[i] = system.NewTermValue(t.WireID(), coeff, s.values[t.WireID()].BigInt(new(big.Int)))
+			tv = s.computeTerm(t)
+		}
+		if product {
+			v.Mul(&v, &tv)
+		} else {
+			v.Add(&v, &tv)
+		}
+	}
+	if solved {
+		e.Value = v.BigInt(new(big.Int))
+	}
+	return e
+}
+
+// sortUnsatisfied sorts the unsatisfied constraints of a level by constraint ID
+func sortUnsatisfied(unsatisfied []*constraint.UnsatisfiedConstraint) {
+	sort.Slice(unsatisfied, func(i, j int) bool {
+		return unsatisfied[i].CID < unsatisfied[j].CID
+	})
+}
+
+// skip sets the unsolved wires of an unsatisfied constraint, such that the solver can keep
+// going (see backend.WithSolverKeepGoing): the hints are called, and the other wires are set
+// to 0.
+func (s *solution) skip(c constraint.Iterable) {
+	next := c.WireIterator()
+	for wID := next(); wID != -1; wID = next() {
+		if wID >= len(s.solved) || s.solved[wID] {
+			continue
+		}
+		if h, ok := s.mHints[wID]; ok {
+			// the outputs are set even if the hint fails
+			_ = s.solveWithHint(wID, h)
+			for _, w := range h.Wires {
+				if !s.solved[w] {
+					s.set(w, fr.Element{})
+				}
+			}
+			continue
+		}
+		s.set(wID, fr.Element{})
+	}
+}
+
+// bigInts returns the values of the wires as big.Int, nil for the wires which are not solved
+func (s *solution) bigInts() []*big.Int {
+	res := make([]*big.Int, len(s.values))
+	for i := range s.values {
+		if s.solved[i] {
+			res[i] = s.values[i].BigInt(new(big.Int))
+		}
+	}
+	return res
+}
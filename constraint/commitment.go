@@ -28,6 +28,15 @@ func (i *Commitment) Is() bool {
 	return len(i.Committed) != 0
 }
 
+// HintIDs returns the ID of the hint computing the commitment, if any. This hint is set by the
+// prover, it must not be replaced by a hint provider nor replayed from a hint transcript.
+func (i *Commitment) HintIDs() []hint.ID {
+	if !i.Is() {
+		return nil
+	}
+	return []hint.ID{i.HintID}
+}
+
 // NewCommitment initialize a Commitment object
 //   - committed are the sorted wireID to commit to (without duplicate)
 //   - nbPublicCommited is the number of public inputs among the commited wireIDs
@@ -43,9 +52,8 @@ func (i *Commitment) SerializeCommitment(privateCommitment []byte, publicCommitt
 	res := make([]byte, len(privateCommitment)+len(publicCommitted)*fieldByteLen)
 	copy(res, privateCommitment)
 
-	offset := len(privateCommitment)
 	for j, inJ := range publicCommitted {
-		offset += j * fieldByteLen
+		offset := len(privateCommitment) + j*fieldByteLen
 		inJ.FillBytes(res[offset : offset+fieldByteLen])
 	}
 
@@ -0,0 +1,312 @@
+// Package debugger runs the solver of a constraint system step by step, and stops at breakpoints
+// to inspect the values of the wires.
+//
+// A breakpoint is a constraint, a source location recorded in the debug symbol table of the
+// constraint system (file:line), or a wire, which stops the solver once the wire is solved:
+//
+//	d := debugger.New(ccs)
+//	d.Break(debugger.AtLine("circuit.go", 42), debugger.AtWire("v12"))
+//	stop, err := d.Start(witness)
+//	for stop != nil {
+//		v, err := stop.Eval("X * Y + v12")
+//		// ...
+//		stop, err = d.Continue()
+//	}
+//	// err is the error of the solver, d.Solution() the solution if it succeeded
+//
+// The same commands are available in a terminal with Debugger.REPL, see cmd/csdebug.
+package debugger
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/constraint"
+)
+
+// ErrAborted is returned by the solver when the run is aborted with Debugger.Abort
+var ErrAborted = errors.New("solver aborted by the debugger")
+
+// Breakpoint stops the solver, see AtConstraint, AtLine and AtWire
+type Breakpoint struct {
+	cID  int
+	file string
+	line int
+	wire string
+}
+
+// AtConstraint returns a breakpoint stopping the solver before it solves the constraint cID
+func AtConstraint(cID int) Breakpoint {
+	return Breakpoint{cID: cID}
+}
+
+// AtLine returns a breakpoint stopping the solver before it solves a constraint added at
+// file:line (file is matched by suffix). The location must be in the call stack of the debug
+// info of the constraint: the constraints without debug info (e.g. added by api.Mul) can't be
+// matched.
+func AtLine(file string, line int) Breakpoint {
+	return Breakpoint{cID: -1, file: file, line: line}
+}
+
+// AtWire returns a breakpoint stopping the solver once the wire with the given name is solved
+// (see constraint.System.VariableToString). The inputs are solved before the first constraint.
+func AtWire(name string) Breakpoint {
+	return Breakpoint{cID: -1, wire: name}
+}
+
+func (b Breakpoint) String() string {
+	switch {
+	case b.wire != "":
+		return "wire " + b.wire
+	case b.file != "":
+		return fmt.Sprintf("%s:%d", b.file, b.line)
+	default:
+		return fmt.Sprintf("constraint #%d", b.cID)
+	}
+}
+
+// system is implemented by the constraint systems of gnark (see constraint.System)
+type system interface {
+	constraint.ConstraintSystem
+	VariableToString(vID int) string
+	Stack(cID int) []constraint.StackFrame
+}
+
+// Debugger runs the solver of a constraint system step by step. It is not safe for concurrent
+// use.
+type Debugger struct {
+	ccs         system
+	wires       map[string]int // wire name to ID
+	breakpoints []Breakpoint
+
+	// current run
+	stops    chan *Stop
+	commands chan command
+	done     chan error
+	solution *constraint.Solution
+	running  bool
+}
+
+type command int
+
+const (
+	cmdContinue command = iota
+	cmdStep
+	cmdAbort
+)
+
+// Stop is the state of the solver stopped at a breakpoint, or after a step
+type Stop struct {
+	// CID is the constraint the solver is about to solve
+	CID int
+
+	// Breakpoint is the breakpoint which stopped the solver, nil after a step
+	Breakpoint *Breakpoint
+
+	d     *Debugger
+	state backend.SolverState
+}
+
+// New returns a debugger for the constraint system. The constraint system must be compiled by
+// gnark.
+func New(ccs constraint.ConstraintSystem) (*Debugger, error) {
+	s, ok := ccs.(system)
+	if !ok {
+		return nil, fmt.Errorf("unsupported constraint system %T", ccs)
+	}
+	d := &Debugger{ccs: s, wires: make(map[string]int)}
+	nbWires := s.GetNbPublicVariables() + s.GetNbSecretVariables() + s.GetNbInternalVariables()
+	for i := 0; i < nbWires; i++ {
+		d.wires[s.VariableToString(i)] = i
+	}
+	return d, nil
+}
+
+// Break adds breakpoints. The breakpoints are active in the current run and the next ones.
+func (d *Debugger) Break(breakpoints ...Breakpoint) error {
+	for _, b := range breakpoints {
+		if b.wire != "" {
+			if _, ok := d.wires[b.wire]; !ok {
+				return fmt.Errorf("unknown wire %s", b.wire)
+			}
+		} else if b.file == "" && (b.cID < 0 || b.cID >= d.ccs.GetNbConstraints()) {
+			return fmt.Errorf("invalid constraint #%d", b.cID)
+		}
+	}
+	d.breakpoints = append(d.breakpoints, breakpoints...)
+	return nil
+}
+
+// Breakpoints returns the breakpoints
+func (d *Debugger) Breakpoints() []Breakpoint {
+	return d.breakpoints
+}
+
+// ClearBreakpoints removes all the breakpoints
+func (d *Debugger) ClearBreakpoints() {
+	d.breakpoints = nil
+}
+
+// Start starts the solver with the given witness and options, and returns once it stops at a
+// breakpoint. If the solver is done, it returns a nil Stop with the error of the solver, see
+// Solution. A previous run is aborted.
+func (d *Debugger) Start(w witness.Witness, opts ...backend.ProverOption) (*Stop, error) {
+	return d.start(w, false, opts)
+}
+
+// StartStep is as Start, but stops before the first constraint
+func (d *Debugger) StartStep(w witness.Witness, opts ...backend.ProverOption) (*Stop, error) {
+	return d.start(w, true, opts)
+}
+
+func (d *Debugger) start(w witness.Witness, step bool, opts []backend.ProverOption) (*Stop, error) {
+	d.Abort()
+	d.stops = make(chan *Stop)
+	d.commands = make(chan command)
+	d.done = make(chan error, 1)
+	d.solution = nil
+	d.running = true
+
+	// the wire breakpoints stop the solver once
+	triggered := make(map[Breakpoint]bool)
+	stepping := step
+	stops, commands, done := d.stops, d.commands, d.done
+	hook := func(cID int, state backend.SolverState) error {
+		stop := &Stop{CID: cID, d: d, state: state}
+		for i := range d.breakpoints {
+			b := d.breakpoints[i]
+			if !triggered[b] && d.hits(&b, cID, state) {
+				triggered[b] = b.wire != ""
+				stop.Breakpoint = &b
+				break
+			}
+		}
+		if !stepping && stop.Breakpoint == nil {
+			return nil
+		}
+		stops <- stop
+		switch <-commands {
+		case cmdStep:
+			stepping = true
+		case cmdAbort:
+			return ErrAborted
+		default:
+			stepping = false
+		}
+		return nil
+	}
+
+	opts = append(opts, backend.WithSolverHook(hook))
+	go func() {
+		solution, err := d.ccs.Solve(w, opts...)
+		d.solution = solution
+		done <- err
+	}()
+	return d.wait()
+}
+
+func (d *Debugger) hits(b *Breakpoint, cID int, state backend.SolverState) bool {
+	switch {
+	case b.wire != "":
+		_, solved := state.Value(d.wires[b.wire])
+		return solved
+	case b.file != "":
+		for _, f := range d.ccs.Stack(cID) {
+			if f.Line == b.line && strings.HasSuffix(f.File, b.file) {
+				return true
+			}
+		}
+		return false
+	default:
+		return b.cID == cID
+	}
+}
+
+// Continue resumes the solver until the next breakpoint, see Start
+func (d *Debugger) Continue() (*Stop, error) {
+	return d.resume(cmdContinue)
+}
+
+// Step solves the constraint the solver is stopped at, and stops before the next one, see Start
+func (d *Debugger) Step() (*Stop, error) {
+	return d.resume(cmdStep)
+}
+
+func (d *Debugger) resume(c command) (*Stop, error) {
+	if !d.running {
+		return nil, errors.New("the solver is not running")
+	}
+	d.commands <- c
+	return d.wait()
+}
+
+func (d *Debugger) wait() (*Stop, error) {
+	select {
+	case stop := <-d.stops:
+		return stop, nil
+	case err := <-d.done:
+		d.running = false
+		return nil, err
+	}
+}
+
+// Abort stops the current run, if any
+func (d *Debugger) Abort() {
+	if !d.running {
+		return
+	}
+	d.commands <- cmdAbort
+	<-d.done
+	d.running = false
+}
+
+// Running returns true if the solver is stopped at a breakpoint
+func (d *Debugger) Running() bool {
+	return d.running
+}
+
+// Solution returns the solution of the last run, if the solver succeeded
+func (d *Debugger) Solution() *constraint.Solution {
+	return d.solution
+}
+
+// Value returns the value of the wire with the given name, or false if it is not solved yet
+func (s *Stop) Value(name string) (*big.Int, bool) {
+	wire, ok := s.d.wires[name]
+	if !ok {
+		return nil, false
+	}
+	return s.state.Value(wire)
+}
+
+// Eval evaluates an expression of the values of the wires in the field of the constraint
+// system, for example "X * (Y - 1) + 2*v3". The expressions have integer constants (decimal,
+// or hexadecimal with the 0x prefix), wire names, the operators + - * / and parentheses.
+func (s *Stop) Eval(expression string) (*big.Int, error) {
+	return eval(expression, s.d.ccs.Field(), s.Value)
+}
+
+// Constraint returns the constraint the solver is about to solve, formatted with the names of
+// the wires
+func (s *Stop) Constraint() string {
+	switch ccs := s.d.ccs.(type) {
+	case constraint.R1CS:
+		constraints, r := ccs.GetConstraints()
+		return constraints[s.CID].String(r)
+	case constraint.SparseR1CS:
+		constraints, r := ccs.GetConstraints()
+		return constraints[s.CID].String(r)
+	default:
+		return fmt.Sprintf("constraint #%d", s.CID)
+	}
+}
+
+// Stack returns the call stack where the constraint the solver is about to solve was added, if
+// recorded
+func (s *Stop) Stack() []constraint.StackFrame {
+	return s.d.ccs.Stack(s.CID)
+}
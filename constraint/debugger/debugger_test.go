@@ -0,0 +1,179 @@
+package debugger_test
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/constraint/debugger"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/frontend/cs/scs"
+	"github.com/stretchr/testify/require"
+)
+
+type circuit struct {
+	X, Y frontend.Variable
+	Z    frontend.Variable `gnark:",public"`
+}
+
+// assertLine is the line of the assertion in Define
+var assertLine int
+
+func (c *circuit) Define(api frontend.API) error {
+	b := api.Add(api.Mul(c.X, c.Y), c.X)
+	_, _, assertLine, _ = runtime.Caller(0)
+	api.AssertIsEqual(api.Mul(b, b), c.Z)
+	return nil
+}
+
+func TestDebugger(t *testing.T) {
+	assert := require.New(t)
+
+	w, err := frontend.NewWitness(&circuit{X: 2, Y: 3, Z: 64}, ecc.BN254.ScalarField())
+	assert.NoError(err)
+	for _, builder := range []frontend.NewBuilder{r1cs.NewBuilder, scs.NewBuilder} {
+		ccs, err := frontend.Compile(ecc.BN254.ScalarField(), builder, &circuit{})
+		assert.NoError(err)
+		d, err := debugger.New(ccs)
+		assert.NoError(err)
+		last := ccs.GetNbConstraints() - 1
+
+		// constraint breakpoint
+		assert.Error(d.Break(debugger.AtConstraint(last + 1)))
+		assert.Error(d.Break(debugger.AtWire("W")))
+		assert.NoError(d.Break(debugger.AtConstraint(last)))
+		stop, err := d.Start(w)
+		assert.NoError(err)
+		assert.Equal(last, stop.CID)
+		assert.NotNil(stop.Breakpoint)
+		assert.NotEmpty(stop.Constraint())
+		v, err := stop.Eval("(X * Y + X) * (X*Y + X) - Z")
+		assert.NoError(err)
+		assert.Equal(int64(0), v.Int64())
+		v, err = stop.Eval("-Z / 0x2")
+		assert.NoError(err)
+		assert.Equal(int64(-32), v.Sub(v, ecc.BN254.ScalarField()).Int64())
+		_, err = stop.Eval("X / (Y - 3)")
+		assert.Error(err)
+		_, err = stop.Eval("X +")
+		assert.Error(err)
+
+		// the solver completes
+		stop, err = d.Continue()
+		assert.NoError(err)
+		assert.Nil(stop)
+		assert.NotNil(d.Solution())
+		assert.False(d.Running())
+
+		// line breakpoint
+		d.ClearBreakpoints()
+		assert.NoError(d.Break(debugger.AtLine("debugger_test.go", assertLine+1)))
+		stop, err = d.Start(w)
+		assert.NoError(err)
+		assert.NotNil(stop)
+		frame := stop.Stack()[len(stop.Stack())-1]
+		assert.Contains(frame.Function, "Define")
+		assert.Equal(assertLine+1, frame.Line)
+		d.Abort()
+		assert.Nil(d.Solution())
+
+		// wire breakpoint
+		d.ClearBreakpoints()
+		assert.NoError(d.Break(debugger.AtWire("v0")))
+		stop, err = d.Start(w)
+		assert.NoError(err)
+		assert.Equal("v0", stop.Breakpoint.String()[len("wire "):])
+		_, solved := stop.Value("v0")
+		assert.True(solved)
+		stop, err = d.Continue()
+		assert.NoError(err)
+		assert.Nil(stop, "a wire breakpoint stops the solver once")
+
+		// steps
+		d.ClearBreakpoints()
+		stop, err = d.StartStep(w)
+		assert.NoError(err)
+		for cID := 0; stop != nil; cID++ {
+			assert.Equal(cID, stop.CID)
+			stop, err = d.Step()
+		}
+		assert.NoError(err)
+
+		// the errors of the solver are returned
+		invalid, err := frontend.NewWitness(&circuit{X: 2, Y: 3, Z: 65}, ecc.BN254.ScalarField())
+		assert.NoError(err)
+		stop, err = d.Start(invalid)
+		assert.Error(err)
+		assert.Nil(stop)
+	}
+}
+
+func TestREPL(t *testing.T) {
+	assert := require.New(t)
+
+	w, err := frontend.NewWitness(&circuit{X: 2, Y: 3, Z: 64}, ecc.BN254.ScalarField())
+	assert.NoError(err)
+	ccs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &circuit{})
+	assert.NoError(err)
+	d, err := debugger.New(ccs)
+	assert.NoError(err)
+
+	script := strings.Join([]string{
+		"p X",
+		"b 1",
+		"b unknown",
+		"breakpoints",
+		"run",
+		"p X * Y",
+		"where",
+		"c",
+		"delete",
+		"s",
+		"abort",
+		"q",
+	}, "\n")
+	var out strings.Builder
+	assert.NoError(d.REPL(strings.NewReader(script), &out, w))
+
+	for _, expected := range []string{
+		"error: the solver is not stopped",
+		"breakpoint 1 at constraint #1",
+		"error: unknown wire unknown",
+		"1: constraint #1",
+		"stopped at constraint #1, constraint #1: ",
+		"(csdebug) 6\n",
+		"solver done: all the constraints are satisfied",
+		"constraint #0: ",
+	} {
+		assert.Contains(out.String(), expected)
+	}
+	assert.False(d.Running())
+}
+
+type toBinaryCircuit struct {
+	X frontend.Variable
+}
+
+func (c *toBinaryCircuit) Define(api frontend.API) error {
+	api.ToBinary(c.X, 8)
+	api.ToBinary(c.X, 16)
+	return nil
+}
+
+func TestDebuggerReusedHintInputs(t *testing.T) {
+	assert := require.New(t)
+
+	w, err := frontend.NewWitness(&toBinaryCircuit{X: 5}, ecc.BN254.ScalarField())
+	assert.NoError(err)
+	for _, builder := range []frontend.NewBuilder{r1cs.NewBuilder, scs.NewBuilder} {
+		ccs, err := frontend.Compile(ecc.BN254.ScalarField(), builder, &toBinaryCircuit{})
+		assert.NoError(err)
+		d, err := debugger.New(ccs)
+		assert.NoError(err)
+		stop, err := d.Start(w)
+		assert.NoError(err)
+		assert.Nil(stop)
+	}
+}
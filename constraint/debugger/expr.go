@@ -0,0 +1,166 @@
+package debugger
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// eval evaluates an expression of integer constants, wire names, + - * / and parentheses, modulo
+// field. value returns the value of a wire, or false if it is not solved.
+//
+//	expr   = term { ("+" | "-") term }
+//	term   = unary { ("*" | "/") unary }
+//	unary  = "-" unary | factor
+//	factor = number | name | "(" expr ")"
+func eval(expression string, field *big.Int, value func(name string) (*big.Int, bool)) (*big.Int, error) {
+	p := parser{s: expression, field: field, value: value}
+	r, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpaces()
+	if p.pos != len(p.s) {
+		return nil, p.errorf("unexpected %q", p.s[p.pos:])
+	}
+	return r.Mod(r, field), nil
+}
+
+type parser struct {
+	s     string
+	pos   int
+	field *big.Int
+	value func(name string) (*big.Int, bool)
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("invalid expression at %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) skipSpaces() {
+	for p.pos < len(p.s) && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// next skips the spaces and consumes c if it is the next character
+func (p *parser) next(c byte) bool {
+	p.skipSpaces()
+	if p.pos < len(p.s) && p.s[p.pos] == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expr() (*big.Int, error) {
+	r, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.next('+'):
+			t, err := p.term()
+			if err != nil {
+				return nil, err
+			}
+			r.Add(r, t)
+		case p.next('-'):
+			t, err := p.term()
+			if err != nil {
+				return nil, err
+			}
+			r.Sub(r, t)
+		default:
+			return r, nil
+		}
+	}
+}
+
+func (p *parser) term() (*big.Int, error) {
+	r, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.next('*'):
+			t, err := p.unary()
+			if err != nil {
+				return nil, err
+			}
+			r.Mul(r, t).Mod(r, p.field)
+		case p.next('/'):
+			t, err := p.unary()
+			if err != nil {
+				return nil, err
+			}
+			if t.Mod(t, p.field).Sign() == 0 {
+				return nil, errors.New("division by zero")
+			}
+			t.ModInverse(t, p.field)
+			r.Mul(r, t).Mod(r, p.field)
+		default:
+			return r, nil
+		}
+	}
+}
+
+func (p *parser) unary() (*big.Int, error) {
+	if p.next('-') {
+		r, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return r.Neg(r), nil
+	}
+	return p.factor()
+}
+
+func (p *parser) factor() (*big.Int, error) {
+	if p.next('(') {
+		r, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.next(')') {
+			return nil, p.errorf("missing )")
+		}
+		return r, nil
+	}
+
+	p.skipSpaces()
+	start := p.pos
+	for p.pos < len(p.s) && isNameChar(p.s[p.pos]) {
+		p.pos++
+	}
+	token := p.s[start:p.pos]
+	switch {
+	case token == "":
+		if p.pos == len(p.s) {
+			return nil, p.errorf("unexpected end")
+		}
+		return nil, p.errorf("unexpected %q", p.s[p.pos])
+	case token[0] >= '0' && token[0] <= '9':
+		r, ok := new(big.Int).SetString(token, 0)
+		if !ok {
+			return nil, p.errorf("invalid number %s", token)
+		}
+		return r, nil
+	default:
+		v, ok := p.value(token)
+		if !ok {
+			return nil, fmt.Errorf("unknown or unsolved wire %s", token)
+		}
+		return new(big.Int).Set(v), nil
+	}
+}
+
+// isNameChar returns true for the characters of the wire names (see
+// constraint.System.VariableToString) and of the numbers
+func isNameChar(c byte) bool {
+	return strings.IndexByte("_.[]", c) >= 0 ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,144 @@
+package debugger
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/backend/witness"
+)
+
+const replHelp = `commands:
+  break, b <constraint ID | file:line | wire>   add a breakpoint
+  delete                                      remove all the breakpoints
+  breakpoints                                 list the breakpoints
+  run, r                                      start the solver, and stop at the first breakpoint
+  step, s                                     solve the current constraint, and stop at the next one
+  continue, c                                 resume the solver until the next breakpoint
+  print, p <expression>                       evaluate an expression, e.g. p X * (Y - 1) + v2
+  where, w                                    print the current constraint and its call stack
+  abort                                       abort the solver
+  help, h                                     print this help
+  quit, q                                     abort the solver and quit
+`
+
+// REPL runs a read-eval-print loop debugging the solver with the given witness and options. It
+// reads the commands (see the help command) line by line from in, and writes the results to
+// out. It returns when in is consumed or on the quit command, after aborting the solver.
+func (d *Debugger) REPL(in io.Reader, out io.Writer, w witness.Witness, opts ...backend.ProverOption) error {
+	defer d.Abort()
+
+	var stop *Stop
+	scanner := bufio.NewScanner(in)
+	fmt.Fprint(out, "(csdebug) ")
+	for scanner.Scan() {
+		command, arg, _ := strings.Cut(strings.TrimSpace(scanner.Text()), " ")
+		arg = strings.TrimSpace(arg)
+
+		var (
+			err     error
+			resumed bool
+		)
+		switch command {
+		case "":
+		case "break", "b":
+			var b Breakpoint
+			if b, err = parseBreakpoint(arg); err == nil {
+				if err = d.Break(b); err == nil {
+					fmt.Fprintf(out, "breakpoint %d at %s\n", len(d.breakpoints), b)
+				}
+			}
+		case "delete":
+			d.ClearBreakpoints()
+		case "breakpoints":
+			for i, b := range d.breakpoints {
+				fmt.Fprintf(out, "%d: %s\n", i+1, b)
+			}
+		case "run", "r":
+			stop, err = d.Start(w, opts...)
+			resumed = true
+		case "step", "s":
+			if d.running {
+				stop, err = d.Step()
+			} else {
+				stop, err = d.StartStep(w, opts...)
+			}
+			resumed = true
+		case "continue", "c":
+			if !d.running {
+				err = errNotStopped
+				break
+			}
+			stop, err = d.Continue()
+			resumed = true
+		case "print", "p":
+			if stop == nil {
+				err = errNotStopped
+				break
+			}
+			var v *big.Int
+			if v, err = stop.Eval(arg); err == nil {
+				fmt.Fprintln(out, v)
+			}
+		case "where", "w":
+			if stop == nil {
+				err = errNotStopped
+				break
+			}
+			fmt.Fprintf(out, "constraint #%d: %s\n", stop.CID, stop.Constraint())
+			for _, f := range stop.Stack() {
+				fmt.Fprintf(out, "\t%s\n\t\t%s:%d\n", f.Function, f.File, f.Line)
+			}
+		case "abort":
+			d.Abort()
+			stop = nil
+		case "help", "h":
+			fmt.Fprint(out, replHelp)
+		case "quit", "q":
+			return nil
+		default:
+			err = fmt.Errorf("unknown command %s, see help", command)
+		}
+
+		switch {
+		case resumed && stop != nil:
+			if stop.Breakpoint != nil {
+				fmt.Fprintf(out, "stopped at %s, ", stop.Breakpoint)
+			}
+			fmt.Fprintf(out, "constraint #%d: %s\n", stop.CID, stop.Constraint())
+		case resumed && err == nil:
+			fmt.Fprintln(out, "solver done: all the constraints are satisfied")
+		case resumed:
+			fmt.Fprintf(out, "solver done: %v\n", err)
+		case err != nil:
+			fmt.Fprintln(out, "error:", err)
+		}
+		fmt.Fprint(out, "(csdebug) ")
+	}
+	return scanner.Err()
+}
+
+var errNotStopped = errors.New("the solver is not stopped, see run and step")
+
+// parseBreakpoint parses a constraint ID, a file:line location or a wire name
+func parseBreakpoint(s string) (Breakpoint, error) {
+	if s == "" {
+		return Breakpoint{}, errors.New("missing breakpoint")
+	}
+	if cID, err := strconv.Atoi(s); err == nil {
+		return AtConstraint(cID), nil
+	}
+	if i := strings.LastIndexByte(s, ':'); i >= 0 {
+		line, err := strconv.Atoi(s[i+1:])
+		if err != nil {
+			return Breakpoint{}, fmt.Errorf("invalid line in %s", s)
+		}
+		return AtLine(s[:i], line), nil
+	}
+	return AtWire(s), nil
+}
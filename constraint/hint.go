@@ -11,4 +11,5 @@ type Hint struct {
 	ID     hint.ID            // hint function id
 	Inputs []LinearExpression // terms to inject in the hint function
 	Wires  []int              // IDs of wires the hint outputs map to
+	Stack  []int              // location of the hint call in the symbol table
 }
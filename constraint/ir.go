@@ -0,0 +1,722 @@
+// Copyright 2020 ConsenSys AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package constraint
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/hint"
+	"github.com/consensys/gnark/debug"
+	"github.com/consensys/gnark/frontend/schema"
+	"github.com/consensys/gnark/internal/utils"
+)
+
+// IRVersion is the version of the IR format. It is bumped on incompatible changes.
+const IRVersion = 1
+
+// kinds of constraint systems in the IR
+const (
+	IRKindR1CS       = "r1cs"
+	IRKindSparseR1CS = "sparse_r1cs"
+)
+
+// IR is a stable, curve agnostic representation of a compiled constraint system, meant to be
+// inspected, diffed or analysed by external tools. Coefficients are written as base 10 integers
+// (small negative values as -x), and the constraint system can be rebuilt from its IR with Build.
+//
+// Wires are numbered as in the constraint system: [public | secret | internal]. The terms of
+// hint inputs and logs with Wire == -1 are constants.
+//
+// ! this is an experimental API.
+type IR struct {
+	Version    int      `json:"version"`
+	Kind       string   `json:"kind"`
+	Field      string   `json:"field"` // scalar field modulus, base 16
+	Public     []string `json:"public"`
+	Secret     []string `json:"secret"`
+	NbInternal int      `json:"nbInternal"`
+
+	Hints      []IRHint       `json:"hints,omitempty"`
+	R1Cs       []IRR1C        `json:"r1cs,omitempty"`
+	SparseR1Cs []IRSparseR1C  `json:"sparseR1cs,omitempty"`
+	DebugInfo  []IRLogEntry   `json:"debugInfo,omitempty"`
+	Messages   []IRLogEntry   `json:"messages,omitempty"` // messages of the assertions
+	Logs       []IRLogEntry   `json:"logs,omitempty"`
+	Commitment *Commitment    `json:"commitment,omitempty"`
+	Schema     *schema.Schema `json:"schema,omitempty"`
+}
+
+// IRTerm is a coeff * wire term, see IR
+type IRTerm struct {
+	Wire  int    `json:"wire"`
+	Coeff string `json:"coeff"`
+}
+
+// IRLinearExpression is a sum of IRTerm
+type IRLinearExpression []IRTerm
+
+// IRR1C is a L⋅R == O constraint
+type IRR1C struct {
+	L IRLinearExpression `json:"l"`
+	R IRLinearExpression `json:"r"`
+	O IRLinearExpression `json:"o"`
+}
+
+// IRSparseR1C is a qL⋅xa + qR⋅xb + qO⋅xc + qM⋅(xaxb) + qC == 0 constraint
+type IRSparseR1C struct {
+	L IRTerm    `json:"l"`
+	R IRTerm    `json:"r"`
+	O IRTerm    `json:"o"`
+	M [2]IRTerm `json:"m"`
+	K string    `json:"k"`
+}
+
+// IRHint is a solver hint, computing the Outputs wires from the Inputs
+type IRHint struct {
+	ID      hint.ID              `json:"id"`
+	Name    string               `json:"name"`
+	Inputs  []IRLinearExpression `json:"inputs"`
+	Outputs []int                `json:"outputs"`
+	Stack   []IRFrame            `json:"stack,omitempty"` // location of the hint call
+}
+
+// IRLogEntry is a debug info or a log entry. Format has a verb per ToResolve element (or
+// group of limbs, see LogEntry), and debug info has a last one for the stack.
+type IRLogEntry struct {
+	Constraints []int                `json:"constraints,omitempty"` // constraints the debug info is attached to
+	Caller      string               `json:"caller,omitempty"`
+	Format      string               `json:"format"`
+	ToResolve   []IRLinearExpression `json:"toResolve,omitempty"`
+	Limbs       []LogLimbs           `json:"limbs,omitempty"`
+	Conditional bool                 `json:"conditional,omitempty"` // the last ToResolve element is the condition
+	Label       string               `json:"label,omitempty"`       // label of a message
+	Stack       []IRFrame            `json:"stack,omitempty"`
+}
+
+// IRFrame is a stack frame of a debug info or log entry
+type IRFrame struct {
+	Function string `json:"function"` // fully qualified function name
+	File     string `json:"file"`
+	Line     int64  `json:"line"`
+}
+
+// systemProvider is implemented by the constraint systems through the embedded System
+type systemProvider interface {
+	getSystem() *System
+}
+
+func (system *System) getSystem() *System {
+	return system
+}
+
+// NewIR returns the IR of a R1CS or SparseR1CS
+func NewIR(cs ConstraintSystem) (*IR, error) {
+	sp, ok := cs.(systemProvider)
+	if !ok {
+		return nil, errors.New("unsupported constraint system")
+	}
+	system := sp.getSystem()
+
+	ir := &IR{
+		Version:    IRVersion,
+		Field:      cs.Field().Text(16),
+		Public:     append([]string{}, system.Public...),
+		Secret:     append([]string{}, system.Secret...),
+		NbInternal: system.NbInternalVariables,
+		Schema:     system.Schema,
+	}
+	if system.CommitmentInfo.Is() {
+		c := system.CommitmentInfo
+		ir.Commitment = &c
+	}
+
+	var r Resolver
+	switch t := cs.(type) {
+	case R1CS:
+		var constraints []R1C
+		constraints, r = t.GetConstraints()
+		ir.Kind = IRKindR1CS
+		ir.R1Cs = make([]IRR1C, len(constraints))
+		for i, c := range constraints {
+			ir.R1Cs[i] = IRR1C{L: irLinearExpression(r, c.L), R: irLinearExpression(r, c.R), O: irLinearExpression(r, c.O)}
+		}
+	case SparseR1CS:
+		var constraints []SparseR1C
+		constraints, r = t.GetConstraints()
+		ir.Kind = IRKindSparseR1CS
+		ir.SparseR1Cs = make([]IRSparseR1C, len(constraints))
+		for i, c := range constraints {
+			ir.SparseR1Cs[i] = IRSparseR1C{
+				L: irTerm(r, c.L),
+				R: irTerm(r, c.R),
+				O: irTerm(r, c.O),
+				M: [2]IRTerm{irTerm(r, c.M[0]), irTerm(r, c.M[1])},
+				K: r.CoeffToString(c.K),
+			}
+		}
+	default:
+		return nil, errors.New("unsupported constraint system")
+	}
+
+	// hints, ordered by their first output wire
+	hints := make([]*Hint, 0, len(system.MHints))
+	seen := make(map[*Hint]struct{}, len(system.MHints))
+	for _, h := range system.MHints {
+		if _, ok := seen[h]; !ok {
+			seen[h] = struct{}{}
+			hints = append(hints, h)
+		}
+	}
+	sort.Slice(hints, func(i, j int) bool { return hints[i].Wires[0] < hints[j].Wires[0] })
+	ir.Hints = make([]IRHint, len(hints))
+	for i, h := range hints {
+		ir.Hints[i] = IRHint{
+			ID:      h.ID,
+			Name:    system.MHintsDependencies[h.ID],
+			Inputs:  make([]IRLinearExpression, len(h.Inputs)),
+			Outputs: append([]int{}, h.Wires...),
+			Stack:   system.irStack(h.Stack),
+		}
+		for j := range h.Inputs {
+			ir.Hints[i].Inputs[j] = irLinearExpression(r, h.Inputs[j])
+		}
+	}
+
+	ir.DebugInfo = make([]IRLogEntry, len(system.DebugInfo))
+	for i := range system.DebugInfo {
+		ir.DebugInfo[i] = system.irLogEntry(r, system.DebugInfo[i])
+	}
+	for cID, dID := range system.MDebug {
+		ir.DebugInfo[dID].Constraints = append(ir.DebugInfo[dID].Constraints, cID)
+	}
+	for i := range ir.DebugInfo {
+		sort.Ints(ir.DebugInfo[i].Constraints)
+	}
+
+	if len(system.Messages) != 0 {
+		ir.Messages = make([]IRLogEntry, len(system.Messages))
+	}
+	for i := range system.Messages {
+		ir.Messages[i] = system.irLogEntry(r, system.Messages[i])
+	}
+	for cID, mID := range system.MMessages {
+		ir.Messages[mID].Constraints = append(ir.Messages[mID].Constraints, cID)
+	}
+	for i := range ir.Messages {
+		sort.Ints(ir.Messages[i].Constraints)
+	}
+
+	ir.Logs = make([]IRLogEntry, len(system.Logs))
+	for i := range system.Logs {
+		ir.Logs[i] = system.irLogEntry(r, system.Logs[i])
+	}
+
+	return ir, nil
+}
+
+// ReadIR reads an IR written with WriteJSON
+func ReadIR(r io.Reader) (*IR, error) {
+	var ir IR
+	if err := json.NewDecoder(r).Decode(&ir); err != nil {
+		return nil, err
+	}
+	if ir.Version != IRVersion {
+		return nil, fmt.Errorf("unsupported IR version %d", ir.Version)
+	}
+	return &ir, nil
+}
+
+// WriteJSON writes the machine readable form of the IR, which can be read with ReadIR
+func (ir *IR) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(ir)
+}
+
+// Curve returns the curve whose scalar field is the field of the IR, or ecc.UNKNOWN
+func (ir *IR) Curve() ecc.ID {
+	q, ok := new(big.Int).SetString(ir.Field, 16)
+	if !ok {
+		return ecc.UNKNOWN
+	}
+	return utils.FieldToCurve(q)
+}
+
+// Build adds the variables, hints, constraints, debug info and logs of the IR to an empty
+// constraint system of the same kind and field, for example:
+//
+//	ir, err := constraint.ReadIR(r)
+//	ccs := groth16.NewCS(ir.Curve())
+//	err = ir.Build(ccs)
+//
+// Coefficient ids and levels are computed by the constraint system, they may differ from the ones
+// of the system the IR was exported from.
+func (ir *IR) Build(cs ConstraintSystem) error {
+	sp, ok := cs.(systemProvider)
+	if !ok {
+		return errors.New("unsupported constraint system")
+	}
+	system := sp.getSystem()
+	if cs.Field().Text(16) != ir.Field {
+		return fmt.Errorf("field mismatch: IR has modulus 0x%s, constraint system 0x%s", ir.Field, cs.Field().Text(16))
+	}
+	if cs.GetNbConstraints() != 0 || len(system.Public)+len(system.Secret)+system.NbInternalVariables != 0 {
+		return errors.New("constraint system is not empty")
+	}
+
+	b := irBuilder{cs: cs, nbWires: len(ir.Public) + len(ir.Secret) + ir.NbInternal, coeffs: make(map[string]Coeff)}
+
+	for _, name := range ir.Public {
+		cs.AddPublicVariable(name)
+	}
+	for _, name := range ir.Secret {
+		cs.AddSecretVariable(name)
+	}
+	for i := 0; i < ir.NbInternal; i++ {
+		cs.AddInternalVariable()
+	}
+
+	for i := range ir.Hints {
+		h := &ir.Hints[i]
+		if name, ok := system.MHintsDependencies[h.ID]; ok && name != h.Name {
+			return fmt.Errorf("hint %d: %s previously registered with same ID as %s", i, h.Name, name)
+		}
+		system.MHintsDependencies[h.ID] = h.Name
+		if len(h.Outputs) == 0 {
+			return fmt.Errorf("hint %d: no output", i)
+		}
+		ch := &Hint{ID: h.ID, Inputs: make([]LinearExpression, len(h.Inputs)), Wires: append([]int{}, h.Outputs...), Stack: b.stack(h.Stack)}
+		for j := range h.Inputs {
+			var err error
+			if ch.Inputs[j], err = b.linearExpression(h.Inputs[j], true); err != nil {
+				return fmt.Errorf("hint %d: %w", i, err)
+			}
+		}
+		for _, w := range h.Outputs {
+			if w < len(ir.Public)+len(ir.Secret) || w >= b.nbWires {
+				return fmt.Errorf("hint %d: output %d is not an internal wire", i, w)
+			}
+			if _, ok := system.MHints[w]; ok {
+				return fmt.Errorf("hint %d: wire %d is the output of several hints", i, w)
+			}
+			system.MHints[w] = ch
+		}
+	}
+
+	if ir.Commitment != nil {
+		if err := cs.AddCommitment(*ir.Commitment); err != nil {
+			return err
+		}
+	}
+	if ir.Schema != nil {
+		cs.SetSchema(ir.Schema)
+	}
+
+	// debug info, in the same order such that the ids are preserved
+	nbConstraints := ir.NbConstraints()
+	for i := range ir.DebugInfo {
+		l, err := b.logEntry(&ir.DebugInfo[i])
+		if err != nil {
+			return fmt.Errorf("debug info %d: %w", i, err)
+		}
+		system.DebugInfo = append(system.DebugInfo, l)
+		for _, cID := range ir.DebugInfo[i].Constraints {
+			if cID < 0 || cID >= nbConstraints {
+				return fmt.Errorf("debug info %d: invalid constraint %d", i, cID)
+			}
+			system.MDebug[cID] = len(system.DebugInfo) - 1
+		}
+	}
+	for i := range ir.Messages {
+		l, err := b.logEntry(&ir.Messages[i])
+		if err != nil {
+			return fmt.Errorf("message %d: %w", i, err)
+		}
+		for _, cID := range ir.Messages[i].Constraints {
+			if cID < 0 || cID >= nbConstraints {
+				return fmt.Errorf("message %d: invalid constraint %d", i, cID)
+			}
+		}
+		cs.AttachMessage(l, ir.Messages[i].Constraints)
+	}
+
+	switch t := cs.(type) {
+	case R1CS:
+		if ir.Kind != IRKindR1CS {
+			return fmt.Errorf("can't build a %s IR in a R1CS", ir.Kind)
+		}
+		for i, c := range ir.R1Cs {
+			var r1c R1C
+			var err error
+			if r1c.L, err = b.linearExpression(c.L, false); err == nil {
+				if r1c.R, err = b.linearExpression(c.R, false); err == nil {
+					r1c.O, err = b.linearExpression(c.O, false)
+				}
+			}
+			if err != nil {
+				return fmt.Errorf("constraint %d: %w", i, err)
+			}
+			t.AddConstraint(r1c)
+		}
+	case SparseR1CS:
+		if ir.Kind != IRKindSparseR1CS {
+			return fmt.Errorf("can't build a %s IR in a SparseR1CS", ir.Kind)
+		}
+		for i, c := range ir.SparseR1Cs {
+			var src = [...]IRTerm{c.L, c.R, c.O, c.M[0], c.M[1]}
+			var terms [5]Term
+			for j := range src {
+				var err error
+				if terms[j], err = b.term(src[j], false); err != nil {
+					return fmt.Errorf("constraint %d: %w", i, err)
+				}
+			}
+			k, err := b.term(IRTerm{Coeff: c.K}, false)
+			if err != nil {
+				return fmt.Errorf("constraint %d: %w", i, err)
+			}
+			t.AddConstraint(SparseR1C{L: terms[0], R: terms[1], O: terms[2], M: [2]Term{terms[3], terms[4]}, K: k.CoeffID()})
+		}
+	default:
+		return errors.New("unsupported constraint system")
+	}
+
+	for i := range ir.Logs {
+		l, err := b.logEntry(&ir.Logs[i])
+		if err != nil {
+			return fmt.Errorf("log %d: %w", i, err)
+		}
+		system.Logs = append(system.Logs, l)
+	}
+
+	return nil
+}
+
+// WriteText writes a human readable form of the IR: the variables, the hints and the
+// constraints, each followed by its debug info if any.
+func (ir *IR) WriteText(w io.Writer) error {
+	var sbb strings.Builder
+	nbPublic, nbSecret := len(ir.Public), len(ir.Secret)
+
+	fmt.Fprintf(&sbb, "# gnark constraint system IR v%d\n", ir.Version)
+	fmt.Fprintf(&sbb, "# %s, field 0x%s", ir.Kind, ir.Field)
+	if curve := ir.Curve(); curve != ecc.UNKNOWN {
+		fmt.Fprintf(&sbb, " (%s)", curve)
+	}
+	sbb.WriteByte('\n')
+	fmt.Fprintf(&sbb, "# %d public, %d secret, %d internal variables, %d constraints\n\n",
+		nbPublic, nbSecret, ir.NbInternal, ir.NbConstraints())
+
+	for i, name := range ir.Public {
+		fmt.Fprintf(&sbb, "public %d %s\n", i, name)
+	}
+	for i, name := range ir.Secret {
+		fmt.Fprintf(&sbb, "secret %d %s\n", nbPublic+i, name)
+	}
+	if len(ir.Hints) != 0 {
+		sbb.WriteByte('\n')
+	}
+	for _, h := range ir.Hints {
+		outputs := make([]string, len(h.Outputs))
+		for i, o := range h.Outputs {
+			outputs[i] = ir.variableString(o)
+		}
+		inputs := make([]string, len(h.Inputs))
+		for i, in := range h.Inputs {
+			inputs[i] = ir.linearExpressionString(in)
+		}
+		fmt.Fprintf(&sbb, "hint %s ← %s(%s)\n", strings.Join(outputs, ", "), h.Name, strings.Join(inputs, ", "))
+	}
+	sbb.WriteByte('\n')
+
+	debugInfo := ir.constraintsDebugInfo()
+	messages := make(map[int]*IRLogEntry)
+	for i := range ir.Messages {
+		for _, cID := range ir.Messages[i].Constraints {
+			messages[cID] = &ir.Messages[i]
+		}
+	}
+	for cID := 0; cID < ir.NbConstraints(); cID++ {
+		fmt.Fprintf(&sbb, "c%d: %s\n", cID, ir.ConstraintString(cID))
+		if m, ok := messages[cID]; ok {
+			fmt.Fprintf(&sbb, "\t# message: %s\n", ir.logEntryString(m))
+		}
+		if d, ok := debugInfo[cID]; ok {
+			for _, line := range strings.Split(ir.logEntryString(d), "\n") {
+				sbb.WriteString("\t# ")
+				sbb.WriteString(line)
+				sbb.WriteByte('\n')
+			}
+		}
+	}
+
+	if len(ir.Logs) != 0 {
+		sbb.WriteByte('\n')
+	}
+	for i := range ir.Logs {
+		fmt.Fprintf(&sbb, "log %s\n", ir.logEntryString(&ir.Logs[i]))
+	}
+
+	_, err := io.WriteString(w, sbb.String())
+	return err
+}
+
+// NbConstraints returns the number of constraints of the IR
+func (ir *IR) NbConstraints() int {
+	return len(ir.R1Cs) + len(ir.SparseR1Cs)
+}
+
+// ConstraintString formats the constraint cID as in R1C.String and SparseR1C.String
+func (ir *IR) ConstraintString(cID int) string {
+	if ir.Kind == IRKindR1CS {
+		c := &ir.R1Cs[cID]
+		return ir.linearExpressionString(c.L) + " ⋅ " + ir.linearExpressionString(c.R) + " == " + ir.linearExpressionString(c.O)
+	}
+	c := &ir.SparseR1Cs[cID]
+	s := ir.termString(c.L) + " + " + ir.termString(c.R) + " + " + ir.termString(c.O)
+	if c.M[0].Coeff != "0" {
+		s += " + " + c.M[0].Coeff + "⋅(" + ir.variableString(c.M[0].Wire) + "×" + ir.variableString(c.M[1].Wire) + ")"
+	}
+	return s + " + " + c.K + " == 0"
+}
+
+// constraintsDebugInfo maps the constraint ids to their debug info
+func (ir *IR) constraintsDebugInfo() map[int]*IRLogEntry {
+	debugInfo := make(map[int]*IRLogEntry)
+	for i := range ir.DebugInfo {
+		for _, cID := range ir.DebugInfo[i].Constraints {
+			debugInfo[cID] = &ir.DebugInfo[i]
+		}
+	}
+	return debugInfo
+}
+
+func (ir *IR) variableString(wire int) string {
+	nbPublic, nbSecret := len(ir.Public), len(ir.Secret)
+	switch {
+	case wire < nbPublic:
+		return ir.Public[wire]
+	case wire < nbPublic+nbSecret:
+		return ir.Secret[wire-nbPublic]
+	default:
+		return fmt.Sprintf("v%d", wire-nbPublic-nbSecret)
+	}
+}
+
+func (ir *IR) termString(t IRTerm) string {
+	if t.Coeff == "0" {
+		return "0"
+	}
+	if t.Wire == -1 || (t.Wire == 0 && ir.Kind == IRKindR1CS && len(ir.Public) != 0 && ir.Public[0] == "1") {
+		// constant, or the one wire of a R1CS
+		return t.Coeff
+	}
+	if t.Coeff == "1" {
+		return ir.variableString(t.Wire)
+	}
+	return t.Coeff + "⋅" + ir.variableString(t.Wire)
+}
+
+func (ir *IR) linearExpressionString(l IRLinearExpression) string {
+	s := make([]string, len(l))
+	for i := range l {
+		s[i] = ir.termString(l[i])
+	}
+	return strings.Join(s, " + ")
+}
+
+func (ir *IR) logEntryString(l *IRLogEntry) string {
+	toResolve := l.ToResolve
+	var condition string
+	if l.Conditional {
+		condition = " if " + ir.linearExpressionString(toResolve[len(toResolve)-1]) + " != 0"
+		toResolve = toResolve[:len(toResolve)-1]
+	}
+	args := make([]interface{}, 0, len(toResolve)+1)
+	limbs := l.Limbs
+	for i := 0; i < len(toResolve); i++ {
+		if len(limbs) == 0 || limbs[0].First != i {
+			args = append(args, irArg(ir.linearExpressionString(toResolve[i])))
+			continue
+		}
+		s := make([]string, limbs[0].NbLimbs)
+		for j := range s {
+			s[j] = ir.linearExpressionString(toResolve[i+j])
+		}
+		args = append(args, irArg(fmt.Sprintf("limbs%d(%s)", limbs[0].BitsPerLimb, strings.Join(s, ", "))))
+		i += limbs[0].NbLimbs - 1
+		limbs = limbs[1:]
+	}
+	if len(l.Stack) != 0 {
+		var stack strings.Builder
+		for _, f := range l.Stack {
+			fmt.Fprintf(&stack, "%s\n\t%s:%d\n", shortFunctionName(f.Function), f.File, f.Line)
+		}
+		args = append(args, stack.String())
+	}
+	return strings.TrimSpace(fmt.Sprintf(l.Format, args...)) + condition
+}
+
+// irArg is an expression of a log entry, printed as is whatever the verb
+type irArg string
+
+func (a irArg) Format(f fmt.State, _ rune) {
+	_, _ = f.Write([]byte(a))
+}
+
+func irTerm(r Resolver, t Term) IRTerm {
+	if t.IsConstant() {
+		return IRTerm{Wire: -1, Coeff: r.CoeffToString(t.CoeffID())}
+	}
+	return IRTerm{Wire: t.WireID(), Coeff: r.CoeffToString(t.CoeffID())}
+}
+
+func irLinearExpression(r Resolver, l LinearExpression) IRLinearExpression {
+	res := make(IRLinearExpression, len(l))
+	for i := range l {
+		res[i] = irTerm(r, l[i])
+	}
+	return res
+}
+
+func (system *System) irLogEntry(r Resolver, l LogEntry) IRLogEntry {
+	res := IRLogEntry{
+		Caller:      l.Caller,
+		Format:      l.Format,
+		ToResolve:   make([]IRLinearExpression, len(l.ToResolve)),
+		Limbs:       l.Limbs,
+		Conditional: l.Conditional,
+		Label:       l.Label,
+		Stack:       make([]IRFrame, len(l.Stack)),
+	}
+	for i := range l.ToResolve {
+		res.ToResolve[i] = irLinearExpression(r, l.ToResolve[i])
+	}
+	copy(res.Stack, system.irStack(l.Stack))
+	return res
+}
+
+// irStack resolves a stack of locations in the symbol table
+func (system *System) irStack(stack []int) []IRFrame {
+	if len(stack) == 0 {
+		return nil
+	}
+	res := make([]IRFrame, len(stack))
+	for i, id := range stack {
+		loc := system.SymbolTable.Locations[id]
+		fn := system.SymbolTable.Functions[loc.FunctionID]
+		res[i] = IRFrame{Function: fn.SystemName, File: fn.Filename, Line: loc.Line}
+	}
+	return res
+}
+
+// irBuilder converts the IR elements to the ones of a constraint system
+type irBuilder struct {
+	cs        ConstraintSystem
+	nbWires   int
+	coeffs    map[string]Coeff
+	functions map[debug.Function]int
+	locations map[debug.Location]int
+}
+
+func (b *irBuilder) term(t IRTerm, allowConstant bool) (Term, error) {
+	c, ok := b.coeffs[t.Coeff]
+	if !ok {
+		v, ok := new(big.Int).SetString(t.Coeff, 0)
+		if !ok {
+			return Term{}, fmt.Errorf("invalid coefficient %q", t.Coeff)
+		}
+		c = b.cs.FromInterface(v)
+		b.coeffs[t.Coeff] = c
+	}
+	if t.Wire == -1 && allowConstant {
+		r := b.cs.MakeTerm(&c, 0)
+		r.MarkConstant()
+		return r, nil
+	}
+	if t.Wire < 0 || t.Wire >= b.nbWires {
+		return Term{}, fmt.Errorf("invalid wire %d", t.Wire)
+	}
+	return b.cs.MakeTerm(&c, t.Wire), nil
+}
+
+func (b *irBuilder) linearExpression(l IRLinearExpression, allowConstant bool) (LinearExpression, error) {
+	res := make(LinearExpression, len(l))
+	for i := range l {
+		var err error
+		if res[i], err = b.term(l[i], allowConstant); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+func (b *irBuilder) logEntry(l *IRLogEntry) (LogEntry, error) {
+	res := LogEntry{Caller: l.Caller, Format: l.Format, Limbs: l.Limbs, Conditional: l.Conditional, Label: l.Label}
+	if len(l.ToResolve) != 0 {
+		res.ToResolve = make([]LinearExpression, len(l.ToResolve))
+	}
+	for i := range l.ToResolve {
+		var err error
+		if res.ToResolve[i], err = b.linearExpression(l.ToResolve[i], true); err != nil {
+			return LogEntry{}, err
+		}
+	}
+	res.Stack = b.stack(l.Stack)
+	return res, nil
+}
+
+// stack adds the frames to the symbol table and returns their locations
+func (b *irBuilder) stack(frames []IRFrame) []int {
+	if len(frames) == 0 {
+		return nil
+	}
+	res := make([]int, len(frames))
+	st := &b.cs.(systemProvider).getSystem().SymbolTable
+	if b.functions == nil {
+		b.functions = make(map[debug.Function]int)
+		b.locations = make(map[debug.Location]int)
+	}
+	for i, f := range frames {
+		fn := debug.Function{Name: shortFunctionName(f.Function), SystemName: f.Function, Filename: f.File}
+		fID, ok := b.functions[fn]
+		if !ok {
+			fID = len(st.Functions)
+			st.Functions = append(st.Functions, fn)
+			b.functions[fn] = fID
+		}
+		loc := debug.Location{FunctionID: fID, Line: f.Line}
+		lID, ok := b.locations[loc]
+		if !ok {
+			lID = len(st.Locations)
+			st.Locations = append(st.Locations, loc)
+			b.locations[loc] = lID
+		}
+		res[i] = lID
+	}
+	return res
+}
+
+// shortFunctionName returns the function name without the package path, see debug.SymbolTable
+func shortFunctionName(function string) string {
+	return function[strings.LastIndexByte(function, '/')+1:]
+}
@@ -0,0 +1,366 @@
+// Copyright 2020 ConsenSys AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package constraint
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// IRDiff describes the differences between two constraint systems, see DiffIR.
+//
+// ! this is an experimental API.
+type IRDiff struct {
+	NbConstraints [2]int // number of constraints before and after
+
+	Inputs []IRInputChange // changes in the public and secret inputs layout
+	Hints  []IRHintChange  // hints which are called a different number of times
+	Groups []IRDiffGroup   // constraint changes, grouped by the location which produced them
+}
+
+// IRInputChange is a change of the input at position Index of the Visibility inputs.
+// Before or After are empty if the input was added or removed.
+type IRInputChange struct {
+	Visibility    string // "public" or "secret"
+	Index         int
+	Before, After string
+}
+
+// IRHintChange is a hint which is called Before times in the first system and After times
+// in the second one
+type IRHintChange struct {
+	Name          string
+	Before, After int
+}
+
+// IRDiffGroup lists the constraint changes produced at a location (the stack of the debug info
+// attached to the constraints, empty if there is none). Constraints are identified by their id.
+type IRDiffGroup struct {
+	Location string
+	Removed  []int    // constraints of the first system which are not in the second one
+	Added    []int    // constraints of the second system which are not in the first one
+	Changed  [][2]int // removed and added constraints at the same location, paired in order
+}
+
+// Diff returns the differences between the constraint systems a and b, see DiffIR
+func Diff(a, b ConstraintSystem) (*IRDiff, error) {
+	irA, err := NewIR(a)
+	if err != nil {
+		return nil, err
+	}
+	irB, err := NewIR(b)
+	if err != nil {
+		return nil, err
+	}
+	return DiffIR(irA, irB), nil
+}
+
+// DiffIR returns the differences between the constraint systems a and b.
+//
+// Constraints are compared modulo the numbering of the internal wires: an internal wire is
+// identified by the hint or the constraint it is solved by, recursively. A refactoring which
+// adds or removes a constraint thus only reports the constraint itself and the ones which depend
+// on the wires it solves.
+//
+// Removed and added constraints produced at the same location are reported as changed.
+func DiffIR(a, b *IR) *IRDiff {
+	d := &IRDiff{NbConstraints: [2]int{a.NbConstraints(), b.NbConstraints()}}
+
+	d.Inputs = append(diffInputs("public", a.Public, b.Public), diffInputs("secret", a.Secret, b.Secret)...)
+
+	// hint usage
+	count := func(ir *IR) map[string]int {
+		m := make(map[string]int)
+		for _, h := range ir.Hints {
+			m[h.Name]++
+		}
+		return m
+	}
+	hintsA, hintsB := count(a), count(b)
+	for name, n := range hintsA {
+		if hintsB[name] != n {
+			d.Hints = append(d.Hints, IRHintChange{Name: name, Before: n, After: hintsB[name]})
+		}
+	}
+	for name, n := range hintsB {
+		if _, ok := hintsA[name]; !ok {
+			d.Hints = append(d.Hints, IRHintChange{Name: name, After: n})
+		}
+	}
+	sort.Slice(d.Hints, func(i, j int) bool { return d.Hints[i].Name < d.Hints[j].Name })
+
+	// constraints, as multisets of canonical keys
+	keysA, keysB := newIRCanonicalizer(a).constraintKeys(), newIRCanonicalizer(b).constraintKeys()
+	idsB := make(map[string][]int, len(keysB))
+	for cID, k := range keysB {
+		idsB[k] = append(idsB[k], cID)
+	}
+	var removed, added []int
+	for cID, k := range keysA {
+		if ids := idsB[k]; len(ids) != 0 {
+			idsB[k] = ids[1:]
+		} else {
+			removed = append(removed, cID)
+		}
+	}
+	for cID, k := range keysB {
+		if ids := idsB[k]; len(ids) != 0 && ids[0] == cID {
+			idsB[k] = ids[1:]
+			added = append(added, cID)
+		}
+	}
+
+	// group by location
+	groups := make(map[string]*IRDiffGroup)
+	group := func(debugInfo map[int]*IRLogEntry, cID int) *IRDiffGroup {
+		var location string
+		if l, ok := debugInfo[cID]; ok {
+			frames := make([]string, len(l.Stack))
+			for i, f := range l.Stack {
+				frames[i] = shortFunctionName(f.Function) + " " + f.File + ":" + strconv.FormatInt(f.Line, 10)
+			}
+			location = strings.Join(frames, " < ")
+		}
+		g, ok := groups[location]
+		if !ok {
+			g = &IRDiffGroup{Location: location}
+			groups[location] = g
+		}
+		return g
+	}
+	debugInfoA, debugInfoB := a.constraintsDebugInfo(), b.constraintsDebugInfo()
+	for _, cID := range removed {
+		g := group(debugInfoA, cID)
+		g.Removed = append(g.Removed, cID)
+	}
+	for _, cID := range added {
+		g := group(debugInfoB, cID)
+		g.Added = append(g.Added, cID)
+	}
+	for _, g := range groups {
+		n := len(g.Removed)
+		if len(g.Added) < n {
+			n = len(g.Added)
+		}
+		for i := 0; i < n; i++ {
+			g.Changed = append(g.Changed, [2]int{g.Removed[i], g.Added[i]})
+		}
+		g.Removed, g.Added = g.Removed[n:], g.Added[n:]
+		d.Groups = append(d.Groups, *g)
+	}
+	sort.Slice(d.Groups, func(i, j int) bool { return d.Groups[i].Location < d.Groups[j].Location })
+
+	return d
+}
+
+// IsEmpty returns true if the constraint systems are the same, modulo the numbering of the
+// internal wires
+func (d *IRDiff) IsEmpty() bool {
+	return len(d.Inputs) == 0 && len(d.Hints) == 0 && len(d.Groups) == 0
+}
+
+// WriteText writes a human readable report of the differences; a and b are the IRs d was
+// computed from.
+func (d *IRDiff) WriteText(w io.Writer, a, b *IR) error {
+	var sbb strings.Builder
+	var nbAdded, nbRemoved, nbChanged int
+	for _, g := range d.Groups {
+		nbAdded += len(g.Added)
+		nbRemoved += len(g.Removed)
+		nbChanged += len(g.Changed)
+	}
+	fmt.Fprintf(&sbb, "constraints: %d → %d (%d added, %d removed, %d changed)\n", d.NbConstraints[0], d.NbConstraints[1], nbAdded, nbRemoved, nbChanged)
+
+	if len(d.Inputs) != 0 {
+		sbb.WriteString("\ninputs:\n")
+	}
+	for _, in := range d.Inputs {
+		switch {
+		case in.Before == "":
+			fmt.Fprintf(&sbb, "\t+ %s %d %s\n", in.Visibility, in.Index, in.After)
+		case in.After == "":
+			fmt.Fprintf(&sbb, "\t- %s %d %s\n", in.Visibility, in.Index, in.Before)
+		default:
+			fmt.Fprintf(&sbb, "\t~ %s %d %s → %s\n", in.Visibility, in.Index, in.Before, in.After)
+		}
+	}
+
+	if len(d.Hints) != 0 {
+		sbb.WriteString("\nhints:\n")
+	}
+	for _, h := range d.Hints {
+		fmt.Fprintf(&sbb, "\t%s: %d → %d calls\n", h.Name, h.Before, h.After)
+	}
+
+	for _, g := range d.Groups {
+		location := g.Location
+		if location == "" {
+			location = "unknown location"
+		}
+		fmt.Fprintf(&sbb, "\nat %s:\n", location)
+		for _, cID := range g.Removed {
+			fmt.Fprintf(&sbb, "\t- c%d: %s\n", cID, a.ConstraintString(cID))
+		}
+		for _, cID := range g.Added {
+			fmt.Fprintf(&sbb, "\t+ c%d: %s\n", cID, b.ConstraintString(cID))
+		}
+		for _, c := range g.Changed {
+			fmt.Fprintf(&sbb, "\t~ c%d: %s\n\t→ c%d: %s\n", c[0], a.ConstraintString(c[0]), c[1], b.ConstraintString(c[1]))
+		}
+	}
+
+	_, err := io.WriteString(w, sbb.String())
+	return err
+}
+
+func diffInputs(visibility string, a, b []string) []IRInputChange {
+	var r []IRInputChange
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var before, after string
+		if i < len(a) {
+			before = a[i]
+		}
+		if i < len(b) {
+			after = b[i]
+		}
+		if before != after {
+			r = append(r, IRInputChange{Visibility: visibility, Index: i, Before: before, After: after})
+		}
+	}
+	return r
+}
+
+// irCanonicalizer labels the wires of an IR independently of their numbering: inputs by
+// their name, hint outputs by the hint and the labels of its inputs, and other internal wires
+// by the constraint which solves them.
+type irCanonicalizer struct {
+	ir     *IR
+	labels []string
+	hints  map[int]int // maps a wire to the hint computing it
+}
+
+func newIRCanonicalizer(ir *IR) *irCanonicalizer {
+	c := &irCanonicalizer{
+		ir:     ir,
+		labels: make([]string, len(ir.Public)+len(ir.Secret)+ir.NbInternal),
+		hints:  make(map[int]int),
+	}
+	for i, name := range ir.Public {
+		c.labels[i] = "public:" + name
+	}
+	for i, name := range ir.Secret {
+		c.labels[len(ir.Public)+i] = "secret:" + name
+	}
+	for i, h := range ir.Hints {
+		for _, w := range h.Outputs {
+			c.hints[w] = i
+		}
+	}
+	return c
+}
+
+// constraintKeys returns the canonical key of each constraint, labeling the wires they solve
+func (c *irCanonicalizer) constraintKeys() []string {
+	keys := make([]string, c.ir.NbConstraints())
+	for cID := range keys {
+		var unsolved []int
+		var key string
+		if c.ir.Kind == IRKindR1CS {
+			r1c := &c.ir.R1Cs[cID]
+			l, r := c.linearExpression(r1c.L, &unsolved), c.linearExpression(r1c.R, &unsolved)
+			if r < l {
+				l, r = r, l
+			}
+			key = l + "*" + r + "=" + c.linearExpression(r1c.O, &unsolved)
+		} else {
+			s := &c.ir.SparseR1Cs[cID]
+			key = strings.Join([]string{
+				c.term(s.L, &unsolved),
+				c.term(s.R, &unsolved),
+				c.term(s.O, &unsolved),
+				c.term(s.M[0], &unsolved) + "*" + c.term(s.M[1], &unsolved),
+				s.K,
+			}, "|")
+		}
+		for i, w := range unsolved {
+			c.labels[w] = irHash(key, strconv.Itoa(i))
+		}
+		keys[cID] = key
+	}
+	return keys
+}
+
+func (c *irCanonicalizer) label(wire int, unsolved *[]int) string {
+	if wire == -1 {
+		return "const"
+	}
+	if wire < 0 || wire >= len(c.labels) {
+		return "invalid:" + strconv.Itoa(wire)
+	}
+	if c.labels[wire] != "" {
+		return c.labels[wire]
+	}
+	if hID, ok := c.hints[wire]; ok {
+		h := &c.ir.Hints[hID]
+		inputs := make([]string, len(h.Inputs))
+		for i := range h.Inputs {
+			inputs[i] = c.linearExpression(h.Inputs[i], unsolved)
+		}
+		hh := irHash(append([]string{"hint", h.Name}, inputs...)...)
+		for i, w := range h.Outputs {
+			c.labels[w] = irHash(hh, strconv.Itoa(i))
+		}
+		return c.labels[wire]
+	}
+	// the wire is solved by the current constraint
+	for i, w := range *unsolved {
+		if w == wire {
+			return "?" + strconv.Itoa(i)
+		}
+	}
+	*unsolved = append(*unsolved, wire)
+	return "?" + strconv.Itoa(len(*unsolved)-1)
+}
+
+func (c *irCanonicalizer) term(t IRTerm, unsolved *[]int) string {
+	if t.Coeff == "0" {
+		return "0"
+	}
+	return t.Coeff + "." + c.label(t.Wire, unsolved)
+}
+
+func (c *irCanonicalizer) linearExpression(l IRLinearExpression, unsolved *[]int) string {
+	terms := make([]string, len(l))
+	for i := range l {
+		terms[i] = c.term(l[i], unsolved)
+	}
+	sort.Strings(terms)
+	return strings.Join(terms, "+")
+}
+
+// irHash returns a short hash of the strings, used as a wire label
+func irHash(s ...string) string {
+	h := sha256.New()
+	for _, e := range s {
+		h.Write([]byte(e))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
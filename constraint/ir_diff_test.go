@@ -0,0 +1,114 @@
+package constraint_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/frontend/cs/scs"
+	"github.com/stretchr/testify/require"
+)
+
+type diffCircuit struct {
+	X, Y    frontend.Variable
+	Z       frontend.Variable `gnark:",public"`
+	variant int
+}
+
+func (c *diffCircuit) Define(api frontend.API) error {
+	if c.variant == 1 {
+		// unrelated constraints, shifting the internal wires
+		api.ToBinary(api.Mul(c.Y, c.Y, c.Y), 8)
+	}
+	x2 := api.Mul(c.X, c.X)
+	x3 := api.Mul(x2, c.X)
+	if c.variant == 2 {
+		// change a coefficient
+		x3 = api.Mul(x3, 3)
+	}
+	api.AssertIsEqual(api.Add(x3, c.Y), c.Z)
+	api.AssertIsBoolean(c.Y)
+	return nil
+}
+
+func TestDiff(t *testing.T) {
+	for _, builder := range []frontend.NewBuilder{r1cs.NewBuilder, scs.NewBuilder} {
+		assert := require.New(t)
+		compile := func(variant int) constraint.ConstraintSystem {
+			ccs, err := frontend.Compile(ecc.BN254.ScalarField(), builder, &diffCircuit{variant: variant})
+			assert.NoError(err)
+			return ccs
+		}
+		base := compile(0)
+
+		// same circuit
+		d, err := constraint.Diff(base, compile(0))
+		assert.NoError(err)
+		assert.True(d.IsEmpty())
+
+		// added constraints, the other ones are the same modulo the renumbering of the wires
+		d, err = constraint.Diff(base, compile(1))
+		assert.NoError(err)
+		assert.False(d.IsEmpty())
+		assert.Empty(d.Inputs)
+		var nbAdded, nbRemoved, nbChanged int
+		for _, g := range d.Groups {
+			nbAdded += len(g.Added)
+			nbRemoved += len(g.Removed)
+			nbChanged += len(g.Changed)
+		}
+		assert.Equal(0, nbRemoved)
+		assert.Equal(0, nbChanged)
+		assert.Equal(d.NbConstraints[1]-d.NbConstraints[0], nbAdded)
+		assert.NotEmpty(d.Hints, "ToBinary uses a hint")
+
+		// changed constraints
+		d, err = constraint.Diff(base, compile(2))
+		assert.NoError(err)
+		assert.False(d.IsEmpty())
+		nbAdded, nbRemoved = 0, 0
+		for _, g := range d.Groups {
+			nbAdded += len(g.Added) + len(g.Changed)
+			nbRemoved += len(g.Removed) + len(g.Changed)
+		}
+		assert.LessOrEqual(nbRemoved, 2)
+		assert.Less(nbRemoved, d.NbConstraints[0])
+		assert.Equal(nbAdded-nbRemoved, d.NbConstraints[1]-d.NbConstraints[0])
+
+		irA, err := constraint.NewIR(base)
+		assert.NoError(err)
+		irB, err := constraint.NewIR(compile(2))
+		assert.NoError(err)
+		var buf bytes.Buffer
+		assert.NoError(d.WriteText(&buf, irA, irB))
+		assert.True(strings.HasPrefix(buf.String(), "constraints: "), buf.String())
+	}
+}
+
+func TestDiffInputs(t *testing.T) {
+	assert := require.New(t)
+
+	a := &constraint.IR{Kind: constraint.IRKindR1CS, Public: []string{"1", "Z"}, Secret: []string{"X", "Y"}}
+	b := &constraint.IR{Kind: constraint.IRKindR1CS, Public: []string{"1", "Y", "Z"}, Secret: []string{"X"}}
+
+	d := constraint.DiffIR(a, b)
+	assert.Equal([]constraint.IRInputChange{
+		{Visibility: "public", Index: 1, Before: "Z", After: "Y"},
+		{Visibility: "public", Index: 2, After: "Z"},
+		{Visibility: "secret", Index: 1, Before: "Y"},
+	}, d.Inputs)
+
+	var buf bytes.Buffer
+	assert.NoError(d.WriteText(&buf, a, b))
+	assert.Equal(`constraints: 0 → 0 (0 added, 0 removed, 0 changed)
+
+inputs:
+	~ public 1 Z → Y
+	+ public 2 Z
+	- secret 1 Y
+`, buf.String())
+}
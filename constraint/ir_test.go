@@ -0,0 +1,172 @@
+package constraint_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/constraint"
+	cs "github.com/consensys/gnark/constraint/bn254"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/frontend/cs/scs"
+	"github.com/consensys/gnark/internal/backend/circuits"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIRRoundTrip(t *testing.T) {
+	names := make([]string, 0, len(circuits.Circuits))
+	for name := range circuits.Circuits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		tc := circuits.Circuits[name]
+		for _, builder := range []struct {
+			name    string
+			builder frontend.NewBuilder
+			newCS   func() constraint.ConstraintSystem
+		}{
+			{"r1cs", r1cs.NewBuilder, func() constraint.ConstraintSystem { return cs.NewR1CS(0) }},
+			{"scs", scs.NewBuilder, func() constraint.ConstraintSystem { return cs.NewSparseR1CS(0) }},
+		} {
+			t.Run(name+"/"+builder.name, func(t *testing.T) {
+				assert := require.New(t)
+
+				ccs, err := frontend.Compile(ecc.BN254.ScalarField(), builder.builder, tc.Circuit)
+				assert.NoError(err)
+				if testing.Short() && ccs.GetNbConstraints() > 50 {
+					return
+				}
+
+				ir, err := constraint.NewIR(ccs)
+				assert.NoError(err)
+				assert.Equal(ecc.BN254, ir.Curve())
+
+				var buf bytes.Buffer
+				assert.NoError(ir.WriteJSON(&buf))
+				expected := buf.String()
+
+				read, err := constraint.ReadIR(&buf)
+				assert.NoError(err)
+				reconstructed := builder.newCS()
+				assert.NoError(read.Build(reconstructed))
+
+				// the IR of the reconstructed constraint system is the same
+				ir2, err := constraint.NewIR(reconstructed)
+				assert.NoError(err)
+				buf.Reset()
+				assert.NoError(ir2.WriteJSON(&buf))
+				assert.Equal(expected, buf.String())
+
+				// and it is solved by the same assignments
+				for _, a := range tc.ValidAssignments {
+					w, err := frontend.NewWitness(a, ecc.BN254.ScalarField())
+					assert.NoError(err)
+					assert.NoError(reconstructed.IsSolved(w, backend.WithHints(tc.HintFunctions...)))
+				}
+				for _, a := range tc.InvalidAssignments {
+					w, err := frontend.NewWitness(a, ecc.BN254.ScalarField())
+					assert.NoError(err)
+					assert.Error(reconstructed.IsSolved(w, backend.WithHints(tc.HintFunctions...)))
+				}
+
+				// text output doesn't fail
+				assert.NoError(ir.WriteText(&buf))
+			})
+		}
+	}
+}
+
+func TestIRBuildErrors(t *testing.T) {
+	assert := require.New(t)
+
+	ir := &constraint.IR{
+		Version: constraint.IRVersion,
+		Kind:    constraint.IRKindR1CS,
+		Field:   ecc.BN254.ScalarField().Text(16),
+		Public:  []string{"1"},
+		Secret:  []string{"X"},
+		R1Cs: []constraint.IRR1C{{
+			L: constraint.IRLinearExpression{{Wire: 1, Coeff: "1"}},
+			R: constraint.IRLinearExpression{{Wire: 1, Coeff: "1"}},
+			O: constraint.IRLinearExpression{{Wire: 2, Coeff: "1"}},
+		}},
+	}
+	assert.Error(ir.Build(cs.NewR1CS(0)), "wire out of range")
+
+	ir.R1Cs[0].O[0] = constraint.IRTerm{Wire: 0, Coeff: "x"}
+	assert.Error(ir.Build(cs.NewR1CS(0)), "invalid coefficient")
+
+	ir.R1Cs[0].O[0] = constraint.IRTerm{Wire: 0, Coeff: "0x10"}
+	assert.Error(ir.Build(cs.NewSparseR1CS(0)), "kind mismatch")
+	assert.NoError(ir.Build(cs.NewR1CS(0)))
+
+	ir.Field = "11"
+	assert.Error(ir.Build(cs.NewR1CS(0)), "field mismatch")
+
+	_, err := constraint.ReadIR(bytes.NewReader([]byte(`{"version":1000}`)))
+	assert.Error(err)
+}
+
+func ExampleIR_WriteText() {
+	r1cs := cs.NewR1CS(0)
+
+	ONE := r1cs.AddPublicVariable("1")
+	Y := r1cs.AddPublicVariable("Y")
+	X := r1cs.AddSecretVariable("X")
+	v0 := r1cs.AddInternalVariable() // X²
+
+	cOne := r1cs.FromInterface(1)
+	cFive := r1cs.FromInterface(5)
+	cMinusOne := r1cs.FromInterface(-1)
+
+	// X² == X * X
+	r1cs.AddConstraint(constraint.R1C{
+		L: constraint.LinearExpression{r1cs.MakeTerm(&cOne, X)},
+		R: constraint.LinearExpression{r1cs.MakeTerm(&cOne, X)},
+		O: constraint.LinearExpression{r1cs.MakeTerm(&cOne, v0)},
+	})
+
+	// Y == X² - X + 5
+	r1cs.AddConstraint(constraint.R1C{
+		L: constraint.LinearExpression{r1cs.MakeTerm(&cOne, Y)},
+		R: constraint.LinearExpression{r1cs.MakeTerm(&cOne, ONE)},
+		O: constraint.LinearExpression{
+			r1cs.MakeTerm(&cFive, ONE),
+			r1cs.MakeTerm(&cMinusOne, X),
+			r1cs.MakeTerm(&cOne, v0),
+		},
+	}, constraint.DebugInfo{Format: "[assertIsEqual] %s == %s\n", ToResolve: []constraint.LinearExpression{
+		{r1cs.MakeTerm(&cOne, Y)},
+		{r1cs.MakeTerm(&cFive, ONE), r1cs.MakeTerm(&cMinusOne, X), r1cs.MakeTerm(&cOne, v0)},
+	}})
+
+	ir, err := constraint.NewIR(r1cs)
+	if err != nil {
+		panic(err)
+	}
+	if err := ir.WriteText(os.Stdout); err != nil {
+		panic(err)
+	}
+	fmt.Println(ir.R1Cs[1].O[1].Coeff)
+
+	// Output:
+	// # gnark constraint system IR v1
+	// # r1cs, field 0x30644e72e131a029b85045b68181585d2833e84879b9709143e1f593f0000001 (bn254)
+	// # 2 public, 1 secret, 1 internal variables, 2 constraints
+	//
+	// public 0 1
+	// public 1 Y
+	// secret 2 X
+	//
+	// c0: X ⋅ X == v0
+	// c1: Y ⋅ 1 == 5 + -1⋅X + v0
+	// 	# [assertIsEqual] Y == 5 + -1⋅X + v0
+	// -1
+}
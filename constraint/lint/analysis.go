@@ -0,0 +1,421 @@
+// Copyright 2020 ConsenSys AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/consensys/gnark/backend/hint"
+	"github.com/consensys/gnark/constraint"
+)
+
+// term is a coeff * wire term, wire == -1 denotes a constant (see constraint.IR)
+type term struct {
+	wire  int
+	coeff *big.Int
+}
+
+type linearExpression []term
+
+type r1c struct {
+	l, r, o linearExpression
+}
+
+// sparseR1C is a qL⋅xa + qR⋅xb + qO⋅xc + qM⋅(xaxb) + qC == 0 constraint
+type sparseR1C struct {
+	l, r, o term
+	qM, k   *big.Int
+}
+
+type hintCall struct {
+	id      hint.ID
+	name    string
+	inputs  []linearExpression
+	outputs []int
+	stack   []constraint.IRFrame
+}
+
+// slots of a constraint
+const (
+	slotL = iota
+	slotR
+	slotO
+)
+
+// analysis holds the constraint system IR with the coefficients reduced modulo q
+type analysis struct {
+	ir       *constraint.IR
+	q        *big.Int
+	sparse   bool
+	nbInputs int
+	nbWires  int
+
+	r1cs   []r1c
+	scs    []sparseR1C
+	hints  []hintCall
+	hintOf map[int]int                  // maps a hint output to its hint
+	stacks map[int][]constraint.IRFrame // maps a constraint to its location
+}
+
+func newAnalysis(ir *constraint.IR) (*analysis, error) {
+	q, ok := new(big.Int).SetString(ir.Field, 16)
+	if !ok {
+		return nil, fmt.Errorf("invalid field %q", ir.Field)
+	}
+	a := &analysis{
+		ir:       ir,
+		q:        q,
+		sparse:   ir.Kind == constraint.IRKindSparseR1CS,
+		nbInputs: len(ir.Public) + len(ir.Secret),
+		hintOf:   make(map[int]int),
+		stacks:   make(map[int][]constraint.IRFrame),
+	}
+	a.nbWires = a.nbInputs + ir.NbInternal
+
+	var err error
+	coeff := func(s string) *big.Int {
+		c, ok := new(big.Int).SetString(s, 0)
+		if !ok {
+			err = fmt.Errorf("invalid coefficient %q", s)
+			return new(big.Int)
+		}
+		return c.Mod(c, q)
+	}
+	toTerm := func(t constraint.IRTerm) term {
+		return term{wire: t.Wire, coeff: coeff(t.Coeff)}
+	}
+	toLinearExpression := func(l constraint.IRLinearExpression) linearExpression {
+		r := make(linearExpression, len(l))
+		for i := range l {
+			r[i] = toTerm(l[i])
+		}
+		return r
+	}
+
+	if a.sparse {
+		a.scs = make([]sparseR1C, len(ir.SparseR1Cs))
+		for i, c := range ir.SparseR1Cs {
+			a.scs[i] = sparseR1C{l: toTerm(c.L), r: toTerm(c.R), o: toTerm(c.O), k: coeff(c.K)}
+			a.scs[i].qM = a.mul(coeff(c.M[0].Coeff), coeff(c.M[1].Coeff))
+		}
+	} else {
+		a.r1cs = make([]r1c, len(ir.R1Cs))
+		for i, c := range ir.R1Cs {
+			a.r1cs[i] = r1c{l: toLinearExpression(c.L), r: toLinearExpression(c.R), o: toLinearExpression(c.O)}
+		}
+	}
+	a.hints = make([]hintCall, len(ir.Hints))
+	for i, h := range ir.Hints {
+		a.hints[i] = hintCall{id: h.ID, name: h.Name, outputs: h.Outputs, stack: h.Stack, inputs: make([]linearExpression, len(h.Inputs))}
+		for j := range h.Inputs {
+			a.hints[i].inputs[j] = toLinearExpression(h.Inputs[j])
+		}
+		for _, w := range h.Outputs {
+			a.hintOf[w] = i
+		}
+	}
+	for _, d := range ir.DebugInfo {
+		for _, cID := range d.Constraints {
+			a.stacks[cID] = d.Stack
+		}
+	}
+	return a, err
+}
+
+// nbConstraints returns the number of constraints
+func (a *analysis) nbConstraints() int {
+	if a.sparse {
+		return len(a.scs)
+	}
+	return len(a.r1cs)
+}
+
+// wireName returns the name of the input, or v<i> for the i-th internal wire
+func (a *analysis) wireName(w int) string {
+	switch {
+	case w < len(a.ir.Public):
+		return a.ir.Public[w]
+	case w < a.nbInputs:
+		return a.ir.Secret[w-len(a.ir.Public)]
+	default:
+		return "v" + strconv.Itoa(w-a.nbInputs)
+	}
+}
+
+// relevantSlots returns the wires of the slots of a SparseR1C which have a non-zero
+// coefficient (-1 otherwise)
+func (c *sparseR1C) relevantSlots() [3]int {
+	res := [3]int{-1, -1, -1}
+	if c.l.coeff.Sign() != 0 || c.qM.Sign() != 0 {
+		res[slotL] = c.l.wire
+	}
+	if c.r.coeff.Sign() != 0 || c.qM.Sign() != 0 {
+		res[slotR] = c.r.wire
+	}
+	if c.o.coeff.Sign() != 0 {
+		res[slotO] = c.o.wire
+	}
+	return res
+}
+
+// isConstant returns true if l has no wire but the one wire (R1CS), and its value
+func (a *analysis) isConstant(l linearExpression) (*big.Int, bool) {
+	v := new(big.Int)
+	for _, t := range l {
+		if t.coeff.Sign() == 0 {
+			continue
+		}
+		if t.wire > 0 {
+			return nil, false
+		}
+		v = a.add(v, t.coeff)
+	}
+	return v, true
+}
+
+// checkWires reports the inputs and hint outputs which are not, or only linearly, constrained
+func (a *analysis) checkWires(report *Report) {
+	used := make([]bool, a.nbWires)
+	nonLinear := make([]bool, a.nbWires)
+	inHintInputs := make([]bool, a.nbWires)
+	for cID := 0; cID < a.nbConstraints(); cID++ {
+		if a.sparse {
+			c := &a.scs[cID]
+			for slot, w := range c.relevantSlots() {
+				if w == -1 {
+					continue
+				}
+				used[w] = true
+				nonLinear[w] = nonLinear[w] || (slot != slotO && c.qM.Sign() != 0)
+			}
+			continue
+		}
+		c := &a.r1cs[cID]
+		_, lConstant := a.isConstant(c.l)
+		_, rConstant := a.isConstant(c.r)
+		for _, e := range [...]struct {
+			l         linearExpression
+			nonLinear bool
+		}{{c.l, !rConstant}, {c.r, !lConstant}, {c.o, false}} {
+			for _, t := range e.l {
+				if t.coeff.Sign() != 0 {
+					used[t.wire] = true
+					nonLinear[t.wire] = nonLinear[t.wire] || e.nonLinear
+				}
+			}
+		}
+	}
+	for _, h := range a.hints {
+		for _, in := range h.inputs {
+			for _, t := range in {
+				if t.wire >= 0 {
+					inHintInputs[t.wire] = true
+				}
+			}
+		}
+	}
+
+	for w := 0; w < a.nbInputs; w++ {
+		if !a.sparse && w == 0 {
+			continue // the one wire
+		}
+		if !used[w] {
+			report.Issues = append(report.Issues, Issue{
+				Kind:       UnconstrainedInput,
+				Severity:   Error,
+				Wire:       a.wireName(w),
+				Constraint: -1,
+				Message:    fmt.Sprintf("input %s is not constrained", a.wireName(w)),
+			})
+		}
+	}
+	for _, h := range a.hints {
+		for i, w := range h.outputs {
+			issue := Issue{Severity: Warning, Wire: a.wireName(w), Constraint: -1, Stack: h.stack}
+			switch {
+			case !used[w]:
+				issue.Kind = UnconstrainedHintOutput
+				issue.Message = fmt.Sprintf("output %d (%s) of hint %s is not constrained", i, a.wireName(w), h.name)
+				if inHintInputs[w] {
+					issue.Message += ", it is only an input of other hints"
+				}
+			case !nonLinear[w]:
+				issue.Kind = LinearHintOutput
+				issue.Message = fmt.Sprintf("output %d (%s) of hint %s is only constrained linearly", i, a.wireName(w), h.name)
+			default:
+				continue
+			}
+			report.Issues = append(report.Issues, issue)
+		}
+	}
+}
+
+// solverOrder returns, for each constraint, the wire it solves (-1 if none) and its slot.
+// Hint outputs are solved by the hints.
+func (a *analysis) solverOrder() (wires, slots []int) {
+	solved := make([]bool, a.nbWires)
+	for w := range solved {
+		_, isHint := a.hintOf[w]
+		solved[w] = w < a.nbInputs || isHint
+	}
+	wires, slots = make([]int, a.nbConstraints()), make([]int, a.nbConstraints())
+	for cID := range wires {
+		wires[cID] = -1
+		if a.sparse {
+			for slot, w := range a.scs[cID].relevantSlots() {
+				if w >= 0 && !solved[w] {
+					wires[cID], slots[cID] = w, slot
+				}
+			}
+		} else {
+			c := &a.r1cs[cID]
+			for slot, l := range [...]linearExpression{c.l, c.r, c.o} {
+				for _, t := range l {
+					if t.wire >= 0 && !solved[t.wire] && wires[cID] == -1 {
+						wires[cID], slots[cID] = t.wire, slot
+					}
+				}
+			}
+		}
+		if wires[cID] != -1 {
+			solved[wires[cID]] = true
+		}
+	}
+	return
+}
+
+// checkDivisions reports the wires solved by a division by a value which isn't constrained
+// to be non-zero: when it is zero, the wire can take any value.
+func (a *analysis) checkDivisions(report *Report) {
+	// expressions constrained to be non-zero, by a constraint x ⋅ y == k with k ≠ 0
+	nonZero := make(map[string]struct{})
+	for cID := 0; cID < a.nbConstraints(); cID++ {
+		if a.sparse {
+			c := &a.scs[cID]
+			if c.qM.Sign() != 0 && c.l.coeff.Sign() == 0 && c.r.coeff.Sign() == 0 && c.o.coeff.Sign() == 0 && c.k.Sign() != 0 {
+				nonZero[strconv.Itoa(c.l.wire)] = struct{}{}
+				nonZero[strconv.Itoa(c.r.wire)] = struct{}{}
+			}
+			continue
+		}
+		c := &a.r1cs[cID]
+		if k, ok := a.isConstant(c.o); ok && k.Sign() != 0 {
+			nonZero[a.key(c.l)] = struct{}{}
+			nonZero[a.key(c.r)] = struct{}{}
+		}
+	}
+
+	wires, slots := a.solverOrder()
+	for cID, w := range wires {
+		if w == -1 || slots[cID] == slotO {
+			continue
+		}
+		var divisor string
+		if a.sparse {
+			// l ⋅ (qL + qM⋅r) == -(qR⋅r + qO⋅o + k)
+			c := &a.scs[cID]
+			if c.qM.Sign() == 0 || (c.r.coeff.Sign() == 0 && c.o.coeff.Sign() == 0 && c.k.Sign() != 0) {
+				continue
+			}
+			if _, ok := nonZero[strconv.Itoa(c.r.wire)]; ok && c.l.coeff.Sign() == 0 {
+				continue
+			}
+			divisor = a.wireName(c.r.wire)
+			if c.l.coeff.Sign() != 0 {
+				divisor = fmt.Sprintf("%s + %s⋅%s", c.l.coeff, c.qM, divisor)
+			}
+		} else {
+			c := &a.r1cs[cID]
+			d := c.r
+			if slots[cID] == slotR {
+				d = c.l
+			}
+			if _, ok := a.isConstant(d); ok {
+				continue
+			}
+			if k, ok := a.isConstant(c.o); ok && k.Sign() != 0 {
+				continue
+			}
+			if _, ok := nonZero[a.key(d)]; ok {
+				continue
+			}
+			divisor = a.linearExpressionString(d)
+		}
+		report.Issues = append(report.Issues, Issue{
+			Kind:       UncheckedDivision,
+			Severity:   Warning,
+			Wire:       a.wireName(w),
+			Constraint: cID,
+			Message:    fmt.Sprintf("%s is solved by a division by %s, which isn't constrained to be non-zero", a.wireName(w), divisor),
+			Stack:      a.stacks[cID],
+		})
+	}
+}
+
+// key returns a string identifying the linear expression
+func (a *analysis) key(l linearExpression) string {
+	coeffs := make(map[int]*big.Int)
+	for _, t := range l {
+		if c, ok := coeffs[t.wire]; ok {
+			coeffs[t.wire] = a.add(c, t.coeff)
+		} else {
+			coeffs[t.wire] = t.coeff
+		}
+	}
+	wires := make([]int, 0, len(coeffs))
+	for w, c := range coeffs {
+		if c.Sign() != 0 {
+			wires = append(wires, w)
+		}
+	}
+	sort.Ints(wires)
+	var sbb strings.Builder
+	for _, w := range wires {
+		sbb.WriteString(coeffs[w].String())
+		sbb.WriteByte('.')
+		sbb.WriteString(strconv.Itoa(w))
+		sbb.WriteByte(' ')
+	}
+	return sbb.String()
+}
+
+func (a *analysis) linearExpressionString(l linearExpression) string {
+	var sbb strings.Builder
+	for i, t := range l {
+		if i > 0 {
+			sbb.WriteString(" + ")
+		}
+		if t.coeff.Cmp(big.NewInt(1)) != 0 {
+			sbb.WriteString(t.coeff.String())
+			sbb.WriteString("⋅")
+		}
+		sbb.WriteString(a.wireName(t.wire))
+	}
+	return sbb.String()
+}
+
+func (a *analysis) add(x, y *big.Int) *big.Int {
+	r := new(big.Int).Add(x, y)
+	return r.Mod(r, a.q)
+}
+
+func (a *analysis) mul(x, y *big.Int) *big.Int {
+	r := new(big.Int).Mul(x, y)
+	return r.Mod(r, a.q)
+}
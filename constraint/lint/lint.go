@@ -0,0 +1,264 @@
+// Copyright 2020 ConsenSys AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package lint detects under-constrained circuits.
+//
+// The static checks look for inputs and hint outputs which are not (or only linearly)
+// constrained, and for wires solved by a division by a value which isn't constrained to be
+// non-zero (see frontend.API.DivUnchecked).
+//
+// Given some valid assignments (see WithAssignments), the dynamic checks look for other
+// witnesses satisfying the constraints with the same inputs, and report the hint calls (or
+// the wires solved by a division by zero) which take other values: the witness is then not
+// determined by the inputs. An issue they report comes with another valid witness.
+//
+// Over small fields (see internal/tinyfield), the witnesses are enumerated exhaustively: the
+// values of the hint outputs and of the free wires are chosen one at a time, and the choices
+// which don't satisfy a constraint are pruned. If the search completes within its budget
+// (see WithSearchBudget and Report.Exhaustive), the witness is proven to be the only one for
+// the inputs of the assignment. This makes small instances of a circuit over a small field
+// the best target of the dynamic checks.
+//
+// Otherwise, and in addition, the dynamic checks solve the constraint system with a sample
+// of other outputs for each hint call: all the values of each output and of the pairs of
+// outputs over small fields, and a few perturbations and random values otherwise. This is a
+// heuristic, which can miss issues: a report without issues doesn't prove the circuit sound.
+package lint
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+
+	"github.com/consensys/gnark/backend/hint"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/constraint"
+)
+
+// Severity of an issue
+type Severity int
+
+const (
+	Warning Severity = iota
+	Error
+)
+
+func (s Severity) String() string {
+	if s == Error {
+		return "error"
+	}
+	return "warning"
+}
+
+// Kind of an issue
+type Kind int
+
+const (
+	// UnconstrainedInput is an input which doesn't appear in any constraint
+	UnconstrainedInput Kind = iota
+	// UnconstrainedHintOutput is a hint output which doesn't appear in any constraint
+	UnconstrainedHintOutput
+	// LinearHintOutput is a hint output which is never multiplied by a wire
+	LinearHintOutput
+	// UncheckedDivision is a wire solved by a division by a value which isn't constrained
+	// to be non-zero
+	UncheckedDivision
+	// FreeWire is a wire which can take another value for a given assignment (dynamic
+	// check)
+	FreeWire
+	// NonDeterministicHint is a hint call whose outputs can be changed without violating
+	// the constraints for a given assignment (dynamic check)
+	NonDeterministicHint
+)
+
+func (k Kind) String() string {
+	switch k {
+	case UnconstrainedInput:
+		return "unconstrained-input"
+	case UnconstrainedHintOutput:
+		return "unconstrained-hint-output"
+	case LinearHintOutput:
+		return "linear-hint-output"
+	case UncheckedDivision:
+		return "unchecked-division"
+	case FreeWire:
+		return "free-wire"
+	case NonDeterministicHint:
+		return "non-deterministic-hint"
+	default:
+		return fmt.Sprintf("kind(%d)", int(k))
+	}
+}
+
+// Issue is a potential soundness issue
+type Issue struct {
+	Kind       Kind
+	Severity   Severity
+	Wire       string // wire the issue is about
+	Constraint int    // constraint the issue is about, -1 if none
+	Message    string
+
+	// Stack is the location of the hint call or of the constraint, from the symbol table of
+	// the constraint system (innermost call first). It is empty if the location is unknown.
+	Stack []constraint.IRFrame
+}
+
+// Location returns the file:line of the outermost frame of the stack, or "?"
+func (issue *Issue) Location() string {
+	if len(issue.Stack) == 0 {
+		return "?"
+	}
+	f := issue.Stack[len(issue.Stack)-1]
+	return fmt.Sprintf("%s:%d", filepath.Base(f.File), f.Line)
+}
+
+func (issue *Issue) String() string {
+	return fmt.Sprintf("%s: %s: %s (%s)", issue.Location(), issue.Severity, issue.Message, issue.Kind)
+}
+
+// Report lists the issues found in a constraint system
+type Report struct {
+	Issues []Issue
+
+	// Exhaustive tells, for each assignment, if the exhaustive search of its witnesses
+	// completed. If so and it didn't report an issue, the witness of the assignment is the
+	// only one for its inputs.
+	Exhaustive []bool
+}
+
+// NbErrors returns the number of issues with the Error severity
+func (r *Report) NbErrors() int {
+	n := 0
+	for i := range r.Issues {
+		if r.Issues[i].Severity == Error {
+			n++
+		}
+	}
+	return n
+}
+
+// Filter returns the issues of the given kind
+func (r *Report) Filter(kind Kind) []Issue {
+	var res []Issue
+	for _, issue := range r.Issues {
+		if issue.Kind == kind {
+			res = append(res, issue)
+		}
+	}
+	return res
+}
+
+// WriteText writes the issues with their stack, one per line
+func (r *Report) WriteText(w io.Writer) error {
+	for i := range r.Issues {
+		if _, err := fmt.Fprintln(w, r.Issues[i].String()); err != nil {
+			return err
+		}
+		for _, f := range r.Issues[i].Stack {
+			if _, err := fmt.Fprintf(w, "\t%s\n\t\t%s:%d\n", f.Function, filepath.Base(f.File), f.Line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Option configures Check
+type Option func(*config) error
+
+type config struct {
+	assignments  []witness.Witness
+	hints        []hint.Function
+	nbSamples    int
+	searchBudget int
+}
+
+// WithAssignments enables the dynamic checks on the given full witnesses, which must
+// satisfy the constraint system.
+func WithAssignments(assignments ...witness.Witness) Option {
+	return func(c *config) error {
+		c.assignments = append(c.assignments, assignments...)
+		return nil
+	}
+}
+
+// WithHints registers hint functions needed to solve the constraint system, in addition
+// to the ones of the hint registry.
+func WithHints(hints ...hint.Function) Option {
+	return func(c *config) error {
+		c.hints = append(c.hints, hints...)
+		return nil
+	}
+}
+
+// WithSamples sets the number of random outputs tried for each hint call by the dynamic
+// checks, in addition to small perturbations of the actual outputs. The default is 16.
+// Over small fields, all the values of each output are tried.
+func WithSamples(n int) Option {
+	return func(c *config) error {
+		if n < 0 {
+			return errors.New("invalid number of samples")
+		}
+		c.nbSamples = n
+		return nil
+	}
+}
+
+// WithSearchBudget sets the maximum number of times the constraint system is solved by the
+// exhaustive search of the witnesses of an assignment over a small field. The default is
+// 1 << 16, and 0 disables the search.
+func WithSearchBudget(n int) Option {
+	return func(c *config) error {
+		if n < 0 {
+			return errors.New("invalid search budget")
+		}
+		c.searchBudget = n
+		return nil
+	}
+}
+
+// Check runs the static checks on the constraint system, and the dynamic ones if
+// assignments are given.
+func Check(cs constraint.ConstraintSystem, opts ...Option) (*Report, error) {
+	cfg := config{nbSamples: 16, searchBudget: 1 << 16}
+	for _, opt := range opts {
+		if err := opt(&cfg); err != nil {
+			return nil, err
+		}
+	}
+	ir, err := constraint.NewIR(cs)
+	if err != nil {
+		return nil, err
+	}
+	a, err := newAnalysis(ir)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{}
+	a.checkWires(report)
+	a.checkDivisions(report)
+	for i, w := range cfg.assignments {
+		if err := a.checkAssignment(report, w, &cfg); err != nil {
+			return nil, fmt.Errorf("assignment %d: %w", i, err)
+		}
+	}
+
+	sort.SliceStable(report.Issues, func(i, j int) bool {
+		return report.Issues[i].Severity > report.Issues[j].Severity
+	})
+	return report, nil
+}
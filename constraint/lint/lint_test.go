@@ -0,0 +1,264 @@
+package lint_test
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/hint"
+	"github.com/consensys/gnark/constraint/lint"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/frontend/cs/scs"
+	"github.com/consensys/gnark/internal/backend/circuits"
+	"github.com/consensys/gnark/internal/tinyfield"
+	"github.com/consensys/gnark/std/math/bits"
+	"github.com/stretchr/testify/require"
+)
+
+var builders = []struct {
+	name    string
+	builder frontend.NewBuilder
+}{
+	{"r1cs", r1cs.NewBuilder},
+	{"scs", scs.NewBuilder},
+}
+
+func kinds(report *lint.Report) []string {
+	var res []string
+	for _, issue := range report.Issues {
+		res = append(res, issue.Kind.String())
+	}
+	sort.Strings(res)
+	return res
+}
+
+type staticCircuit struct {
+	X, Y, Unused frontend.Variable
+	Z            frontend.Variable `gnark:",public"`
+}
+
+func (c *staticCircuit) Define(api frontend.API) error {
+	q := api.DivUnchecked(c.X, c.Y)
+	api.AssertIsEqual(api.Mul(q, c.Y), c.Z)
+
+	b, err := api.Compiler().NewHint(bits.NBits, 2, c.X)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(api.Add(b[0], api.Mul(b[1], 2)), c.X)
+
+	if _, err := api.Compiler().NewHint(hint.InvZero, 1, c.Y); err != nil {
+		return err
+	}
+
+	// the divisor of a checked division is constrained to be non-zero
+	api.AssertIsEqual(api.Div(c.X, c.Z), c.Y)
+	api.AssertIsEqual(api.DivUnchecked(c.Y, c.Z), c.X)
+	return nil
+}
+
+func TestStatic(t *testing.T) {
+	for _, b := range builders {
+		t.Run(b.name, func(t *testing.T) {
+			assert := require.New(t)
+			ccs, err := frontend.Compile(ecc.BN254.ScalarField(), b.builder, &staticCircuit{}, frontend.IgnoreUnconstrainedInputs())
+			assert.NoError(err)
+
+			report, err := lint.Check(ccs)
+			assert.NoError(err)
+			assert.Equal([]string{
+				"linear-hint-output", "linear-hint-output",
+				"unchecked-division",
+				"unconstrained-hint-output",
+				"unconstrained-input",
+			}, kinds(report))
+			assert.Equal(1, report.NbErrors())
+			assert.Equal("Unused", report.Filter(lint.UnconstrainedInput)[0].Wire)
+
+			// the issues point to the circuit
+			for _, issue := range report.Issues {
+				if issue.Kind != lint.UnconstrainedInput {
+					assert.True(strings.HasPrefix(issue.Location(), "lint_test.go:"), issue.String())
+				}
+			}
+
+			var buf bytes.Buffer
+			assert.NoError(report.WriteText(&buf))
+			assert.Contains(buf.String(), "error: input Unused is not constrained (unconstrained-input)")
+		})
+	}
+}
+
+// decomposition decomposes X in bits, and forgets to constrain them to be boolean
+type decomposition struct {
+	X       frontend.Variable
+	Boolean bool `gnark:"-"`
+}
+
+func (c *decomposition) Define(api frontend.API) error {
+	b, err := api.Compiler().NewHint(bits.NBits, 3, c.X)
+	if err != nil {
+		return err
+	}
+	if c.Boolean {
+		for i := range b {
+			api.AssertIsBoolean(b[i])
+		}
+	}
+	api.AssertIsEqual(api.Add(b[0], api.Mul(b[1], 2), api.Mul(b[2], 4)), c.X)
+	return nil
+}
+
+// division doesn't check its divisor
+type division struct {
+	X, Y frontend.Variable
+}
+
+func (c *division) Define(api frontend.API) error {
+	api.DivUnchecked(c.X, c.Y)
+	return nil
+}
+
+// split splits X in two hint outputs, only constrained by their sum: the outputs of each hint
+// are determined by the other one, and can only be changed together
+type split struct {
+	X frontend.Variable
+}
+
+func copyHint(_ *big.Int, inputs, outputs []*big.Int) error {
+	outputs[0].Set(inputs[0])
+	return nil
+}
+
+func (c *split) Define(api frontend.API) error {
+	a, err := api.Compiler().NewHint(copyHint, 1, c.X)
+	if err != nil {
+		return err
+	}
+	b, err := api.Compiler().NewHint(copyHint, 1, c.X)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(api.Add(a[0], b[0]), api.Mul(c.X, 2))
+	return nil
+}
+
+func TestDynamic(t *testing.T) {
+	for _, b := range builders {
+		t.Run(b.name, func(t *testing.T) {
+			assert := require.New(t)
+			field := tinyfield.Modulus()
+
+			check := func(circuit, assignment frontend.Circuit) *lint.Report {
+				ccs, err := frontend.Compile(field, b.builder, circuit)
+				assert.NoError(err)
+				w, err := frontend.NewWitness(assignment, field)
+				assert.NoError(err)
+				report, err := lint.Check(ccs, lint.WithAssignments(w))
+				assert.NoError(err)
+				return report
+			}
+
+			report := check(&decomposition{}, &decomposition{X: 5})
+			issues := report.Filter(lint.NonDeterministicHint)
+			assert.Len(issues, 1, kinds(report))
+			assert.Contains(issues[0].Message, "instead of [1, 0, 1]")
+			assert.True(strings.HasPrefix(issues[0].Location(), "lint_test.go:"))
+
+			report = check(&decomposition{Boolean: true}, &decomposition{X: 5})
+			assert.Empty(report.Issues)
+			assert.Equal([]bool{true}, report.Exhaustive)
+
+			report = check(&division{}, &division{X: 0, Y: 0})
+			assert.Len(report.Filter(lint.UncheckedDivision), 1)
+			issues = report.Filter(lint.FreeWire)
+			assert.Len(issues, 1, kinds(report))
+			assert.Equal(lint.Error, issues[0].Severity)
+
+			report = check(&division{}, &division{X: 3, Y: 2})
+			assert.Empty(report.Filter(lint.FreeWire))
+
+			// an invalid assignment is an error
+			ccs, err := frontend.Compile(field, b.builder, &decomposition{})
+			assert.NoError(err)
+			w, err := frontend.NewWitness(&decomposition{X: 9}, field)
+			assert.NoError(err)
+			_, err = lint.Check(ccs, lint.WithAssignments(w))
+			assert.Error(err)
+		})
+	}
+}
+
+// TestExhaustive checks that the exhaustive search finds the witnesses which differ in several
+// hint calls, which the sampling of the outputs of each hint call misses
+func TestExhaustive(t *testing.T) {
+	for _, b := range builders {
+		t.Run(b.name, func(t *testing.T) {
+			assert := require.New(t)
+			field := tinyfield.Modulus()
+			ccs, err := frontend.Compile(field, b.builder, &split{})
+			assert.NoError(err)
+			w, err := frontend.NewWitness(&split{X: 5}, field)
+			assert.NoError(err)
+
+			// sampling only
+			report, err := lint.Check(ccs, lint.WithAssignments(w), lint.WithHints(copyHint), lint.WithSearchBudget(0))
+			assert.NoError(err)
+			assert.Empty(report.Filter(lint.NonDeterministicHint))
+			assert.Equal([]bool{false}, report.Exhaustive)
+
+			report, err = lint.Check(ccs, lint.WithAssignments(w), lint.WithHints(copyHint))
+			assert.NoError(err)
+			issues := report.Filter(lint.NonDeterministicHint)
+			assert.Len(issues, 1, kinds(report))
+			assert.Equal(lint.Error, issues[0].Severity)
+			assert.Contains(issues[0].Message, "instead of 5, changing")
+			assert.Contains(issues[0].Message, "(found by exhaustive search)")
+			assert.Equal([]bool{false}, report.Exhaustive)
+
+			// a budget too small leaves the search incomplete
+			report, err = lint.Check(ccs, lint.WithAssignments(w), lint.WithHints(copyHint), lint.WithSearchBudget(2))
+			assert.NoError(err)
+			assert.Empty(report.Filter(lint.NonDeterministicHint))
+			assert.Equal([]bool{false}, report.Exhaustive)
+		})
+	}
+}
+
+// TestCircuits checks that the dynamic checks solve the test circuits
+func TestCircuits(t *testing.T) {
+	names := make([]string, 0, len(circuits.Circuits))
+	for name := range circuits.Circuits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		tc := circuits.Circuits[name]
+		for _, b := range builders {
+			t.Run(name+"/"+b.name, func(t *testing.T) {
+				assert := require.New(t)
+				ccs, err := frontend.Compile(ecc.BN254.ScalarField(), b.builder, tc.Circuit)
+				assert.NoError(err)
+				if ccs.GetNbConstraints() > 100 {
+					return
+				}
+				opts := []lint.Option{lint.WithHints(tc.HintFunctions...), lint.WithSamples(4)}
+				for _, a := range tc.ValidAssignments {
+					w, err := frontend.NewWitness(a, ecc.BN254.ScalarField())
+					assert.NoError(err)
+					opts = append(opts, lint.WithAssignments(w))
+				}
+				report, err := lint.Check(ccs, opts...)
+				assert.NoError(err)
+				for _, issue := range report.Issues {
+					t.Log(issue.String())
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,617 @@
+// Copyright 2020 ConsenSys AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/consensys/gnark/backend/hint"
+	"github.com/consensys/gnark/backend/witness"
+)
+
+// smallField is the size under which all the values of a hint output are tried
+const smallField = 1 << 10
+
+// maxPairs and maxPairValues bound the number of outputs of a hint and the number of values
+// of a pair of outputs under which all the values of each pair are tried
+const (
+	maxPairs      = 8
+	maxPairValues = 1 << 14
+)
+
+var (
+	errNotSatisfied = errors.New("constraint not satisfied")
+	errBudget       = errors.New("search budget exhausted")
+)
+
+// errChoice is returned by the solver of the exhaustive search when the value of a hint
+// output or of a free wire must be chosen
+type errChoice struct {
+	wire int
+}
+
+func (e *errChoice) Error() string {
+	return fmt.Sprintf("wire %d must be chosen", e.wire)
+}
+
+// solver solves the constraint system over big.Int, in the order of the constraint system
+// solvers, with the hint outputs optionally replaced
+type solver struct {
+	*analysis
+	functions map[hint.ID]hint.Function
+
+	values     []*big.Int
+	free       []int                           // wires which can take any value
+	freeValues map[int]*big.Int                // values of the free wires (0 by default)
+	override   func(h int, outputs []*big.Int) // replaces the outputs of the hint h
+
+	// choices are the values of the hint outputs and of the free wires, if not nil: the
+	// hints aren't called, and the solver returns an errChoice for the wires without value
+	choices map[int]*big.Int
+}
+
+// checkAssignment runs the dynamic checks on a valid assignment
+func (a *analysis) checkAssignment(report *Report, w witness.Witness, cfg *config) error {
+	inputs, err := a.inputs(w)
+	if err != nil {
+		return err
+	}
+	s := &solver{analysis: a, functions: make(map[hint.ID]hint.Function)}
+	for _, f := range append(hint.GetRegistered(), cfg.hints...) {
+		s.functions[hint.UUID(f)] = f
+		s.functions[hint.LegacyUUID(f)] = f
+	}
+
+	if err := s.solve(inputs); err != nil {
+		return err
+	}
+	base, free := s.values, s.free
+
+	// over a small field, all the witnesses of the inputs are enumerated
+	exhaustive := false
+	if a.q.Cmp(big.NewInt(smallField)) < 0 && cfg.searchBudget > 0 {
+		budget := cfg.searchBudget
+		alt, err := s.search(inputs, base, &budget)
+		switch {
+		case err == errBudget:
+		case err != nil:
+			return err
+		case alt == nil:
+			exhaustive = true
+		default:
+			a.reportWitness(report, base, alt)
+		}
+	}
+	report.Exhaustive = append(report.Exhaustive, exhaustive)
+
+	// wires solved by a division by zero, which may be constrained by other constraints
+	for _, w := range free {
+		for _, alt := range a.alternatives([]*big.Int{base[w]}, cfg.nbSamples) {
+			s.freeValues = map[int]*big.Int{w: alt[0]}
+			if s.solve(inputs) != nil {
+				continue
+			}
+			cID := a.solvingConstraint(w)
+			a.addIssue(report, Issue{
+				Kind:       FreeWire,
+				Severity:   Error,
+				Wire:       a.wireName(w),
+				Constraint: cID,
+				Message:    fmt.Sprintf("%s can be %s instead of %s (division by zero, found by sampling)", a.wireName(w), alt[0], base[w]),
+				Stack:      a.stacks[cID],
+			})
+			break
+		}
+	}
+	s.freeValues = nil
+
+	for h := range a.hints {
+		outputs := make([]*big.Int, len(a.hints[h].outputs))
+		for i, w := range a.hints[h].outputs {
+			outputs[i] = base[w]
+		}
+		if outputs[0] == nil {
+			continue // the hint isn't called
+		}
+		for _, alt := range a.alternatives(outputs, cfg.nbSamples) {
+			s.override = func(i int, outputs []*big.Int) {
+				if i == h {
+					copy(outputs, alt)
+				}
+			}
+			if s.solve(inputs) != nil {
+				continue
+			}
+			// other wires which changed
+			var changed []string
+			isOutput := make(map[int]bool)
+			for _, w := range a.hints[h].outputs {
+				isOutput[w] = true
+			}
+			for w := range base {
+				if !isOutput[w] && base[w] != nil && s.values[w] != nil && base[w].Cmp(s.values[w]) != 0 {
+					changed = append(changed, a.wireName(w))
+				}
+			}
+			issue := Issue{
+				Kind:       NonDeterministicHint,
+				Severity:   Warning,
+				Wire:       a.wireName(a.hints[h].outputs[0]),
+				Constraint: -1,
+				Message:    fmt.Sprintf("the outputs of hint %s can be %s instead of %s", a.hints[h].name, valuesString(alt), valuesString(outputs)),
+				Stack:      a.hints[h].stack,
+			}
+			if len(changed) != 0 {
+				issue.Severity = Error
+				if len(changed) > 4 {
+					changed = append(changed[:4], "...")
+				}
+				issue.Message += fmt.Sprintf(", changing %s", strings.Join(changed, ", "))
+			}
+			issue.Message += " (found by sampling)"
+			a.addIssue(report, issue)
+			break
+		}
+	}
+	return nil
+}
+
+// search enumerates the witnesses of the inputs, and returns one which differs from base, if
+// any. The values of the hint outputs and of the free wires are chosen one at a time, in the
+// order the solver needs them, and the choices which don't satisfy a constraint are pruned.
+// It returns errBudget if the constraint system is solved more than budget times.
+func (s *solver) search(inputs, base []*big.Int, budget *int) ([]*big.Int, error) {
+	if s.choices == nil {
+		s.choices = make(map[int]*big.Int)
+		defer func() { s.choices = nil }()
+	}
+	if *budget <= 0 {
+		return nil, errBudget
+	}
+	*budget--
+
+	err := s.solve(inputs)
+	var choice *errChoice
+	switch {
+	case err == nil:
+		for w := range base {
+			if base[w] != nil && s.values[w] != nil && base[w].Cmp(s.values[w]) != 0 {
+				return s.values, nil
+			}
+		}
+		return nil, nil
+	case errors.Is(err, errNotSatisfied):
+		return nil, nil
+	case errors.As(err, &choice):
+		q := s.q.Int64()
+		for v := int64(0); v < q; v++ {
+			s.choices[choice.wire] = big.NewInt(v)
+			if alt, err := s.search(inputs, base, budget); err != nil || alt != nil {
+				return alt, err
+			}
+		}
+		delete(s.choices, choice.wire)
+		return nil, nil
+	default:
+		return nil, err
+	}
+}
+
+// reportWitness reports the hint call or the free wire which takes another value in alt, a
+// valid witness found by the exhaustive search
+func (a *analysis) reportWitness(report *Report, base, alt []*big.Int) {
+	for h := range a.hints {
+		outputs := make([]*big.Int, len(a.hints[h].outputs))
+		altOutputs := make([]*big.Int, len(outputs))
+		differ := false
+		for i, w := range a.hints[h].outputs {
+			outputs[i], altOutputs[i] = base[w], alt[w]
+			if base[w] == nil || alt[w] == nil {
+				continue
+			}
+			differ = differ || base[w].Cmp(alt[w]) != 0
+		}
+		if !differ {
+			continue
+		}
+		isOutput := make(map[int]bool)
+		for _, w := range a.hints[h].outputs {
+			isOutput[w] = true
+		}
+		var changed []string
+		for w := range base {
+			if !isOutput[w] && base[w] != nil && alt[w] != nil && base[w].Cmp(alt[w]) != 0 {
+				changed = append(changed, a.wireName(w))
+			}
+		}
+		issue := Issue{
+			Kind:       NonDeterministicHint,
+			Severity:   Warning,
+			Wire:       a.wireName(a.hints[h].outputs[0]),
+			Constraint: -1,
+			Message:    fmt.Sprintf("the outputs of hint %s can be %s instead of %s", a.hints[h].name, valuesString(altOutputs), valuesString(outputs)),
+			Stack:      a.hints[h].stack,
+		}
+		if len(changed) != 0 {
+			issue.Severity = Error
+			if len(changed) > 4 {
+				changed = append(changed[:4], "...")
+			}
+			issue.Message += fmt.Sprintf(", changing %s", strings.Join(changed, ", "))
+		}
+		issue.Message += " (found by exhaustive search)"
+		a.addIssue(report, issue)
+		return
+	}
+
+	// no hint output changed: the first wire which changed is solved by a division by zero
+	for w := range base {
+		if base[w] == nil || alt[w] == nil || base[w].Cmp(alt[w]) == 0 {
+			continue
+		}
+		cID := a.solvingConstraint(w)
+		a.addIssue(report, Issue{
+			Kind:       FreeWire,
+			Severity:   Error,
+			Wire:       a.wireName(w),
+			Constraint: cID,
+			Message:    fmt.Sprintf("%s can be %s instead of %s (division by zero, found by exhaustive search)", a.wireName(w), alt[w], base[w]),
+			Stack:      a.stacks[cID],
+		})
+		return
+	}
+}
+
+// addIssue adds the issue to the report unless it is already reported
+func (a *analysis) addIssue(report *Report, issue Issue) {
+	for _, i := range report.Issues {
+		if i.Kind == issue.Kind && i.Wire == issue.Wire {
+			return
+		}
+	}
+	report.Issues = append(report.Issues, issue)
+}
+
+// solvingConstraint returns the first constraint solving w, -1 if none
+func (a *analysis) solvingConstraint(w int) int {
+	wires, _ := a.solverOrder()
+	for cID := range wires {
+		if wires[cID] == w {
+			return cID
+		}
+	}
+	return -1
+}
+
+// alternatives returns a sample of other values for the outputs of a hint, it isn't an
+// exhaustive search (see search). In a small field, each output, and each pair of outputs if there are
+// few, takes all the values. Otherwise each output is slightly perturbed. Then all the
+// outputs are replaced by random values.
+func (a *analysis) alternatives(outputs []*big.Int, nbSamples int) [][]*big.Int {
+	var res [][]*big.Int
+	with := func(i int, v *big.Int) {
+		v = new(big.Int).Mod(v, a.q)
+		if v.Cmp(outputs[i]) == 0 {
+			return
+		}
+		alt := append([]*big.Int{}, outputs...)
+		alt[i] = v
+		res = append(res, alt)
+	}
+	if a.q.Cmp(big.NewInt(smallField)) < 0 {
+		q := a.q.Int64()
+		for i := range outputs {
+			for v := int64(0); v < q; v++ {
+				with(i, big.NewInt(v))
+			}
+		}
+		if len(outputs) <= maxPairs && q*q <= maxPairValues {
+			for i := range outputs {
+				for j := i + 1; j < len(outputs); j++ {
+					for v := int64(0); v < q*q; v++ {
+						alt := append([]*big.Int{}, outputs...)
+						alt[i], alt[j] = big.NewInt(v%q), big.NewInt(v/q)
+						if alt[i].Cmp(outputs[i]) != 0 && alt[j].Cmp(outputs[j]) != 0 {
+							res = append(res, alt)
+						}
+					}
+				}
+			}
+		}
+	}
+	for i, o := range outputs {
+		if a.q.Cmp(big.NewInt(smallField)) < 0 {
+			continue
+		}
+		for _, v := range []*big.Int{
+			big.NewInt(0), big.NewInt(1), big.NewInt(-1),
+			new(big.Int).Add(o, big.NewInt(1)), new(big.Int).Sub(o, big.NewInt(1)), new(big.Int).Lsh(o, 1),
+		} {
+			with(i, v)
+		}
+	}
+	for n := 0; n < nbSamples; n++ {
+		alt := make([]*big.Int, len(outputs))
+		same := true
+		for i := range alt {
+			v, err := rand.Int(rand.Reader, a.q)
+			if err != nil {
+				panic(err)
+			}
+			alt[i] = v
+			same = same && v.Cmp(outputs[i]) == 0
+		}
+		if !same {
+			res = append(res, alt)
+		}
+	}
+	return res
+}
+
+// inputs returns the values of the input wires from the witness
+func (a *analysis) inputs(w witness.Witness) ([]*big.Int, error) {
+	type bigInt interface {
+		BigInt(*big.Int) *big.Int
+	}
+	v := reflect.ValueOf(w.Vector())
+	if v.Kind() != reflect.Slice {
+		return nil, errors.New("invalid witness vector")
+	}
+	var res []*big.Int
+	if !a.sparse {
+		res = append(res, big.NewInt(1)) // the one wire
+	}
+	for i := 0; i < v.Len(); i++ {
+		e, ok := v.Index(i).Addr().Interface().(bigInt)
+		if !ok {
+			return nil, errors.New("invalid witness vector")
+		}
+		res = append(res, e.BigInt(new(big.Int)))
+	}
+	if len(res) != a.nbInputs {
+		return nil, fmt.Errorf("witness has %d inputs, expected %d", len(res), a.nbInputs)
+	}
+	return res, nil
+}
+
+// solve solves the constraint system and checks that the constraints are satisfied
+func (s *solver) solve(inputs []*big.Int) error {
+	s.values = make([]*big.Int, s.nbWires)
+	s.free = nil
+	copy(s.values, inputs)
+	for cID := 0; cID < s.nbConstraints(); cID++ {
+		var err error
+		if s.sparse {
+			err = s.solveSparseR1C(&s.scs[cID])
+		} else {
+			err = s.solveR1C(&s.r1cs[cID])
+		}
+		if err != nil {
+			return fmt.Errorf("constraint %d: %w", cID, err)
+		}
+	}
+	return nil
+}
+
+// value returns the value of w, calling its hint if needed; nil if w isn't solved
+func (s *solver) value(w int) (*big.Int, error) {
+	if w == -1 {
+		return big.NewInt(1), nil
+	}
+	if s.values[w] != nil {
+		return s.values[w], nil
+	}
+	h, ok := s.hintOf[w]
+	if !ok {
+		return nil, nil
+	}
+	if s.choices != nil {
+		v, ok := s.choices[w]
+		if !ok {
+			return nil, &errChoice{wire: w}
+		}
+		s.values[w] = v
+		return v, nil
+	}
+	call := &s.hints[h]
+	f, ok := s.functions[call.id]
+	if !ok {
+		return nil, fmt.Errorf("missing hint function %s", call.name)
+	}
+	inputs := make([]*big.Int, len(call.inputs))
+	for i, in := range call.inputs {
+		v, unknown, err := s.evaluate(in)
+		if err != nil {
+			return nil, err
+		}
+		if unknown != -1 {
+			return nil, fmt.Errorf("hint %s: input %s is not solved", call.name, s.wireName(unknown))
+		}
+		inputs[i] = v
+	}
+	outputs := make([]*big.Int, len(call.outputs))
+	for i := range outputs {
+		outputs[i] = new(big.Int)
+	}
+	if err := f(s.q, inputs, outputs); err != nil {
+		return nil, fmt.Errorf("hint %s: %w", call.name, err)
+	}
+	if s.override != nil {
+		s.override(h, outputs)
+	}
+	for i, o := range call.outputs {
+		s.values[o] = new(big.Int).Mod(outputs[i], s.q)
+	}
+	return s.values[w], nil
+}
+
+// evaluate returns the value of the solved terms of l, and the wire of the unsolved one
+// (-1 if none)
+func (s *solver) evaluate(l linearExpression) (*big.Int, int, error) {
+	res, unknown := new(big.Int), -1
+	for _, t := range l {
+		v, err := s.value(t.wire)
+		if err != nil {
+			return nil, -1, err
+		}
+		if v == nil {
+			if unknown != -1 && unknown != t.wire {
+				return nil, -1, errors.New("more than one wire to solve")
+			}
+			unknown = t.wire
+			continue
+		}
+		res = s.add(res, s.mul(t.coeff, v))
+	}
+	return res, unknown, nil
+}
+
+// coeffOf returns the coefficient of w in l
+func (s *solver) coeffOf(l linearExpression, w int) *big.Int {
+	c := new(big.Int)
+	for _, t := range l {
+		if t.wire == w {
+			c = s.add(c, t.coeff)
+		}
+	}
+	return c
+}
+
+// solveFor solves c⋅w = numerator / divisor for w
+func (s *solver) solveFor(w int, c, numerator, divisor *big.Int) error {
+	numerator = new(big.Int).Mod(numerator, s.q)
+	if divisor.Sign() == 0 || c.Sign() == 0 {
+		if numerator.Sign() != 0 {
+			return errNotSatisfied
+		}
+		if s.choices != nil {
+			v, ok := s.choices[w]
+			if !ok {
+				return &errChoice{wire: w}
+			}
+			s.values[w] = v
+			return nil
+		}
+		s.free = append(s.free, w)
+		s.values[w] = new(big.Int)
+		if v, ok := s.freeValues[w]; ok {
+			s.values[w] = v
+		}
+		return nil
+	}
+	v := new(big.Int).ModInverse(s.mul(c, divisor), s.q)
+	s.values[w] = s.mul(v, numerator)
+	return nil
+}
+
+func (s *solver) solveR1C(c *r1c) error {
+	l, ul, err := s.evaluate(c.l)
+	if err != nil {
+		return err
+	}
+	r, ur, err := s.evaluate(c.r)
+	if err != nil {
+		return err
+	}
+	o, uo, err := s.evaluate(c.o)
+	if err != nil {
+		return err
+	}
+	switch {
+	case ul != -1 && ur == -1 && uo == -1:
+		// (l + cl⋅w) ⋅ r == o
+		if err := s.solveFor(ul, s.coeffOf(c.l, ul), new(big.Int).Sub(o, s.mul(l, r)), r); err != nil {
+			return err
+		}
+	case ur != -1 && ul == -1 && uo == -1:
+		if err := s.solveFor(ur, s.coeffOf(c.r, ur), new(big.Int).Sub(o, s.mul(l, r)), l); err != nil {
+			return err
+		}
+	case uo != -1 && ul == -1 && ur == -1:
+		if err := s.solveFor(uo, s.coeffOf(c.o, uo), new(big.Int).Sub(s.mul(l, r), o), big.NewInt(1)); err != nil {
+			return err
+		}
+	case ul != -1 || ur != -1 || uo != -1:
+		return errors.New("more than one wire to solve")
+	default:
+		if s.mul(l, r).Cmp(o) != 0 {
+			return errNotSatisfied
+		}
+		return nil
+	}
+	return nil
+}
+
+func (s *solver) solveSparseR1C(c *sparseR1C) error {
+	var values [3]*big.Int
+	unknown := -1
+	for slot, w := range c.relevantSlots() {
+		if w == -1 {
+			values[slot] = new(big.Int)
+			continue
+		}
+		v, err := s.value(w)
+		if err != nil {
+			return err
+		}
+		if v == nil {
+			if unknown != -1 {
+				return errors.New("more than one wire to solve")
+			}
+			unknown = slot
+			v = new(big.Int)
+		}
+		values[slot] = v
+	}
+	l, r, o := values[slotL], values[slotR], values[slotO]
+
+	// qL⋅l + qR⋅r + qO⋅o + qM⋅l⋅r + k
+	eval := func() *big.Int {
+		res := s.add(s.mul(c.l.coeff, l), s.mul(c.r.coeff, r))
+		res = s.add(res, s.mul(c.o.coeff, o))
+		res = s.add(res, s.mul(c.qM, s.mul(l, r)))
+		return s.add(res, c.k)
+	}
+	one := big.NewInt(1)
+	switch unknown {
+	case slotL:
+		// l ⋅ (qL + qM⋅r) == -(qR⋅r + qO⋅o + k)
+		return s.solveFor(c.l.wire, one, new(big.Int).Neg(eval()), s.add(c.l.coeff, s.mul(c.qM, r)))
+	case slotR:
+		return s.solveFor(c.r.wire, one, new(big.Int).Neg(eval()), s.add(c.r.coeff, s.mul(c.qM, l)))
+	case slotO:
+		return s.solveFor(c.o.wire, c.o.coeff, new(big.Int).Neg(eval()), one)
+	}
+	if eval().Sign() != 0 {
+		return errNotSatisfied
+	}
+	return nil
+}
+
+func valuesString(values []*big.Int) string {
+	if len(values) == 1 {
+		return values[0].String()
+	}
+	s := make([]string, len(values))
+	for i, v := range values {
+		s[i] = v.String()
+	}
+	return "[" + strings.Join(s, ", ") + "]"
+}
@@ -15,6 +15,8 @@
 package constraint
 
 import (
+	"fmt"
+	"math/big"
 	"strings"
 )
 
@@ -26,6 +28,24 @@ type LogEntry struct {
 	Format    string
 	ToResolve []LinearExpression // TODO @gbotrel we could store here a struct with a flag that says if we expand or evaluate the expression
 	Stack     []int
+
+	// Limbs groups the expressions of ToResolve which are the limbs of a single value (e.g. an
+	// emulated field element), printed as its integer value. Sorted by First.
+	Limbs []LogLimbs
+
+	// Conditional is set if the log is printed only when the value of the last expression of
+	// ToResolve is not zero (see frontend.API.PrintlnIf). The condition is not printed.
+	Conditional bool
+
+	// Label is the format of the message of an assertion as given to frontend.WithMessage,
+	// before the constant arguments are formatted. Empty for the other entries.
+	Label string
+}
+
+// LogLimbs are the little-endian limbs ToResolve[First:First+NbLimbs] of a value of a LogEntry,
+// whose integer value is Σ limbs[i]⋅2^(i⋅BitsPerLimb)
+type LogLimbs struct {
+	First, NbLimbs, BitsPerLimb int
 }
 
 func (l *LogEntry) WriteVariable(le LinearExpression, sbb *strings.Builder) {
@@ -35,3 +55,67 @@ func (l *LogEntry) WriteVariable(le LinearExpression, sbb *strings.Builder) {
 	sbb.WriteString("%s")
 	l.ToResolve = append(l.ToResolve, le)
 }
+
+// Resolve returns the arguments of Format from the values of the expressions of ToResolve (nil
+// if a wire is not solved), as LogValue. It returns false if the log is conditional and its
+// condition is zero or not solved.
+func (l *LogEntry) Resolve(values []*big.Int, field *big.Int) ([]interface{}, bool) {
+	if l.Conditional {
+		cond := values[len(values)-1]
+		if cond == nil || cond.Sign() == 0 {
+			return nil, false
+		}
+		values = values[:len(values)-1]
+	}
+
+	args := make([]interface{}, 0, len(values))
+	limbs := l.Limbs
+	for i := 0; i < len(values); i++ {
+		if len(limbs) == 0 || limbs[0].First != i {
+			args = append(args, LogValue{Value: values[i], Field: field})
+			continue
+		}
+		value := new(big.Int)
+		for j := limbs[0].NbLimbs - 1; j >= 0; j-- {
+			limb := values[i+j]
+			if limb == nil {
+				value = nil
+				break
+			}
+			value.Lsh(value, uint(limbs[0].BitsPerLimb)).Add(value, limb)
+		}
+		args = append(args, LogValue{Value: value})
+		i += limbs[0].NbLimbs - 1
+		limbs = limbs[1:]
+	}
+	return args, true
+}
+
+// LogValue is a resolved argument of a LogEntry. The verbs of fmt for integers (%d, %x, %X, %o,
+// %O, %b) format its canonical value, and %v and %s format it as the solver prints the field
+// elements, where the small negative values (-65535 to -1) are printed as such. A nil Value (the
+// wires are not solved) is printed as <unsolved>.
+type LogValue struct {
+	Value *big.Int
+	Field *big.Int // nil for an integer which is not a field element (e.g. an emulated element)
+}
+
+// Format implements fmt.Formatter
+func (v LogValue) Format(f fmt.State, verb rune) {
+	switch {
+	case v.Value == nil:
+		_, _ = f.Write([]byte("<unsolved>"))
+	case verb == 'v' || verb == 's':
+		if v.Field != nil && v.Value.Sign() != 0 {
+			var neg big.Int
+			neg.Sub(v.Field, v.Value)
+			if neg.IsUint64() && neg.Uint64() <= 65535 {
+				_, _ = f.Write([]byte("-" + neg.String()))
+				return
+			}
+		}
+		_, _ = f.Write([]byte(v.Value.String()))
+	default:
+		v.Value.Format(f, verb)
+	}
+}
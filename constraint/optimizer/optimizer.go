@@ -0,0 +1,136 @@
+// Copyright 2020 ConsenSys AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package optimizer implements optimization passes over compiled constraint systems.
+//
+// The passes work on the IR of a constraint system (see constraint.IR) and preserve its
+// semantics: for any assignment of the inputs, the optimized constraint system is solved
+// if and only if the original one is. Internal wires are renumbered, inputs are untouched.
+//
+// A pass is only kept if the resulting constraint system can still be solved constraint
+// after constraint by the solver; the passes are applied until none of them makes progress.
+// Constraint systems with a commitment are left as is.
+package optimizer
+
+import (
+	"fmt"
+
+	"github.com/consensys/gnark/constraint"
+)
+
+// maxRounds bounds the number of times the passes are applied
+const maxRounds = 16
+
+// Pass is an optimization pass
+type Pass struct {
+	name string
+	run  func(p *program) bool
+}
+
+// String returns the name of the pass
+func (p Pass) String() string {
+	return p.name
+}
+
+var (
+	// DeadConstraints removes the constraints which are trivially satisfied, duplicated, or
+	// which only solve a wire which is not used anywhere else.
+	DeadConstraints = Pass{"dead-constraints", deadConstraints}
+
+	// UnusedHints removes the hints whose outputs are not used.
+	UnusedHints = Pass{"unused-hints", unusedHints}
+
+	// LinearSubstitution substitutes the wires defined by a linear constraint in their users.
+	LinearSubstitution = Pass{"linear-substitution", linearSubstitution}
+
+	// CommonProducts merges the wires defined as (a multiple of) the same product.
+	CommonProducts = Pass{"common-products", commonProducts}
+
+	// ConstantFolding substitutes the wires defined as constants in their users.
+	ConstantFolding = Pass{"constant-folding", constantFolding}
+)
+
+// DefaultPasses returns the passes applied when none is specified
+func DefaultPasses() []Pass {
+	return []Pass{ConstantFolding, LinearSubstitution, CommonProducts, DeadConstraints, UnusedHints}
+}
+
+// Optimize returns the IR optimized with the given passes (DefaultPasses if none).
+func Optimize(ir *constraint.IR, passes ...Pass) (*constraint.IR, error) {
+	if len(passes) == 0 {
+		passes = DefaultPasses()
+	}
+	p, err := load(ir)
+	if err != nil {
+		return nil, err
+	}
+	if ir.Commitment != nil {
+		return ir, nil
+	}
+	_, nbUnsolved, err := p.solverOrder()
+	if err != nil {
+		return nil, fmt.Errorf("constraint system can't be solved: %w", err)
+	}
+
+	for round := 0; round < maxRounds; round++ {
+		changed := false
+		for _, pass := range passes {
+			p, err := load(ir)
+			if err != nil {
+				return nil, err
+			}
+			if !pass.run(p) {
+				continue
+			}
+			next := p.toIR()
+
+			// the pass is discarded if the constraint system can't be solved anymore
+			p, err = load(next)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", pass, err)
+			}
+			_, n, err := p.solverOrder()
+			if err == nil && n > nbUnsolved && unusedHints(p) {
+				// the hints left without users by the pass are removed with it
+				next = p.toIR()
+				if p, err = load(next); err != nil {
+					return nil, fmt.Errorf("%s: %w", pass, err)
+				}
+				_, n, err = p.solverOrder()
+			}
+			if err != nil || n > nbUnsolved {
+				continue
+			}
+			ir, nbUnsolved, changed = next, n, true
+		}
+		if !changed {
+			break
+		}
+	}
+
+	return ir, nil
+}
+
+// Apply optimizes cs with the given passes (DefaultPasses if none) and builds the result
+// in res, which must be an empty constraint system over the same field.
+func Apply(cs, res constraint.ConstraintSystem, passes ...Pass) error {
+	ir, err := constraint.NewIR(cs)
+	if err != nil {
+		return err
+	}
+	if ir, err = Optimize(ir, passes...); err != nil {
+		return err
+	}
+	return ir.Build(res)
+}
@@ -0,0 +1,367 @@
+package optimizer_test
+
+import (
+	"encoding/json"
+	"math/big"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/backend/hint"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/constraint"
+	cs "github.com/consensys/gnark/constraint/bn254"
+	"github.com/consensys/gnark/constraint/optimizer"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/frontend/cs/scs"
+	"github.com/consensys/gnark/internal/backend/circuits"
+	"github.com/stretchr/testify/require"
+)
+
+const nbFuzz = 20
+
+var builders = []struct {
+	name    string
+	builder frontend.NewBuilder
+}{
+	{"r1cs", r1cs.NewBuilder},
+	{"scs", scs.NewBuilder},
+}
+
+// TestOptimizeCircuits checks that the optimized constraint systems of the test circuits
+// are solved by the same (fuzzed) witnesses as the original ones.
+func TestOptimizeCircuits(t *testing.T) {
+	names := make([]string, 0, len(circuits.Circuits))
+	for name := range circuits.Circuits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		tc := circuits.Circuits[name]
+		assignments := append(append([]frontend.Circuit{}, tc.ValidAssignments...), tc.InvalidAssignments...)
+		for _, b := range builders {
+			t.Run(name+"/"+b.name, func(t *testing.T) {
+				checkEquivalence(t, tc.Circuit, b.builder, assignments, tc.HintFunctions)
+			})
+		}
+	}
+}
+
+// optCircuit has something to do for each pass
+type optCircuit struct {
+	X, Y frontend.Variable
+	Z    frontend.Variable `gnark:",public"`
+}
+
+func (c *optCircuit) Define(api frontend.API) error {
+	// common products
+	a := api.Mul(c.X, c.Y)
+	b := api.Mul(c.Y, c.X)
+
+	// linear constraints
+	s := api.Add(a, c.X)
+	s = api.Mul(api.Add(s, 1), api.Inverse(3))
+
+	// constants
+	k := api.Add(c.Z, 1)
+	k = api.Mul(api.Sub(k, c.Z), 5)
+
+	// hint used by a dead constraint only
+	h, err := api.Compiler().NewHint(hint.InvZero, 1, api.Mul(c.X, c.X))
+	if err != nil {
+		return err
+	}
+	api.Mul(h[0], c.Y)
+
+	// dead constraints
+	api.AssertIsEqual(api.Mul(b, k), api.Mul(a, 5))
+	api.AssertIsEqual(api.Mul(b, k), api.Mul(a, 5))
+	api.Mul(c.X, c.Z, c.Y)
+
+	api.AssertIsEqual(api.Mul(s, 3), api.Add(a, c.X, 1))
+	api.AssertIsEqual(api.Mul(api.Add(b, k), c.Z), api.Mul(api.Add(a, 5), 4))
+	return nil
+}
+
+func optAssignments() []frontend.Circuit {
+	return []frontend.Circuit{
+		&optCircuit{X: 3, Y: 5, Z: 4},
+		&optCircuit{X: 3, Y: 5, Z: 5},
+		&optCircuit{X: 2, Y: 7, Z: 4},
+	}
+}
+
+func TestOptimize(t *testing.T) {
+	for _, b := range builders {
+		t.Run(b.name, func(t *testing.T) {
+			checkEquivalence(t, &optCircuit{}, b.builder, optAssignments(), nil)
+
+			assert := require.New(t)
+			ccs, err := frontend.Compile(ecc.BN254.ScalarField(), b.builder, &optCircuit{})
+			assert.NoError(err)
+			optimized, err := frontend.Compile(ecc.BN254.ScalarField(), b.builder, &optCircuit{}, frontend.WithOptimizer())
+			assert.NoError(err)
+			assert.LessOrEqual(2*optimized.GetNbConstraints(), ccs.GetNbConstraints())
+		})
+	}
+}
+
+// TestPasses checks that each pass reduces a constraint system on its own, and that the
+// result is solved by the same witnesses.
+func TestPasses(t *testing.T) {
+	for _, tc := range []struct {
+		pass          optimizer.Pass
+		kind          string
+		nbConstraints int
+		ir            string
+	}{
+		{optimizer.ConstantFolding, constraint.IRKindR1CS, 2, `[
+			{"l": [{"wire": 0, "coeff": "1"}], "r": [{"wire": 0, "coeff": "5"}], "o": [{"wire": 4, "coeff": "1"}]},
+			{"l": [{"wire": 4, "coeff": "1"}], "r": [{"wire": 2, "coeff": "1"}], "o": [{"wire": 5, "coeff": "1"}]},
+			{"l": [{"wire": 5, "coeff": "1"}], "r": [{"wire": 3, "coeff": "1"}], "o": [{"wire": 1, "coeff": "1"}]}]`},
+		{optimizer.LinearSubstitution, constraint.IRKindR1CS, 2, `[
+			{"l": [{"wire": 0, "coeff": "1"}], "r": [{"wire": 2, "coeff": "1"}, {"wire": 3, "coeff": "2"}, {"wire": 0, "coeff": "3"}], "o": [{"wire": 4, "coeff": "1"}]},
+			{"l": [{"wire": 4, "coeff": "1"}], "r": [{"wire": 4, "coeff": "1"}], "o": [{"wire": 5, "coeff": "1"}]},
+			{"l": [{"wire": 5, "coeff": "1"}], "r": [{"wire": 2, "coeff": "1"}], "o": [{"wire": 1, "coeff": "1"}]}]`},
+		{optimizer.CommonProducts, constraint.IRKindR1CS, 2, `[
+			{"l": [{"wire": 2, "coeff": "1"}], "r": [{"wire": 3, "coeff": "1"}], "o": [{"wire": 4, "coeff": "1"}]},
+			{"l": [{"wire": 3, "coeff": "1"}], "r": [{"wire": 2, "coeff": "1"}], "o": [{"wire": 5, "coeff": "2"}]},
+			{"l": [{"wire": 4, "coeff": "1"}, {"wire": 5, "coeff": "1"}], "r": [{"wire": 0, "coeff": "1"}], "o": [{"wire": 1, "coeff": "1"}]}]`},
+		{optimizer.DeadConstraints, constraint.IRKindR1CS, 2, `[
+			{"l": [{"wire": 2, "coeff": "1"}], "r": [{"wire": 3, "coeff": "1"}], "o": [{"wire": 4, "coeff": "1"}]},
+			{"l": [{"wire": 2, "coeff": "1"}], "r": [{"wire": 3, "coeff": "1"}], "o": [{"wire": 4, "coeff": "1"}]},
+			{"l": [{"wire": 4, "coeff": "1"}], "r": [{"wire": 2, "coeff": "1"}], "o": [{"wire": 5, "coeff": "1"}]},
+			{"l": [{"wire": 4, "coeff": "1"}], "r": [{"wire": 0, "coeff": "1"}], "o": [{"wire": 1, "coeff": "1"}]},
+			{"l": [{"wire": 0, "coeff": "2"}], "r": [{"wire": 0, "coeff": "3"}], "o": [{"wire": 0, "coeff": "6"}]}]`},
+
+		{optimizer.ConstantFolding, constraint.IRKindSparseR1CS, 2, `[
+			{"l": {"wire": 0, "coeff": "0"}, "r": {"wire": 0, "coeff": "0"}, "o": {"wire": 3, "coeff": "-1"}, "m": [{"wire": 0, "coeff": "0"}, {"wire": 0, "coeff": "0"}], "k": "5"},
+			{"l": {"wire": 1, "coeff": "0"}, "r": {"wire": 3, "coeff": "0"}, "o": {"wire": 4, "coeff": "-1"}, "m": [{"wire": 1, "coeff": "1"}, {"wire": 3, "coeff": "1"}], "k": "0"},
+			{"l": {"wire": 4, "coeff": "0"}, "r": {"wire": 2, "coeff": "0"}, "o": {"wire": 0, "coeff": "-1"}, "m": [{"wire": 4, "coeff": "1"}, {"wire": 2, "coeff": "1"}], "k": "0"}]`},
+		{optimizer.LinearSubstitution, constraint.IRKindSparseR1CS, 2, `[
+			{"l": {"wire": 1, "coeff": "1"}, "r": {"wire": 0, "coeff": "0"}, "o": {"wire": 3, "coeff": "-1"}, "m": [{"wire": 1, "coeff": "0"}, {"wire": 0, "coeff": "0"}], "k": "3"},
+			{"l": {"wire": 3, "coeff": "0"}, "r": {"wire": 3, "coeff": "0"}, "o": {"wire": 4, "coeff": "-1"}, "m": [{"wire": 3, "coeff": "1"}, {"wire": 3, "coeff": "1"}], "k": "0"},
+			{"l": {"wire": 4, "coeff": "1"}, "r": {"wire": 0, "coeff": "-1"}, "o": {"wire": 0, "coeff": "0"}, "m": [{"wire": 4, "coeff": "0"}, {"wire": 0, "coeff": "0"}], "k": "0"}]`},
+		{optimizer.CommonProducts, constraint.IRKindSparseR1CS, 2, `[
+			{"l": {"wire": 1, "coeff": "0"}, "r": {"wire": 2, "coeff": "0"}, "o": {"wire": 3, "coeff": "-1"}, "m": [{"wire": 1, "coeff": "1"}, {"wire": 2, "coeff": "1"}], "k": "0"},
+			{"l": {"wire": 2, "coeff": "0"}, "r": {"wire": 1, "coeff": "0"}, "o": {"wire": 4, "coeff": "-1"}, "m": [{"wire": 2, "coeff": "2"}, {"wire": 1, "coeff": "1"}], "k": "0"},
+			{"l": {"wire": 3, "coeff": "1"}, "r": {"wire": 4, "coeff": "1"}, "o": {"wire": 0, "coeff": "-1"}, "m": [{"wire": 3, "coeff": "0"}, {"wire": 4, "coeff": "0"}], "k": "0"}]`},
+		{optimizer.DeadConstraints, constraint.IRKindSparseR1CS, 2, `[
+			{"l": {"wire": 1, "coeff": "0"}, "r": {"wire": 2, "coeff": "0"}, "o": {"wire": 3, "coeff": "-1"}, "m": [{"wire": 1, "coeff": "1"}, {"wire": 2, "coeff": "1"}], "k": "0"},
+			{"l": {"wire": 3, "coeff": "1"}, "r": {"wire": 0, "coeff": "-1"}, "o": {"wire": 0, "coeff": "0"}, "m": [{"wire": 3, "coeff": "0"}, {"wire": 0, "coeff": "0"}], "k": "0"},
+			{"l": {"wire": 3, "coeff": "1"}, "r": {"wire": 0, "coeff": "-1"}, "o": {"wire": 0, "coeff": "0"}, "m": [{"wire": 3, "coeff": "0"}, {"wire": 0, "coeff": "0"}], "k": "0"},
+			{"l": {"wire": 1, "coeff": "0"}, "r": {"wire": 3, "coeff": "0"}, "o": {"wire": 4, "coeff": "-1"}, "m": [{"wire": 1, "coeff": "1"}, {"wire": 3, "coeff": "1"}], "k": "0"},
+			{"l": {"wire": 0, "coeff": "0"}, "r": {"wire": 0, "coeff": "0"}, "o": {"wire": 0, "coeff": "0"}, "m": [{"wire": 0, "coeff": "0"}, {"wire": 0, "coeff": "0"}], "k": "0"}]`},
+	} {
+		t.Run(tc.kind+"/"+tc.pass.String(), func(t *testing.T) {
+			assert := require.New(t)
+
+			// wires are Z (public), X, Y and the internal ones, after the one wire in a R1CS
+			field := ecc.BN254.ScalarField()
+			ir := &constraint.IR{
+				Version:    constraint.IRVersion,
+				Kind:       tc.kind,
+				Field:      field.Text(16),
+				Public:     []string{"Z"},
+				Secret:     []string{"X", "Y"},
+				NbInternal: 2,
+			}
+			newCS := func() constraint.ConstraintSystem { return cs.NewSparseR1CS(0) }
+			var constraints any = &ir.SparseR1Cs
+			if tc.kind == constraint.IRKindR1CS {
+				ir.Public = []string{"1", "Z"}
+				newCS = func() constraint.ConstraintSystem { return cs.NewR1CS(0) }
+				constraints = &ir.R1Cs
+			}
+			assert.NoError(json.Unmarshal([]byte(tc.ir), constraints))
+
+			opt, err := optimizer.Optimize(ir, tc.pass)
+			assert.NoError(err)
+			assert.Equal(tc.nbConstraints, opt.NbConstraints())
+
+			original, optimized := newCS(), newCS()
+			assert.NoError(ir.Build(original))
+			assert.NoError(opt.Build(optimized))
+
+			// X, Y random, and Z the result of the circuit or a random value
+			rnd := rand.New(rand.NewSource(42)) //#nosec G404 -- reproducible fuzzing
+			nbSolved := 0
+			for i := 0; i < nbFuzz; i++ {
+				var x, y fr.Element
+				x.SetUint64(uint64(1 + rnd.Intn(10)))
+				y.SetRandom()
+				for _, z := range solutions(tc.pass, x, y) {
+					w, err := witness.New(field)
+					assert.NoError(err)
+					c := make(chan any, 3)
+					c <- z
+					c <- x
+					c <- y
+					close(c)
+					assert.NoError(w.Fill(1, 2, c))
+					errOriginal, errOptimized := original.IsSolved(w), optimized.IsSolved(w)
+					assert.Equal(errOriginal == nil, errOptimized == nil, "original: %v, optimized: %v", errOriginal, errOptimized)
+					if errOriginal == nil {
+						nbSolved++
+					}
+				}
+			}
+			assert.Equal(nbFuzz, nbSolved)
+		})
+	}
+}
+
+// solutions returns the expected value of Z for the constraint systems of TestPasses, and a
+// random value
+func solutions(pass optimizer.Pass, x, y fr.Element) []fr.Element {
+	var z, one, r fr.Element
+	one.SetOne()
+	r.SetRandom()
+	switch pass.String() {
+	case optimizer.ConstantFolding.String():
+		// 5 x y
+		z.Mul(&x, &y).Mul(&z, new(fr.Element).SetUint64(5))
+	case optimizer.LinearSubstitution.String():
+		// R1CS: (x + 2y + 3)² x, SparseR1CS: (x + 3)²
+		var t fr.Element
+		t.Add(&x, &y).Add(&t, &y).Add(&t, new(fr.Element).SetUint64(3))
+		z.Square(&t).Mul(&z, &x)
+		r.Add(&x, new(fr.Element).SetUint64(3)).Square(&r)
+	case optimizer.CommonProducts.String():
+		// x y + x y / 2 in a R1CS, 3 x y in a SparseR1CS
+		var half fr.Element
+		half.SetUint64(2).Inverse(&half).Add(&half, &one)
+		z.Mul(&x, &y).Mul(&z, &half)
+		r.Mul(&x, &y).Mul(&r, new(fr.Element).SetUint64(3))
+	default:
+		z.Mul(&x, &y)
+	}
+	return []fr.Element{z, r}
+}
+
+type unusedHintCircuit struct {
+	X, Y frontend.Variable
+}
+
+func (c *unusedHintCircuit) Define(api frontend.API) error {
+	if _, err := api.Compiler().NewHint(hint.InvZero, 1, c.X); err != nil {
+		return err
+	}
+	api.AssertIsEqual(api.Mul(c.X, c.X), c.Y)
+	return nil
+}
+
+func TestUnusedHints(t *testing.T) {
+	for _, b := range builders {
+		t.Run(b.name, func(t *testing.T) {
+			assert := require.New(t)
+			ccs, err := frontend.Compile(ecc.BN254.ScalarField(), b.builder, &unusedHintCircuit{}, frontend.IgnoreUnconstrainedInputs())
+			assert.NoError(err)
+			ir, err := constraint.NewIR(ccs)
+			assert.NoError(err)
+			opt, err := optimizer.Optimize(ir, optimizer.UnusedHints)
+			assert.NoError(err)
+			assert.Len(ir.Hints, 1)
+			assert.Len(opt.Hints, 0)
+			assert.Equal(ir.NbInternal-1, opt.NbInternal)
+
+			// the original constraint system can't be solved since the hint is never called
+			optimized, err := frontend.Compile(ecc.BN254.ScalarField(), b.builder, &unusedHintCircuit{}, frontend.IgnoreUnconstrainedInputs(), frontend.WithOptimizer(optimizer.UnusedHints))
+			assert.NoError(err)
+			for _, a := range []struct {
+				assignment unusedHintCircuit
+				solved     bool
+			}{
+				{unusedHintCircuit{X: 3, Y: 9}, true},
+				{unusedHintCircuit{X: 3, Y: 8}, false},
+			} {
+				w, err := frontend.NewWitness(&a.assignment, ecc.BN254.ScalarField())
+				assert.NoError(err)
+				assert.Equal(a.solved, optimized.IsSolved(w) == nil)
+			}
+		})
+	}
+}
+
+func TestOptimizeCommitment(t *testing.T) {
+	assert := require.New(t)
+	ccs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &optCircuit{}, frontend.IgnoreUnconstrainedInputs())
+	assert.NoError(err)
+	ir, err := constraint.NewIR(ccs)
+	assert.NoError(err)
+	ir.Commitment = &constraint.Commitment{}
+	opt, err := optimizer.Optimize(ir)
+	assert.NoError(err)
+	assert.Equal(ir, opt)
+}
+
+// checkEquivalence compiles the circuit with and without the optimizer and checks that the
+// constraint systems are solved by the same witnesses: the given assignments and fuzzed ones.
+func checkEquivalence(t *testing.T, circuit frontend.Circuit, newBuilder frontend.NewBuilder, assignments []frontend.Circuit, hints []hint.Function, opts ...frontend.CompileOption) {
+	assert := require.New(t)
+	field := ecc.BN254.ScalarField()
+
+	ccs, err := frontend.Compile(field, newBuilder, circuit, frontend.IgnoreUnconstrainedInputs())
+	assert.NoError(err)
+	if testing.Short() && ccs.GetNbConstraints() > 50 {
+		return
+	}
+	if len(opts) == 0 {
+		opts = []frontend.CompileOption{frontend.WithOptimizer()}
+	}
+	opts = append(opts, frontend.IgnoreUnconstrainedInputs())
+	optimized, err := frontend.Compile(field, newBuilder, circuit, opts...)
+	assert.NoError(err)
+	assert.LessOrEqual(optimized.GetNbConstraints(), ccs.GetNbConstraints())
+	assert.Equal(ccs.GetNbPublicVariables(), optimized.GetNbPublicVariables())
+	assert.Equal(ccs.GetNbSecretVariables(), optimized.GetNbSecretVariables())
+
+	nbPublic, nbSecret := ccs.GetNbPublicVariables(), ccs.GetNbSecretVariables()
+	if _, ok := ccs.(constraint.R1CS); ok {
+		nbPublic-- // the one wire
+	}
+
+	rnd := rand.New(rand.NewSource(42)) //#nosec G404 -- reproducible fuzzing
+	check := func(values fr.Vector) {
+		w, err := witness.New(field)
+		assert.NoError(err)
+		c := make(chan any, len(values))
+		for i := range values {
+			c <- values[i]
+		}
+		close(c)
+		assert.NoError(w.Fill(nbPublic, nbSecret, c))
+
+		errOriginal := ccs.IsSolved(w, backend.WithHints(hints...))
+		errOptimized := optimized.IsSolved(w, backend.WithHints(hints...))
+		assert.Equal(errOriginal == nil, errOptimized == nil, "witness %s: original: %v, optimized: %v", values, errOriginal, errOptimized)
+	}
+
+	for _, a := range assignments {
+		w, err := frontend.NewWitness(a, field)
+		assert.NoError(err)
+		values := w.Vector().(fr.Vector)
+		check(values)
+
+		// fuzz the inputs around the assignment
+		for i := 0; i < nbFuzz; i++ {
+			fuzzed := make(fr.Vector, len(values))
+			copy(fuzzed, values)
+			j := rnd.Intn(len(fuzzed))
+			switch rnd.Intn(3) {
+			case 0:
+				fuzzed[j].SetRandom()
+			case 1:
+				fuzzed[j].SetInt64(int64(rnd.Intn(4)))
+			default:
+				fuzzed[j].Add(&fuzzed[j], new(fr.Element).SetBigInt(big.NewInt(int64(rnd.Intn(3)-1))))
+			}
+			check(fuzzed)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+// Copyright 2020 ConsenSys AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package optimizer
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// maxSubstitutionTerms bounds the size of the linear expressions substituted in a R1CS, to
+// avoid growing the users of a wire more than the removed constraint saves
+const maxSubstitutionTerms = 8
+
+// deadConstraints removes the constraints which are trivially satisfied, the duplicates of
+// a previous constraint, and the constraints solving a wire which isn't used elsewhere
+// (when the wire can always be solved, that is, when the constraint doesn't check anything).
+func deadConstraints(p *program) bool {
+	defs, _, err := p.solverOrder()
+	if err != nil {
+		return false
+	}
+	uses := p.uses()
+	seen := make(map[string]struct{})
+	changed := false
+	for cID := range p.removed {
+		if p.removed[cID] {
+			continue
+		}
+		v := defs[cID]
+		key := p.constraintKey(cID)
+		if v == -1 {
+			if _, ok := seen[key]; ok || p.isTriviallySatisfied(cID) {
+				p.removeConstraint(cID, -1)
+				changed = true
+				continue
+			}
+		}
+		seen[key] = struct{}{}
+		if v != -1 && uses[v] == 1 && p.isFreeDefinition(cID, v) {
+			p.removeConstraint(cID, v)
+			changed = true
+		}
+	}
+	return changed
+}
+
+// isTriviallySatisfied returns true if the constraint has no wire and is satisfied
+func (p *program) isTriviallySatisfied(cID int) bool {
+	if p.sparse {
+		c := &p.scs[cID]
+		for _, t := range [...]term{c.l, c.r, c.o, c.m[0], c.m[1]} {
+			if t.coeff.Sign() != 0 {
+				return false
+			}
+		}
+		return c.k.Sign() == 0
+	}
+	c := &p.r1cs[cID]
+	l, okL := p.isConstant(c.l)
+	r, okR := p.isConstant(c.r)
+	o, okO := p.isConstant(c.o)
+	return okL && okR && okO && p.mul(l, r).Cmp(o) == 0
+}
+
+// isFreeDefinition returns true if the constraint can be solved for v whatever the values
+// of its other wires are, that is, if v appears linearly with an invertible coefficient
+func (p *program) isFreeDefinition(cID, v int) bool {
+	if p.sparse {
+		c := &p.scs[cID]
+		return c.o.wire == v && c.o.coeff.Sign() != 0 &&
+			!(c.l.wire == v && (c.l.coeff.Sign() != 0 || c.m[0].coeff.Sign() != 0)) &&
+			!(c.r.wire == v && (c.r.coeff.Sign() != 0 || c.m[1].coeff.Sign() != 0))
+	}
+	c := &p.r1cs[cID]
+	for _, l := range [...]linearExpression{c.l, c.r} {
+		for _, t := range l {
+			if t.wire == v {
+				return false
+			}
+		}
+	}
+	for _, t := range c.o {
+		if t.wire == v && t.coeff.Sign() != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// unusedHints removes the hints whose outputs are not used
+func unusedHints(p *program) bool {
+	uses := p.uses()
+	changed := false
+	for i, h := range p.hints {
+		if h == nil {
+			continue
+		}
+		used := false
+		for _, w := range h.outputs {
+			used = used || uses[w] != 0
+		}
+		if used {
+			continue
+		}
+		for _, w := range h.outputs {
+			p.eliminated[w] = true
+			delete(p.hintOf, w)
+		}
+		p.hints[i] = nil
+		changed = true
+	}
+	return changed
+}
+
+// linearSubstitution substitutes the wires defined by a linear constraint (v = Σ cᵢ⋅xᵢ + k)
+// in their users, and removes the constraint. In a SparseR1CS, only v = c⋅x + k is substituted
+// since a slot holds a single wire.
+func linearSubstitution(p *program) bool {
+	return substituteDefinitions(p, false)
+}
+
+// constantFolding substitutes the wires defined as constants (v = k) in their users, and
+// removes the constraint. The users may then become linear or constant themselves.
+func constantFolding(p *program) bool {
+	return substituteDefinitions(p, true)
+}
+
+func substituteDefinitions(p *program, constantsOnly bool) bool {
+	defs, _, err := p.solverOrder()
+	if err != nil {
+		return false
+	}
+	changed := false
+	for cID := range p.removed {
+		v := defs[cID]
+		if p.removed[cID] || v == -1 || v < p.nbInputs {
+			continue
+		}
+		if _, ok := p.hintOf[v]; ok {
+			continue
+		}
+		e, ok := p.linearDefinition(cID, v)
+		if !ok {
+			continue
+		}
+		nbWires := 0
+		for _, t := range e {
+			if !p.isConstantWire(t.wire) {
+				nbWires++
+			}
+		}
+		if constantsOnly != (nbWires == 0) || (p.sparse && nbWires > 1) || len(e) > maxSubstitutionTerms {
+			continue
+		}
+		p.removeConstraint(cID, -1)
+		p.substitute(v, e)
+		changed = true
+	}
+	return changed
+}
+
+// isConstantWire returns true if w holds a constant: the one wire of a R1CS, or -1
+func (p *program) isConstantWire(w int) bool {
+	return w == -1 || (!p.sparse && w == 0)
+}
+
+// linearDefinition returns e such that the constraint is equivalent to v = e, if the
+// constraint is linear in its wires
+func (p *program) linearDefinition(cID, v int) (linearExpression, bool) {
+	var l linearExpression // l == 0
+	if p.sparse {
+		c := &p.scs[cID]
+		if p.mul(c.m[0].coeff, c.m[1].coeff).Sign() != 0 {
+			return nil, false
+		}
+		l = p.normalize(linearExpression{c.l, c.r, c.o, {wire: -1, coeff: c.k}})
+	} else {
+		c := &p.r1cs[cID]
+		a, b := c.l, c.r
+		k, ok := p.isConstant(a)
+		if !ok {
+			a, b = b, a
+			if k, ok = p.isConstant(a); !ok {
+				return nil, false
+			}
+		}
+		// k⋅b - o == 0
+		for _, t := range b {
+			l = append(l, term{wire: t.wire, coeff: p.mul(k, t.coeff)})
+		}
+		for _, t := range c.o {
+			l = append(l, term{wire: t.wire, coeff: p.neg(t.coeff)})
+		}
+		l = p.normalize(l)
+	}
+
+	var cv *big.Int
+	e := make(linearExpression, 0, len(l))
+	for _, t := range l {
+		if t.wire == v {
+			cv = t.coeff
+		} else {
+			e = append(e, t)
+		}
+	}
+	if cv == nil {
+		return nil, false
+	}
+	// v = -e / cv
+	f := p.neg(p.div(big.NewInt(1), cv))
+	for i := range e {
+		e[i] = term{wire: e[i].wire, coeff: p.mul(e[i].coeff, f)}
+	}
+	return e, true
+}
+
+// commonProducts merges the wires defined as the same product: if u = c₁⋅(a × b) is solved
+// before v = c₂⋅(a × b), v is substituted by (c₂/c₁)⋅u and its constraint is removed.
+func commonProducts(p *program) bool {
+	defs, _, err := p.solverOrder()
+	if err != nil {
+		return false
+	}
+	type product struct {
+		wire  int
+		ratio *big.Int // wire = ratio ⋅ product
+	}
+	seen := make(map[string]product)
+	changed := false
+	for cID := range p.removed {
+		v := defs[cID]
+		if p.removed[cID] || v == -1 {
+			continue
+		}
+		if _, ok := p.hintOf[v]; ok {
+			continue
+		}
+		key, ratio, ok := p.productDefinition(cID, v)
+		if !ok {
+			continue
+		}
+		u, ok := seen[key]
+		if !ok {
+			seen[key] = product{wire: v, ratio: ratio}
+			continue
+		}
+		p.removeConstraint(cID, -1)
+		p.substitute(v, linearExpression{{wire: u.wire, coeff: p.div(ratio, u.ratio)}})
+		changed = true
+	}
+	return changed
+}
+
+// productDefinition returns a key identifying the product and the ratio such that the
+// constraint is equivalent to v = ratio ⋅ product, if the constraint is a product definition
+func (p *program) productDefinition(cID, v int) (string, *big.Int, bool) {
+	if p.sparse {
+		c := &p.scs[cID]
+		qM := p.mul(c.m[0].coeff, c.m[1].coeff)
+		if qM.Sign() == 0 || c.l.coeff.Sign() != 0 || c.r.coeff.Sign() != 0 || c.k.Sign() != 0 ||
+			c.o.wire != v || c.o.coeff.Sign() == 0 || c.m[0].wire == v || c.m[1].wire == v {
+			return "", nil, false
+		}
+		a, b := c.m[0].wire, c.m[1].wire
+		if a > b {
+			a, b = b, a
+		}
+		// qM⋅a⋅b + qO⋅v == 0
+		return fmt.Sprintf("%d*%d", a, b), p.neg(p.div(qM, c.o.coeff)), true
+	}
+	c := &p.r1cs[cID]
+	o := p.normalize(c.o)
+	if len(o) != 1 || o[0].wire != v {
+		return "", nil, false
+	}
+	for _, l := range [...]linearExpression{c.l, c.r} {
+		if _, ok := p.isConstant(l); ok {
+			return "", nil, false
+		}
+		for _, t := range l {
+			if t.wire == v {
+				return "", nil, false
+			}
+		}
+	}
+	a, b := p.linearExpressionKey(c.l), p.linearExpressionKey(c.r)
+	if a > b {
+		a, b = b, a
+	}
+	// a ⋅ b == cv ⋅ v
+	return a + "*" + b, p.div(big.NewInt(1), o[0].coeff), true
+}
+
+func (p *program) linearExpressionKey(l linearExpression) string {
+	var sbb strings.Builder
+	for _, t := range p.sorted(l) {
+		sbb.WriteString(t.coeff.String())
+		sbb.WriteByte('.')
+		sbb.WriteString(strconv.Itoa(t.wire))
+		sbb.WriteByte(' ')
+	}
+	return sbb.String()
+}
@@ -0,0 +1,664 @@
+// Copyright 2020 ConsenSys AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package optimizer
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/consensys/gnark/backend/hint"
+	"github.com/consensys/gnark/constraint"
+)
+
+// term is a coeff * wire term, wire == -1 denotes a constant (see constraint.IR)
+type term struct {
+	wire  int
+	coeff *big.Int
+}
+
+type linearExpression []term
+
+type r1c struct {
+	l, r, o linearExpression
+}
+
+// sparseR1C is a qL⋅xa + qR⋅xb + qO⋅xc + qM⋅(xaxb) + qC == 0 constraint, m[0] (resp. m[1])
+// has the same wire as l (resp. r).
+type sparseR1C struct {
+	l, r, o term
+	m       [2]term
+	k       *big.Int
+}
+
+type hintCall struct {
+	id      hint.ID
+	name    string
+	inputs  []linearExpression
+	outputs []int
+	stack   []constraint.IRFrame
+}
+
+type logEntry struct {
+	constraints    []int
+	caller, format string
+	toResolve      []linearExpression
+	limbs          []constraint.LogLimbs
+	conditional    bool
+	label          string
+	stack          []constraint.IRFrame
+}
+
+// program is the working representation of a constraint system IR, with the coefficients
+// reduced modulo q. Passes remove constraints, hints and wires in place, the wires are
+// renumbered when the program is converted back to an IR.
+type program struct {
+	ir       *constraint.IR
+	q        *big.Int
+	sparse   bool
+	nbInputs int
+	nbWires  int
+
+	r1cs    []r1c
+	scs     []sparseR1C
+	removed []bool // removed constraints
+
+	hints      []*hintCall // nil once removed
+	hintOf     map[int]int // maps a hint output to its hint
+	debugInfo  []logEntry
+	messages   []logEntry
+	logs       []logEntry
+	eliminated []bool // removed wires
+}
+
+func load(ir *constraint.IR) (*program, error) {
+	q, ok := new(big.Int).SetString(ir.Field, 16)
+	if !ok {
+		return nil, fmt.Errorf("invalid field %q", ir.Field)
+	}
+	p := &program{
+		ir:       ir,
+		q:        q,
+		sparse:   ir.Kind == constraint.IRKindSparseR1CS,
+		nbInputs: len(ir.Public) + len(ir.Secret),
+		hintOf:   make(map[int]int),
+	}
+	p.nbWires = p.nbInputs + ir.NbInternal
+	p.eliminated = make([]bool, p.nbWires)
+
+	var err error
+	coeff := func(s string) *big.Int {
+		c, ok := new(big.Int).SetString(s, 0)
+		if !ok {
+			err = fmt.Errorf("invalid coefficient %q", s)
+			return new(big.Int)
+		}
+		return c.Mod(c, q)
+	}
+	toTerm := func(t constraint.IRTerm) term {
+		if t.Wire < -1 || t.Wire >= p.nbWires {
+			err = fmt.Errorf("invalid wire %d", t.Wire)
+		}
+		return term{wire: t.Wire, coeff: coeff(t.Coeff)}
+	}
+	toLinearExpression := func(l constraint.IRLinearExpression) linearExpression {
+		r := make(linearExpression, len(l))
+		for i := range l {
+			r[i] = toTerm(l[i])
+		}
+		return r
+	}
+	toLogEntry := func(l *constraint.IRLogEntry) logEntry {
+		r := logEntry{
+			constraints: append([]int{}, l.Constraints...),
+			caller:      l.Caller,
+			format:      l.Format,
+			limbs:       l.Limbs,
+			conditional: l.Conditional,
+			label:       l.Label,
+			stack:       l.Stack,
+			toResolve:   make([]linearExpression, len(l.ToResolve)),
+		}
+		for i := range l.ToResolve {
+			r.toResolve[i] = toLinearExpression(l.ToResolve[i])
+		}
+		return r
+	}
+
+	if p.sparse {
+		p.scs = make([]sparseR1C, len(ir.SparseR1Cs))
+		for i, c := range ir.SparseR1Cs {
+			p.scs[i] = sparseR1C{
+				l: toTerm(c.L), r: toTerm(c.R), o: toTerm(c.O),
+				m: [2]term{toTerm(c.M[0]), toTerm(c.M[1])},
+				k: coeff(c.K),
+			}
+		}
+		p.removed = make([]bool, len(p.scs))
+	} else {
+		p.r1cs = make([]r1c, len(ir.R1Cs))
+		for i, c := range ir.R1Cs {
+			p.r1cs[i] = r1c{l: toLinearExpression(c.L), r: toLinearExpression(c.R), o: toLinearExpression(c.O)}
+		}
+		p.removed = make([]bool, len(p.r1cs))
+	}
+
+	p.hints = make([]*hintCall, len(ir.Hints))
+	for i, h := range ir.Hints {
+		p.hints[i] = &hintCall{id: h.ID, name: h.Name, outputs: append([]int{}, h.Outputs...), inputs: make([]linearExpression, len(h.Inputs)), stack: h.Stack}
+		for j := range h.Inputs {
+			p.hints[i].inputs[j] = toLinearExpression(h.Inputs[j])
+		}
+		for _, w := range h.Outputs {
+			p.hintOf[w] = i
+		}
+	}
+	p.debugInfo = make([]logEntry, len(ir.DebugInfo))
+	for i := range ir.DebugInfo {
+		p.debugInfo[i] = toLogEntry(&ir.DebugInfo[i])
+	}
+	p.messages = make([]logEntry, len(ir.Messages))
+	for i := range ir.Messages {
+		p.messages[i] = toLogEntry(&ir.Messages[i])
+	}
+	p.logs = make([]logEntry, len(ir.Logs))
+	for i := range ir.Logs {
+		p.logs[i] = toLogEntry(&ir.Logs[i])
+	}
+
+	return p, err
+}
+
+// toIR returns the IR of the program, without the removed constraints, hints and wires
+func (p *program) toIR() *constraint.IR {
+	ir := *p.ir
+	ir.Hints, ir.R1Cs, ir.SparseR1Cs, ir.DebugInfo, ir.Messages, ir.Logs = nil, nil, nil, nil, nil, nil
+
+	// renumber the internal wires
+	wires := make([]int, p.nbWires)
+	nbWires := 0
+	for w := range wires {
+		wires[w] = nbWires
+		if !p.eliminated[w] {
+			nbWires++
+		}
+	}
+	ir.NbInternal = nbWires - p.nbInputs
+
+	toTerm := func(t term) constraint.IRTerm {
+		if t.wire == -1 {
+			return constraint.IRTerm{Wire: -1, Coeff: t.coeff.String()}
+		}
+		if p.eliminated[t.wire] {
+			if t.coeff.Sign() != 0 {
+				panic("eliminated wire still in use")
+			}
+			return constraint.IRTerm{Wire: 0, Coeff: t.coeff.String()}
+		}
+		return constraint.IRTerm{Wire: wires[t.wire], Coeff: t.coeff.String()}
+	}
+	toLinearExpression := func(l linearExpression) constraint.IRLinearExpression {
+		r := make(constraint.IRLinearExpression, len(l))
+		for i := range l {
+			r[i] = toTerm(l[i])
+		}
+		return r
+	}
+
+	// renumber the constraints
+	constraints := make([]int, len(p.removed))
+	nbConstraints := 0
+	for cID := range p.removed {
+		constraints[cID] = nbConstraints
+		if p.removed[cID] {
+			continue
+		}
+		nbConstraints++
+		if p.sparse {
+			c := &p.scs[cID]
+			ir.SparseR1Cs = append(ir.SparseR1Cs, constraint.IRSparseR1C{
+				L: toTerm(c.l), R: toTerm(c.r), O: toTerm(c.o),
+				M: [2]constraint.IRTerm{toTerm(c.m[0]), toTerm(c.m[1])},
+				K: c.k.String(),
+			})
+		} else {
+			c := &p.r1cs[cID]
+			ir.R1Cs = append(ir.R1Cs, constraint.IRR1C{L: toLinearExpression(c.l), R: toLinearExpression(c.r), O: toLinearExpression(c.o)})
+		}
+	}
+
+	for _, h := range p.hints {
+		if h == nil {
+			continue
+		}
+		irh := constraint.IRHint{ID: h.id, Name: h.name, Inputs: make([]constraint.IRLinearExpression, len(h.inputs)), Outputs: make([]int, len(h.outputs)), Stack: h.stack}
+		for i := range h.inputs {
+			irh.Inputs[i] = toLinearExpression(h.inputs[i])
+		}
+		for i, w := range h.outputs {
+			irh.Outputs[i] = wires[w]
+		}
+		ir.Hints = append(ir.Hints, irh)
+	}
+
+	toLogEntry := func(l *logEntry) constraint.IRLogEntry {
+		r := constraint.IRLogEntry{Caller: l.caller, Format: l.format, Limbs: l.limbs, Conditional: l.conditional, Label: l.label, Stack: l.stack}
+		for _, cID := range l.constraints {
+			if !p.removed[cID] {
+				r.Constraints = append(r.Constraints, constraints[cID])
+			}
+		}
+		for i := range l.toResolve {
+			r.ToResolve = append(r.ToResolve, toLinearExpression(l.toResolve[i]))
+		}
+		return r
+	}
+	for i := range p.debugInfo {
+		ir.DebugInfo = append(ir.DebugInfo, toLogEntry(&p.debugInfo[i]))
+	}
+	for i := range p.messages {
+		ir.Messages = append(ir.Messages, toLogEntry(&p.messages[i]))
+	}
+	for i := range p.logs {
+		ir.Logs = append(ir.Logs, toLogEntry(&p.logs[i]))
+	}
+
+	return &ir
+}
+
+// solverOrder mirrors the solvers of the constraint systems: it returns the wire solved by
+// each constraint (-1 if none) and the number of wires which are never solved. It returns an
+// error if a constraint can't be solved, that is, if it has more than one unsolved wire (which
+// is not a hint output), or if the unsolved wire can't be isolated.
+func (p *program) solverOrder() (defs []int, nbUnsolved int, err error) {
+	solved := make([]bool, p.nbWires)
+	for w := 0; w < p.nbInputs; w++ {
+		solved[w] = true
+	}
+
+	var solveHint func(h int) error
+	solveHint = func(h int) error {
+		for _, in := range p.hints[h].inputs {
+			for _, t := range in {
+				if t.wire == -1 || solved[t.wire] {
+					continue
+				}
+				hh, ok := p.hintOf[t.wire]
+				if !ok || hh == h {
+					return fmt.Errorf("hint %s: input wire %d is not solved", p.hints[h].name, t.wire)
+				}
+				if err := solveHint(hh); err != nil {
+					return err
+				}
+			}
+		}
+		for _, w := range p.hints[h].outputs {
+			solved[w] = true
+		}
+		return nil
+	}
+	// unsolved returns true if w is not solved (and isn't a hint output)
+	unsolved := func(w int) (bool, error) {
+		if w == -1 || solved[w] {
+			return false, nil
+		}
+		if h, ok := p.hintOf[w]; ok && p.hints[h] != nil {
+			return false, solveHint(h)
+		}
+		return true, nil
+	}
+
+	defs = make([]int, len(p.removed))
+	for cID := range defs {
+		defs[cID] = -1
+		if p.removed[cID] {
+			continue
+		}
+		if p.sparse {
+			c := &p.scs[cID]
+			slots := [3]bool{
+				c.l.coeff.Sign() != 0 || c.m[0].coeff.Sign() != 0,
+				c.r.coeff.Sign() != 0 || c.m[1].coeff.Sign() != 0,
+				c.o.coeff.Sign() != 0,
+			}
+			wires := [3]int{c.l.wire, c.r.wire, c.o.wire}
+			slot := -1
+			for i := range slots {
+				if !slots[i] {
+					continue
+				}
+				u, err := unsolved(wires[i])
+				if err != nil {
+					return nil, 0, err
+				}
+				if u {
+					if slot != -1 {
+						return nil, 0, fmt.Errorf("constraint %d has more than one unsolved wire", cID)
+					}
+					slot = i
+				}
+			}
+			switch slot {
+			case -1:
+				continue
+			case 0:
+				if !solved[c.r.wire] {
+					return nil, 0, fmt.Errorf("constraint %d: R wire should be solved when solving L", cID)
+				}
+			case 1:
+				// the solver doesn't support solving R
+				return nil, 0, fmt.Errorf("constraint %d: can't solve R", cID)
+			}
+			if wires[slot] == -1 {
+				return nil, 0, fmt.Errorf("constraint %d: constant in a wire slot", cID)
+			}
+			defs[cID] = wires[slot]
+			solved[wires[slot]] = true
+		} else {
+			c := &p.r1cs[cID]
+			for _, l := range [...]linearExpression{c.l, c.r, c.o} {
+				for _, t := range l {
+					if t.wire == -1 {
+						return nil, 0, fmt.Errorf("constraint %d: constant term", cID)
+					}
+					u, err := unsolved(t.wire)
+					if err != nil {
+						return nil, 0, err
+					}
+					if u {
+						if defs[cID] != -1 {
+							return nil, 0, fmt.Errorf("constraint %d has more than one unsolved wire", cID)
+						}
+						defs[cID] = t.wire
+					}
+				}
+			}
+			if defs[cID] != -1 {
+				solved[defs[cID]] = true
+			}
+		}
+	}
+
+	for w := range solved {
+		if !solved[w] && !p.eliminated[w] {
+			nbUnsolved++
+		}
+	}
+	return defs, nbUnsolved, nil
+}
+
+// uses returns the number of references to each wire
+func (p *program) uses() []int {
+	uses := make([]int, p.nbWires)
+	addTerm := func(t term) {
+		if t.wire != -1 && t.coeff.Sign() != 0 {
+			uses[t.wire]++
+		}
+	}
+	addLinearExpression := func(l linearExpression) {
+		for _, t := range l {
+			addTerm(t)
+		}
+	}
+	for cID := range p.removed {
+		if p.removed[cID] {
+			continue
+		}
+		if p.sparse {
+			c := &p.scs[cID]
+			if c.l.coeff.Sign() != 0 || c.m[0].coeff.Sign() != 0 {
+				uses[c.l.wire]++
+			}
+			if c.r.coeff.Sign() != 0 || c.m[1].coeff.Sign() != 0 {
+				uses[c.r.wire]++
+			}
+			addTerm(c.o)
+		} else {
+			c := &p.r1cs[cID]
+			addLinearExpression(c.l)
+			addLinearExpression(c.r)
+			addLinearExpression(c.o)
+		}
+	}
+	for _, h := range p.hints {
+		if h == nil {
+			continue
+		}
+		for _, in := range h.inputs {
+			addLinearExpression(in)
+		}
+	}
+	for _, entries := range [...][]logEntry{p.debugInfo, p.messages, p.logs} {
+		for i := range entries {
+			for _, l := range entries[i].toResolve {
+				addLinearExpression(l)
+			}
+		}
+	}
+	return uses
+}
+
+// removeConstraint removes a constraint, and the wire it solves if any
+func (p *program) removeConstraint(cID, solvedWire int) {
+	p.removed[cID] = true
+	if solvedWire != -1 {
+		p.eliminated[solvedWire] = true
+	}
+}
+
+// substitute replaces the wire v by the linear expression e everywhere. e has at most one
+// non constant wire in a SparseR1CS; the constants are on the wire 0 (the one wire) in a R1CS
+// and on the wire -1 in a SparseR1CS.
+func (p *program) substitute(v int, e linearExpression) {
+	for cID := range p.removed {
+		if p.removed[cID] {
+			continue
+		}
+		if p.sparse {
+			p.substituteSparse(&p.scs[cID], v, e)
+		} else {
+			c := &p.r1cs[cID]
+			c.l, c.r, c.o = p.substituteLinear(c.l, v, e), p.substituteLinear(c.r, v, e), p.substituteLinear(c.o, v, e)
+		}
+	}
+	for _, h := range p.hints {
+		if h == nil {
+			continue
+		}
+		for i := range h.inputs {
+			h.inputs[i] = p.substituteLinear(h.inputs[i], v, e)
+		}
+	}
+	for _, entries := range [...][]logEntry{p.debugInfo, p.messages, p.logs} {
+		for i := range entries {
+			for j := range entries[i].toResolve {
+				entries[i].toResolve[j] = p.substituteLinear(entries[i].toResolve[j], v, e)
+			}
+		}
+	}
+	p.eliminated[v] = true
+}
+
+func (p *program) substituteLinear(l linearExpression, v int, e linearExpression) linearExpression {
+	var c *big.Int
+	for _, t := range l {
+		if t.wire == v {
+			if c == nil {
+				c = new(big.Int)
+			}
+			c.Add(c, t.coeff)
+		}
+	}
+	if c == nil {
+		return l
+	}
+	res := make(linearExpression, 0, len(l)+len(e))
+	for _, t := range l {
+		if t.wire != v {
+			res = append(res, t)
+		}
+	}
+	for _, t := range e {
+		res = append(res, term{wire: t.wire, coeff: p.mul(c, t.coeff)})
+	}
+	return p.normalize(res)
+}
+
+// substituteSparse replaces v by e = α⋅x + β in a SparseR1C
+func (p *program) substituteSparse(c *sparseR1C, v int, e linearExpression) {
+	x, alpha, beta := -1, new(big.Int), new(big.Int)
+	for _, t := range e {
+		if t.wire == -1 {
+			beta.Add(beta, t.coeff)
+		} else {
+			x, alpha = t.wire, t.coeff
+		}
+	}
+	replace := func(t *term) {
+		// t = coeff⋅v → coeff⋅α⋅x, the constant coeff⋅β is accounted for by the caller
+		t.coeff = p.mul(t.coeff, alpha)
+		t.wire = x
+		if x == -1 {
+			t.wire = 0
+		}
+	}
+	if c.l.wire == v && (c.l.coeff.Sign() != 0 || c.m[0].coeff.Sign() != 0) {
+		// qL⋅v + qM⋅v⋅b → qL⋅α⋅x + qL⋅β + qM⋅α⋅x⋅b + qM⋅β⋅b
+		qM := p.mul(c.m[0].coeff, c.m[1].coeff)
+		c.k = p.add(c.k, p.mul(c.l.coeff, beta))
+		c.r.coeff = p.add(c.r.coeff, p.mul(qM, beta))
+		if c.r.coeff.Sign() != 0 {
+			c.r.wire = c.m[1].wire
+		}
+		replace(&c.l)
+		replace(&c.m[0])
+		if c.m[0].coeff.Sign() == 0 {
+			c.m[1].coeff = new(big.Int)
+		}
+	}
+	if c.r.wire == v && (c.r.coeff.Sign() != 0 || c.m[1].coeff.Sign() != 0) {
+		qM := p.mul(c.m[0].coeff, c.m[1].coeff)
+		c.k = p.add(c.k, p.mul(c.r.coeff, beta))
+		c.l.coeff = p.add(c.l.coeff, p.mul(qM, beta))
+		if c.l.coeff.Sign() != 0 {
+			c.l.wire = c.m[0].wire
+		}
+		replace(&c.r)
+		replace(&c.m[1])
+		if c.m[1].coeff.Sign() == 0 {
+			c.m[0].coeff = new(big.Int)
+		}
+	}
+	if c.o.wire == v && c.o.coeff.Sign() != 0 {
+		c.k = p.add(c.k, p.mul(c.o.coeff, beta))
+		replace(&c.o)
+	}
+}
+
+// normalize merges the terms of the same wire and removes the zero ones
+func (p *program) normalize(l linearExpression) linearExpression {
+	index := make(map[int]int, len(l))
+	res := make(linearExpression, 0, len(l))
+	for _, t := range l {
+		if i, ok := index[t.wire]; ok {
+			res[i].coeff = p.add(res[i].coeff, t.coeff)
+			continue
+		}
+		index[t.wire] = len(res)
+		res = append(res, term{wire: t.wire, coeff: new(big.Int).Set(t.coeff)})
+	}
+	n := 0
+	for _, t := range res {
+		if t.coeff.Sign() != 0 {
+			res[n] = t
+			n++
+		}
+	}
+	return res[:n]
+}
+
+// isConstant returns true if l has no wire but the one wire (R1CS) and returns its value
+func (p *program) isConstant(l linearExpression) (*big.Int, bool) {
+	v := new(big.Int)
+	for _, t := range l {
+		if t.coeff.Sign() == 0 {
+			continue
+		}
+		if t.wire != 0 {
+			return nil, false
+		}
+		v = p.add(v, t.coeff)
+	}
+	return v, true
+}
+
+// constraintKey returns a string identifying the constraint
+func (p *program) constraintKey(cID int) string {
+	var sbb strings.Builder
+	writeTerm := func(t term) {
+		sbb.WriteString(t.coeff.String())
+		sbb.WriteByte('.')
+		sbb.WriteString(fmt.Sprint(t.wire))
+		sbb.WriteByte(' ')
+	}
+	if p.sparse {
+		c := &p.scs[cID]
+		for _, t := range [...]term{c.l, c.r, c.o, c.m[0], c.m[1]} {
+			writeTerm(t)
+		}
+		sbb.WriteString(c.k.String())
+		return sbb.String()
+	}
+	c := &p.r1cs[cID]
+	for _, l := range [...]linearExpression{c.l, c.r, c.o} {
+		for _, t := range p.sorted(l) {
+			writeTerm(t)
+		}
+		sbb.WriteByte('|')
+	}
+	return sbb.String()
+}
+
+// sorted returns the normalized linear expression sorted by wire
+func (p *program) sorted(l linearExpression) linearExpression {
+	l = p.normalize(l)
+	sort.Slice(l, func(i, j int) bool { return l[i].wire < l[j].wire })
+	return l
+}
+
+func (p *program) add(a, b *big.Int) *big.Int {
+	r := new(big.Int).Add(a, b)
+	return r.Mod(r, p.q)
+}
+
+func (p *program) mul(a, b *big.Int) *big.Int {
+	r := new(big.Int).Mul(a, b)
+	return r.Mod(r, p.q)
+}
+
+func (p *program) neg(a *big.Int) *big.Int {
+	r := new(big.Int).Neg(a)
+	return r.Mod(r, p.q)
+}
+
+func (p *program) div(a, b *big.Int) *big.Int {
+	inv := new(big.Int).ModInverse(b, p.q)
+	if inv == nil {
+		panic(errors.New("division by zero"))
+	}
+	return p.mul(a, inv)
+}
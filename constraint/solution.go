@@ -0,0 +1,182 @@
+package constraint
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+
+	"github.com/consensys/gnark/backend/hint"
+	"github.com/consensys/gnark/backend/witness"
+)
+
+// Solution is the assignment of all the wires of a constraint system, returned by
+// ConstraintSystem.Solve. It is curve agnostic, and can be saved with WriteTo and reloaded with
+// ReadSolution.
+//
+// A partial Solution, returned by ConstraintSystem.SolvePartial, has nil values for the internal
+// wires which are not solved yet. The solver can be resumed later with ConstraintSystem.Resume,
+// for example once the challenge of an interactive protocol is known:
+//
+//	partial, err := ccs.SolvePartial(w, constraint.SolveTarget{Wires: committed})
+//	// ...
+//	solution, err := ccs.Resume(partial, constraint.SolveTarget{All: true})
+//
+// A Solution records the hint calls of the solver: a prover can solve the constraint system
+// again without running the hints, for example when the witness is generated on another
+// machine, with
+//
+//	w, err := solution.Witness()
+//	proof, err := groth16.Prove(ccs, pk, w, backend.WithHintTranscript(solution.Transcript()))
+type Solution struct {
+	Field  *big.Int `json:"field"`
+	Public []string `json:"public"` // names of the public wires
+	Secret []string `json:"secret"` // names of the secret wires
+
+	// OneWire is set if the first public wire is the constant 1 (R1CS), which is not part of
+	// the witness.
+	OneWire bool `json:"oneWire,omitempty"`
+
+	// Values of the wires, numbered as in the constraint system: [public | secret | internal].
+	// The values of the internal wires which are not solved are nil.
+	Values []*big.Int `json:"values"`
+
+	// Hints are the hint calls made by the solver
+	Hints []hint.TranscriptEntry `json:"hints,omitempty"`
+}
+
+// NewSolution returns the Solution of the constraint system with the given wire values, and
+// the hint calls recorded in the transcript.
+func (system *System) NewSolution(field *big.Int, values []*big.Int, hints *hint.Transcript, oneWire bool) *Solution {
+	s := &Solution{
+		Field:   new(big.Int).Set(field),
+		Public:  append([]string{}, system.Public...),
+		Secret:  append([]string{}, system.Secret...),
+		OneWire: oneWire,
+		Values:  values,
+	}
+	if hints != nil {
+		s.Hints = hints.Entries()
+	}
+	return s
+}
+
+// ReadSolution reads a Solution written with Solution.WriteTo
+func ReadSolution(r io.Reader) (*Solution, error) {
+	var s Solution
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, err
+	}
+	if s.Field == nil || len(s.Values) < len(s.Public)+len(s.Secret) {
+		return nil, errors.New("invalid solution")
+	}
+	for i := range s.Values[:len(s.Public)+len(s.Secret)] {
+		if s.Values[i] == nil {
+			return nil, fmt.Errorf("invalid solution: value of input %s missing", s.WireName(i))
+		}
+	}
+	return &s, nil
+}
+
+// CheckSolution returns an error if the solution, possibly partial, is not a solution of the
+// constraint system over the given field.
+func (system *System) CheckSolution(s *Solution, field *big.Int) error {
+	if s.Field == nil || s.Field.Cmp(field) != 0 {
+		return errors.New("invalid solution: field mismatch")
+	}
+	if len(s.Public) != len(system.Public) || len(s.Secret) != len(system.Secret) {
+		return fmt.Errorf("invalid solution: got %d public and %d secret inputs, expected %d and %d", len(s.Public), len(s.Secret), len(system.Public), len(system.Secret))
+	}
+	if nbWires := len(system.Public) + len(system.Secret) + system.NbInternalVariables; len(s.Values) != nbWires {
+		return fmt.Errorf("invalid solution: got %d wires, expected %d", len(s.Values), nbWires)
+	}
+	for i := range s.Values[:len(s.Public)+len(s.Secret)] {
+		if s.Values[i] == nil {
+			return fmt.Errorf("invalid solution: value of input %s missing", s.WireName(i))
+		}
+	}
+	return nil
+}
+
+// WriteTo writes the solution in JSON
+func (s *Solution) WriteTo(w io.Writer) (int64, error) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
+// IsPartial returns true if some wires are not solved
+func (s *Solution) IsPartial() bool {
+	for _, v := range s.Values {
+		if v == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// NbInternal returns the number of internal wires
+func (s *Solution) NbInternal() int {
+	return len(s.Values) - len(s.Public) - len(s.Secret)
+}
+
+// WireName returns the name of the wire: the name of the input for public and secret wires,
+// and v0, v1, ... for the internal wires.
+func (s *Solution) WireName(wire int) string {
+	switch nbInputs := len(s.Public) + len(s.Secret); {
+	case wire < len(s.Public):
+		return s.Public[wire]
+	case wire < nbInputs:
+		return s.Secret[wire-len(s.Public)]
+	default:
+		return "v" + strconv.Itoa(wire-nbInputs)
+	}
+}
+
+// Value returns the value of the wire with the given name (see WireName), if it is solved
+func (s *Solution) Value(name string) (*big.Int, bool) {
+	for i := range s.Values {
+		if s.WireName(i) == name {
+			return s.Values[i], s.Values[i] != nil
+		}
+	}
+	return nil, false
+}
+
+// Witness returns the full witness of the solution, with the values of its public and secret
+// wires.
+func (s *Solution) Witness() (witness.Witness, error) {
+	w, err := witness.New(s.Field)
+	if err != nil {
+		return nil, err
+	}
+	public := s.Values[:len(s.Public)]
+	if s.OneWire {
+		public = public[1:]
+	}
+	secret := s.Values[len(s.Public) : len(s.Public)+len(s.Secret)]
+
+	values := make(chan any, len(public)+len(secret))
+	for _, v := range public {
+		values <- v
+	}
+	for _, v := range secret {
+		values <- v
+	}
+	close(values)
+	if err := w.Fill(len(public), len(secret), values); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Transcript returns a hint transcript replaying the hint calls of the solution, see
+// backend.WithHintTranscript.
+func (s *Solution) Transcript() *hint.Transcript {
+	return hint.NewTranscript(s.Hints...)
+}
@@ -0,0 +1,233 @@
+package constraint_test
+
+import (
+	"bytes"
+	"sort"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/constraint"
+	cs_bn254 "github.com/consensys/gnark/constraint/bn254"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/frontend/cs/scs"
+	"github.com/consensys/gnark/internal/backend/circuits"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSolution(t *testing.T) {
+	names := make([]string, 0, len(circuits.Circuits))
+	for name := range circuits.Circuits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		tc := circuits.Circuits[name]
+		for _, builder := range []struct {
+			name    string
+			builder frontend.NewBuilder
+		}{
+			{"r1cs", r1cs.NewBuilder},
+			{"scs", scs.NewBuilder},
+		} {
+			t.Run(name+"/"+builder.name, func(t *testing.T) {
+				assert := require.New(t)
+
+				ccs, err := frontend.Compile(ecc.BN254.ScalarField(), builder.builder, tc.Circuit)
+				assert.NoError(err)
+
+				for _, a := range tc.ValidAssignments {
+					w, err := frontend.NewWitness(a, ecc.BN254.ScalarField())
+					assert.NoError(err)
+					solution, err := ccs.Solve(w, backend.WithHints(tc.HintFunctions...))
+					assert.NoError(err)
+					nbPublic, nbSecret := ccs.GetNbPublicVariables(), ccs.GetNbSecretVariables()
+					assert.Len(solution.Values, nbPublic+nbSecret+ccs.GetNbInternalVariables())
+					assert.Equal(ccs.GetNbInternalVariables(), solution.NbInternal())
+
+					// the inputs are named
+					if builder.name == "r1cs" {
+						assert.Equal("1", solution.WireName(0))
+						assert.Equal(int64(1), solution.Values[0].Int64())
+					}
+					if nbSecret != 0 {
+						v, ok := solution.Value(solution.WireName(nbPublic))
+						assert.True(ok)
+						assert.Equal(solution.Values[nbPublic], v)
+					}
+
+					// save and reload the solution
+					var buf bytes.Buffer
+					_, err = solution.WriteTo(&buf)
+					assert.NoError(err)
+					expected := buf.String()
+					reloaded, err := constraint.ReadSolution(&buf)
+					assert.NoError(err)
+					_, err = reloaded.WriteTo(&buf)
+					assert.NoError(err)
+					assert.Equal(expected, buf.String())
+
+					// the witness and the hint calls of the solution are enough to solve again
+					w2, err := reloaded.Witness()
+					assert.NoError(err)
+					wExpected, err := w.MarshalBinary()
+					assert.NoError(err)
+					wActual, err := w2.MarshalBinary()
+					assert.NoError(err)
+					assert.Equal(wExpected, wActual)
+					solution2, err := ccs.Solve(w2, backend.WithHintTranscript(reloaded.Transcript()))
+					assert.NoError(err)
+					buf.Reset()
+					_, err = solution2.WriteTo(&buf)
+					assert.NoError(err)
+					assert.Equal(expected, buf.String())
+				}
+
+				for _, a := range tc.InvalidAssignments {
+					w, err := frontend.NewWitness(a, ecc.BN254.ScalarField())
+					assert.NoError(err)
+					_, err = ccs.Solve(w, backend.WithHints(tc.HintFunctions...))
+					assert.Error(err)
+				}
+			})
+		}
+	}
+}
+
+type branchesCircuit struct {
+	X, Y frontend.Variable
+	A, B frontend.Variable `gnark:",public"`
+}
+
+func (c *branchesCircuit) Define(api frontend.API) error {
+	api.AssertIsEqual(api.Mul(c.X, c.X, c.X), c.A)
+	api.AssertIsEqual(api.Mul(c.Y, c.Y, c.Y), c.B)
+	return nil
+}
+
+func TestSolvePartial(t *testing.T) {
+	assert := require.New(t)
+
+	w, err := frontend.NewWitness(&branchesCircuit{X: 2, Y: 3, A: 8, B: 27}, ecc.BN254.ScalarField())
+	assert.NoError(err)
+	for _, builder := range []frontend.NewBuilder{r1cs.NewBuilder, scs.NewBuilder} {
+		ccs, err := frontend.Compile(ecc.BN254.ScalarField(), builder, &branchesCircuit{})
+		assert.NoError(err)
+		nbInputs := ccs.GetNbPublicVariables() + ccs.GetNbSecretVariables()
+
+		// the first internal wire is X*X, the Y branch is not solved
+		partial, err := ccs.SolvePartial(w, constraint.SolveTarget{Wires: []int{nbInputs}})
+		assert.NoError(err)
+		assert.True(partial.IsPartial())
+		assert.Equal(int64(4), partial.Values[nbInputs].Int64())
+		for i, v := range partial.Values[nbInputs+1:] {
+			if v != nil {
+				assert.NotEqual(int64(9), v.Int64(), "wire %d", nbInputs+1+i)
+			}
+		}
+		_, ok := partial.Value(partial.WireName(len(partial.Values) - 1))
+		assert.False(ok)
+
+		_, err = ccs.SolvePartial(w, constraint.SolveTarget{Wires: []int{len(partial.Values)}})
+		assert.Error(err)
+		_, err = ccs.SolvePartial(w, constraint.SolveTarget{Levels: -1})
+		assert.Error(err)
+	}
+}
+
+func TestResume(t *testing.T) {
+	names := make([]string, 0, len(circuits.Circuits))
+	for name := range circuits.Circuits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		tc := circuits.Circuits[name]
+		for _, builder := range []frontend.NewBuilder{r1cs.NewBuilder, scs.NewBuilder} {
+			assert := require.New(t)
+
+			ccs, err := frontend.Compile(ecc.BN254.ScalarField(), builder, tc.Circuit)
+			assert.NoError(err, name)
+			var levels int
+			switch ccs := ccs.(type) {
+			case *cs_bn254.R1CS:
+				levels = len(ccs.Levels)
+			case *cs_bn254.SparseR1CS:
+				levels = len(ccs.Levels)
+			}
+
+			for _, a := range tc.ValidAssignments {
+				w, err := frontend.NewWitness(a, ecc.BN254.ScalarField())
+				assert.NoError(err)
+				hints := backend.WithHints(tc.HintFunctions...)
+				solution, err := ccs.Solve(w, hints)
+				assert.NoError(err)
+				var expected bytes.Buffer
+				_, err = solution.WriteTo(&expected)
+				assert.NoError(err)
+
+				// solve the last wire, then the first half of the levels
+				target := constraint.SolveTarget{Wires: []int{len(solution.Values) - 1}}
+				if ccs.GetNbInternalVariables() == 0 {
+					target.Wires = nil
+				}
+				partial, err := ccs.SolvePartial(w, target, hints)
+				assert.NoError(err, name)
+				for _, wire := range target.Wires {
+					assert.Zero(solution.Values[wire].Cmp(partial.Values[wire]), name)
+				}
+				partial, err = ccs.Resume(partial, constraint.SolveTarget{Levels: levels / 2}, hints)
+				assert.NoError(err, name)
+
+				// save and reload the partial solution, and resume with the recorded hint calls
+				var buf bytes.Buffer
+				_, err = partial.WriteTo(&buf)
+				assert.NoError(err)
+				partial, err = constraint.ReadSolution(&buf)
+				assert.NoError(err)
+				for i, v := range partial.Values {
+					if v != nil {
+						assert.Zero(solution.Values[i].Cmp(v), name)
+					}
+				}
+				resumed, err := ccs.Resume(partial, constraint.SolveTarget{All: true}, hints)
+				assert.NoError(err, name)
+				assert.False(resumed.IsPartial())
+				buf.Reset()
+				_, err = resumed.WriteTo(&buf)
+				assert.NoError(err)
+				assert.Equal(expected.String(), buf.String(), name)
+			}
+		}
+	}
+}
+
+type reusedHintInputsCircuit struct {
+	X frontend.Variable
+}
+
+func (c *reusedHintInputsCircuit) Define(api frontend.API) error {
+	// the same hint is called on the same input with different numbers of outputs
+	api.ToBinary(c.X, 8)
+	api.ToBinary(c.X, 16)
+	return nil
+}
+
+func TestSolveReusedHintInputs(t *testing.T) {
+	assert := require.New(t)
+
+	w, err := frontend.NewWitness(&reusedHintInputsCircuit{X: 5}, ecc.BN254.ScalarField())
+	assert.NoError(err)
+	for _, builder := range []frontend.NewBuilder{r1cs.NewBuilder, scs.NewBuilder} {
+		ccs, err := frontend.Compile(ecc.BN254.ScalarField(), builder, &reusedHintInputsCircuit{})
+		assert.NoError(err)
+		solution, err := ccs.Solve(w)
+		assert.NoError(err)
+		assert.Len(solution.Hints, 2)
+		_, err = ccs.Solve(w, backend.WithHintTranscript(solution.Transcript()))
+		assert.NoError(err)
+	}
+}
@@ -0,0 +1,163 @@
+package constraint
+
+import (
+	"fmt"
+)
+
+// SolveTarget selects the wires solved by ConstraintSystem.SolvePartial and
+// ConstraintSystem.Resume. The selections are cumulative.
+type SolveTarget struct {
+	// All selects all the wires
+	All bool
+
+	// Levels selects the wires solved by the constraints of the first Levels levels (see
+	// System.Levels)
+	Levels int
+
+	// Wires selects the given wires, with the wires they depend on. For example, with the
+	// committed wires of a constraint system, the solver stops once it can compute the
+	// commitment.
+	Wires []int
+}
+
+// SolvingPlan returns the constraints the solver runs to solve the target, by level. The wires
+// for which solved returns true are not solved again (solved may be nil).
+func (r1cs *R1CSCore) SolvingPlan(target SolveTarget, solved func(wire int) bool) ([][]int, error) {
+	return r1cs.solvingPlan(target, len(r1cs.Constraints), func(cID int, visit func(wire int)) {
+		c := &r1cs.Constraints[cID]
+		for _, l := range []LinearExpression{c.L, c.R, c.O} {
+			for _, t := range l {
+				if !t.IsConstant() {
+					visit(t.WireID())
+				}
+			}
+		}
+	}, solved)
+}
+
+// SolvingPlan returns the constraints the solver runs to solve the target, by level. The wires
+// for which solved returns true are not solved again (solved may be nil).
+func (system *SparseR1CSCore) SolvingPlan(target SolveTarget, solved func(wire int) bool) ([][]int, error) {
+	return system.solvingPlan(target, len(system.Constraints), func(cID int, visit func(wire int)) {
+		// as in the solver, only the slots with a non-zero coefficient are relevant
+		c := &system.Constraints[cID]
+		if c.L.CoeffID() != CoeffIdZero || c.M[0].CoeffID() != CoeffIdZero {
+			visit(c.L.WireID())
+		}
+		if c.R.CoeffID() != CoeffIdZero || c.M[1].CoeffID() != CoeffIdZero {
+			visit(c.R.WireID())
+		}
+		if c.O.CoeffID() != CoeffIdZero {
+			visit(c.O.WireID())
+		}
+	}, solved)
+}
+
+// solvingPlan selects the constraints solving the target. wires calls visit for the wires of
+// a constraint which the solver reads or solves.
+//
+// As in the level builder, a wire is solved by the first constraint (in level order) where it
+// appears, and the outputs of a hint are solved by the first constraint where one of them, or
+// the output of a hint depending on them, appears.
+func (system *System) solvingPlan(target SolveTarget, nbConstraints int, wires func(cID int, visit func(wire int)), solved func(wire int) bool) ([][]int, error) {
+	if target.All {
+		return system.Levels, nil
+	}
+	if target.Levels < 0 || target.Levels > len(system.Levels) {
+		return nil, fmt.Errorf("invalid number of levels %d, the constraint system has %d levels", target.Levels, len(system.Levels))
+	}
+	if solved == nil {
+		solved = func(int) bool { return false }
+	}
+
+	selected := make([]bool, nbConstraints)
+	for _, level := range system.Levels[:target.Levels] {
+		for _, cID := range level {
+			selected[cID] = true
+		}
+	}
+
+	if len(target.Wires) != 0 {
+		nbInputs := system.GetNbPublicVariables() + system.GetNbSecretVariables()
+		nbWires := nbInputs + system.NbInternalVariables
+
+		// constraint solving each internal wire
+		producer := make([]int, nbWires)
+		for i := range producer {
+			producer[i] = -1
+		}
+		var assign func(wire, cID int)
+		assign = func(wire, cID int) {
+			if wire < nbInputs || producer[wire] != -1 {
+				return
+			}
+			producer[wire] = cID
+			if h, ok := system.MHints[wire]; ok {
+				for _, w := range h.Wires {
+					producer[w] = cID
+				}
+				for _, in := range h.Inputs {
+					for _, t := range in {
+						if !t.IsConstant() {
+							assign(t.WireID(), cID)
+						}
+					}
+				}
+			}
+		}
+		for _, level := range system.Levels {
+			for _, cID := range level {
+				wires(cID, func(wire int) { assign(wire, cID) })
+			}
+		}
+
+		// select the constraints solving the target wires and their dependencies
+		visited := make([]bool, nbWires)
+		stack := make([]int, 0, len(target.Wires))
+		for _, w := range target.Wires {
+			if w < 0 || w >= nbWires {
+				return nil, fmt.Errorf("invalid wire %d", w)
+			}
+			stack = append(stack, w)
+		}
+		for len(stack) != 0 {
+			w := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if w < nbInputs || visited[w] || solved(w) {
+				continue
+			}
+			visited[w] = true
+			cID := producer[w]
+			if cID == -1 {
+				return nil, fmt.Errorf("wire %d is not solved by any constraint", w)
+			}
+			if h, ok := system.MHints[w]; ok {
+				for _, in := range h.Inputs {
+					for _, t := range in {
+						if !t.IsConstant() {
+							stack = append(stack, t.WireID())
+						}
+					}
+				}
+			}
+			if !selected[cID] {
+				selected[cID] = true
+				wires(cID, func(wire int) { stack = append(stack, wire) })
+			}
+		}
+	}
+
+	var levels [][]int
+	for _, level := range system.Levels {
+		var l []int
+		for _, cID := range level {
+			if selected[cID] {
+				l = append(l, cID)
+			}
+		}
+		if len(l) != 0 {
+			levels = append(levels, l)
+		}
+	}
+	return levels, nil
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/consensys/gnark/backend/hint"
 	"github.com/consensys/gnark/backend/witness"
 	"github.com/consensys/gnark/debug"
+	"github.com/consensys/gnark/frontend/schema"
 	"github.com/consensys/gnark/internal/tinyfield"
 	"github.com/consensys/gnark/internal/utils"
 	"github.com/consensys/gnark/logger"
@@ -26,6 +27,17 @@ type ConstraintSystem interface {
 	// IsSolved returns nil if given witness solves the constraint system and error otherwise
 	IsSolved(witness witness.Witness, opts ...backend.ProverOption) error
 
+	// Solve solves the constraint system with the given full witness and returns the values
+	// of all the wires, with the hint calls of the solver.
+	Solve(witness witness.Witness, opts ...backend.ProverOption) (*Solution, error)
+
+	// SolvePartial solves the wires selected by the target and the wires they depend on, and
+	// returns a partial solution.
+	SolvePartial(witness witness.Witness, target SolveTarget, opts ...backend.ProverOption) (*Solution, error)
+
+	// Resume solves the wires selected by the target, starting from a partial solution.
+	Resume(from *Solution, target SolveTarget, opts ...backend.ProverOption) (*Solution, error)
+
 	// GetNbVariables return number of internal, secret and public Variables
 	// Deprecated: use GetNbSecretVariables() instead
 	GetNbVariables() (internal, secret, public int)
@@ -63,9 +75,20 @@ type ConstraintSystem interface {
 	// debug information only once.
 	AttachDebugInfo(debugInfo DebugInfo, constraintID []int)
 
-	// CheckUnconstrainedWires returns and error if the constraint system has wires that are not uniquely constrained.
-	// This is experimental.
+	// AttachMessage attaches the message of an assertion (see frontend.WithMessage) to the
+	// constraints added by the assertion. The message is stored once.
+	AttachMessage(message LogEntry, constraintID []int)
+
+	// CheckUnconstrainedWires returns and error if the constraint system has inputs that don't appear
+	// in any constraint. See package constraint/lint for a soundness analysis of the constraint system.
 	CheckUnconstrainedWires() error
+
+	// GetSchema returns the schema of the circuit inputs (names, visibility, array shapes), or nil
+	// if the constraint system wasn't compiled from a circuit structure.
+	GetSchema() *schema.Schema
+
+	// SetSchema sets the schema of the circuit inputs. It is called by frontend.Compile.
+	SetSchema(s *schema.Schema)
 }
 
 type Iterable interface {
@@ -105,6 +128,11 @@ type System struct {
 	// several constraints may point to the same debug info
 	MDebug map[int]int
 
+	// messages of the assertions (see frontend.WithMessage), resolved when a constraint is not
+	// satisfied, and map of the constraint ids to the message ids
+	Messages  []LogEntry
+	MMessages map[int]int
+
 	MHints             map[int]*Hint      // maps wireID to hint
 	MHintsDependencies map[hint.ID]string // maps hintID to hint string identifier
 
@@ -126,6 +154,10 @@ type System struct {
 	lbHints     map[*Hint]struct{} `cbor:"-"` // hints we processed in current round
 
 	CommitmentInfo Commitment
+
+	// schema of the circuit inputs, serialized with the system such that witnesses can
+	// be built without the circuit structure (see witness.FromJSON)
+	Schema *schema.Schema
 }
 
 // NewSystem initialize the common structure among constraint system
@@ -133,6 +165,7 @@ func NewSystem(scalarField *big.Int) System {
 	return System{
 		SymbolTable:        debug.NewSymbolTable(),
 		MDebug:             map[int]int{},
+		MMessages:          map[int]int{},
 		GnarkVersion:       gnark.Version.String(),
 		ScalarField:        scalarField.Text(16),
 		MHints:             make(map[int]*Hint),
@@ -153,6 +186,14 @@ func (system *System) GetNbInternalVariables() int {
 	return system.NbInternalVariables
 }
 
+func (system *System) GetSchema() *schema.Schema {
+	return system.Schema
+}
+
+func (system *System) SetSchema(s *schema.Schema) {
+	system.Schema = s
+}
+
 // CheckSerializationHeader parses the scalar field and gnark version headers
 //
 // This is meant to be use at the deserialization step, and will error for illegal values
@@ -241,7 +282,7 @@ func (system *System) AddSolverHint(f hint.Function, input []LinearExpression, n
 	}
 
 	// associate these wires with the solver hint
-	ch := &Hint{ID: hintUUID, Inputs: input, Wires: internalVariables}
+	ch := &Hint{ID: hintUUID, Inputs: input, Wires: internalVariables, Stack: system.SymbolTable.CollectStack()}
 	for _, vID := range internalVariables {
 		system.MHints[vID] = ch
 	}
@@ -271,6 +312,17 @@ func (system *System) AttachDebugInfo(debugInfo DebugInfo, constraintID []int) {
 	}
 }
 
+func (system *System) AttachMessage(message LogEntry, constraintID []int) {
+	if system.MMessages == nil {
+		system.MMessages = make(map[int]int)
+	}
+	system.Messages = append(system.Messages, message)
+	id := len(system.Messages) - 1
+	for _, cID := range constraintID {
+		system.MMessages[cID] = id
+	}
+}
+
 // VariableToString implements Resolver
 func (system *System) VariableToString(vID int) string {
 	nbPublic := system.GetNbPublicVariables()
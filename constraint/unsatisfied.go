@@ -0,0 +1,195 @@
+package constraint
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// UnsatisfiedConstraint describes a constraint which is not satisfied by the solver, with the
+// values of its terms.
+type UnsatisfiedConstraint struct {
+	CID   int    // constraint ID
+	Err   error  // error of the solver
+	Debug string // debug info of the constraint (e.g. the assertion which added it), if any
+
+	// Label and Message are the label of the message of the assertion which added the
+	// constraint (see frontend.WithMessage), and the message formatted with its arguments.
+	// Label is stable across witnesses, and may be used to map the failure to an error of the
+	// application.
+	Label, Message string
+
+	// Expressions are the values of L, R and O (R1CS), or of qL⋅xa, qR⋅xb, qO⋅xc, qM⋅xa⋅xb
+	// and qK (SparseR1CS)
+	Expressions []ExpressionValue
+
+	// Stack is the call stack where the constraint was added, if recorded
+	Stack []StackFrame
+}
+
+// ExpressionValue is the value of an expression of an unsatisfied constraint
+type ExpressionValue struct {
+	Name    string
+	Terms   []TermValue
+	Product bool     // the value is the product of the terms, instead of their sum
+	Value   *big.Int // nil if a wire is not solved
+}
+
+// TermValue is the value of a term of an unsatisfied constraint
+type TermValue struct {
+	Wire  int      // -1 for a constant term
+	Name  string   // name of the wire (see System.VariableToString)
+	Coeff *big.Int // coefficient of the term
+	Value *big.Int // value of the wire, nil if the wire is not solved
+
+	// Hint is the name of the hint which solved the wire, if any, and HintStack the call stack
+	// of the hint
+	Hint      string
+	HintStack []StackFrame
+}
+
+// StackFrame is a frame of the call stack where a constraint was added
+type StackFrame struct {
+	Function string
+	File     string
+	Line     int
+}
+
+// NewTermValue returns the value of the term coeff⋅wire, with the name of the wire and the
+// hint which solved it. value is nil if the wire is not solved, wire is -1 for a constant.
+func (system *System) NewTermValue(wire int, coeff, value *big.Int) TermValue {
+	t := TermValue{Wire: wire, Coeff: coeff, Value: value}
+	if wire >= 0 {
+		t.Name = system.VariableToString(wire)
+		if h, ok := system.MHints[wire]; ok {
+			t.Hint = system.MHintsDependencies[h.ID]
+			t.HintStack = system.stackFrames(h.Stack)
+		}
+	}
+	return t
+}
+
+// Stack returns the call stack where the constraint was added, if recorded
+func (system *System) Stack(cID int) []StackFrame {
+	dID, ok := system.MDebug[cID]
+	if !ok {
+		return nil
+	}
+	return system.stackFrames(system.DebugInfo[dID].Stack)
+}
+
+func (system *System) stackFrames(stack []int) []StackFrame {
+	if len(stack) == 0 {
+		return nil
+	}
+	frames := make([]StackFrame, len(stack))
+	for i, lID := range stack {
+		location := system.SymbolTable.Locations[lID]
+		function := system.SymbolTable.Functions[location.FunctionID]
+		frames[i] = StackFrame{Function: function.Name, File: function.Filename, Line: int(location.Line)}
+	}
+	return frames
+}
+
+// Error returns the error message of the solver for the constraint
+func (u *UnsatisfiedConstraint) Error() string {
+	reason := u.Debug
+	if reason == "" {
+		reason = fmt.Sprintf("%s", u.Err)
+	}
+	if u.Message != "" {
+		return fmt.Sprintf("constraint #%d is not satisfied: %s: %s", u.CID, u.Message, reason)
+	}
+	return fmt.Sprintf("constraint #%d is not satisfied: %s", u.CID, reason)
+}
+
+// Unwrap returns the error of the solver
+func (u *UnsatisfiedConstraint) Unwrap() error {
+	return u.Err
+}
+
+// String returns the error message with the values of the terms, the hints which solved the
+// wires, and the call stack, for example
+//
+//	constraint #2 is not satisfied: 6 ⋅ 1 != 7
+//		L = 6 = 2⋅X[3]
+//		R = 1 = 1⋅v0[1] (hint github.com/consensys/gnark/std/math/bits.IthBit)
+//		O = 7 = 1⋅Y[7]
+//		main.(*Circuit).Define
+//			circuit.go:12
+//
+// The call stack is part of the debug info, if any.
+func (u *UnsatisfiedConstraint) String() string {
+	var sbb strings.Builder
+	sbb.WriteString(u.Error())
+	for _, e := range u.Expressions {
+		sbb.WriteString("\n\t")
+		sbb.WriteString(e.Name)
+		sbb.WriteString(" = ")
+		sbb.WriteString(valueString(e.Value))
+		for i, t := range e.Terms {
+			switch {
+			case i == 0:
+				sbb.WriteString(" = ")
+			case e.Product:
+				sbb.WriteString(" × ")
+			default:
+				sbb.WriteString(" + ")
+			}
+			sbb.WriteString(t.Coeff.String())
+			if t.Wire < 0 {
+				continue
+			}
+			sbb.WriteString("⋅")
+			sbb.WriteString(t.Name)
+			sbb.WriteByte('[')
+			sbb.WriteString(valueString(t.Value))
+			sbb.WriteByte(']')
+			if t.Hint != "" {
+				sbb.WriteString(" (hint ")
+				sbb.WriteString(t.Hint)
+				sbb.WriteByte(')')
+			}
+		}
+	}
+	if u.Debug == "" {
+		// the debug info has the stack
+		for _, f := range u.Stack {
+			fmt.Fprintf(&sbb, "\n\t%s\n\t\t%s:%d", f.Function, f.File, f.Line)
+		}
+	}
+	return sbb.String()
+}
+
+func valueString(v *big.Int) string {
+	if v == nil {
+		return "<unsolved>"
+	}
+	return v.String()
+}
+
+// UnsatisfiedConstraintsError is returned by the solver when it keeps going after an
+// unsatisfied constraint (see backend.WithSolverKeepGoing). It lists all the constraints which
+// are not satisfied, in the order they were solved.
+type UnsatisfiedConstraintsError struct {
+	Constraints []*UnsatisfiedConstraint
+}
+
+func (e *UnsatisfiedConstraintsError) Error() string {
+	if len(e.Constraints) == 1 {
+		return e.Constraints[0].Error()
+	}
+	return fmt.Sprintf("%d constraints are not satisfied, first: %s", len(e.Constraints), e.Constraints[0].Error())
+}
+
+// String returns the details of all the unsatisfied constraints, see UnsatisfiedConstraint.String
+func (e *UnsatisfiedConstraintsError) String() string {
+	var sbb strings.Builder
+	for i, u := range e.Constraints {
+		if i != 0 {
+			sbb.WriteByte('\n')
+		}
+		sbb.WriteString(u.String())
+	}
+	return sbb.String()
+}
@@ -0,0 +1,144 @@
+package constraint_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/constraint"
+	cs_bn254 "github.com/consensys/gnark/constraint/bn254"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/frontend/cs/scs"
+	"github.com/consensys/gnark/test"
+	"github.com/stretchr/testify/require"
+)
+
+type unsatisfiedCircuit struct {
+	X, Y frontend.Variable
+}
+
+func (c *unsatisfiedCircuit) Define(api frontend.API) error {
+	bits := api.ToBinary(c.X, 3)
+	api.AssertIsEqual(bits[0], 0)
+	api.AssertIsEqual(c.Y, 3)
+	return nil
+}
+
+func TestUnsatisfiedConstraint(t *testing.T) {
+	assert := require.New(t)
+
+	w, err := frontend.NewWitness(&unsatisfiedCircuit{X: 3, Y: 2}, ecc.BN254.ScalarField())
+	assert.NoError(err)
+	for _, builder := range []struct {
+		builder       frontend.NewBuilder
+		nbExpressions int
+	}{
+		{r1cs.NewBuilder, 3},
+		{scs.NewBuilder, 5},
+	} {
+		ccs, err := frontend.Compile(ecc.BN254.ScalarField(), builder.builder, &unsatisfiedCircuit{})
+		assert.NoError(err)
+
+		// the solver stops at the first unsatisfied constraint
+		err = ccs.IsSolved(w)
+		var unsatisfiedErr *cs_bn254.UnsatisfiedConstraintError
+		assert.True(errors.As(err, &unsatisfiedErr))
+		details := unsatisfiedErr.Details
+		assert.Equal(unsatisfiedErr.CID, details.CID)
+		assert.Equal(unsatisfiedErr.Error(), details.Error())
+		assert.Len(details.Expressions, builder.nbExpressions)
+		assert.NotEmpty(details.Debug)
+		assert.NotEmpty(details.Stack)
+		assert.Contains(details.Stack[len(details.Stack)-1].Function, "Define")
+		for _, e := range details.Expressions {
+			assert.NotNil(e.Value)
+		}
+
+		// the solver keeps going, and returns the two unsatisfied constraints
+		err = ccs.IsSolved(w, backend.WithSolverKeepGoing())
+		var unsatisfiedErrs *constraint.UnsatisfiedConstraintsError
+		assert.True(errors.As(err, &unsatisfiedErrs))
+		assert.Len(unsatisfiedErrs.Constraints, 2)
+		assert.Equal(details.CID, unsatisfiedErrs.Constraints[0].CID)
+		assert.Contains(unsatisfiedErrs.String(), "Y[2]")
+
+		// the bit of X is solved by a hint
+		var hinted bool
+		for _, e := range unsatisfiedErrs.Constraints[1].Expressions {
+			for _, t := range e.Terms {
+				if t.Hint != "" {
+					hinted = true
+					assert.NotEmpty(t.HintStack)
+					assert.Equal(int64(1), t.Value.Int64())
+				}
+			}
+		}
+		assert.True(hinted)
+		assert.Contains(unsatisfiedErrs.Constraints[1].String(), "(hint ")
+
+		// the satisfied constraints are not reported
+		valid, err := frontend.NewWitness(&unsatisfiedCircuit{X: 2, Y: 3}, ecc.BN254.ScalarField())
+		assert.NoError(err)
+		assert.NoError(ccs.IsSolved(valid, backend.WithSolverKeepGoing()))
+	}
+}
+
+type messageCircuit struct {
+	Balances [2]frontend.Variable
+	Total    frontend.Variable
+}
+
+func (c *messageCircuit) Define(api frontend.API) error {
+	for i := range c.Balances {
+		api.AssertIsLessOrEqual(c.Balances[i], 100, frontend.WithMessage("balance %d out of range", i))
+	}
+	api.AssertIsEqual(api.Add(c.Balances[0], c.Balances[1]), c.Total, frontend.WithMessage("balance mismatch for %v", c.Balances[0]))
+	return nil
+}
+
+func TestAssertionMessage(t *testing.T) {
+	assert := require.New(t)
+
+	w, err := frontend.NewWitness(&messageCircuit{Balances: [2]frontend.Variable{7, 8}, Total: 16}, ecc.BN254.ScalarField())
+	assert.NoError(err)
+	for _, builder := range []frontend.NewBuilder{r1cs.NewBuilder, scs.NewBuilder} {
+		ccs, err := frontend.Compile(ecc.BN254.ScalarField(), builder, &messageCircuit{})
+		assert.NoError(err)
+
+		err = ccs.IsSolved(w)
+		var details *constraint.UnsatisfiedConstraint
+		assert.True(errors.As(err, &details))
+		assert.Equal("balance mismatch for %v", details.Label)
+		assert.Equal("balance mismatch for 7", details.Message)
+		assert.Contains(err.Error(), "is not satisfied: balance mismatch for 7: ")
+
+		// the messages are kept by the IR
+		ir, err := constraint.NewIR(ccs)
+		assert.NoError(err)
+		assert.Len(ir.Messages, 3)
+		var reconstructed constraint.ConstraintSystem = cs_bn254.NewR1CS(0)
+		if _, ok := ccs.(*cs_bn254.SparseR1CS); ok {
+			reconstructed = cs_bn254.NewSparseR1CS(0)
+		}
+		assert.NoError(ir.Build(reconstructed))
+		assert.True(errors.As(reconstructed.IsSolved(w), &details))
+		assert.Equal("balance mismatch for 7", details.Message)
+
+		// the messages of the satisfied assertions are not reported
+		valid, err := frontend.NewWitness(&messageCircuit{Balances: [2]frontend.Variable{7, 8}, Total: 15}, ecc.BN254.ScalarField())
+		assert.NoError(err)
+		assert.NoError(ccs.IsSolved(valid))
+		invalid, err := frontend.NewWitness(&messageCircuit{Balances: [2]frontend.Variable{7, 101}, Total: 108}, ecc.BN254.ScalarField())
+		assert.NoError(err)
+		err = ccs.IsSolved(invalid)
+		assert.True(errors.As(err, &details))
+		assert.Equal("balance 1 out of range", details.Message)
+	}
+
+	// test engine
+	err = test.IsSolved(&messageCircuit{}, &messageCircuit{Balances: [2]frontend.Variable{7, 8}, Total: 16}, ecc.BN254.ScalarField())
+	assert.Error(err)
+	assert.Contains(err.Error(), "balance mismatch for 7")
+}
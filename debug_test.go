@@ -12,6 +12,7 @@ import (
 	"github.com/consensys/gnark/frontend"
 	"github.com/consensys/gnark/frontend/cs/r1cs"
 	"github.com/consensys/gnark/frontend/cs/scs"
+	"github.com/consensys/gnark/std/math/emulated"
 	"github.com/consensys/gnark/test"
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/require"
@@ -46,11 +47,11 @@ func TestPrintln(t *testing.T) {
 	witness.B = 11
 
 	var expected bytes.Buffer
-	expected.WriteString("debug_test.go:28 > 13 is the addition\n")
-	expected.WriteString("debug_test.go:30 > 26 42\n")
-	expected.WriteString("debug_test.go:32 > bits 1\n")
-	expected.WriteString("debug_test.go:33 > circuit {A: 2, B: 11}\n")
-	expected.WriteString("debug_test.go:37 > m .*\n")
+	expected.WriteString("debug_test.go:29 > 13 is the addition\n")
+	expected.WriteString("debug_test.go:31 > 26 42\n")
+	expected.WriteString("debug_test.go:33 > bits 1\n")
+	expected.WriteString("debug_test.go:34 > circuit {A: 2, B: 11}\n")
+	expected.WriteString("debug_test.go:38 > m .*\n")
 
 	{
 		trace, _ := getGroth16Trace(&circuit, &witness)
@@ -63,6 +64,63 @@ func TestPrintln(t *testing.T) {
 	}
 }
 
+// -------------------------------------------------------------------------------------------------
+// test printf, conditional println and log sink
+type printfCircuit struct {
+	A, B frontend.Variable
+	E    emulated.Element[emulated.Secp256k1Fp]
+}
+
+type printfPair struct {
+	X, Y frontend.Variable
+}
+
+func (circuit *printfCircuit) Define(api frontend.API) error {
+	c := api.Add(circuit.A, circuit.B)
+	api.Printf("%d + %d = 0x%x (%b), %v%%", circuit.A, circuit.B, c, c, api.Neg(circuit.A))
+	api.Printf("pair %x", printfPair{X: circuit.A, Y: c})
+	api.Printf("E = %d, %d", &circuit.E, emulated.ValueOf[emulated.Secp256k1Fp](3))
+	api.PrintlnIf(api.IsZero(api.Sub(circuit.A, 2)), "A is", 2)
+	api.PrintlnIf(api.IsZero(circuit.A), "A is", 0)
+	api.PrintlnIf(0, "never printed")
+
+	// all the inputs are constrained
+	api.AssertIsDifferent(api.Add(c, circuit.E.Limbs[0], circuit.E.Limbs[1], circuit.E.Limbs[2], circuit.E.Limbs[3]), 0)
+	return nil
+}
+
+func TestPrintf(t *testing.T) {
+	assert := require.New(t)
+
+	witness := printfCircuit{A: 2, B: 11, E: emulated.ValueOf[emulated.Secp256k1Fp](new(big.Int).Lsh(big.NewInt(1), 64))}
+	expected := []string{
+		"2 + 11 = 0xd (1101), -2%",
+		"pair {X: 2, Y: d}",
+		"E = 18446744073709551616, 3",
+		"A is 2",
+	}
+
+	var logs []string
+	sink := backend.WithCircuitLogSink(func(caller, msg string) {
+		assert.Contains(caller, "debug_test.go:")
+		logs = append(logs, msg)
+	})
+
+	w, err := frontend.NewWitness(&witness, ecc.BN254.ScalarField())
+	assert.NoError(err)
+	for _, builder := range []frontend.NewBuilder{r1cs.NewBuilder, scs.NewBuilder} {
+		ccs, err := frontend.Compile(ecc.BN254.ScalarField(), builder, &printfCircuit{})
+		assert.NoError(err)
+		logs = nil
+		assert.NoError(ccs.IsSolved(w, sink))
+		assert.Equal(expected, logs)
+	}
+
+	logs = nil
+	assert.NoError(test.IsSolved(&printfCircuit{}, &witness, ecc.BN254.ScalarField(), test.WithBackendProverOptions(sink)))
+	assert.Equal(expected, logs)
+}
+
 // -------------------------------------------------------------------------------------------------
 // Div by 0
 type divBy0Trace struct {
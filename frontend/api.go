@@ -99,22 +99,36 @@ type API interface {
 	// ---------------------------------------------------------------------------------------------
 	// Assertions
 
+	// The assertions accept options, see WithMessage.
+
 	// AssertIsEqual fails if i1 != i2
-	AssertIsEqual(i1, i2 Variable)
+	AssertIsEqual(i1, i2 Variable, opts ...AssertOption)
 
 	// AssertIsDifferent fails if i1 == i2
-	AssertIsDifferent(i1, i2 Variable)
+	AssertIsDifferent(i1, i2 Variable, opts ...AssertOption)
 
 	// AssertIsBoolean fails if v != 0 ∥ v != 1
-	AssertIsBoolean(i1 Variable)
+	AssertIsBoolean(i1 Variable, opts ...AssertOption)
 
 	// AssertIsLessOrEqual fails if  v > bound
-	AssertIsLessOrEqual(v Variable, bound Variable)
+	AssertIsLessOrEqual(v Variable, bound Variable, opts ...AssertOption)
 
 	// Println behaves like fmt.Println but accepts cd.Variable as parameter
 	// whose value will be resolved at runtime when computed by the solver
 	Println(a ...Variable)
 
+	// Printf behaves like fmt.Printf but accepts cd.Variable as parameter
+	// whose value will be resolved at runtime when computed by the solver.
+	// The variables are formatted with the integer verbs (%d, %x, %b, ...) as
+	// their canonical value, or with %v and %s as Println; the structs of variables
+	// verb-wise for each variable, and the values made of limbs (see LimbsPrinter)
+	// as their integer value. Explicit argument indexes and * are not supported.
+	Printf(format string, a ...Variable)
+
+	// PrintlnIf behaves like Println but prints only if cond is not zero when
+	// solving
+	PrintlnIf(cond Variable, a ...Variable)
+
 	// Compiler returns the compiler object for advanced circuit development
 	Compiler() Compiler
 
@@ -128,3 +142,46 @@ type API interface {
 	// Deprecated: use api.Compiler().ConstantValue() instead
 	ConstantValue(v Variable) (*big.Int, bool)
 }
+
+// LimbsPrinter is implemented by the values made of limbs, such as the emulated field elements
+// of std/math/emulated, which the print methods of the API (Println, Printf) print as their
+// integer value Σ limbs[i]⋅2^(i⋅bitsPerLimb), limbs being little-endian.
+type LimbsPrinter interface {
+	PrintLimbs() (limbs []Variable, bitsPerLimb uint)
+}
+
+// AssertOption is an option of the assertions of the API
+type AssertOption func(opt *AssertConfig)
+
+// AssertConfig is the configuration of an assertion with the options applied
+type AssertConfig struct {
+	// Label and Args are the message of the assertion (see WithMessage), Label is empty if
+	// none is set
+	Label string
+	Args  []Variable
+}
+
+// NewAssertConfig returns the configuration of an assertion with the options applied
+func NewAssertConfig(opts ...AssertOption) AssertConfig {
+	var opt AssertConfig
+	for _, o := range opts {
+		o(&opt)
+	}
+	return opt
+}
+
+// WithMessage is an assertion option that sets the message of the error when the assertion
+// fails: label formatted with args as with API.Printf, for example
+//
+//	api.AssertIsEqual(a, b, frontend.WithMessage("balance mismatch for %v", idx))
+//
+// The message is stored once in the constraint system, and the solver resolves it only when a
+// constraint of the assertion is not satisfied. The label and the message are then set in the
+// details of the error (see constraint.UnsatisfiedConstraint), such that the applications can
+// map the failures to their errors.
+func WithMessage(label string, args ...Variable) AssertOption {
+	return func(opt *AssertConfig) {
+		opt.Label = label
+		opt.Args = args
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"reflect"
 
 	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/constraint/optimizer"
 	"github.com/consensys/gnark/debug"
 	"github.com/consensys/gnark/frontend/schema"
 	"github.com/consensys/gnark/logger"
@@ -51,6 +52,14 @@ func Compile(field *big.Int, newBuilder NewBuilder, circuit Circuit, opts ...Com
 		return nil, fmt.Errorf("new compiler: %w", err)
 	}
 
+	// the schema of the inputs is recorded in the constraint system, it is built before
+	// the inputs are allocated
+	inputs, err := schema.New(circuit, tVariable)
+	if err != nil {
+		log.Err(err).Msg("parsing circuit schema")
+		return nil, fmt.Errorf("parse circuit schema: %w", err)
+	}
+
 	// parse the circuit builds a schema of the circuit
 	// and call circuit.Define() method to initialize a list of constraints in the compiler
 	if err = parseCircuit(builder, circuit); err != nil {
@@ -60,7 +69,12 @@ func Compile(field *big.Int, newBuilder NewBuilder, circuit Circuit, opts ...Com
 	}
 
 	// compile the circuit into its final form
-	return builder.Compile()
+	ccs, err := builder.Compile()
+	if err != nil {
+		return nil, err
+	}
+	ccs.SetSchema(inputs)
+	return ccs, nil
 }
 
 func parseCircuit(builder Builder, circuit Circuit) (err error) {
@@ -135,6 +149,7 @@ type CompileConfig struct {
 	Capacity                  int
 	IgnoreUnconstrainedInputs bool
 	CompressThreshold         int
+	Optimizations             []optimizer.Pass
 }
 
 // WithCapacity is a compile option that specifies the estimated capacity needed
@@ -181,6 +196,23 @@ func WithCompressThreshold(threshold int) CompileOption {
 	}
 }
 
+// WithOptimizer is a compile option which applies the given optimization passes
+// (optimizer.DefaultPasses if none) to the compiled constraint system. The optimized
+// constraint system is solved by exactly the same witnesses as the original one, but
+// its internal wires are renumbered and the debug information of the removed
+// constraints is lost.
+//
+// Constraint systems with a commitment are not optimized.
+func WithOptimizer(passes ...optimizer.Pass) CompileOption {
+	return func(opt *CompileConfig) error {
+		if len(passes) == 0 {
+			passes = optimizer.DefaultPasses()
+		}
+		opt.Optimizations = passes
+		return nil
+	}
+}
+
 var tVariable reflect.Type
 
 func init() {
@@ -0,0 +1,206 @@
+package cs
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/schema"
+	"github.com/consensys/gnark/internal/utils"
+)
+
+// LogArg is a variable argument of a log, resolved when solving (see ParseLog)
+type LogArg struct {
+	// Variables is the variable, or the little-endian limbs of a value (see
+	// frontend.LimbsPrinter)
+	Variables []frontend.Variable
+
+	// BitsPerLimb is the size of the limbs, 0 for a variable
+	BitsPerLimb uint
+}
+
+// ParseLog parses the arguments of api.Printf(format, a...). It returns the format of the
+// LogArg values (see constraint.LogValue), where the constants are already formatted.
+// isVariable returns true if v is a variable, false for a constant.
+//
+// The structs of variables are formatted with the verb for each of their variables, the
+// slices of variables as fmt does ([a b c]), and the frontend.LimbsPrinter values as their
+// integer value.
+func ParseLog(format string, a []frontend.Variable, isVariable func(v frontend.Variable) bool) (string, []LogArg) {
+	var (
+		sbb  strings.Builder
+		args []LogArg
+	)
+
+	// constant writes a constant formatted with the verb
+	constant := func(verb string, v interface{}) {
+		sbb.WriteString(escape(fmt.Sprintf(verb, v)))
+	}
+	// variable writes the verb of a variable
+	variable := func(verb string, v frontend.Variable) {
+		if !isVariable(v) {
+			constant(verb, v)
+			return
+		}
+		sbb.WriteString(verb)
+		args = append(args, LogArg{Variables: []frontend.Variable{v}})
+	}
+
+	var arg func(verb string, v frontend.Variable)
+	arg = func(verb string, v frontend.Variable) {
+		if l, ok := v.(frontend.LimbsPrinter); ok {
+			limbs, bitsPerLimb := l.PrintLimbs()
+			for _, limb := range limbs {
+				if isVariable(limb) {
+					sbb.WriteString(verb)
+					args = append(args, LogArg{Variables: limbs, BitsPerLimb: bitsPerLimb})
+					return
+				}
+			}
+			// all the limbs are constants
+			value := new(big.Int)
+			for i := len(limbs) - 1; i >= 0; i-- {
+				limb := utils.FromInterface(limbs[i])
+				value.Lsh(value, bitsPerLimb).Add(value, &limb)
+			}
+			constant(verb, value)
+			return
+		}
+		if isVariable(v) {
+			variable(verb, v)
+			return
+		}
+		if s, ok := v.([]frontend.Variable); ok {
+			sbb.WriteByte('[')
+			for i := range s {
+				if i != 0 {
+					sbb.WriteByte(' ')
+				}
+				arg(verb, s[i])
+			}
+			sbb.WriteByte(']')
+			return
+		}
+
+		leafCount, err := schema.Walk(v, tVariable, nil)
+		count := leafCount.Public + leafCount.Secret
+		if count == 0 || err != nil {
+			// no variables in nested struct, we use fmt std print function
+			constant(verb, v)
+			return
+		}
+		sbb.WriteByte('{')
+		printer := func(f schema.LeafInfo, tValue reflect.Value) error {
+			count--
+			sbb.WriteString(escape(f.FullName()))
+			sbb.WriteString(": ")
+			variable(verb, tValue.Interface())
+			if count != 0 {
+				sbb.WriteString(", ")
+			}
+			return nil
+		}
+		// ignoring error, printer() doesn't return errors
+		_, _ = schema.Walk(v, tVariable, printer)
+		sbb.WriteByte('}')
+	}
+
+	for i := 0; i < len(format); {
+		if format[i] != '%' {
+			sbb.WriteByte(format[i])
+			i++
+			continue
+		}
+
+		// flags, width and precision
+		j := i + 1
+		for j < len(format) && strings.IndexByte("+-# 0123456789.", format[j]) >= 0 {
+			j++
+		}
+		if j == len(format) {
+			sbb.WriteString("%!(NOVERB)")
+			break
+		}
+		_, size := utf8.DecodeRuneInString(format[j:])
+		verb := format[i : j+size]
+		i = j + size
+
+		switch {
+		case verb == "%%":
+			sbb.WriteString("%%")
+		case len(a) == 0:
+			sbb.WriteString(escape("%!" + verb[len(verb)-size:] + "(MISSING)"))
+		default:
+			arg(verb, a[0])
+			a = a[1:]
+		}
+	}
+
+	if len(a) != 0 {
+		sbb.WriteString("%!(EXTRA ")
+		for i := range a {
+			if i != 0 {
+				sbb.WriteString(", ")
+			}
+			arg("%v", a[i])
+		}
+		sbb.WriteByte(')')
+	}
+	return sbb.String(), args
+}
+
+// NewLogEntry returns the log entry of api.Printf(format, a...) (see ParseLog). expression
+// returns the linear expression of a variable, or false for a constant.
+func NewLogEntry(cs constraint.ConstraintSystem, format string, a []frontend.Variable, expression func(v frontend.Variable) (constraint.LinearExpression, bool)) constraint.LogEntry {
+	isVariable := func(v frontend.Variable) bool {
+		_, ok := expression(v)
+		return ok
+	}
+
+	var log constraint.LogEntry
+	var args []LogArg
+	log.Format, args = ParseLog(format, a, isVariable)
+	for _, arg := range args {
+		if arg.BitsPerLimb != 0 {
+			log.Limbs = append(log.Limbs, constraint.LogLimbs{
+				First:       len(log.ToResolve),
+				NbLimbs:     len(arg.Variables),
+				BitsPerLimb: int(arg.BitsPerLimb),
+			})
+		}
+		for _, v := range arg.Variables {
+			le, ok := expression(v)
+			if !ok {
+				// a constant limb
+				c := cs.FromInterface(v)
+				t := cs.MakeTerm(&c, 0)
+				t.MarkConstant()
+				le = constraint.LinearExpression{t}
+			}
+			log.ToResolve = append(log.ToResolve, le)
+		}
+	}
+	return log
+}
+
+// PrintlnFormat returns the format of api.Println(a...) for ParseLog
+func PrintlnFormat(nbArgs int) string {
+	if nbArgs == 0 {
+		return ""
+	}
+	return strings.Repeat("%v ", nbArgs-1) + "%v"
+}
+
+func escape(s string) string {
+	return strings.ReplaceAll(s, "%", "%%")
+}
+
+var tVariable reflect.Type
+
+func init() {
+	tVariable = reflect.ValueOf(struct{ A frontend.Variable }{}).FieldByName("A").Type()
+}
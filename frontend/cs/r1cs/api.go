@@ -21,15 +21,13 @@ import (
 	"fmt"
 	"math/big"
 	"path/filepath"
-	"reflect"
 	"runtime"
-	"strings"
 
 	"github.com/consensys/gnark/backend/hint"
 	"github.com/consensys/gnark/constraint"
 	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs"
 	"github.com/consensys/gnark/frontend/internal/expr"
-	"github.com/consensys/gnark/frontend/schema"
 	"github.com/consensys/gnark/std/math/bits"
 )
 
@@ -592,67 +590,71 @@ func (builder *builder) Cmp(i1, i2 frontend.Variable) frontend.Variable {
 //
 // if one of the input is a variable, its value will be resolved avec R1CS.Solve() method is called
 func (builder *builder) Println(a ...frontend.Variable) {
-	var log constraint.LogEntry
-
-	// prefix log line with file.go:line
-	if _, file, line, ok := runtime.Caller(1); ok {
-		log.Caller = fmt.Sprintf("%s:%d", filepath.Base(file), line)
-	}
+	builder.printf(cs.PrintlnFormat(len(a)), a, nil)
+}
 
-	var sbb strings.Builder
+// Printf behaves like fmt.Printf, the values of the variables are formatted once solved (see
+// frontend.API.Printf)
+func (builder *builder) Printf(format string, a ...frontend.Variable) {
+	builder.printf(format, a, nil)
+}
 
-	for i, arg := range a {
-		if i > 0 {
-			sbb.WriteByte(' ')
+// PrintlnIf behaves like Println, but the line is printed by the solver only if cond is not zero
+func (builder *builder) PrintlnIf(cond frontend.Variable, a ...frontend.Variable) {
+	if c, ok := builder.ConstantValue(cond); ok {
+		if c.Sign() == 0 {
+			return
 		}
-		if v, ok := arg.(expr.LinearExpression); ok {
-			assertIsSet(v)
+		cond = nil
+	}
+	builder.printf(cs.PrintlnFormat(len(a)), a, cond)
+}
 
-			sbb.WriteString("%s")
-			// we set limits to the linear expression, so that the log printer
-			// can evaluate it before printing it
-			log.ToResolve = append(log.ToResolve, builder.getLinearExpression(v))
-		} else {
-			builder.printArg(&log, &sbb, arg)
-		}
+// printf adds the log of api.Printf(format, a...) to the constraint system, printed if cond is
+// not zero (if cond is not nil)
+func (builder *builder) printf(format string, a []frontend.Variable, cond frontend.Variable) {
+	log := cs.NewLogEntry(builder.cs, format, a, builder.logExpression)
+
+	// prefix log line with file.go:line
+	if _, file, line, ok := runtime.Caller(2); ok {
+		log.Caller = fmt.Sprintf("%s:%d", filepath.Base(file), line)
 	}
 
-	// set format string to be used with fmt.Sprintf, once the variables are solved in the R1CS.Solve() method
-	log.Format = sbb.String()
+	if cond != nil {
+		le, _ := builder.logExpression(cond)
+		log.ToResolve = append(log.ToResolve, le)
+		log.Conditional = true
+	}
 
 	builder.cs.AddLog(log)
 }
 
-func (builder *builder) printArg(log *constraint.LogEntry, sbb *strings.Builder, a frontend.Variable) {
-
-	leafCount, err := schema.Walk(a, tVariable, nil)
-	count := leafCount.Public + leafCount.Secret
-
-	// no variables in nested struct, we use fmt std print function
-	if count == 0 || err != nil {
-		sbb.WriteString(fmt.Sprint(a))
+// attachMessage attaches the message of an assertion (see frontend.WithMessage) to the
+// constraints added since the constraint from
+func (builder *builder) attachMessage(from int, opts []frontend.AssertOption) {
+	opt := frontend.NewAssertConfig(opts...)
+	to := builder.cs.GetNbConstraints()
+	if opt.Label == "" || from == to {
 		return
 	}
+	message := cs.NewLogEntry(builder.cs, opt.Label, opt.Args, builder.logExpression)
+	message.Label = opt.Label
+	added := make([]int, 0, to-from)
+	for cID := from; cID < to; cID++ {
+		added = append(added, cID)
+	}
+	builder.cs.AttachMessage(message, added)
+}
 
-	sbb.WriteByte('{')
-	printer := func(f schema.LeafInfo, tValue reflect.Value) error {
-		count--
-		sbb.WriteString(f.FullName())
-		sbb.WriteString(": ")
-		sbb.WriteString("%s")
-		if count != 0 {
-			sbb.WriteString(", ")
-		}
-
-		v := tValue.Interface().(expr.LinearExpression)
-		// we set limits to the linear expression, so that the log printer
-		// can evaluate it before printing it
-		log.ToResolve = append(log.ToResolve, builder.getLinearExpression(v))
-		return nil
+// logExpression returns the linear expression of a variable to be resolved by the log printer,
+// or false if v is a constant
+func (builder *builder) logExpression(v frontend.Variable) (constraint.LinearExpression, bool) {
+	le, ok := v.(expr.LinearExpression)
+	if !ok {
+		return nil, false
 	}
-	// ignoring error, printer() doesn't return errors
-	_, _ = schema.Walk(a, tVariable, printer)
-	sbb.WriteByte('}')
+	assertIsSet(le)
+	return builder.getLinearExpression(le), true
 }
 
 // returns -le, the result is a copy
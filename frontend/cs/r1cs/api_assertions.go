@@ -27,7 +27,8 @@ import (
 )
 
 // AssertIsEqual adds an assertion in the constraint builder (i1 == i2)
-func (builder *builder) AssertIsEqual(i1, i2 frontend.Variable) {
+func (builder *builder) AssertIsEqual(i1, i2 frontend.Variable, opts ...frontend.AssertOption) {
+	defer builder.attachMessage(builder.cs.GetNbConstraints(), opts)
 	// encoded 1 * i1 == i2
 	r := builder.getLinearExpression(builder.toVariable(i1))
 	o := builder.getLinearExpression(builder.toVariable(i2))
@@ -38,12 +39,14 @@ func (builder *builder) AssertIsEqual(i1, i2 frontend.Variable) {
 }
 
 // AssertIsDifferent constrain i1 and i2 to be different
-func (builder *builder) AssertIsDifferent(i1, i2 frontend.Variable) {
+func (builder *builder) AssertIsDifferent(i1, i2 frontend.Variable, opts ...frontend.AssertOption) {
+	defer builder.attachMessage(builder.cs.GetNbConstraints(), opts)
 	builder.Inverse(builder.Sub(i1, i2))
 }
 
 // AssertIsBoolean adds an assertion in the constraint builder (v == 0 ∥ v == 1)
-func (builder *builder) AssertIsBoolean(i1 frontend.Variable) {
+func (builder *builder) AssertIsBoolean(i1 frontend.Variable, opts ...frontend.AssertOption) {
+	defer builder.attachMessage(builder.cs.GetNbConstraints(), opts)
 
 	v := builder.toVariable(i1)
 
@@ -80,7 +83,8 @@ func (builder *builder) AssertIsBoolean(i1 frontend.Variable) {
 //
 // derived from:
 // https://github.com/zcash/zips/blob/main/protocol/protocol.pdf
-func (builder *builder) AssertIsLessOrEqual(_v frontend.Variable, bound frontend.Variable) {
+func (builder *builder) AssertIsLessOrEqual(_v frontend.Variable, bound frontend.Variable, opts ...frontend.AssertOption) {
+	defer builder.attachMessage(builder.cs.GetNbConstraints(), opts)
 	v := builder.toVariable(_v)
 
 	if b, ok := bound.(expr.LinearExpression); ok {
@@ -19,12 +19,12 @@ package r1cs
 import (
 	"errors"
 	"math/big"
-	"reflect"
 	"sort"
 
 	"github.com/consensys/gnark-crypto/ecc"
 	"github.com/consensys/gnark/backend/hint"
 	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/constraint/optimizer"
 	"github.com/consensys/gnark/debug"
 	"github.com/consensys/gnark/frontend"
 	"github.com/consensys/gnark/frontend/internal/expr"
@@ -82,30 +82,7 @@ func newBuilder(field *big.Int, config frontend.CompileConfig) *builder {
 
 	// by default the circuit is given a public wire equal to 1
 
-	curve := utils.FieldToCurve(field)
-
-	switch curve {
-	case ecc.BLS12_377:
-		builder.cs = bls12377r1cs.NewR1CS(config.Capacity)
-	case ecc.BLS12_381:
-		builder.cs = bls12381r1cs.NewR1CS(config.Capacity)
-	case ecc.BN254:
-		builder.cs = bn254r1cs.NewR1CS(config.Capacity)
-	case ecc.BW6_761:
-		builder.cs = bw6761r1cs.NewR1CS(config.Capacity)
-	case ecc.BW6_633:
-		builder.cs = bw6633r1cs.NewR1CS(config.Capacity)
-	case ecc.BLS24_315:
-		builder.cs = bls24315r1cs.NewR1CS(config.Capacity)
-	case ecc.BLS24_317:
-		builder.cs = bls24317r1cs.NewR1CS(config.Capacity)
-	default:
-		if field.Cmp(tinyfield.Modulus()) == 0 {
-			builder.cs = tinyfieldr1cs.NewR1CS(config.Capacity)
-			break
-		}
-		panic("not implemented")
-	}
+	builder.cs = newR1CS(field, config.Capacity)
 
 	builder.tOne = builder.cs.One()
 	builder.cs.AddPublicVariable("1")
@@ -155,6 +132,31 @@ func (builder *builder) isCstOne(c *constraint.Coeff) bool {
 	return builder.cs.IsOne(c)
 }
 
+// newR1CS returns an empty constraint system over the given field
+func newR1CS(field *big.Int, capacity int) constraint.R1CS {
+	switch utils.FieldToCurve(field) {
+	case ecc.BLS12_377:
+		return bls12377r1cs.NewR1CS(capacity)
+	case ecc.BLS12_381:
+		return bls12381r1cs.NewR1CS(capacity)
+	case ecc.BN254:
+		return bn254r1cs.NewR1CS(capacity)
+	case ecc.BW6_761:
+		return bw6761r1cs.NewR1CS(capacity)
+	case ecc.BW6_633:
+		return bw6633r1cs.NewR1CS(capacity)
+	case ecc.BLS24_315:
+		return bls24315r1cs.NewR1CS(capacity)
+	case ecc.BLS24_317:
+		return bls24317r1cs.NewR1CS(capacity)
+	default:
+		if field.Cmp(tinyfield.Modulus()) == 0 {
+			return tinyfieldr1cs.NewR1CS(capacity)
+		}
+		panic("not implemented")
+	}
+}
+
 func (builder *builder) Field() *big.Int {
 	return builder.cs.Field()
 }
@@ -248,12 +250,6 @@ func (builder *builder) IsBoolean(v frontend.Variable) bool {
 	return false
 }
 
-var tVariable reflect.Type
-
-func init() {
-	tVariable = reflect.ValueOf(struct{ A frontend.Variable }{}).FieldByName("A").Type()
-}
-
 // Compile constructs a rank-1 constraint sytem
 func (builder *builder) Compile() (constraint.ConstraintSystem, error) {
 	// TODO if already compiled, return builder.cs object
@@ -270,6 +266,14 @@ func (builder *builder) Compile() (constraint.ConstraintSystem, error) {
 		}
 	}
 
+	if len(builder.config.Optimizations) != 0 {
+		cs := newR1CS(builder.q, 0)
+		if err := optimizer.Apply(builder.cs, cs, builder.config.Optimizations...); err != nil {
+			return nil, err
+		}
+		return cs, nil
+	}
+
 	return builder.cs, nil
 }
 
@@ -20,15 +20,13 @@ import (
 	"fmt"
 	"math/big"
 	"path/filepath"
-	"reflect"
 	"runtime"
-	"strings"
 
 	"github.com/consensys/gnark/backend/hint"
 	"github.com/consensys/gnark/constraint"
 	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs"
 	"github.com/consensys/gnark/frontend/internal/expr"
-	"github.com/consensys/gnark/frontend/schema"
 	"github.com/consensys/gnark/std/math/bits"
 )
 
@@ -140,11 +138,13 @@ func (builder *scs) DivUnchecked(i1, i2 frontend.Variable) frontend.Variable {
 	}
 
 	res := builder.newInternalVariable()
+	debug := builder.newDebugInfo("div", i1, "/", i2, " == ", res)
+	// note that here we don't ensure that divisor is != 0
 	r := i2.(expr.TermToRefactor)
 	o := builder.Neg(i1).(expr.TermToRefactor)
 	cr, _ := r.Unpack()
 	co, _ := o.Unpack()
-	builder.addPlonkConstraint(res, r, o, constraint.CoeffIdZero, constraint.CoeffIdZero, constraint.CoeffIdOne, cr, co, constraint.CoeffIdZero)
+	builder.addPlonkConstraint(res, r, o, constraint.CoeffIdZero, constraint.CoeffIdZero, constraint.CoeffIdOne, cr, co, constraint.CoeffIdZero, debug)
 	return res
 }
 
@@ -433,66 +433,70 @@ func (builder *scs) Cmp(i1, i2 frontend.Variable) frontend.Variable {
 //
 // if one of the input is a variable, its value will be resolved avec R1CS.Solve() method is called
 func (builder *scs) Println(a ...frontend.Variable) {
-	var log constraint.LogEntry
+	builder.printf(cs.PrintlnFormat(len(a)), a, nil)
+}
 
-	// prefix log line with file.go:line
-	if _, file, line, ok := runtime.Caller(1); ok {
-		log.Caller = fmt.Sprintf("%s:%d", filepath.Base(file), line)
+// Printf behaves like fmt.Printf, the values of the variables are formatted once solved (see
+// frontend.API.Printf)
+func (builder *scs) Printf(format string, a ...frontend.Variable) {
+	builder.printf(format, a, nil)
+}
+
+// PrintlnIf behaves like Println, but the line is printed by the solver only if cond is not zero
+func (builder *scs) PrintlnIf(cond frontend.Variable, a ...frontend.Variable) {
+	if c, ok := builder.ConstantValue(cond); ok {
+		if c.Sign() == 0 {
+			return
+		}
+		cond = nil
 	}
+	builder.printf(cs.PrintlnFormat(len(a)), a, cond)
+}
 
-	var sbb strings.Builder
+// printf adds the log of api.Printf(format, a...) to the constraint system, printed if cond is
+// not zero (if cond is not nil)
+func (builder *scs) printf(format string, a []frontend.Variable, cond frontend.Variable) {
+	log := cs.NewLogEntry(builder.cs, format, a, builder.logExpression)
 
-	for i, arg := range a {
-		if i > 0 {
-			sbb.WriteByte(' ')
-		}
-		if v, ok := arg.(expr.TermToRefactor); ok {
-
-			sbb.WriteString("%s")
-			// we set limits to the linear expression, so that the log printer
-			// can evaluate it before printing it
-			log.ToResolve = append(log.ToResolve, constraint.LinearExpression{builder.TOREFACTORMakeTerm(&builder.st.Coeffs[v.CID], v.VID)})
-		} else {
-			builder.printArg(&log, &sbb, arg)
-		}
+	// prefix log line with file.go:line
+	if _, file, line, ok := runtime.Caller(2); ok {
+		log.Caller = fmt.Sprintf("%s:%d", filepath.Base(file), line)
 	}
 
-	// set format string to be used with fmt.Sprintf, once the variables are solved in the R1CS.Solve() method
-	log.Format = sbb.String()
+	if cond != nil {
+		le, _ := builder.logExpression(cond)
+		log.ToResolve = append(log.ToResolve, le)
+		log.Conditional = true
+	}
 
 	builder.cs.AddLog(log)
 }
 
-func (builder *scs) printArg(log *constraint.LogEntry, sbb *strings.Builder, a frontend.Variable) {
-
-	leafCount, err := schema.Walk(a, tVariable, nil)
-	count := leafCount.Public + leafCount.Secret
-
-	// no variables in nested struct, we use fmt std print function
-	if count == 0 || err != nil {
-		sbb.WriteString(fmt.Sprint(a))
+// attachMessage attaches the message of an assertion (see frontend.WithMessage) to the
+// constraints added since the constraint from
+func (builder *scs) attachMessage(from int, opts []frontend.AssertOption) {
+	opt := frontend.NewAssertConfig(opts...)
+	to := builder.cs.GetNbConstraints()
+	if opt.Label == "" || from == to {
 		return
 	}
+	message := cs.NewLogEntry(builder.cs, opt.Label, opt.Args, builder.logExpression)
+	message.Label = opt.Label
+	added := make([]int, 0, to-from)
+	for cID := from; cID < to; cID++ {
+		added = append(added, cID)
+	}
+	builder.cs.AttachMessage(message, added)
+}
 
-	sbb.WriteByte('{')
-	printer := func(f schema.LeafInfo, tValue reflect.Value) error {
-		count--
-		sbb.WriteString(f.FullName())
-		sbb.WriteString(": ")
-		sbb.WriteString("%s")
-		if count != 0 {
-			sbb.WriteString(", ")
-		}
-
-		v := tValue.Interface().(expr.TermToRefactor)
-		// we set limits to the linear expression, so that the log printer
-		// can evaluate it before printing it
-		log.ToResolve = append(log.ToResolve, constraint.LinearExpression{builder.TOREFACTORMakeTerm(&builder.st.Coeffs[v.CID], v.VID)})
-		return nil
+// logExpression returns the linear expression of a variable to be resolved by the log printer,
+// or false if v is a constant
+func (builder *scs) logExpression(v frontend.Variable) (constraint.LinearExpression, bool) {
+	t, ok := v.(expr.TermToRefactor)
+	if !ok {
+		return nil, false
 	}
-	// ignoring error, printer() doesn't return errors
-	_, _ = schema.Walk(a, tVariable, printer)
-	sbb.WriteByte('}')
+	return constraint.LinearExpression{builder.TOREFACTORMakeTerm(&builder.st.Coeffs[t.CID], t.VID)}, true
 }
 
 func (builder *scs) Compiler() frontend.Compiler {
@@ -28,7 +28,8 @@ import (
 )
 
 // AssertIsEqual fails if i1 != i2
-func (builder *scs) AssertIsEqual(i1, i2 frontend.Variable) {
+func (builder *scs) AssertIsEqual(i1, i2 frontend.Variable, opts ...frontend.AssertOption) {
+	defer builder.attachMessage(builder.cs.GetNbConstraints(), opts)
 
 	c1, i1Constant := builder.ConstantValue(i1)
 	c2, i2Constant := builder.ConstantValue(i2)
@@ -64,12 +65,14 @@ func (builder *scs) AssertIsEqual(i1, i2 frontend.Variable) {
 }
 
 // AssertIsDifferent fails if i1 == i2
-func (builder *scs) AssertIsDifferent(i1, i2 frontend.Variable) {
+func (builder *scs) AssertIsDifferent(i1, i2 frontend.Variable, opts ...frontend.AssertOption) {
+	defer builder.attachMessage(builder.cs.GetNbConstraints(), opts)
 	builder.Inverse(builder.Sub(i1, i2))
 }
 
 // AssertIsBoolean fails if v != 0 ∥ v != 1
-func (builder *scs) AssertIsBoolean(i1 frontend.Variable) {
+func (builder *scs) AssertIsBoolean(i1 frontend.Variable, opts ...frontend.AssertOption) {
+	defer builder.attachMessage(builder.cs.GetNbConstraints(), opts)
 	if c, ok := builder.ConstantValue(i1); ok {
 		if !(c.IsUint64() && (c.Uint64() == 0 || c.Uint64() == 1)) {
 			panic(fmt.Sprintf("assertIsBoolean failed: constant(%s)", c.String()))
@@ -91,7 +94,8 @@ func (builder *scs) AssertIsBoolean(i1 frontend.Variable) {
 }
 
 // AssertIsLessOrEqual fails if  v > bound
-func (builder *scs) AssertIsLessOrEqual(v frontend.Variable, bound frontend.Variable) {
+func (builder *scs) AssertIsLessOrEqual(v frontend.Variable, bound frontend.Variable, opts ...frontend.AssertOption) {
+	defer builder.attachMessage(builder.cs.GetNbConstraints(), opts)
 	switch b := bound.(type) {
 	case expr.TermToRefactor:
 		builder.mustBeLessOrEqVar(v.(expr.TermToRefactor), b)
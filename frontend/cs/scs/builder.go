@@ -19,12 +19,12 @@ package scs
 import (
 	"fmt"
 	"math/big"
-	"reflect"
 	"sort"
 
 	"github.com/consensys/gnark-crypto/ecc"
 	"github.com/consensys/gnark/backend/hint"
 	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/constraint/optimizer"
 	"github.com/consensys/gnark/frontend"
 	"github.com/consensys/gnark/frontend/cs"
 	"github.com/consensys/gnark/frontend/internal/expr"
@@ -69,37 +69,39 @@ func newBuilder(field *big.Int, config frontend.CompileConfig) *scs {
 		config:     config,
 	}
 
-	curve := utils.FieldToCurve(field)
+	builder.cs = newSparseR1CS(field, config.Capacity)
 
-	switch curve {
+	builder.q = builder.cs.Field()
+	if builder.q.Cmp(field) != 0 {
+		panic("invalid modulus on cs impl") // sanity check
+	}
+
+	return &builder
+}
+
+// newSparseR1CS returns an empty constraint system over the given field
+func newSparseR1CS(field *big.Int, capacity int) constraint.SparseR1CS {
+	switch utils.FieldToCurve(field) {
 	case ecc.BLS12_377:
-		builder.cs = bls12377r1cs.NewSparseR1CS(config.Capacity)
+		return bls12377r1cs.NewSparseR1CS(capacity)
 	case ecc.BLS12_381:
-		builder.cs = bls12381r1cs.NewSparseR1CS(config.Capacity)
+		return bls12381r1cs.NewSparseR1CS(capacity)
 	case ecc.BN254:
-		builder.cs = bn254r1cs.NewSparseR1CS(config.Capacity)
+		return bn254r1cs.NewSparseR1CS(capacity)
 	case ecc.BW6_761:
-		builder.cs = bw6761r1cs.NewSparseR1CS(config.Capacity)
+		return bw6761r1cs.NewSparseR1CS(capacity)
 	case ecc.BW6_633:
-		builder.cs = bw6633r1cs.NewSparseR1CS(config.Capacity)
+		return bw6633r1cs.NewSparseR1CS(capacity)
 	case ecc.BLS24_315:
-		builder.cs = bls24315r1cs.NewSparseR1CS(config.Capacity)
+		return bls24315r1cs.NewSparseR1CS(capacity)
 	case ecc.BLS24_317:
-		builder.cs = bls24317r1cs.NewSparseR1CS(config.Capacity)
+		return bls24317r1cs.NewSparseR1CS(capacity)
 	default:
 		if field.Cmp(tinyfield.Modulus()) == 0 {
-			builder.cs = tinyfieldr1cs.NewSparseR1CS(config.Capacity)
-			break
+			return tinyfieldr1cs.NewSparseR1CS(capacity)
 		}
 		panic("not implemtented")
 	}
-
-	builder.q = builder.cs.Field()
-	if builder.q.Cmp(field) != 0 {
-		panic("invalid modulus on cs impl") // sanity check
-	}
-
-	return &builder
 }
 
 func (builder *scs) Field() *big.Int {
@@ -212,12 +214,6 @@ func (builder *scs) MarkBoolean(v frontend.Variable) {
 	builder.mtBooleans[int(v.(expr.TermToRefactor).CID|(int(v.(expr.TermToRefactor).VID)<<32))] = struct{}{} // TODO @gbotrel fixme this is sketchy
 }
 
-var tVariable reflect.Type
-
-func init() {
-	tVariable = reflect.ValueOf(struct{ A frontend.Variable }{}).FieldByName("A").Type()
-}
-
 func (builder *scs) Compile() (constraint.ConstraintSystem, error) {
 	log := logger.Logger()
 	log.Info().
@@ -233,6 +229,14 @@ func (builder *scs) Compile() (constraint.ConstraintSystem, error) {
 		}
 	}
 
+	if len(builder.config.Optimizations) != 0 {
+		cs := newSparseR1CS(builder.q, 0)
+		if err := optimizer.Apply(builder.cs, cs, builder.config.Optimizations...); err != nil {
+			return nil, err
+		}
+		return cs, nil
+	}
+
 	return builder.cs, nil
 }
 
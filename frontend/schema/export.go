@@ -0,0 +1,318 @@
+/*
+Copyright © 2022 ConsenSys Software Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// elementPattern matches the strings accepted for a field element in a JSON assignment,
+// that is, integers parsed by big.Int.SetString with base 0.
+const elementPattern = `^[+-]?(0[xX][0-9a-fA-F_]+|0[bB][01_]+|0[oO]?[0-7_]+|[0-9][0-9_]*)$`
+
+// WriteJSONSchema writes a JSON Schema (draft 2020-12) document describing the JSON
+// assignments accepted by witness.FromJSON for this schema.
+//
+// Field elements are JSON integers or strings (base 10, or 0x, 0b, 0o prefixed), as the
+// FieldElement type of WriteTypeScript. Objects containing public inputs are required, secret
+// inputs are optional since a public witness may be built from the public inputs only.
+func (s Schema) WriteJSONSchema(w io.Writer, title string) error {
+	doc := jsonObject{
+		{"$schema", "https://json-schema.org/draft/2020-12/schema"},
+		{"title", title},
+	}
+	doc = append(doc, jsonStruct(s.Fields)...)
+	doc = append(doc, jsonMember{"$defs", jsonObject{
+		{"element", jsonObject{
+			{"description", "field element"},
+			{"oneOf", []any{
+				jsonObject{{"type", "integer"}},
+				jsonObject{{"type", "string"}, {"pattern", elementPattern}},
+			}},
+		}},
+	}})
+
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(b); err != nil {
+		return err
+	}
+	_, err = w.Write([]byte{'\n'})
+	return err
+}
+
+// WriteTypeScript writes a TypeScript interface named name describing the JSON
+// assignments accepted by witness.FromJSON for this schema. Secret inputs are optional.
+func (s Schema) WriteTypeScript(w io.Writer, name string) error {
+	var b strings.Builder
+	b.WriteString("// Code generated by gnark. DO NOT EDIT.\n\n")
+	b.WriteString("// FieldElement is a string in base 10 or 0x, 0b, 0o prefixed, or an integer. The numbers\n")
+	b.WriteString("// past Number.MAX_SAFE_INTEGER (2^53 - 1) lose their precision: use strings for them.\n")
+	b.WriteString("export type FieldElement = string | number;\n\n")
+	fmt.Fprintf(&b, "export interface %s ", name)
+	tsStruct(&b, s.Fields, 0)
+	b.WriteString("\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteGoStruct writes a Go source file of package pkg declaring a struct type named
+// name, with frontend.Variable leaves and the gnark struct tags matching this schema.
+// The type can be used to build assignments for frontend.NewWitness.
+func (s Schema) WriteGoStruct(w io.Writer, pkg, name string) error {
+	var b bytes.Buffer
+	b.WriteString("// Code generated by gnark. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "package %s\n\n", pkg)
+	b.WriteString("import \"github.com/consensys/gnark/frontend\"\n\n")
+	fmt.Fprintf(&b, "type %s ", name)
+	goStruct(&b, s.Fields)
+	b.WriteString("\n")
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// jsonKey returns the key of a field in a JSON assignment, see Instantiate
+func (f *Field) jsonKey() string {
+	if f.NameTag != "" {
+		return f.NameTag
+	}
+	return f.Name
+}
+
+// hasPublic returns true if f is, or contains, a public input
+func (f *Field) hasPublic() bool {
+	if f.Visibility == Public {
+		return true
+	}
+	for i := range f.SubFields {
+		if f.SubFields[i].hasPublic() {
+			return true
+		}
+	}
+	return false
+}
+
+// visibility returns the visibility of the leaves of f, Unset if it is a struct (or an
+// array of structs) with mixed visibilities
+func (f *Field) visibility() Visibility {
+	for e := f; e.Type != Leaf; e = &e.SubFields[0] {
+		if e.Type == Struct {
+			return f.Visibility
+		}
+		if len(e.SubFields) == 0 {
+			break
+		}
+	}
+	if f.Visibility == Public {
+		return Public
+	}
+	return Secret
+}
+
+// jsonMember is a member of a jsonObject
+type jsonMember struct {
+	key   string
+	value any
+}
+
+// jsonObject is a JSON object whose members are encoded in order
+type jsonObject []jsonMember
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, m := range o {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		k, err := json.Marshal(m.key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(m.value)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(k)
+		b.WriteByte(':')
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+func jsonStruct(fields []Field) jsonObject {
+	properties := make(jsonObject, len(fields))
+	required := []string{}
+	for i := range fields {
+		properties[i] = jsonMember{fields[i].jsonKey(), jsonField(&fields[i])}
+		if fields[i].hasPublic() {
+			required = append(required, fields[i].jsonKey())
+		}
+	}
+	return jsonObject{
+		{"type", "object"},
+		{"properties", properties},
+		{"required", required},
+		{"additionalProperties", false},
+	}
+}
+
+func jsonField(f *Field) jsonObject {
+	var r jsonObject
+	switch f.Type {
+	case Leaf:
+		r = jsonObject{{"$ref", "#/$defs/element"}}
+	case Struct:
+		r = jsonStruct(f.SubFields)
+	case Array:
+		var items jsonObject
+		if len(f.SubFields) == 0 {
+			items = jsonObject{{"$ref", "#/$defs/element"}}
+		} else {
+			items = jsonField(&f.SubFields[0])
+		}
+		r = jsonObject{
+			{"type", "array"},
+			{"items", items},
+			{"minItems", f.ArraySize},
+			{"maxItems", f.ArraySize},
+		}
+	}
+	if v := f.visibility(); v != Unset {
+		r = append(jsonObject{{"description", v.String()}}, r...)
+	}
+	return r
+}
+
+func tsStruct(b *strings.Builder, fields []Field, depth int) {
+	indent := strings.Repeat("\t", depth)
+	b.WriteString("{\n")
+	for i := range fields {
+		f := &fields[i]
+		b.WriteString(indent + "\t")
+		b.WriteString(tsKey(f.jsonKey()))
+		if !f.hasPublic() {
+			b.WriteString("?")
+		}
+		b.WriteString(": ")
+		tsField(b, f, depth+1)
+		b.WriteString(";")
+		var comments []string
+		if v := f.visibility(); v != Unset {
+			comments = append(comments, v.String())
+		}
+		for a := f; a.Type == Array; a = &a.SubFields[0] {
+			comments = append(comments, fmt.Sprintf("length %d", a.ArraySize))
+			if len(a.SubFields) == 0 {
+				break
+			}
+		}
+		if len(comments) > 0 {
+			b.WriteString(" // " + strings.Join(comments, ", "))
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(indent + "}")
+}
+
+func tsField(b *strings.Builder, f *Field, depth int) {
+	switch f.Type {
+	case Leaf:
+		b.WriteString("FieldElement")
+	case Struct:
+		tsStruct(b, f.SubFields, depth)
+	case Array:
+		if len(f.SubFields) == 0 {
+			b.WriteString("FieldElement")
+		} else {
+			tsField(b, &f.SubFields[0], depth)
+		}
+		b.WriteString("[]")
+	}
+}
+
+// tsKey quotes the key if it isn't a valid TypeScript identifier
+func tsKey(key string) string {
+	for i, c := range key {
+		if !(c == '_' || c == '$' || unicode.IsLetter(c) || (i > 0 && unicode.IsDigit(c))) {
+			return strconv.Quote(key)
+		}
+	}
+	return key
+}
+
+func goStruct(b *bytes.Buffer, fields []Field) {
+	b.WriteString("struct {\n")
+	for i := range fields {
+		f := &fields[i]
+		b.WriteString(f.Name + " ")
+		goField(b, f)
+		if tag := goTag(f); tag != "" {
+			b.WriteString(" `" + tag + "`")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("}")
+}
+
+func goField(b *bytes.Buffer, f *Field) {
+	switch f.Type {
+	case Leaf:
+		b.WriteString("frontend.Variable")
+	case Struct:
+		goStruct(b, f.SubFields)
+	case Array:
+		fmt.Fprintf(b, "[%d]", f.ArraySize)
+		if len(f.SubFields) == 0 {
+			b.WriteString("frontend.Variable")
+		} else {
+			goField(b, &f.SubFields[0])
+		}
+	}
+}
+
+// goTag returns the gnark struct tag of a field, with the json name if it is renamed
+func goTag(f *Field) string {
+	var opt string
+	if f.Visibility == Public || f.Visibility == Secret {
+		opt = "," + f.Visibility.String()
+	}
+	if f.NameTag == "" && opt == "" {
+		return ""
+	}
+	tag := fmt.Sprintf("gnark:%q", f.NameTag+opt)
+	if f.NameTag != "" {
+		tag += fmt.Sprintf(" json:%q", f.NameTag)
+	}
+	return tag
+}
@@ -0,0 +1,132 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type exportCircuit struct {
+	X variable `gnark:"x,public"`
+	Y [2]struct {
+		A variable
+		B [3]variable `gnark:",public"`
+	}
+	Z [2][2]variable `gnark:"z-values"`
+}
+
+func TestWriteJSONSchema(t *testing.T) {
+	assert := require.New(t)
+
+	s, err := New(&exportCircuit{}, tVariable)
+	assert.NoError(err)
+
+	var buf bytes.Buffer
+	assert.NoError(s.WriteJSONSchema(&buf, "exportCircuit"))
+
+	type jsonSchema struct {
+		Type                 string
+		Description          string
+		Ref                  string `json:"$ref"`
+		Properties           map[string]*jsonSchema
+		Required             []string
+		AdditionalProperties *bool
+		Items                *jsonSchema
+		MinItems, MaxItems   int
+		Defs                 map[string]any `json:"$defs"`
+	}
+	var doc jsonSchema
+	assert.NoError(json.Unmarshal(buf.Bytes(), &doc))
+
+	assert.Equal("object", doc.Type)
+	assert.Equal([]string{"x", "Y"}, doc.Required, "secret inputs are optional")
+	assert.False(*doc.AdditionalProperties)
+	assert.Contains(doc.Defs, "element")
+
+	assert.Equal("#/$defs/element", doc.Properties["x"].Ref)
+	assert.Equal("public", doc.Properties["x"].Description)
+
+	y := doc.Properties["Y"]
+	assert.Equal("array", y.Type)
+	assert.Equal(2, y.MinItems)
+	assert.Equal(2, y.MaxItems)
+	assert.Equal([]string{"B"}, y.Items.Required)
+	assert.Equal("secret", y.Items.Properties["A"].Description)
+	assert.Equal(3, y.Items.Properties["B"].MaxItems)
+
+	z := doc.Properties["z-values"]
+	assert.Equal(2, z.MaxItems)
+	assert.Equal(2, z.Items.MaxItems)
+	assert.Equal("#/$defs/element", z.Items.Items.Ref)
+}
+
+func TestWriteTypeScript(t *testing.T) {
+	assert := require.New(t)
+
+	s, err := New(&exportCircuit{}, tVariable)
+	assert.NoError(err)
+
+	var buf bytes.Buffer
+	assert.NoError(s.WriteTypeScript(&buf, "ExportCircuit"))
+
+	const expected = `// Code generated by gnark. DO NOT EDIT.
+
+// FieldElement is a string in base 10 or 0x, 0b, 0o prefixed, or an integer. The numbers
+// past Number.MAX_SAFE_INTEGER (2^53 - 1) lose their precision: use strings for them.
+export type FieldElement = string | number;
+
+export interface ExportCircuit {
+	x: FieldElement; // public
+	Y: {
+		A?: FieldElement; // secret
+		B: FieldElement[]; // public, length 3
+	}[]; // length 2
+	"z-values"?: FieldElement[][]; // secret, length 2, length 2
+}
+`
+	assert.Equal(expected, buf.String())
+}
+
+func TestWriteGoStruct(t *testing.T) {
+	assert := require.New(t)
+
+	s, err := New(&exportCircuit{}, tVariable)
+	assert.NoError(err)
+
+	var buf bytes.Buffer
+	assert.NoError(s.WriteGoStruct(&buf, "circuits", "ExportCircuit"))
+
+	const expected = "// Code generated by gnark. DO NOT EDIT.\n\n" +
+		"package circuits\n\n" +
+		"import \"github.com/consensys/gnark/frontend\"\n\n" +
+		"type ExportCircuit struct {\n" +
+		"\tX frontend.Variable `gnark:\"x,public\" json:\"x\"`\n" +
+		"\tY [2]struct {\n" +
+		"\t\tA frontend.Variable    `gnark:\",secret\"`\n" +
+		"\t\tB [3]frontend.Variable `gnark:\",public\"`\n" +
+		"\t}\n" +
+		"\tZ [2][2]frontend.Variable `gnark:\"z-values,secret\" json:\"z-values\"`\n" +
+		"}\n"
+	assert.Equal(expected, buf.String())
+
+	// the generated struct has the same schema
+	type exported struct {
+		X variable `gnark:"x,public" json:"x"`
+		Y [2]struct {
+			A variable    `gnark:",secret"`
+			B [3]variable `gnark:",public"`
+		}
+		Z [2][2]variable `gnark:"z-values,secret" json:"z-values"`
+	}
+	s2, err := New(&exported{}, tVariable)
+	assert.NoError(err)
+	assert.Equal(s.NbPublic, s2.NbPublic)
+	assert.Equal(s.NbSecret, s2.NbSecret)
+	var buf2 bytes.Buffer
+	assert.NoError(s2.WriteJSONSchema(&buf2, "exportCircuit"))
+	buf.Reset()
+	assert.NoError(s.WriteJSONSchema(&buf, "exportCircuit"))
+	assert.Equal(buf.String(), buf2.String())
+}
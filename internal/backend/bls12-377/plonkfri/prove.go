@@ -17,10 +17,8 @@
 package plonkfri
 
 import (
-	"crypto/sha256"
 	"math/big"
 	"math/bits"
-	"runtime"
 
 	"github.com/consensys/gnark-crypto/ecc/bls12-377/fr"
 
@@ -28,51 +26,51 @@ import (
 
 	"github.com/consensys/gnark/constraint/bls12-377"
 
-	"github.com/consensys/gnark-crypto/ecc/bls12-377/fr/fri"
-
 	fiatshamir "github.com/consensys/gnark-crypto/fiat-shamir"
 	"github.com/consensys/gnark/backend"
-	"github.com/consensys/gnark/internal/utils"
 )
 
 type Proof struct {
 
 	// commitments to the solution vectors
-	LROpp [3]fri.ProofOfProximity
+	LROpp [3]ProofOfProximity
 
 	// commitment to Z (permutation polynomial)
 	// Z   Commitment
-	Zpp fri.ProofOfProximity
+	Zpp ProofOfProximity
 
 	// commitment to h1,h2,h3 such that h = h1 + X**n*h2 + X**2nh3 the quotient polynomial
-	Hpp [3]fri.ProofOfProximity
+	Hpp [3]ProofOfProximity
 
 	// opening proofs for L, R, O
-	OpeningsLROmp [3]fri.OpeningProof
+	OpeningsLROmp [3]OpeningProof
 
 	// opening proofs for Z, Zu
-	OpeningsZmp [2]fri.OpeningProof
+	OpeningsZmp [2]OpeningProof
 
 	// opening proof for H
-	OpeningsHmp [3]fri.OpeningProof
+	OpeningsHmp [3]OpeningProof
 
 	// opening proofs for ql, qr, qm, qo, qk
-	OpeningsQlQrQmQoQkincompletemp [5]fri.OpeningProof
+	OpeningsQlQrQmQoQkincompletemp [5]OpeningProof
 
 	// openings of S1, S2, S3
 	// OpeningsS1S2S3   [3]OpeningProof
-	OpeningsS1S2S3mp [3]fri.OpeningProof
+	OpeningsS1S2S3mp [3]OpeningProof
 
 	// openings of Id1, Id2, Id3
-	OpeningsId1Id2Id3mp [3]fri.OpeningProof
+	OpeningsId1Id2Id3mp [3]OpeningProof
 }
 
 func Prove(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness fr.Vector, opt backend.ProverConfig) (*Proof, error) {
 
 	var proof Proof
 
+	// the loops of the prover run on the workers of the pool
+	pool := opt.Workers()
+
 	// pick a hash function that will be used to derive the challenges
-	hFunc := sha256.New()
+	hFunc := pk.Vk.Iopp.newHash()
 
 	// 0 - Fiat Shamir
 	fs := fiatshamir.NewTranscript(hFunc, "gamma", "beta", "alpha", "zeta")
@@ -80,7 +78,12 @@ func Prove(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness fr.Vector, opt backen
 	// 1 - solve the system
 	var solution []fr.Element
 	var err error
-	if solution, err = spr.Solve(fullWitness, opt); err != nil {
+	if err = opt.BeginStage(backend.StageSolve); err != nil {
+		return nil, err
+	}
+	solution, err = spr.SolveWires(fullWitness, opt)
+	opt.EndStage(backend.StageSolve)
+	if err != nil {
 		if !opt.Force {
 			return nil, err
 		} else {
@@ -97,6 +100,9 @@ func Prove(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness fr.Vector, opt backen
 	evaluationLDomainSmall, evaluationRDomainSmall, evaluationODomainSmall := evaluateLROSmallDomain(spr, pk, solution)
 
 	// 2 - commit to lro
+	if err := opt.BeginStage(backend.StageRound1); err != nil {
+		return nil, err
+	}
 	blindedLCanonical, blindedRCanonical, blindedOCanonical, err := computeBlindedLROCanonical(
 		evaluationLDomainSmall,
 		evaluationRDomainSmall,
@@ -118,7 +124,12 @@ func Prove(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness fr.Vector, opt backen
 		return nil, err
 	}
 
+	opt.EndStage(backend.StageRound1)
+
 	// 3 - compute Z, challenges are derived using L, R, O + public inputs
+	if err := opt.BeginStage(backend.StageRound2); err != nil {
+		return nil, err
+	}
 	dataFiatShamir := make([][fr.Bytes]byte, len(spr.Public)+3)
 	for i := 0; i < len(spr.Public); i++ {
 		copy(dataFiatShamir[i][:], fullWitness[i].Marshal())
@@ -144,7 +155,7 @@ func Prove(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness fr.Vector, opt backen
 		evaluationLDomainSmall,
 		evaluationRDomainSmall,
 		evaluationODomainSmall,
-		pk, beta, gamma)
+		pk, beta, gamma, pool)
 	if err != nil {
 		return nil, err
 	}
@@ -155,7 +166,12 @@ func Prove(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness fr.Vector, opt backen
 		return nil, err
 	}
 
+	opt.EndStage(backend.StageRound2)
+
 	// 5 - compute H
+	if err := opt.BeginStage(backend.StageRound3); err != nil {
+		return nil, err
+	}
 	// var alpha fr.Element
 	alpha, err := deriveRandomness(&fs, "alpha", proof.Zpp.ID)
 	if err != nil {
@@ -169,20 +185,21 @@ func Prove(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness fr.Vector, opt backen
 	pk.Domain[0].FFTInverse(evaluationQkCompleteDomainBigBitReversed[:pk.Domain[0].Cardinality], fft.DIF)
 	fft.BitReverse(evaluationQkCompleteDomainBigBitReversed[:pk.Domain[0].Cardinality])
 
-	evaluationQkCompleteDomainBigBitReversed = fftBigCosetWOBitReverse(evaluationQkCompleteDomainBigBitReversed, &pk.Domain[1])
+	evaluationQkCompleteDomainBigBitReversed = fftBigCosetWOBitReverse(evaluationQkCompleteDomainBigBitReversed, &pk.Domain[1], pool)
 
-	evaluationBlindedLDomainBigBitReversed := fftBigCosetWOBitReverse(blindedLCanonical, &pk.Domain[1])
-	evaluationBlindedRDomainBigBitReversed := fftBigCosetWOBitReverse(blindedRCanonical, &pk.Domain[1])
-	evaluationBlindedODomainBigBitReversed := fftBigCosetWOBitReverse(blindedOCanonical, &pk.Domain[1])
+	evaluationBlindedLDomainBigBitReversed := fftBigCosetWOBitReverse(blindedLCanonical, &pk.Domain[1], pool)
+	evaluationBlindedRDomainBigBitReversed := fftBigCosetWOBitReverse(blindedRCanonical, &pk.Domain[1], pool)
+	evaluationBlindedODomainBigBitReversed := fftBigCosetWOBitReverse(blindedOCanonical, &pk.Domain[1], pool)
 
 	evaluationConstraintsDomainBigBitReversed := evalConstraintsInd(
 		pk,
 		evaluationBlindedLDomainBigBitReversed,
 		evaluationBlindedRDomainBigBitReversed,
 		evaluationBlindedODomainBigBitReversed,
-		evaluationQkCompleteDomainBigBitReversed)
+		evaluationQkCompleteDomainBigBitReversed,
+		pool)
 
-	evaluationBlindedZDomainBigBitReversed := fftBigCosetWOBitReverse(blindedZCanonical, &pk.Domain[1])
+	evaluationBlindedZDomainBigBitReversed := fftBigCosetWOBitReverse(blindedZCanonical, &pk.Domain[1], pool)
 
 	evaluationOrderingDomainBigBitReversed := evaluateOrderingDomainBigBitReversed(
 		pk,
@@ -190,14 +207,14 @@ func Prove(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness fr.Vector, opt backen
 		evaluationBlindedLDomainBigBitReversed,
 		evaluationBlindedRDomainBigBitReversed,
 		evaluationBlindedODomainBigBitReversed,
-		beta, gamma)
+		beta, gamma, pool)
 
 	h1Canonical, h2Canonical, h3Canonical := computeQuotientCanonical(
 		pk,
 		evaluationConstraintsDomainBigBitReversed,
 		evaluationOrderingDomainBigBitReversed,
 		evaluationBlindedZDomainBigBitReversed,
-		alpha)
+		alpha, pool)
 
 	// 6 - commit to H
 	proof.Hpp[0], err = pk.Vk.Iopp.BuildProofOfProximity(h1Canonical)
@@ -213,12 +230,16 @@ func Prove(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness fr.Vector, opt backen
 		return nil, err
 	}
 
+	opt.EndStage(backend.StageRound3)
+
 	// 7 - build the opening proofs
+	if err := opt.BeginStage(backend.StageRound4); err != nil {
+		return nil, err
+	}
 	// compute the size of the domain of evaluation of the committed polynomial,
 	// the opening position. The challenge zeta will be g^{i} where i is the opening
 	// position, and g is the generator of the fri domain.
-	rho := uint64(fri.GetRho())
-	friSize := 2 * rho * pk.Vk.Size
+	friSize := pk.Vk.Iopp.Size()
 	var bFriSize big.Int
 	bFriSize.SetInt64(int64(friSize))
 	frOpeningPosition, err := deriveRandomness(&fs, "zeta", proof.Hpp[0].ID, proof.Hpp[1].ID, proof.Hpp[2].ID)
@@ -228,6 +249,10 @@ func Prove(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness fr.Vector, opt backen
 	var bOpeningPosition big.Int
 	bOpeningPosition.SetBytes(frOpeningPosition.Marshal()).Mod(&bOpeningPosition, &bFriSize)
 	openingPosition := bOpeningPosition.Uint64()
+	opt.EndStage(backend.StageRound4)
+	if err := opt.BeginStage(backend.StageRound5); err != nil {
+		return nil, err
+	}
 
 	// ql, qr, qm, qo, qkIncomplete
 	proof.OpeningsQlQrQmQoQkincompletemp[0], err = pk.Vk.Iopp.Open(pk.CQl, openingPosition)
@@ -307,11 +332,9 @@ func Prove(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness fr.Vector, opt backen
 		return &proof, err
 	}
 
-	// zeta is shifted by g, the generator of Z/nZ where n is the number of constraints. We need
-	// to query the "rho" factor from FRI to know by what should be shifted the opening position.
-	// We multiply by 2 because FRI is instantiated with pk.Domain[0].Cardinality+2, which makes
-	// the iop's domain of size rho*(2*pk.Domain[0].Cardinality).
-	shiftedOpeningPosition := (openingPosition + uint64(2*rho)) % friSize
+	// zeta is shifted by g, the generator of Z/nZ where n is the number of constraints, that
+	// is g = genOpening^{friSize/n}.
+	shiftedOpeningPosition := (openingPosition + friSize/pk.Vk.Size) % friSize
 	proof.OpeningsZmp[0], err = pk.Vk.Iopp.Open(blindedZCanonical, openingPosition)
 	if err != nil {
 		return &proof, err
@@ -320,6 +343,7 @@ func Prove(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness fr.Vector, opt backen
 	if err != nil {
 		return &proof, err
 	}
+	opt.EndStage(backend.StageRound5)
 
 	return &proof, nil
 }
@@ -330,7 +354,7 @@ func Prove(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness fr.Vector, opt backen
 // * z evaluation of the blinded permutation accumulator polynomial on odd cosets
 // * l, r, o evaluation of the blinded solution vectors on odd cosets
 // * gamma randomization
-func evaluateOrderingDomainBigBitReversed(pk *ProvingKey, z, l, r, o []fr.Element, beta, gamma fr.Element) []fr.Element {
+func evaluateOrderingDomainBigBitReversed(pk *ProvingKey, z, l, r, o []fr.Element, beta, gamma fr.Element, pool *backend.WorkerPool) []fr.Element {
 
 	nbElmts := int(pk.Domain[1].Cardinality)
 
@@ -348,7 +372,7 @@ func evaluateOrderingDomainBigBitReversed(pk *ProvingKey, z, l, r, o []fr.Elemen
 	cosetShift.Set(&pk.Vk.CosetShift)
 	cosetShiftSquare.Square(&pk.Vk.CosetShift)
 
-	utils.Parallelize(int(pk.Domain[1].Cardinality), func(start, end int) {
+	pool.Parallelize(int(pk.Domain[1].Cardinality), 1, func(start, end int) {
 
 		var evaluationIDBigDomain fr.Element
 		evaluationIDBigDomain.Exp(pk.Domain[1].Generator, big.NewInt(int64(start))).
@@ -390,12 +414,12 @@ func evaluateOrderingDomainBigBitReversed(pk *ProvingKey, z, l, r, o []fr.Elemen
 // * lsQk is the completed version of qk, in canonical version
 //
 // lsL, lsR, lsO are in bit reversed order, lsQk is in the correct order.
-func evalConstraintsInd(pk *ProvingKey, lsL, lsR, lsO, lsQk []fr.Element) []fr.Element {
+func evalConstraintsInd(pk *ProvingKey, lsL, lsR, lsO, lsQk []fr.Element, pool *backend.WorkerPool) []fr.Element {
 
 	res := make([]fr.Element, pk.Domain[1].Cardinality)
 	// nn := uint64(64 - bits.TrailingZeros64(pk.Domain[1].Cardinality))
 
-	utils.Parallelize(len(res), func(start, end int) {
+	pool.Parallelize(len(res), 1, func(start, end int) {
 
 		var t0, t1 fr.Element
 
@@ -425,17 +449,17 @@ func evalConstraintsInd(pk *ProvingKey, lsL, lsR, lsO, lsQk []fr.Element) []fr.E
 //
 // Puts the result in res of size n.
 // Warning: result is in bit reversed order, we do a bit reverse operation only once in computeQuotientCanonical
-func fftBigCosetWOBitReverse(poly []fr.Element, domainBig *fft.Domain) []fr.Element {
+func fftBigCosetWOBitReverse(poly []fr.Element, domainBig *fft.Domain, pool *backend.WorkerPool) []fr.Element {
 
 	res := make([]fr.Element, domainBig.Cardinality)
 
 	// we copy poly in res and scale by coset here
 	// to avoid FFT scaling on domainBig.Cardinality (res is very sparse)
-	utils.Parallelize(len(poly), func(start, end int) {
+	pool.Parallelize(len(poly), 1, func(start, end int) {
 		for i := start; i < end; i++ {
 			res[i].Mul(&poly[i], &domainBig.CosetTable[i])
 		}
-	}, runtime.NumCPU()/2)
+	})
 	domainBig.FFT(res, fft.DIF)
 	return res
 }
@@ -474,7 +498,7 @@ func evaluateXnMinusOneDomainBigCoset(domainBig, domainSmall *fft.Domain) []fr.E
 //	constraintsInd			    constraintOrdering					startsAtOne
 //
 // constraintInd, constraintOrdering are evaluated on the odd cosets of (Z/8mZ)/(Z/mZ)
-func computeQuotientCanonical(pk *ProvingKey, evaluationConstraintsIndBitReversed, evaluationConstraintOrderingBitReversed, evaluationBlindedZDomainBigBitReversed []fr.Element, alpha fr.Element) ([]fr.Element, []fr.Element, []fr.Element) {
+func computeQuotientCanonical(pk *ProvingKey, evaluationConstraintsIndBitReversed, evaluationConstraintOrderingBitReversed, evaluationBlindedZDomainBigBitReversed []fr.Element, alpha fr.Element, pool *backend.WorkerPool) ([]fr.Element, []fr.Element, []fr.Element) {
 
 	h := make([]fr.Element, pk.Domain[1].Cardinality)
 
@@ -498,7 +522,7 @@ func computeQuotientCanonical(pk *ProvingKey, evaluationConstraintsIndBitReverse
 
 	ratio := pk.Domain[1].Cardinality / pk.Domain[0].Cardinality
 
-	utils.Parallelize(int(pk.Domain[1].Cardinality), func(start, end int) {
+	pool.Parallelize(int(pk.Domain[1].Cardinality), 1, func(start, end int) {
 		var t fr.Element
 		for i := uint64(start); i < uint64(end); i++ {
 
@@ -537,7 +561,7 @@ func computeQuotientCanonical(pk *ProvingKey, evaluationConstraintsIndBitReverse
 //     (l_i+s1+gamma)*(r_i+s2+gamma)*(o_i+s3+gamma)
 //
 //   - l, r, o are the solution in Lagrange basis
-func computeBlindedZCanonical(l, r, o []fr.Element, pk *ProvingKey, beta, gamma fr.Element) ([]fr.Element, error) {
+func computeBlindedZCanonical(l, r, o []fr.Element, pk *ProvingKey, beta, gamma fr.Element, pool *backend.WorkerPool) ([]fr.Element, error) {
 
 	// note that z has more capacity has its memory is reused for blinded z later on
 	z := make([]fr.Element, pk.Domain[0].Cardinality, pk.Domain[0].Cardinality+3)
@@ -549,7 +573,7 @@ func computeBlindedZCanonical(l, r, o []fr.Element, pk *ProvingKey, beta, gamma
 
 	evaluationIDSmallDomain := getIDSmallDomain(&pk.Domain[0])
 
-	utils.Parallelize(nbElmts-1, func(start, end int) {
+	pool.Parallelize(nbElmts-1, 1, func(start, end int) {
 
 		var f [3]fr.Element
 		var g [3]fr.Element
@@ -17,8 +17,12 @@
 package groth16
 
 import (
+	"bytes"
+	"errors"
 	curve "github.com/consensys/gnark-crypto/ecc/bls12-381"
 	"io"
+	"math"
+	"unsafe"
 )
 
 // WriteTo writes binary encoding of the Proof elements to writer
@@ -282,15 +286,341 @@ func (pk *ProvingKey) readFrom(r io.Reader, decOptions ...func(*curve.Decoder))
 			return n + dec.BytesRead(), err
 		}
 	}
-	pk.InfinityA = make([]bool, nbWires)
-	pk.InfinityB = make([]bool, nbWires)
-
-	if err := dec.Decode(&pk.InfinityA); err != nil {
+	if pk.InfinityA, err = readBools(dec, nbWires); err != nil {
 		return n + dec.BytesRead(), err
 	}
-	if err := dec.Decode(&pk.InfinityB); err != nil {
+	if pk.InfinityB, err = readBools(dec, nbWires); err != nil {
 		return n + dec.BytesRead(), err
 	}
 
 	return n + dec.BytesRead(), nil
 }
+
+// readBools decodes n booleans by chunks, such that an invalid n read from the input fails
+// when the input ends instead of allocating n booleans
+func readBools(dec *curve.Decoder, n uint64) ([]bool, error) {
+	const chunkSize = 1 << 16
+	res := make([]bool, 0, minUint64(n, chunkSize))
+	for uint64(len(res)) < n {
+		chunk := make([]bool, minUint64(n-uint64(len(res)), chunkSize))
+		if err := dec.Decode(&chunk); err != nil {
+			return nil, err
+		}
+		res = append(res, chunk...)
+	}
+	return res, nil
+}
+
+func minUint64(a, b uint64) uint64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// dumpMagic identifies proving keys written by WriteDump
+var dumpMagic = [8]byte{'g', 'n', 'a', 'r', 'k', 'p', 'k', 1}
+
+// dumpByteOrderMark is written in native byte order to detect dumps produced on a
+// platform with a different endianness
+const dumpByteOrderMark uint64 = 0x0102030405060708
+
+var errInvalidDump = errors.New("invalid proving key dump")
+
+// WriteDump writes the proving key as a memory dump, which can be read back with ReadDump
+// or memory mapped with UnsafeMapDump.
+//
+// serialization format:
+// magic | byte order mark | uint64(len(header)) | header | padding |
+// uint64(len(A)),[A]1 | uint64(len(B)),[B]1 | uint64(len(Z)),[Z]1 | uint64(len(K)),[K]1 | uint64(len(B)),[B]2
+//
+// the header holds the domain, [α]1,[β]1,[δ]1,[β]2,[δ]2 and the infinity flags, as written by WriteRawTo.
+// The point vectors are stored as in memory (Montgomery form, native endianness), 8 bytes aligned;
+// a dump is not portable across platforms with different endianness.
+// As with WriteTo, the CommitmentKey is not serialized.
+func (pk *ProvingKey) WriteDump(w io.Writer) (int64, error) {
+	var header bytes.Buffer
+	if _, err := pk.Domain.WriteTo(&header); err != nil {
+		return 0, err
+	}
+	enc := curve.NewEncoder(&header, curve.RawEncoding())
+	toEncode := []interface{}{
+		&pk.G1.Alpha,
+		&pk.G1.Beta,
+		&pk.G1.Delta,
+		&pk.G2.Beta,
+		&pk.G2.Delta,
+		uint64(len(pk.InfinityA)),
+		pk.NbInfinityA,
+		pk.NbInfinityB,
+		pk.InfinityA,
+		pk.InfinityB,
+	}
+	for _, v := range toEncode {
+		if err := enc.Encode(v); err != nil {
+			return 0, err
+		}
+	}
+	if padding := header.Len() % 8; padding != 0 {
+		header.Write(make([]byte, 8-padding))
+	}
+
+	var n int64
+	write := func(b []byte) error {
+		m, err := w.Write(b)
+		n += int64(m)
+		return err
+	}
+	writeUint64 := func(v uint64) error {
+		return write((*[8]byte)(unsafe.Pointer(&v))[:])
+	}
+
+	if err := write(dumpMagic[:]); err != nil {
+		return n, err
+	}
+	if err := writeUint64(dumpByteOrderMark); err != nil {
+		return n, err
+	}
+	if err := writeUint64(uint64(header.Len())); err != nil {
+		return n, err
+	}
+	if err := write(header.Bytes()); err != nil {
+		return n, err
+	}
+
+	for _, v := range [][]curve.G1Affine{pk.G1.A, pk.G1.B, pk.G1.Z, pk.G1.K} {
+		if err := writeUint64(uint64(len(v))); err != nil {
+			return n, err
+		}
+		if err := write(pointsAsBytes(v)); err != nil {
+			return n, err
+		}
+	}
+	if err := writeUint64(uint64(len(pk.G2.B))); err != nil {
+		return n, err
+	}
+	if err := write(pointsAsBytes(pk.G2.B)); err != nil {
+		return n, err
+	}
+
+	return n, nil
+}
+
+// ReadDump reads a proving key written by WriteDump. Points are not checked to be on the curve
+// or in the correct subgroup.
+func (pk *ProvingKey) ReadDump(r io.Reader) (int64, error) {
+	var n int64
+	read := func(b []byte) error {
+		m, err := io.ReadFull(r, b)
+		n += int64(m)
+		return err
+	}
+
+	data := make([]byte, 24)
+	if err := read(data); err != nil {
+		return n, err
+	}
+	headerLen, err := checkDumpPreamble(data)
+	if err != nil {
+		return n, err
+	}
+	// the header is read as it comes, its length isn't trusted
+	if headerLen > math.MaxInt64 {
+		return n, errInvalidDump
+	}
+	header, err := io.ReadAll(io.LimitReader(r, int64(headerLen)))
+	n += int64(len(header))
+	if err != nil {
+		return n, err
+	}
+	if uint64(len(header)) != headerLen {
+		return n, io.ErrUnexpectedEOF
+	}
+	if err := pk.readDumpHeader(header); err != nil {
+		return n, err
+	}
+
+	var lenBuf [8]byte
+	readLen := func() (uint64, error) {
+		if err := read(lenBuf[:]); err != nil {
+			return 0, err
+		}
+		return *(*uint64)(unsafe.Pointer(&lenBuf[0])), nil
+	}
+
+	for _, v := range []*[]curve.G1Affine{&pk.G1.A, &pk.G1.B, &pk.G1.Z, &pk.G1.K} {
+		l, err := readLen()
+		if err != nil {
+			return n, err
+		}
+		if *v, err = readDumpPoints[curve.G1Affine](read, l); err != nil {
+			return n, err
+		}
+	}
+	l, err := readLen()
+	if err != nil {
+		return n, err
+	}
+	if pk.G2.B, err = readDumpPoints[curve.G2Affine](read, l); err != nil {
+		return n, err
+	}
+
+	return n, pk.checkDumpInfinity()
+}
+
+// readDumpPoints reads l points of a dump by chunks, such that an invalid l read from the
+// input fails when the input ends instead of allocating l points
+func readDumpPoints[T curve.G1Affine | curve.G2Affine](read func([]byte) error, l uint64) ([]T, error) {
+	const chunkSize = 1 << 12
+	res := make([]T, 0, minUint64(l, chunkSize))
+	for uint64(len(res)) < l {
+		start := len(res)
+		res = append(res, make([]T, minUint64(l-uint64(start), chunkSize))...)
+		if err := read(pointsAsBytes(res[start:])); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// UnsafeMapDump sets pk from data, written by WriteDump. The point vectors of pk are not copied
+// but point into data, which is typically a memory mapped file: the pages holding the points of a
+// multi-exponentiation are then only loaded when the prover needs them.
+//
+// data must not be modified nor released while pk is in use. Points are not checked to be
+// on the curve or in the correct subgroup.
+func (pk *ProvingKey) UnsafeMapDump(data []byte) error {
+	if len(data) < 24 {
+		return errInvalidDump
+	}
+	headerLen, err := checkDumpPreamble(data[:24])
+	if err != nil {
+		return err
+	}
+	offset := 24 + headerLen
+	if offset > uint64(len(data)) {
+		return errInvalidDump
+	}
+	if err := pk.readDumpHeader(data[24:offset]); err != nil {
+		return err
+	}
+
+	// section returns the n*size bytes following the length of the next point vector
+	section := func(size uint64) ([]byte, uint64, error) {
+		if offset+8 > uint64(len(data)) {
+			return nil, 0, errInvalidDump
+		}
+		l := *(*uint64)(unsafe.Pointer(&data[offset]))
+		offset += 8
+		if l > (uint64(len(data))-offset)/size {
+			return nil, 0, errInvalidDump
+		}
+		b := data[offset : offset+l*size]
+		offset += l * size
+		if l != 0 && uintptr(unsafe.Pointer(&b[0]))%8 != 0 {
+			// not aligned, we copy the points
+			b = append(make([]byte, 0, len(b)+8), b...)
+		}
+		return b, l, nil
+	}
+
+	for _, v := range []*[]curve.G1Affine{&pk.G1.A, &pk.G1.B, &pk.G1.Z, &pk.G1.K} {
+		b, l, err := section(uint64(unsafe.Sizeof(curve.G1Affine{})))
+		if err != nil {
+			return err
+		}
+		if l == 0 {
+			*v = []curve.G1Affine{}
+			continue
+		}
+		*v = unsafe.Slice((*curve.G1Affine)(unsafe.Pointer(&b[0])), l)
+	}
+	b, l, err := section(uint64(unsafe.Sizeof(curve.G2Affine{})))
+	if err != nil {
+		return err
+	}
+	if l == 0 {
+		pk.G2.B = []curve.G2Affine{}
+	} else {
+		pk.G2.B = unsafe.Slice((*curve.G2Affine)(unsafe.Pointer(&b[0])), l)
+	}
+
+	return pk.checkDumpInfinity()
+}
+
+// checkDumpInfinity checks that NbInfinityA and NbInfinityB match the infinity flags of a dump,
+// and that [A]1, [B]1 and [B]2 hold a point for each wire not at infinity
+func (pk *ProvingKey) checkDumpInfinity() error {
+	var nbInfinityA, nbInfinityB uint64
+	for i := range pk.InfinityA {
+		if pk.InfinityA[i] {
+			nbInfinityA++
+		}
+		if pk.InfinityB[i] {
+			nbInfinityB++
+		}
+	}
+	if nbInfinityA != pk.NbInfinityA || nbInfinityB != pk.NbInfinityB {
+		return errors.New("invalid proving key dump: number of points at infinity doesn't match the infinity flags")
+	}
+	nbWires := uint64(len(pk.InfinityA))
+	if uint64(len(pk.G1.A)) != nbWires-nbInfinityA ||
+		uint64(len(pk.G1.B)) != nbWires-nbInfinityB ||
+		uint64(len(pk.G2.B)) != nbWires-nbInfinityB {
+		return errors.New("invalid proving key dump: number of points doesn't match the infinity flags")
+	}
+	return nil
+}
+
+// checkDumpPreamble checks the magic and byte order mark of a dump, and returns the header length
+func checkDumpPreamble(data []byte) (uint64, error) {
+	if !bytes.Equal(data[:8], dumpMagic[:]) {
+		return 0, errInvalidDump
+	}
+	if *(*uint64)(unsafe.Pointer(&data[8])) != dumpByteOrderMark {
+		return 0, errors.New("proving key dump was written on a platform with a different endianness")
+	}
+	return *(*uint64)(unsafe.Pointer(&data[16])), nil
+}
+
+func (pk *ProvingKey) readDumpHeader(header []byte) error {
+	r := bytes.NewReader(header)
+	if _, err := pk.Domain.ReadFrom(r); err != nil {
+		return err
+	}
+	dec := curve.NewDecoder(r, curve.NoSubgroupChecks())
+
+	var nbWires uint64
+	toDecode := []interface{}{
+		&pk.G1.Alpha,
+		&pk.G1.Beta,
+		&pk.G1.Delta,
+		&pk.G2.Beta,
+		&pk.G2.Delta,
+		&nbWires,
+		&pk.NbInfinityA,
+		&pk.NbInfinityB,
+	}
+	for _, v := range toDecode {
+		if err := dec.Decode(v); err != nil {
+			return err
+		}
+	}
+	// the infinity flags are encoded with a byte each
+	if nbWires > uint64(r.Len())/2 {
+		return errInvalidDump
+	}
+	pk.InfinityA = make([]bool, nbWires)
+	pk.InfinityB = make([]bool, nbWires)
+	if err := dec.Decode(&pk.InfinityA); err != nil {
+		return err
+	}
+	return dec.Decode(&pk.InfinityB)
+}
+
+func pointsAsBytes[T curve.G1Affine | curve.G2Affine](v []T) []byte {
+	if len(v) == 0 {
+		return nil
+	}
+	return unsafe.Slice((*byte)(unsafe.Pointer(&v[0])), len(v)*int(unsafe.Sizeof(v[0])))
+}
@@ -21,7 +21,6 @@ import (
 	curve "github.com/consensys/gnark-crypto/ecc/bls12-381"
 	"github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
 	"github.com/consensys/gnark-crypto/ecc/bls12-381/fr/fft"
-	"github.com/consensys/gnark-crypto/ecc/bls12-381/fr/pedersen"
 	"github.com/consensys/gnark/constraint"
 	"github.com/consensys/gnark/constraint/bls12-381"
 	"math/big"
@@ -52,7 +51,7 @@ type ProvingKey struct {
 	InfinityA, InfinityB     []bool
 	NbInfinityA, NbInfinityB uint64
 
-	CommitmentKey pedersen.Key
+	CommitmentKey CommitmentKey
 }
 
 // VerifyingKey is used by a Groth16 verifier to verify the validity of a proof and a statement
@@ -75,7 +74,7 @@ type VerifyingKey struct {
 	// e(α, β)
 	e curve.GT // not serialized
 
-	CommitmentKey  pedersen.Key
+	CommitmentKey  CommitmentKey
 	CommitmentInfo constraint.Commitment // since the verifier doesn't input a constraint system, this needs to be provided here
 }
 
@@ -255,7 +254,7 @@ func Setup(r1cs *cs.R1CS, pk *ProvingKey, vk *VerifyingKey) error {
 	if nbPrivateCommittedWires != 0 {
 		commitmentBasis := g1PointsAff[offset:]
 
-		vk.CommitmentKey, err = pedersen.Setup(commitmentBasis)
+		vk.CommitmentKey, err = newCommitmentKey(commitmentBasis)
 		if err != nil {
 			return err
 		}
@@ -583,7 +582,11 @@ func (vk *VerifyingKey) CurveID() ecc.ID {
 
 // NbPublicWitness returns the number of elements in the expected public witness
 func (vk *VerifyingKey) NbPublicWitness() int {
-	return (len(vk.G1.K) - 1)
+	if vk.CommitmentInfo.Is() {
+		// the last element of K corresponds to the commitment, which isn't part of the public witness
+		return len(vk.G1.K) - 2
+	}
+	return len(vk.G1.K) - 1
 }
 
 // NbG1 returns the number of G1 elements in the VerifyingKey
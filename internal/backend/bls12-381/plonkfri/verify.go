@@ -17,10 +17,8 @@
 package plonkfri
 
 import (
-	"crypto/sha256"
 	"errors"
 	"github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
-	"github.com/consensys/gnark-crypto/ecc/bls12-381/fr/fri"
 	"math/big"
 
 	fiatshamir "github.com/consensys/gnark-crypto/fiat-shamir"
@@ -31,7 +29,7 @@ var ErrInvalidAlgebraicRelation = errors.New("algebraic relation does not hold")
 func Verify(proof *Proof, vk *VerifyingKey, publicWitness fr.Vector) error {
 
 	// 0 - derive the challenges with Fiat Shamir
-	hFunc := sha256.New()
+	hFunc := vk.Iopp.newHash()
 	fs := fiatshamir.NewTranscript(hFunc, "gamma", "beta", "alpha", "zeta")
 
 	dataFiatShamir := make([][fr.Bytes]byte, len(publicWitness)+3)
@@ -60,8 +58,7 @@ func Verify(proof *Proof, vk *VerifyingKey, publicWitness fr.Vector) error {
 	// compute the size of the domain of evaluation of the committed polynomial,
 	// the opening position. The challenge zeta will be g^{i} where i is the opening
 	// position, and g is the generator of the fri domain.
-	rho := uint64(fri.GetRho())
-	friSize := 2 * rho * vk.Size
+	friSize := vk.Iopp.Size()
 	var bFriSize big.Int
 	bFriSize.SetInt64(int64(friSize))
 	frOpeningPosition, err := deriveRandomness(&fs, "zeta", proof.Hpp[0].ID, proof.Hpp[1].ID, proof.Hpp[2].ID)
@@ -72,7 +69,7 @@ func Verify(proof *Proof, vk *VerifyingKey, publicWitness fr.Vector) error {
 	bOpeningPosition.SetBytes(frOpeningPosition.Marshal()).Mod(&bOpeningPosition, &bFriSize)
 	openingPosition := bOpeningPosition.Uint64()
 
-	shiftedOpeningPosition := (openingPosition + uint64(2*rho)) % friSize
+	shiftedOpeningPosition := (openingPosition + friSize/vk.Size) % friSize
 	err = vk.Iopp.VerifyOpening(shiftedOpeningPosition, proof.OpeningsZmp[1], proof.Zpp)
 	if err != nil {
 		return err
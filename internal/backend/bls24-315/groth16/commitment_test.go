@@ -18,7 +18,9 @@ package groth16_test
 
 import (
 	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend"
 	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/hint"
 	"github.com/consensys/gnark/backend/witness"
 	"github.com/consensys/gnark/constraint"
 	"github.com/consensys/gnark/frontend"
@@ -78,6 +80,23 @@ func TestSingleSecretCommitted(t *testing.T) {
 	test(t, &circuit, &assignment)
 }
 
+func TestCommittedHintTranscript(t *testing.T) {
+	// the commitment hint sets the commitment of the proof, it must run again when the other
+	// hints are replayed from the transcript
+	_r1cs, pk, vk := setup(t, &singleSecretCommittedCircuit{})
+	_witness, err := frontend.NewWitness(&singleSecretCommittedCircuit{One: 1}, ecc.BLS24_315.ScalarField())
+	assert.NoError(t, err)
+	public, err := _witness.Public()
+	assert.NoError(t, err)
+
+	transcript := hint.NewTranscript()
+	for i := 0; i < 2; i++ {
+		proof, err := groth16.Prove(_r1cs, pk, _witness, backend.WithHintTranscript(transcript))
+		assert.NoError(t, err)
+		assert.NoError(t, groth16.Verify(proof, vk, public))
+	}
+}
+
 type noCommitmentCircuit struct { // to see if unadulterated groth16 is still correct
 	One frontend.Variable
 }
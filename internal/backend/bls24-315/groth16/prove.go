@@ -24,10 +24,8 @@ import (
 	"github.com/consensys/gnark-crypto/ecc/bls24-315/fr/fft"
 	"github.com/consensys/gnark/backend"
 	"github.com/consensys/gnark/constraint/bls24-315"
-	"github.com/consensys/gnark/internal/utils"
 	"github.com/consensys/gnark/logger"
 	"math/big"
-	"runtime"
 	"time"
 )
 
@@ -59,64 +57,31 @@ func Prove(r1cs *cs.R1CS, pk *ProvingKey, witness fr.Vector, opt backend.ProverC
 
 	log := logger.Logger().With().Str("curve", r1cs.CurveID().String()).Int("nbConstraints", len(r1cs.Constraints)).Str("backend", "groth16").Logger()
 
-	// solve the R1CS and compute the a, b, c vectors
-	a := make([]fr.Element, len(r1cs.Constraints), pk.Domain.Cardinality)
-	b := make([]fr.Element, len(r1cs.Constraints), pk.Domain.Cardinality)
-	c := make([]fr.Element, len(r1cs.Constraints), pk.Domain.Cardinality)
-
 	proof := &Proof{}
-	if r1cs.CommitmentInfo.Is() {
-		opt.HintFunctions[r1cs.CommitmentInfo.HintID] = func(_ *big.Int, in []*big.Int, out []*big.Int) error {
-			// Perf-TODO: Converting these values to big.Int and back may be a performance bottleneck.
-			// If that is the case, figure out a way to feed the solution vector into this function
-			if len(in) != r1cs.CommitmentInfo.NbCommitted() { // TODO: Remove
-				return fmt.Errorf("unexpected number of committed variables")
-			}
-			values := make([]fr.Element, r1cs.CommitmentInfo.NbPrivateCommitted)
-			nbPublicCommitted := len(in) - len(values)
-			inPrivate := in[nbPublicCommitted:]
-			for i, inI := range inPrivate {
-				values[i].SetBigInt(inI)
-			}
-
-			var err error
-			proof.Commitment, proof.CommitmentPok, err = pk.CommitmentKey.Commit(values)
-			if err != nil {
-				return err
-			}
-
-			var res fr.Element
-			res, err = solveCommitmentWire(&r1cs.CommitmentInfo, &proof.Commitment, in[:r1cs.CommitmentInfo.NbPublicCommitted()])
-			res.BigInt(out[0]) //Perf-TODO: Regular (non-mont) hashToField to obviate this conversion?
-			return err
-		}
-	}
-
-	var wireValues []fr.Element
-	var err error
-	if wireValues, err = r1cs.Solve(witness, a, b, c, opt); err != nil {
-		if !opt.Force {
-			return nil, err
-		} else {
-			// we need to fill wireValues with random values else multi exps don't do much
-			var r fr.Element
-			_, _ = r.SetRandom()
-			for i := r1cs.GetNbPublicVariables() + r1cs.GetNbSecretVariables(); i < len(wireValues); i++ {
-				wireValues[i] = r
-				r.Double(&r)
-			}
-		}
+	wireValues, a, b, c, err := solveR1CS(r1cs, pk, witness, opt, proof)
+	if err != nil {
+		return nil, err
 	}
 	start := time.Now()
 
 	// H (witness reduction / FFT part)
+	if err := opt.BeginStage(backend.StageFFT); err != nil {
+		return nil, err
+	}
+	// the MSMs run on the workers of the pool, which may be shared with other provers
+	pool := opt.Workers()
+	n := pool.NbWorkers()
+
 	var h []fr.Element
 	chHDone := make(chan struct{}, 1)
 	go func() {
-		h = computeH(a, b, c, &pk.Domain)
+		// the FFTs don't take a number of tasks and run on all the CPUs regardless of the pool,
+		// as such they don't hold workers: only the other loops of computeH acquire them
+		h = computeH(a, b, c, &pk.Domain, pool)
 		a = nil
 		b = nil
 		c = nil
+		opt.EndStage(backend.StageFFT)
 		chHDone <- struct{}{}
 	}()
 
@@ -126,25 +91,11 @@ func Prove(r1cs *cs.R1CS, pk *ProvingKey, witness fr.Vector, opt backend.ProverC
 	chWireValuesA, chWireValuesB := make(chan struct{}, 1), make(chan struct{}, 1)
 
 	go func() {
-		wireValuesA = make([]fr.Element, len(wireValues)-int(pk.NbInfinityA))
-		for i, j := 0, 0; j < len(wireValuesA); i++ {
-			if pk.InfinityA[i] {
-				continue
-			}
-			wireValuesA[j] = wireValues[i]
-			j++
-		}
+		wireValuesA = filterInfinity(wireValues, pk.InfinityA, pk.NbInfinityA)
 		close(chWireValuesA)
 	}()
 	go func() {
-		wireValuesB = make([]fr.Element, len(wireValues)-int(pk.NbInfinityB))
-		for i, j := 0, 0; j < len(wireValuesB); i++ {
-			if pk.InfinityB[i] {
-				continue
-			}
-			wireValuesB[j] = wireValues[i]
-			j++
-		}
+		wireValuesB = filterInfinity(wireValues, pk.InfinityB, pk.NbInfinityB)
 		close(chWireValuesB)
 	}()
 
@@ -167,16 +118,24 @@ func Prove(r1cs *cs.R1CS, pk *ProvingKey, witness fr.Vector, opt backend.ProverC
 
 	var bs1, ar curve.G1Jac
 
-	n := runtime.NumCPU()
-
 	chBs1Done := make(chan error, 1)
 	computeBS1 := func() {
 		<-chWireValuesB
-		if _, err := bs1.MultiExp(pk.G1.B, wireValuesB, ecc.MultiExpConfig{NbTasks: n / 2}); err != nil {
+		if err := opt.BeginStage(backend.StageMSMB1); err != nil {
 			chBs1Done <- err
 			close(chBs1Done)
 			return
 		}
+		var err error
+		pool.Run(n/2, func(nbTasks int) {
+			_, err = bs1.MultiExp(pk.G1.B, wireValuesB, ecc.MultiExpConfig{NbTasks: nbTasks})
+		})
+		if err != nil {
+			chBs1Done <- err
+			close(chBs1Done)
+			return
+		}
+		opt.EndStage(backend.StageMSMB1)
 		bs1.AddMixed(&pk.G1.Beta)
 		bs1.AddMixed(&deltas[1])
 		chBs1Done <- nil
@@ -185,11 +144,21 @@ func Prove(r1cs *cs.R1CS, pk *ProvingKey, witness fr.Vector, opt backend.ProverC
 	chArDone := make(chan error, 1)
 	computeAR1 := func() {
 		<-chWireValuesA
-		if _, err := ar.MultiExp(pk.G1.A, wireValuesA, ecc.MultiExpConfig{NbTasks: n / 2}); err != nil {
+		if err := opt.BeginStage(backend.StageMSMA); err != nil {
+			chArDone <- err
+			close(chArDone)
+			return
+		}
+		var err error
+		pool.Run(n/2, func(nbTasks int) {
+			_, err = ar.MultiExp(pk.G1.A, wireValuesA, ecc.MultiExpConfig{NbTasks: nbTasks})
+		})
+		if err != nil {
 			chArDone <- err
 			close(chArDone)
 			return
 		}
+		opt.EndStage(backend.StageMSMA)
 		ar.AddMixed(&pk.G1.Alpha)
 		ar.AddMixed(&deltas[0])
 		proof.Ar.FromJacobian(&ar)
@@ -204,17 +173,34 @@ func Prove(r1cs *cs.R1CS, pk *ProvingKey, witness fr.Vector, opt backend.ProverC
 		var krs, krs2, p1 curve.G1Jac
 		chKrs2Done := make(chan error, 1)
 		go func() {
-			_, err := krs2.MultiExp(pk.G1.Z, h, ecc.MultiExpConfig{NbTasks: n / 2})
+			if err := opt.BeginStage(backend.StageMSMZ); err != nil {
+				chKrs2Done <- err
+				return
+			}
+			var err error
+			pool.Run(n/2, func(nbTasks int) {
+				_, err = krs2.MultiExp(pk.G1.Z, h, ecc.MultiExpConfig{NbTasks: nbTasks})
+			})
+			opt.EndStage(backend.StageMSMZ)
 			chKrs2Done <- err
 		}()
 
 		// filter the wire values if needed;
 		_wireValues := filter(wireValues, r1cs.CommitmentInfo.PrivateToPublic())
 
-		if _, err := krs.MultiExp(pk.G1.K, _wireValues[r1cs.GetNbPublicVariables():], ecc.MultiExpConfig{NbTasks: n / 2}); err != nil {
+		if err := opt.BeginStage(backend.StageMSMK); err != nil {
+			chKrsDone <- err
+			return
+		}
+		var err error
+		pool.Run(n/2, func(nbTasks int) {
+			_, err = krs.MultiExp(pk.G1.K, _wireValues[r1cs.GetNbPublicVariables():], ecc.MultiExpConfig{NbTasks: nbTasks})
+		})
+		if err != nil {
 			chKrsDone <- err
 			return
 		}
+		opt.EndStage(backend.StageMSMK)
 		krs.AddMixed(&deltas[2])
 		n := 3
 		for n != 0 {
@@ -251,15 +237,22 @@ func Prove(r1cs *cs.R1CS, pk *ProvingKey, witness fr.Vector, opt backend.ProverC
 		// Bs2 (1 multi exp G2 - size = len(wires))
 		var Bs, deltaS curve.G2Jac
 
-		nbTasks := n
-		if nbTasks <= 16 {
-			// if we don't have a lot of CPUs, this may artificially split the MSM
-			nbTasks *= 2
-		}
 		<-chWireValuesB
-		if _, err := Bs.MultiExp(pk.G2.B, wireValuesB, ecc.MultiExpConfig{NbTasks: nbTasks}); err != nil {
+		if err := opt.BeginStage(backend.StageMSMB2); err != nil {
 			return err
 		}
+		var err error
+		pool.Run(n, func(nbTasks int) {
+			if nbTasks <= 16 {
+				// if we don't have a lot of CPUs, this may artificially split the MSM
+				nbTasks *= 2
+			}
+			_, err = Bs.MultiExp(pk.G2.B, wireValuesB, ecc.MultiExpConfig{NbTasks: nbTasks})
+		})
+		if err != nil {
+			return err
+		}
+		opt.EndStage(backend.StageMSMB2)
 
 		deltaS.FromAffine(&pk.G2.Delta)
 		deltaS.ScalarMultiplication(&deltaS, &s)
@@ -291,6 +284,76 @@ func Prove(r1cs *cs.R1CS, pk *ProvingKey, witness fr.Vector, opt backend.ProverC
 	return proof, nil
 }
 
+// solveR1CS solves the R1CS and computes the a, b, c vectors; if the circuit has a commitment,
+// proof.Commitment and proof.CommitmentPok are set.
+func solveR1CS(r1cs *cs.R1CS, pk *ProvingKey, witness fr.Vector, opt backend.ProverConfig, proof *Proof) ([]fr.Element, []fr.Element, []fr.Element, []fr.Element, error) {
+	// solve the R1CS and compute the a, b, c vectors
+	a := make([]fr.Element, len(r1cs.Constraints), pk.Domain.Cardinality)
+	b := make([]fr.Element, len(r1cs.Constraints), pk.Domain.Cardinality)
+	c := make([]fr.Element, len(r1cs.Constraints), pk.Domain.Cardinality)
+
+	if r1cs.CommitmentInfo.Is() {
+		opt.HintFunctions[r1cs.CommitmentInfo.HintID] = func(_ *big.Int, in []*big.Int, out []*big.Int) error {
+			// Perf-TODO: Converting these values to big.Int and back may be a performance bottleneck.
+			// If that is the case, figure out a way to feed the solution vector into this function
+			if len(in) != r1cs.CommitmentInfo.NbCommitted() { // TODO: Remove
+				return fmt.Errorf("unexpected number of committed variables")
+			}
+			values := make([]fr.Element, r1cs.CommitmentInfo.NbPrivateCommitted)
+			nbPublicCommitted := len(in) - len(values)
+			inPrivate := in[nbPublicCommitted:]
+			for i, inI := range inPrivate {
+				values[i].SetBigInt(inI)
+			}
+
+			var err error
+			proof.Commitment, proof.CommitmentPok, err = pk.CommitmentKey.Commit(values)
+			if err != nil {
+				return err
+			}
+
+			var res fr.Element
+			res, err = solveCommitmentWire(&r1cs.CommitmentInfo, &proof.Commitment, in[:r1cs.CommitmentInfo.NbPublicCommitted()])
+			res.BigInt(out[0]) //Perf-TODO: Regular (non-mont) hashToField to obviate this conversion?
+			return err
+		}
+	}
+
+	if err := opt.BeginStage(backend.StageSolve); err != nil {
+		return nil, nil, nil, nil, err
+	}
+	wireValues, err := r1cs.SolveWires(witness, a, b, c, opt)
+	opt.EndStage(backend.StageSolve)
+	if err != nil {
+		if !opt.Force {
+			return nil, nil, nil, nil, err
+		} else {
+			// we need to fill wireValues with random values else multi exps don't do much
+			var r fr.Element
+			_, _ = r.SetRandom()
+			for i := r1cs.GetNbPublicVariables() + r1cs.GetNbSecretVariables(); i < len(wireValues); i++ {
+				wireValues[i] = r
+				r.Double(&r)
+			}
+		}
+	}
+	return wireValues, a, b, c, nil
+}
+
+// filterInfinity returns a copy of wireValues without the values matching a point at infinity
+// in the proving key, as pk.G1.A, pk.G1.B and pk.G2.B may have (a significant) number of them
+func filterInfinity(wireValues []fr.Element, infinity []bool, nbInfinity uint64) []fr.Element {
+	res := make([]fr.Element, len(wireValues)-int(nbInfinity))
+	for i, j := 0, 0; j < len(res); i++ {
+		if infinity[i] {
+			continue
+		}
+		res[j] = wireValues[i]
+		j++
+	}
+	return res
+}
+
 // if len(toRemove) == 0, returns slice
 // else, returns a new slice without the indexes in toRemove
 // this assumes toRemove indexes are sorted and len(slice) > len(toRemove)
@@ -314,7 +377,7 @@ func filter(slice []fr.Element, toRemove []int) (r []fr.Element) {
 	return r
 }
 
-func computeH(a, b, c []fr.Element, domain *fft.Domain) []fr.Element {
+func computeH(a, b, c []fr.Element, domain *fft.Domain, pool *backend.WorkerPool) []fr.Element {
 	// H part of Krs
 	// Compute H (hz=ab-c, where z=-2 on ker X^n+1 (z(x)=x^n-1))
 	// 	1 - _a = ifft(a), _b = ifft(b), _c = ifft(c)
@@ -345,7 +408,7 @@ func computeH(a, b, c []fr.Element, domain *fft.Domain) []fr.Element {
 
 	// h = ifft_coset(ca o cb - cc)
 	// reusing a to avoid unecessary memalloc
-	utils.Parallelize(n, func(start, end int) {
+	pool.Parallelize(n, 1, func(start, end int) {
 		for i := start; i < end; i++ {
 			a[i].Mul(&a[i], &b[i]).
 				Sub(&a[i], &c[i]).
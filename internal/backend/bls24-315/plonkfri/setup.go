@@ -17,10 +17,8 @@
 package plonkfri
 
 import (
-	"crypto/sha256"
 	"github.com/consensys/gnark-crypto/ecc/bls24-315/fr"
 	"github.com/consensys/gnark-crypto/ecc/bls24-315/fr/fft"
-	"github.com/consensys/gnark-crypto/ecc/bls24-315/fr/fri"
 	"github.com/consensys/gnark/constraint/bls24-315"
 )
 
@@ -78,27 +76,32 @@ type VerifyingKey struct {
 
 	// S commitments to S1, S2, S3
 	SCanonical [3][]fr.Element
-	Spp        [3]fri.ProofOfProximity
+	Spp        [3]ProofOfProximity
 
 	// Id commitments to Id1, Id2, Id3
 	// Id   [3]Commitment
 	IdCanonical [3][]fr.Element
-	Idpp        [3]fri.ProofOfProximity
+	Idpp        [3]ProofOfProximity
 
 	// Commitments to ql, qr, qm, qo prepended with as many zeroes (ones for l) as there are public inputs.
 	// In particular Qk is not complete.
-	Qpp [5]fri.ProofOfProximity // Ql, Qr, Qm, Qo, Qk
+	Qpp [5]ProofOfProximity // Ql, Qr, Qm, Qo, Qk
 
 	// Iopp scheme (currently one for each size of polynomial)
-	Iopp fri.Iopp
+	Iopp Iopp
 
 	// generator of the group on which the Iopp works. If i is the opening position,
 	// the polynomials will be opened at genOpening^{i}.
 	GenOpening fr.Element
 }
 
-// Setup sets proving and verifying keys
-func Setup(spr *cs.SparseR1CS) (*ProvingKey, *VerifyingKey, error) {
+// Setup sets proving and verifying keys, the polynomials being committed with FRI
+// instantiated with params
+func Setup(spr *cs.SparseR1CS, params FRIParams) (*ProvingKey, *VerifyingKey, error) {
+
+	if err := params.Check(); err != nil {
+		return nil, nil, err
+	}
 
 	var pk ProvingKey
 	var vk VerifyingKey
@@ -130,14 +133,8 @@ func Setup(spr *cs.SparseR1CS) (*ProvingKey, *VerifyingKey, error) {
 	// IOP schemess
 	// The +2 is to handle the blinding.
 	sizeIopp := pk.Domain[0].Cardinality + 2
-	vk.Iopp = fri.RADIX_2_FRI.New(sizeIopp, sha256.New())
-	// only there to access the group used in FRI...
-	rho := uint64(fri.GetRho())
-	// we multiply by 2 because the IOP is created with size pk.Domain[0].Cardinality + 2 (because
-	// of the blinding), so the domain will be rho*size_domain where size_domain is the next power
-	// of 2 after pk.Domain[0].Cardinality + 2, which is 2*rho*pk.Domain[0].Cardinality
-	tmpDomain := fft.NewDomain(2 * rho * pk.Domain[0].Cardinality)
-	vk.GenOpening.Set(&tmpDomain.Generator)
+	vk.Iopp = NewIopp(sizeIopp, params)
+	vk.GenOpening = vk.Iopp.Generator()
 
 	// public polynomials corresponding to constraints: [ placholders | constraints | assertions ]
 	pk.EvaluationQlDomainBigBitReversed = make([]fr.Element, pk.Domain[1].Cardinality)
@@ -405,6 +402,12 @@ func getIDSmallDomain(domain *fft.Domain) []fr.Element {
 	return res
 }
 
+// SecurityLevel returns the conjectured security level in bits of the FRI
+// commitment scheme used by vk
+func (vk *VerifyingKey) SecurityLevel() int {
+	return vk.Iopp.SecurityLevel()
+}
+
 // NbPublicWitness returns the expected public witness size (number of field elements)
 func (vk *VerifyingKey) NbPublicWitness() int {
 	return int(vk.NbPublicVariables)
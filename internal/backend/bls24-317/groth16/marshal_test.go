@@ -22,8 +22,10 @@ import (
 	"github.com/consensys/gnark-crypto/ecc/bls24-317/fr/fft"
 
 	"bytes"
+	"encoding/binary"
 	"math/big"
 	"reflect"
+	"unsafe"
 
 	"github.com/leanovate/gopter"
 	"github.com/leanovate/gopter/prop"
@@ -174,7 +176,7 @@ func TestProvingKeySerialization(t *testing.T) {
 
 	properties.Property("ProvingKey -> writer -> reader -> ProvingKey should stay constant", prop.ForAll(
 		func(p1 curve.G1Affine, p2 curve.G2Affine) bool {
-			var pk, pkCompressed, pkRaw ProvingKey
+			var pk, pkCompressed, pkRaw, pkDump, pkMapped ProvingKey
 
 			// create a random pk
 			domain := fft.NewDomain(8)
@@ -183,8 +185,8 @@ func TestProvingKeySerialization(t *testing.T) {
 			nbWires := 6
 			nbPrivateWires := 4
 
-			// allocate our slices
-			pk.G1.A = make([]curve.G1Affine, nbWires)
+			// allocate our slices, the third wire of A is at infinity
+			pk.G1.A = make([]curve.G1Affine, nbWires-1)
 			pk.G1.B = make([]curve.G1Affine, nbWires)
 			pk.G1.K = make([]curve.G1Affine, nbPrivateWires)
 			pk.G1.Z = make([]curve.G1Affine, pk.Domain.Cardinality)
@@ -238,7 +240,31 @@ func TestProvingKeySerialization(t *testing.T) {
 				return false
 			}
 
-			return reflect.DeepEqual(&pk, &pkCompressed) && reflect.DeepEqual(&pk, &pkRaw)
+			var bufDump bytes.Buffer
+			written, err = pk.WriteDump(&bufDump)
+			if err != nil {
+				t.Log(err)
+				return false
+			}
+
+			read, err = pkDump.ReadDump(bytes.NewReader(bufDump.Bytes()))
+			if err != nil {
+				t.Log(err)
+				return false
+			}
+
+			if read != written {
+				t.Log("read dump != written")
+				return false
+			}
+
+			if err := pkMapped.UnsafeMapDump(bufDump.Bytes()); err != nil {
+				t.Log(err)
+				return false
+			}
+
+			return reflect.DeepEqual(&pk, &pkCompressed) && reflect.DeepEqual(&pk, &pkRaw) &&
+				reflect.DeepEqual(&pk, &pkDump) && reflect.DeepEqual(&pk, &pkMapped)
 		},
 		GenG1(),
 		GenG2(),
@@ -247,6 +273,109 @@ func TestProvingKeySerialization(t *testing.T) {
 	properties.TestingRun(t, gopter.ConsoleReporter(false))
 }
 
+func TestProvingKeyInvalidLength(t *testing.T) {
+	var pk ProvingKey
+	pk.Domain = *fft.NewDomain(8)
+	nbWires := 6
+	pk.G1.A = make([]curve.G1Affine, nbWires)
+	pk.G1.B = make([]curve.G1Affine, nbWires)
+	pk.G1.K = make([]curve.G1Affine, 2)
+	pk.G1.Z = make([]curve.G1Affine, pk.Domain.Cardinality)
+	pk.G2.B = make([]curve.G2Affine, nbWires)
+	pk.InfinityA = make([]bool, nbWires)
+	pk.InfinityB = make([]bool, nbWires)
+
+	// the number of wires precedes NbInfinityA, NbInfinityB and the infinity flags, it is set
+	// to a length which can't be allocated
+	const invalid = uint64(1) << 60
+	flagsLen := 8 + 8 + 8 + 2*nbWires
+
+	var buf bytes.Buffer
+	if _, err := pk.WriteRawTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	binary.BigEndian.PutUint64(data[len(data)-flagsLen:], invalid)
+	var pkRaw ProvingKey
+	if _, err := pkRaw.ReadFrom(bytes.NewReader(data)); err == nil {
+		t.Fatal("invalid number of wires accepted by ReadFrom")
+	}
+
+	// in a dump, the flags end the header, which follows its length
+	buf.Reset()
+	if _, err := pk.WriteDump(&buf); err != nil {
+		t.Fatal(err)
+	}
+	data = buf.Bytes()
+	nativeUint64 := func(v uint64) []byte {
+		var b [8]byte
+		*(*uint64)(unsafe.Pointer(&b[0])) = v
+		return b[:]
+	}
+	var headerLen uint64
+	copy((*[8]byte)(unsafe.Pointer(&headerLen))[:], data[16:24])
+	dump := append([]byte(nil), data...)
+	binary.BigEndian.PutUint64(dump[24+int(headerLen)-flagsLen:], invalid)
+	var pkDump ProvingKey
+	if _, err := pkDump.ReadDump(bytes.NewReader(dump)); err == nil {
+		t.Fatal("invalid number of wires accepted by ReadDump")
+	}
+	if err := pkDump.UnsafeMapDump(dump); err == nil {
+		t.Fatal("invalid number of wires accepted by UnsafeMapDump")
+	}
+
+	// invalid header length
+	dump = append([]byte(nil), data...)
+	copy(dump[16:24], nativeUint64(invalid))
+	if _, err := pkDump.ReadDump(bytes.NewReader(dump)); err == nil {
+		t.Fatal("invalid header length accepted by ReadDump")
+	}
+	if err := pkDump.UnsafeMapDump(dump); err == nil {
+		t.Fatal("invalid header length accepted by UnsafeMapDump")
+	}
+
+	// invalid length of the last point vector, [B]2
+	dump = append([]byte(nil), data...)
+	copy(dump[len(dump)-nbWires*int(unsafe.Sizeof(curve.G2Affine{}))-8:], nativeUint64(invalid))
+	if _, err := pkDump.ReadDump(bytes.NewReader(dump)); err == nil {
+		t.Fatal("invalid number of points accepted by ReadDump")
+	}
+	if err := pkDump.UnsafeMapDump(dump); err == nil {
+		t.Fatal("invalid number of points accepted by UnsafeMapDump")
+	}
+
+	// NbInfinityA doesn't match the infinity flags
+	dump = append([]byte(nil), data...)
+	binary.BigEndian.PutUint64(dump[24+int(headerLen)-flagsLen+8:], 1)
+	if _, err := pkDump.ReadDump(bytes.NewReader(dump)); err == nil {
+		t.Fatal("invalid number of points at infinity accepted by ReadDump")
+	}
+	if err := pkDump.UnsafeMapDump(dump); err == nil {
+		t.Fatal("invalid number of points at infinity accepted by UnsafeMapDump")
+	}
+
+	// an infinity flag of B is set, NbInfinityB and the number of points of [B]1, [B]2 are left
+	dump = append([]byte(nil), data...)
+	dump[24+int(headerLen)-nbWires] = 1
+	if _, err := pkDump.ReadDump(bytes.NewReader(dump)); err == nil {
+		t.Fatal("invalid infinity flags accepted by ReadDump")
+	}
+	if err := pkDump.UnsafeMapDump(dump); err == nil {
+		t.Fatal("invalid infinity flags accepted by UnsafeMapDump")
+	}
+
+	// consistent counts and flags, but [A]1 holds a point for a wire at infinity
+	dump = append([]byte(nil), data...)
+	binary.BigEndian.PutUint64(dump[24+int(headerLen)-flagsLen+8:], 1)
+	dump[24+int(headerLen)-2*nbWires] = 1
+	if _, err := pkDump.ReadDump(bytes.NewReader(dump)); err == nil {
+		t.Fatal("invalid number of points of [A]1 accepted by ReadDump")
+	}
+	if err := pkDump.UnsafeMapDump(dump); err == nil {
+		t.Fatal("invalid number of points of [A]1 accepted by UnsafeMapDump")
+	}
+}
+
 func GenG1() gopter.Gen {
 	_, _, g1GenAff, _ := curve.Generators()
 	return func(genParams *gopter.GenParameters) *gopter.GenResult {
@@ -17,13 +17,89 @@
 package groth16
 
 import (
+	"errors"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
 	curve "github.com/consensys/gnark-crypto/ecc/bn254"
 	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
 	"github.com/consensys/gnark/constraint"
-	"math/big"
 )
 
 func solveCommitmentWire(commitmentInfo *constraint.Commitment, commitment *curve.G1Affine, publicCommitted []*big.Int) (fr.Element, error) {
 	res, err := fr.Hash(commitmentInfo.SerializeCommitment(commitment.Marshal(), publicCommitted, (fr.Bits-1)/8+1), []byte(constraint.CommitmentDst), 1)
 	return res[0], err
 }
+
+// CommitmentKey is the pedersen key used to commit to the private committed wires, with a
+// proof of knowledge of the committed values: for values vᵢ, the commitment is Σ vᵢ⋅[Basisᵢ]1
+// and the proof Σ vᵢ⋅[σ⋅Basisᵢ]1, such that e(commitment, [G]2)⋅e(proof, [-G/σ]2) == 1.
+//
+// G and GRootSigmaNeg are exported, as the solidity verifier needs them.
+type CommitmentKey struct {
+	Basis         []curve.G1Affine
+	BasisExpSigma []curve.G1Affine // [σ⋅Basisᵢ]1
+	G             curve.G2Affine
+	GRootSigmaNeg curve.G2Affine // [-G/σ]2
+}
+
+// newCommitmentKey returns a commitment key for basis with random σ and G
+func newCommitmentKey(basis []curve.G1Affine) (CommitmentKey, error) {
+	k := CommitmentKey{Basis: basis}
+
+	var r, sigma fr.Element
+	if _, err := r.SetRandom(); err != nil {
+		return k, err
+	}
+	for sigma.IsZero() {
+		if _, err := sigma.SetRandom(); err != nil {
+			return k, err
+		}
+	}
+
+	var b big.Int
+	_, _, _, g2 := curve.Generators()
+	k.G.ScalarMultiplication(&g2, r.BigInt(&b))
+
+	var sigmaInvNeg fr.Element
+	sigmaInvNeg.Inverse(&sigma).Neg(&sigmaInvNeg)
+	k.GRootSigmaNeg.ScalarMultiplication(&k.G, sigmaInvNeg.BigInt(&b))
+
+	sigma.BigInt(&b)
+	k.BasisExpSigma = make([]curve.G1Affine, len(basis))
+	for i := range basis {
+		k.BasisExpSigma[i].ScalarMultiplication(&basis[i], &b)
+	}
+	return k, nil
+}
+
+// Commit returns the commitment to values, and the proof of knowledge of values
+func (k *CommitmentKey) Commit(values []fr.Element) (commitment, knowledgeProof curve.G1Affine, err error) {
+	if len(values) != len(k.Basis) {
+		err = errors.New("unexpected number of values")
+		return
+	}
+
+	config := ecc.MultiExpConfig{NbTasks: 1}
+	if _, err = commitment.MultiExp(k.Basis, values, config); err != nil {
+		return
+	}
+	_, err = knowledgeProof.MultiExp(k.BasisExpSigma, values, config)
+	return
+}
+
+// VerifyKnowledgeProof checks the proof of knowledge of the values of commitment
+func (k *CommitmentKey) VerifyKnowledgeProof(commitment, knowledgeProof curve.G1Affine) error {
+	if !commitment.IsInSubGroup() || !knowledgeProof.IsInSubGroup() {
+		return errors.New("subgroup check failed")
+	}
+
+	ok, err := curve.PairingCheck([]curve.G1Affine{commitment, knowledgeProof}, []curve.G2Affine{k.G, k.GRootSigmaNeg})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("proof rejected")
+	}
+	return nil
+}
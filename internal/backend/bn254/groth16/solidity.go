@@ -1,11 +1,104 @@
 package groth16
 
+//go:generate go test -run TestSolidity -solidity.generate
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fp"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/constraint"
+	"golang.org/x/crypto/sha3"
+)
+
+// solidityTemplateData is the data passed to solidityTemplate
+type solidityTemplateData struct {
+	*VerifyingKey
+
+	// NbPublicInputs is the size of the input array of verifyProof, that is
+	// the number of public variables, without the ONE_WIRE
+	NbPublicInputs int
+
+	// PublicCommitted are the indexes in the input array of the committed public inputs
+	PublicCommitted []int
+
+	// CommitmentDst is the domain separation tag used to hash the commitment
+	CommitmentDst string
+}
+
+func newSolidityTemplateData(vk *VerifyingKey) *solidityTemplateData {
+	data := &solidityTemplateData{
+		VerifyingKey:   vk,
+		NbPublicInputs: vk.NbPublicWitness(),
+		CommitmentDst:  constraint.CommitmentDst,
+	}
+	if vk.CommitmentInfo.Is() {
+		data.PublicCommitted = make([]int, vk.CommitmentInfo.NbPublicCommitted())
+		for i := range data.PublicCommitted {
+			data.PublicCommitted[i] = vk.CommitmentInfo.Committed[i] - 1
+		}
+	}
+	return data
+}
+
+// solidityUint256 formats a field element as a solidity uint256 literal. The String method of
+// the elements can't be used: it formats the elements close to the modulus as negative numbers.
+func solidityUint256(v interface{ BigInt(*big.Int) *big.Int }) string {
+	return v.BigInt(new(big.Int)).String()
+}
+
+// SolidityCalldata returns the ABI encoded call to the verifyProof method of the
+// contract generated by vk.ExportSolidity, for the given proof and public witness.
+func SolidityCalldata(proof *Proof, vk *VerifyingKey, publicWitness fr.Vector) ([]byte, error) {
+	nbInputs := vk.NbPublicWitness()
+	if len(publicWitness) != nbInputs {
+		return nil, fmt.Errorf("invalid witness size, got %d, expected %d", len(publicWitness), nbInputs)
+	}
+
+	signature := "verifyProof(uint256[2],uint256[2][2],uint256[2]"
+	if nbInputs > 0 {
+		signature += fmt.Sprintf(",uint256[%d]", nbInputs)
+	}
+	if vk.CommitmentInfo.Is() {
+		signature += ",uint256[2],uint256[2]"
+	}
+	signature += ")"
+
+	h := sha3.NewLegacyKeccak256()
+	h.Write([]byte(signature))
+	res := h.Sum(nil)[:4]
+
+	// all the arguments are static arrays of uint256, encoded in place
+	appendFp := func(elements ...*fp.Element) {
+		for _, e := range elements {
+			b := e.Bytes()
+			res = append(res, b[:]...)
+		}
+	}
+	appendFp(&proof.Ar.X, &proof.Ar.Y)
+	appendFp(&proof.Bs.X.A1, &proof.Bs.X.A0, &proof.Bs.Y.A1, &proof.Bs.Y.A0)
+	appendFp(&proof.Krs.X, &proof.Krs.Y)
+	for i := range publicWitness {
+		b := publicWitness[i].Bytes()
+		res = append(res, b[:]...)
+	}
+	if vk.CommitmentInfo.Is() {
+		appendFp(&proof.Commitment.X, &proof.Commitment.Y)
+		appendFp(&proof.CommitmentPok.X, &proof.CommitmentPok.Y)
+	}
+
+	return res, nil
+}
+
 // solidityTemplate based on an audited template https://github.com/appliedzkp/semaphore/blob/master/contracts/sol/verifier.sol
 // audit report https://github.com/appliedzkp/semaphore/blob/master/audit/Audit%20Report%20Summary%20for%20Semaphore%20and%20MicroMix.pdf
 // But some gas cost optimizations have been made.
 // this is an experimental feature and gnark solidity generator as not been thoroughly tested
 const solidityTemplate = `
 {{- $lenK := len .G1.K }}
+{{- $nbInputs := .NbPublicInputs }}
+{{- $hasCommitment := .CommitmentInfo.Is }}
 // SPDX-License-Identifier: AML
 //
 // Copyright 2017 Christian Reitwiessner
@@ -183,6 +276,36 @@ library Pairing {
 
         return out[0] != 0;
     }
+
+    /* @return The result of computing the pairing check
+     *         e(a1, a2) * e(b1, b2) == 1
+     */
+    function pairing2(
+        G1Point memory a1,
+        G2Point memory a2,
+        G1Point memory b1,
+        G2Point memory b2
+    ) internal view returns (bool) {
+
+        uint256[12] memory input = [
+            a1.X, a1.Y, a2.X[0], a2.X[1], a2.Y[0], a2.Y[1],
+            b1.X, b1.Y, b2.X[0], b2.X[1], b2.Y[0], b2.Y[1]
+        ];
+
+        uint256[1] memory out;
+        bool success;
+
+        // solium-disable-next-line security/no-inline-assembly
+        assembly {
+            success := staticcall(sub(gas(), 2000), 8, input, 0x180, out, 0x20)
+            // Use "invalid" to make gas estimation work
+            switch success case 0 { invalid() }
+        }
+
+        require(success,"pairing-opcode-failed");
+
+        return out[0] != 0;
+    }
 }
 
 contract Verifier {
@@ -207,10 +330,10 @@ contract Verifier {
     }
 
     function verifyingKey() internal pure returns (VerifyingKey memory vk) {
-        vk.alfa1 = Pairing.G1Point(uint256({{.G1.Alpha.X.String}}), uint256({{.G1.Alpha.Y.String}}));
-        vk.beta2 = Pairing.G2Point([uint256({{.G2.Beta.X.A1.String}}), uint256({{.G2.Beta.X.A0.String}})], [uint256({{.G2.Beta.Y.A1.String}}), uint256({{.G2.Beta.Y.A0.String}})]);
-        vk.gamma2 = Pairing.G2Point([uint256({{.G2.Gamma.X.A1.String}}), uint256({{.G2.Gamma.X.A0.String}})], [uint256({{.G2.Gamma.Y.A1.String}}), uint256({{.G2.Gamma.Y.A0.String}})]);
-        vk.delta2 = Pairing.G2Point([uint256({{.G2.Delta.X.A1.String}}), uint256({{.G2.Delta.X.A0.String}})], [uint256({{.G2.Delta.Y.A1.String}}), uint256({{.G2.Delta.Y.A0.String}})]);
+        vk.alfa1 = Pairing.G1Point(uint256({{uint256 .G1.Alpha.X}}), uint256({{uint256 .G1.Alpha.Y}}));
+        vk.beta2 = Pairing.G2Point([uint256({{uint256 .G2.Beta.X.A1}}), uint256({{uint256 .G2.Beta.X.A0}})], [uint256({{uint256 .G2.Beta.Y.A1}}), uint256({{uint256 .G2.Beta.Y.A0}})]);
+        vk.gamma2 = Pairing.G2Point([uint256({{uint256 .G2.Gamma.X.A1}}), uint256({{uint256 .G2.Gamma.X.A0}})], [uint256({{uint256 .G2.Gamma.Y.A1}}), uint256({{uint256 .G2.Gamma.Y.A0}})]);
+        vk.delta2 = Pairing.G2Point([uint256({{uint256 .G2.Delta.X.A1}}), uint256({{uint256 .G2.Delta.X.A0}})], [uint256({{uint256 .G2.Delta.Y.A1}}), uint256({{uint256 .G2.Delta.Y.A0}})]);
     }
 
 
@@ -235,6 +358,24 @@ contract Verifier {
         Pairing.plus_raw(buffer, q);
     }
 
+    {{- if $hasCommitment}}
+
+    /*
+     * @returns The hash of message to the scalar field, as computed by fr.Hash in gnark-crypto:
+     *          expand_message_xmd (RFC 9380) with SHA256 to 48 bytes, interpreted as a big endian
+     *          integer and reduced modulo the scalar field.
+     */
+    function hashToField(bytes memory message) internal view returns (uint256) {
+        bytes memory dst = "{{.CommitmentDst}}";
+        bytes32 b0 = sha256(abi.encodePacked(bytes32(0), bytes32(0), message, uint16(48), uint8(0), dst, uint8(dst.length)));
+        bytes32 b1 = sha256(abi.encodePacked(b0, uint8(1), dst, uint8(dst.length)));
+        bytes32 b2 = sha256(abi.encodePacked(b0 ^ b1, uint8(2), dst, uint8(dst.length)));
+
+        // b1 || b2[:16]
+        return addmod(mulmod(uint256(b1), 2**128, SNARK_SCALAR_FIELD), uint256(b2) >> 128, SNARK_SCALAR_FIELD);
+    }
+    {{- end}}
+
     /*
      * @returns Whether the proof is valid given the hardcoded verifying key
      *          above and the public inputs
@@ -242,8 +383,14 @@ contract Verifier {
     function verifyProof(
         uint256[2] memory a,
         uint256[2][2] memory b,
-        uint256[2] memory c,
-        uint256[{{sub $lenK 1}}] calldata input
+        uint256[2] memory c
+        {{- if gt $nbInputs 0}},
+        uint256[{{$nbInputs}}] calldata input
+        {{- end}}
+        {{- if $hasCommitment}},
+        uint256[2] memory commitment,
+        uint256[2] memory commitmentPok
+        {{- end}}
     ) public view returns (bool r) {
 
         Proof memory proof;
@@ -264,10 +411,13 @@ contract Verifier {
         require(proof.C.X < PRIME_Q, "verifier-cX-gte-prime-q");
         require(proof.C.Y < PRIME_Q, "verifier-cY-gte-prime-q");
 
+        {{- if gt $nbInputs 0}}
+
         // Make sure that every input is less than the snark scalar field
         for (uint256 i = 0; i < input.length; i++) {
             require(input[i] < SNARK_SCALAR_FIELD,"verifier-gte-snark-scalar-field");
         }
+        {{- end}}
 
         VerifyingKey memory vk = verifyingKey();
 
@@ -287,22 +437,60 @@ contract Verifier {
 
         {{- $k0 := index .G1.K 0}}
 
-        vk_x.X = uint256({{$k0.X.String}}); // vk.K[0].X
-        vk_x.Y = uint256({{$k0.Y.String}}); // vk.K[0].Y
+        vk_x.X = uint256({{uint256 $k0.X}}); // vk.K[0].X
+        vk_x.Y = uint256({{uint256 $k0.Y}}); // vk.K[0].Y
 
         {{- if eq (len .G1.K) 1}}
             // no public input, vk_x == vk.K[0]
         {{- end}}
         {{- range $i, $ki := .G1.K }}
-            {{- if gt $i 0 -}}
+            {{- if and (gt $i 0) (le $i $nbInputs) -}}
                 {{- $j := sub $i 1 }}
-        mul_input[0] = uint256({{$ki.X.String}}); // vk.K[{{$i}}].X
-        mul_input[1] = uint256({{$ki.Y.String}}); // vk.K[{{$i}}].Y
+        mul_input[0] = uint256({{uint256 $ki.X}}); // vk.K[{{$i}}].X
+        mul_input[1] = uint256({{uint256 $ki.Y}}); // vk.K[{{$i}}].Y
         mul_input[2] = input[{{$j}}];
         accumulate(mul_input, q, add_input, vk_x); // vk_x += vk.K[{{$i}}] * input[{{$j}}]
             {{- end -}}
         {{- end }}
 
+        {{- if $hasCommitment}}
+        {{- $kc := index .G1.K (sub $lenK 1)}}
+
+        // Make sure that the commitment and its proof of knowledge are less than the prime q
+        require(commitment[0] < PRIME_Q, "verifier-commitmentX-gte-prime-q");
+        require(commitment[1] < PRIME_Q, "verifier-commitmentY-gte-prime-q");
+        require(commitmentPok[0] < PRIME_Q, "verifier-commitmentPokX-gte-prime-q");
+        require(commitmentPok[1] < PRIME_Q, "verifier-commitmentPokY-gte-prime-q");
+
+        // Verify the proof of knowledge of the committed values: e(commitment, g) * e(commitmentPok, g^{-1/σ}) == 1
+        if (!Pairing.pairing2(
+            Pairing.G1Point(commitment[0], commitment[1]),
+            Pairing.G2Point([uint256({{uint256 .CommitmentKey.G.X.A1}}), uint256({{uint256 .CommitmentKey.G.X.A0}})], [uint256({{uint256 .CommitmentKey.G.Y.A1}}), uint256({{uint256 .CommitmentKey.G.Y.A0}})]),
+            Pairing.G1Point(commitmentPok[0], commitmentPok[1]),
+            Pairing.G2Point([uint256({{uint256 .CommitmentKey.GRootSigmaNeg.X.A1}}), uint256({{uint256 .CommitmentKey.GRootSigmaNeg.X.A0}})], [uint256({{uint256 .CommitmentKey.GRootSigmaNeg.Y.A1}}), uint256({{uint256 .CommitmentKey.GRootSigmaNeg.Y.A0}})])
+        )) {
+            return false;
+        }
+
+        // The commitment wire value is the hash of the commitment and of the committed public inputs
+        bytes memory commitmentMessage = abi.encodePacked(commitment[0], commitment[1]);
+        {{- range $j := .PublicCommitted }}
+        commitmentMessage = abi.encodePacked(commitmentMessage, input[{{$j}}]);
+        {{- end }}
+
+        mul_input[0] = uint256({{uint256 $kc.X}}); // vk.K[{{sub $lenK 1}}].X
+        mul_input[1] = uint256({{uint256 $kc.Y}}); // vk.K[{{sub $lenK 1}}].Y
+        mul_input[2] = hashToField(commitmentMessage);
+        accumulate(mul_input, q, add_input, vk_x); // vk_x += vk.K[{{sub $lenK 1}}] * hash(commitment, committed inputs)
+
+        // vk_x += commitment
+        add_input[0] = vk_x.X;
+        add_input[1] = vk_x.Y;
+        add_input[2] = commitment[0];
+        add_input[3] = commitment[1];
+        Pairing.plus_raw(add_input, vk_x);
+        {{- end }}
+
         return Pairing.pairing(
             Pairing.negate(proof.A),
             proof.B,
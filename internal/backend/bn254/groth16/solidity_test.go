@@ -0,0 +1,175 @@
+// Copyright 2020 ConsenSys Software Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package groth16_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fp"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/frontend"
+	groth16_bn254 "github.com/consensys/gnark/internal/backend/bn254/groth16"
+	"github.com/consensys/gnark/internal/evm"
+	"github.com/stretchr/testify/require"
+)
+
+var generateFixtures = flag.Bool("solidity.generate", false, "compile the solidity verifiers with solc and write the test fixtures in testdata/solidity")
+
+type threePublicCommittedCircuit struct {
+	Secret frontend.Variable
+	A, B   frontend.Variable `gnark:",public"`
+	C      frontend.Variable `gnark:",public"`
+}
+
+func (c *threePublicCommittedCircuit) Define(api frontend.API) error {
+	commit, err := api.Compiler().Commit(c.A, c.Secret, c.B, c.C)
+	if err != nil {
+		return err
+	}
+	api.AssertIsDifferent(commit, 0)
+	api.AssertIsEqual(api.Add(c.A, c.B, c.C), c.Secret)
+	return nil
+}
+
+// solidityFixture is a verifier compiled by solc and a valid proof for it, such that the
+// tests run the verifiers without solc. The fixtures are written by go generate.
+type solidityFixture struct {
+	VerifyingKey  *groth16_bn254.VerifyingKey
+	Proof         *groth16_bn254.Proof
+	PublicWitness fr.Vector
+	SourceHash    string // sha256 of the contract compiled to Bytecode
+	Bytecode      string
+}
+
+// writeSolidityFixture proves assignment, compiles the verifier with solc and writes the fixture
+func writeSolidityFixture(t *testing.T, path string, circuit, assignment frontend.Circuit) {
+	_r1cs, pk, vk := setup(t, circuit)
+	public, proof := prove(t, assignment, _r1cs, pk)
+	require.NoError(t, groth16.Verify(proof, vk, public))
+
+	var buf bytes.Buffer
+	require.NoError(t, vk.ExportSolidity(&buf))
+	code, err := evm.CompileSolidity(buf.Bytes(), "Verifier")
+	require.NoError(t, err)
+	hash := sha256.Sum256(buf.Bytes())
+
+	fixture := solidityFixture{
+		VerifyingKey:  vk.(*groth16_bn254.VerifyingKey),
+		Proof:         proof.(*groth16_bn254.Proof),
+		PublicWitness: public.Vector().(fr.Vector),
+		SourceHash:    hex.EncodeToString(hash[:]),
+		Bytecode:      hex.EncodeToString(code),
+	}
+	data, err := json.MarshalIndent(&fixture, "", "\t")
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, data, 0o644))
+}
+
+// testSolidity deploys the verifier of the fixture in the embedded evm and checks that it
+// accepts the proof of assignment, and rejects it once the calldata is tampered with.
+func testSolidity(t *testing.T, circuit, assignment frontend.Circuit, nbPublic int, hasCommitment bool) {
+	path := filepath.Join("testdata", "solidity", t.Name()+".json")
+	if *generateFixtures {
+		writeSolidityFixture(t, path, circuit, assignment)
+	}
+	data, err := os.ReadFile(path)
+	require.NoError(t, err, "missing fixture, run go generate (requires solc)")
+	var fixture solidityFixture
+	require.NoError(t, json.Unmarshal(data, &fixture))
+
+	// the fixture is compiled from the contract exported by the current template
+	var buf bytes.Buffer
+	require.NoError(t, fixture.VerifyingKey.ExportSolidity(&buf))
+	hash := sha256.Sum256(buf.Bytes())
+	require.Equal(t, fixture.SourceHash, hex.EncodeToString(hash[:]), "%s is out of date, run go generate (requires solc)", path)
+
+	calldata, err := groth16_bn254.SolidityCalldata(fixture.Proof, fixture.VerifyingKey, fixture.PublicWitness)
+	require.NoError(t, err)
+	expectedWords := 8 + nbPublic
+	if hasCommitment {
+		expectedWords += 4
+	}
+	require.Equal(t, 4+32*expectedWords, len(calldata))
+
+	code, err := hex.DecodeString(fixture.Bytecode)
+	require.NoError(t, err)
+	vm := evm.New()
+	addr, err := vm.Deploy(code)
+	require.NoError(t, err)
+
+	out, err := vm.StaticCall(addr, calldata)
+	require.NoError(t, err)
+	require.Equal(t, "1", new(big.Int).SetBytes(out).String())
+
+	// A.x out of the base field
+	tampered := append([]byte(nil), calldata...)
+	fp.Modulus().FillBytes(tampered[4 : 4+32])
+	_, err = vm.StaticCall(addr, tampered)
+	var revertErr *evm.RevertError
+	require.ErrorAs(t, err, &revertErr)
+	require.Equal(t, "execution reverted: verifier-aX-gte-prime-q", err.Error())
+
+	// -A instead of A, still on the curve
+	tampered = append([]byte(nil), calldata...)
+	var y fp.Element
+	y.SetBytes(tampered[4+32 : 4+64])
+	y.Neg(&y)
+	yBytes := y.Bytes()
+	copy(tampered[4+32:4+64], yBytes[:])
+	out, err = vm.StaticCall(addr, tampered)
+	require.NoError(t, err)
+	require.Equal(t, "0", new(big.Int).SetBytes(out).String())
+}
+
+func TestSolidityNoPublicInput(t *testing.T) {
+	testSolidity(t, &noCommitmentCircuit{}, &noCommitmentCircuit{One: 1}, 0, false)
+}
+
+func TestSolidityPublicInputs(t *testing.T) {
+	testSolidity(t, &singleSecretFauxCommitmentCircuit{}, &singleSecretFauxCommitmentCircuit{
+		One:        1,
+		Commitment: 2,
+	}, 2, false)
+}
+
+func TestSoliditySecretCommitted(t *testing.T) {
+	testSolidity(t, &singleSecretCommittedCircuit{}, &singleSecretCommittedCircuit{One: 1}, 0, true)
+}
+
+func TestSolidityPublicCommitted(t *testing.T) {
+	testSolidity(t, &oneSecretOnePublicCommittedCircuit{}, &oneSecretOnePublicCommittedCircuit{
+		One: 1,
+		Two: 2,
+	}, 1, true)
+}
+
+func TestSolidityThreePublicCommitted(t *testing.T) {
+	testSolidity(t, &threePublicCommittedCircuit{}, &threePublicCommittedCircuit{
+		Secret: 6,
+		A:      1,
+		B:      2,
+		C:      3,
+	}, 3, true)
+}
@@ -119,12 +119,17 @@ func Verify(proof *Proof, vk *VerifyingKey, publicWitness fr.Vector) error {
 // audit report https://github.com/appliedzkp/semaphore/blob/master/audit/Audit%20Report%20Summary%20for%20Semaphore%20and%20MicroMix.pdf
 // this is an experimental feature and gnark solidity generator as not been thoroughly tested.
 //
+// The generated verifyProof method takes the proof points, the public inputs (omitted if there
+// are none) and, if the circuit uses a commitment, the commitment and its proof of knowledge.
+// See SolidityCalldata to encode a call to it.
+//
 // See https://github.com/ConsenSys/gnark-tests for example usage.
 func (vk *VerifyingKey) ExportSolidity(w io.Writer) error {
 	helpers := template.FuncMap{
 		"sub": func(a, b int) int {
 			return a - b
 		},
+		"uint256": solidityUint256,
 	}
 
 	tmpl, err := template.New("").Funcs(helpers).Parse(solidityTemplate)
@@ -133,5 +138,5 @@ func (vk *VerifyingKey) ExportSolidity(w io.Writer) error {
 	}
 
 	// execute template
-	return tmpl.Execute(w, vk)
+	return tmpl.Execute(w, newSolidityTemplateData(vk))
 }
@@ -1,907 +1,560 @@
 package plonk
 
-const solidityTemplate = `
-// Warning this code was contributed into gnark here: 
-// https://github.com/ConsenSys/gnark/pull/358
-// 
-// It has not been audited and is provided as-is, we make no guarantees or warranties to its safety and reliability. 
-// 
-// According to https://eprint.iacr.org/archive/2019/953/1585767119.pdf
-pragma solidity ^0.8.0;
-pragma experimental ABIEncoderV2;
-
-library PairingsBn254 {
-    uint256 constant q_mod = 21888242871839275222246405745257275088696311157297823662689037894645226208583;
-    uint256 constant r_mod = 21888242871839275222246405745257275088548364400416034343698204186575808495617;
-    uint256 constant bn254_b_coeff = 3;
-
-    struct G1Point {
-        uint256 X;
-        uint256 Y;
-    }
-
-    struct Fr {
-        uint256 value;
-    }
-
-    function new_fr(uint256 fr) internal pure returns (Fr memory) {
-        require(fr < r_mod);
-        return Fr({value: fr});
-    }
+//go:generate go test -run TestSolidity -solidity.generate
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"golang.org/x/crypto/sha3"
+)
+
+// Layout of the serialized proof, see MarshalSolidity. Each entry is a uint256.
+const (
+	solidityProofLROCommitments      = 0  // [l]₁, [r]₁, [o]₁
+	solidityProofZCommitment         = 6  // [Z]₁
+	solidityProofHCommitments        = 8  // [h₁]₁, [h₂]₁, [h₃]₁
+	solidityProofClaimedValues       = 14 // h(ζ), linearizedPolynomial(ζ), l(ζ), r(ζ), o(ζ), s₁(ζ), s₂(ζ)
+	solidityProofZShiftedClaimed     = 21 // Z(μζ)
+	solidityProofBatchedOpening      = 22 // [W_ζ]₁
+	solidityProofZShiftedOpening     = 24 // [W_μζ]₁
+	solidityProofLength              = 26
+	solidityVerifySerializedProofSig = "verify_serialized_proof(uint256[],uint256[])"
+)
+
+// solidityUint256 formats a field element as a solidity uint256 literal. The String method of
+// the elements can't be used: it formats the elements close to the modulus as negative numbers.
+func solidityUint256(v interface{ BigInt(*big.Int) *big.Int }) string {
+	return v.BigInt(new(big.Int)).String()
+}
 
-    function copy(Fr memory self) internal pure returns (Fr memory n) {
-        n.value = self.value;
-    }
+// MarshalSolidity returns the proof encoded as the serialized_proof argument of the
+// verify_serialized_proof method of the contract generated by ExportSolidity, that is
+// a sequence of 26 big endian uint256:
+//
+//	[l]₁, [r]₁, [o]₁, [Z]₁, [h₁]₁, [h₂]₁, [h₃]₁ (x, y coordinates),
+//	h(ζ), linearizedPolynomial(ζ), l(ζ), r(ζ), o(ζ), s₁(ζ), s₂(ζ), Z(μζ),
+//	[W_ζ]₁, [W_μζ]₁ (x, y coordinates)
+func (proof *Proof) MarshalSolidity() []byte {
+	res := make([]byte, 0, solidityProofLength*32)
+
+	appendPoints := func(points ...*[2][32]byte) {
+		for _, p := range points {
+			res = append(res, p[0][:]...)
+			res = append(res, p[1][:]...)
+		}
+	}
+	coordinates := func(x, y [32]byte) *[2][32]byte {
+		return &[2][32]byte{x, y}
+	}
+
+	for i := range proof.LRO {
+		appendPoints(coordinates(proof.LRO[i].X.Bytes(), proof.LRO[i].Y.Bytes()))
+	}
+	appendPoints(coordinates(proof.Z.X.Bytes(), proof.Z.Y.Bytes()))
+	for i := range proof.H {
+		appendPoints(coordinates(proof.H[i].X.Bytes(), proof.H[i].Y.Bytes()))
+	}
+	for i := range proof.BatchedProof.ClaimedValues {
+		b := proof.BatchedProof.ClaimedValues[i].Bytes()
+		res = append(res, b[:]...)
+	}
+	b := proof.ZShiftedOpening.ClaimedValue.Bytes()
+	res = append(res, b[:]...)
+	appendPoints(coordinates(proof.BatchedProof.H.X.Bytes(), proof.BatchedProof.H.Y.Bytes()))
+	appendPoints(coordinates(proof.ZShiftedOpening.H.X.Bytes(), proof.ZShiftedOpening.H.Y.Bytes()))
+
+	return res
+}
 
-    function assign(Fr memory self, Fr memory other) internal pure {
-        self.value = other.value;
-    }
+// SolidityCalldata returns the ABI encoded call to the verify_serialized_proof method of
+// the contract generated by vk.ExportSolidity, for the given proof and public witness.
+func SolidityCalldata(proof *Proof, vk *VerifyingKey, publicWitness fr.Vector) ([]byte, error) {
+	if len(publicWitness) != int(vk.NbPublicVariables) {
+		return nil, fmt.Errorf("invalid witness size, got %d, expected %d", len(publicWitness), vk.NbPublicVariables)
+	}
+	if len(proof.BatchedProof.ClaimedValues) != 7 {
+		return nil, fmt.Errorf("invalid proof, got %d claimed values, expected 7", len(proof.BatchedProof.ClaimedValues))
+	}
+
+	h := sha3.NewLegacyKeccak256()
+	h.Write([]byte(solidityVerifySerializedProofSig))
+	res := h.Sum(nil)[:4]
+
+	word := func(v int) []byte {
+		var buf [32]byte
+		buf[24], buf[25], buf[26], buf[27] = byte(v>>56), byte(v>>48), byte(v>>40), byte(v>>32)
+		buf[28], buf[29], buf[30], buf[31] = byte(v>>24), byte(v>>16), byte(v>>8), byte(v)
+		return buf[:]
+	}
+
+	// head: offsets of the two dynamic arrays
+	res = append(res, word(2*32)...)
+	res = append(res, word(3*32+len(publicWitness)*32)...)
+
+	// public_inputs
+	res = append(res, word(len(publicWitness))...)
+	for i := range publicWitness {
+		b := publicWitness[i].Bytes()
+		res = append(res, b[:]...)
+	}
+
+	// serialized_proof
+	res = append(res, word(solidityProofLength)...)
+	res = append(res, proof.MarshalSolidity()...)
+
+	return res, nil
+}
 
-    function inverse(Fr memory fr) internal view returns (Fr memory) {
-        require(fr.value != 0);
-        return pow(fr, r_mod-2);
-    }
+// solidityTemplate is the template of the PLONK verifier contract. The verification
+// algorithm follows Verify step by step, including the derivation of the challenges,
+// so that a proof accepted by Verify is accepted by the contract.
+const solidityTemplate = `
+// SPDX-License-Identifier: Apache-2.0
+
+// Copyright 2020 ConsenSys Software Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Code generated by gnark DO NOT EDIT
+//
+// It has not been audited and is provided as-is, we make no guarantees or warranties to its safety and reliability.
 
-    function add_assign(Fr memory self, Fr memory other) internal pure {
-        self.value = addmod(self.value, other.value, r_mod);
-    }
+pragma solidity ^0.8.0;
 
-    function sub_assign(Fr memory self, Fr memory other) internal pure {
-        self.value = addmod(self.value, r_mod - other.value, r_mod);
-    }
+library Bn254 {
 
-    function mul_assign(Fr memory self, Fr memory other) internal pure {
-        self.value = mulmod(self.value, other.value, r_mod);
-    }
+    uint256 constant P_MOD = 21888242871839275222246405745257275088696311157297823662689037894645226208583;
+    uint256 constant R_MOD = 21888242871839275222246405745257275088548364400416034343698204186575808495617;
 
-    function pow(Fr memory self, uint256 power) internal view returns (Fr memory) {
-        uint256[6] memory input = [32, 32, 32, self.value, power, r_mod];
-        uint256[1] memory result;
-        bool success;
-        assembly {
-            success := staticcall(gas(), 0x05, input, 0xc0, result, 0x20)
-        }
-        require(success);
-        return Fr({value: result[0]});
+    struct G1Point {
+        uint256 X;
+        uint256 Y;
     }
 
     // Encoding of field elements is: X[0] * z + X[1]
     struct G2Point {
-        uint[2] X;
-        uint[2] Y;
-    }
-
-    function P1() internal pure returns (G1Point memory) {
-        return G1Point(1, 2);
-    }
-
-    function new_g1(uint256 x, uint256 y) internal pure returns (G1Point memory) {
-        return G1Point(x, y);
-    }
-
-    function new_g1_checked(uint256 x, uint256 y) internal pure returns (G1Point memory) {
-        if (x == 0 && y == 0) {
-            // point of infinity is (0,0)
-            return G1Point(x, y);
-        }
-
-        // check encoding
-        require(x < q_mod);
-        require(y < q_mod);
-        // check on curve
-        uint256 lhs = mulmod(y, y, q_mod); // y^2
-        uint256 rhs = mulmod(x, x, q_mod); // x^2
-        rhs = mulmod(rhs, x, q_mod); // x^3
-        rhs = addmod(rhs, bn254_b_coeff, q_mod); // x^3 + b
-        require(lhs == rhs);
-
-        return G1Point(x, y);
-    }
-
-    function new_g2(uint256[2] memory x, uint256[2] memory y) internal pure returns (G2Point memory) {
-        return G2Point(x, y);
+        uint256[2] X;
+        uint256[2] Y;
     }
 
-    function copy_g1(G1Point memory self) internal pure returns (G1Point memory result) {
-        result.X = self.X;
-        result.Y = self.Y;
-    }
-
-    function P2() internal pure returns (G2Point memory) {
-        // for some reason ethereum expects to have c1*v + c0 form
-
-        return G2Point(
-            [0x198e9393920d483a7260bfb731fb5d25f1aa493335a9e71297e485b7aef312c2,
-            0x1800deef121f1e76426a00665e5c4479674322d4f75edadd46debd5cd992f6ed],
-            [0x090689d0585ff075ec9e99ad690c3395bc4b313370b38ef355acdadcd122975b,
-            0x12c85ea5db8c6deb4aab71808dcb408fe3d1e7690c43d37b4ce6cc0166fa7daa]
-        );
-    }
-
-    function negate(G1Point memory self) internal pure {
-        // The prime q in the base field F_q for G1
-        if (self.Y == 0) {
-            require(self.X == 0);
-            return;
+    // @return p + q
+    function add(G1Point memory p, G1Point memory q) internal view returns (G1Point memory r) {
+        uint256[4] memory input = [p.X, p.Y, q.X, q.Y];
+        bool success;
+        // solium-disable-next-line security/no-inline-assembly
+        assembly {
+            success := staticcall(gas(), 0x06, input, 0x80, r, 0x40)
         }
-
-        self.Y = q_mod - self.Y;
+        require(success, "ec-add-failed");
     }
 
-    function point_add(G1Point memory p1, G1Point memory p2)
-    internal view returns (G1Point memory r)
-    {
-        point_add_into_dest(p1, p2, r);
-        return r;
-    }
-
-    function point_add_assign(G1Point memory p1, G1Point memory p2)
-    internal view
-    {
-        point_add_into_dest(p1, p2, p1);
-    }
-
-    function point_add_into_dest(G1Point memory p1, G1Point memory p2, G1Point memory dest)
-    internal view
-    {
-        if (p2.X == 0 && p2.Y == 0) {
-            // we add zero, nothing happens
-            dest.X = p1.X;
-            dest.Y = p1.Y;
-            return;
-        } else if (p1.X == 0 && p1.Y == 0) {
-            // we add into zero, and we add non-zero point
-            dest.X = p2.X;
-            dest.Y = p2.Y;
-            return;
-        } else {
-            uint256[4] memory input;
-
-            input[0] = p1.X;
-            input[1] = p1.Y;
-            input[2] = p2.X;
-            input[3] = p2.Y;
-
-            bool success = false;
-            assembly {
-                success := staticcall(gas(), 6, input, 0x80, dest, 0x40)
-            }
-            require(success);
+    // @return s * p
+    function mul(G1Point memory p, uint256 s) internal view returns (G1Point memory r) {
+        uint256[3] memory input = [p.X, p.Y, s];
+        bool success;
+        // solium-disable-next-line security/no-inline-assembly
+        assembly {
+            success := staticcall(gas(), 0x07, input, 0x60, r, 0x40)
         }
+        require(success, "ec-mul-failed");
     }
 
-    function point_sub_assign(G1Point memory p1, G1Point memory p2)
-    internal view
-    {
-        point_sub_into_dest(p1, p2, p1);
-    }
-
-    function point_sub_into_dest(G1Point memory p1, G1Point memory p2, G1Point memory dest)
-    internal view
-    {
-        if (p2.X == 0 && p2.Y == 0) {
-            // we subtracted zero, nothing happens
-            dest.X = p1.X;
-            dest.Y = p1.Y;
-            return;
-        } else if (p1.X == 0 && p1.Y == 0) {
-            // we subtract from zero, and we subtract non-zero point
-            dest.X = p2.X;
-            dest.Y = q_mod - p2.Y;
-            return;
-        } else {
-            uint256[4] memory input;
-
-            input[0] = p1.X;
-            input[1] = p1.Y;
-            input[2] = p2.X;
-            input[3] = q_mod - p2.Y;
-
-            bool success = false;
-            assembly {
-                success := staticcall(gas(), 6, input, 0x80, dest, 0x40)
-            }
-            require(success);
+    // @return -p
+    function neg(G1Point memory p) internal pure returns (G1Point memory) {
+        if (p.X == 0 && p.Y == 0) {
+            return G1Point(0, 0);
         }
+        return G1Point(p.X, P_MOD - (p.Y % P_MOD));
     }
 
-    function point_mul(G1Point memory p, Fr memory s)
-    internal view returns (G1Point memory r)
-    {
-        point_mul_into_dest(p, s, r);
-        return r;
-    }
-
-    function point_mul_assign(G1Point memory p, Fr memory s)
-    internal view
-    {
-        point_mul_into_dest(p, s, p);
-    }
-
-    function point_mul_into_dest(G1Point memory p, Fr memory s, G1Point memory dest)
-    internal view
-    {
-        uint[3] memory input;
-        input[0] = p.X;
-        input[1] = p.Y;
-        input[2] = s.value;
+    // @return e(a1, a2) * e(b1, b2) == 1
+    function pairing2(
+        G1Point memory a1,
+        G2Point memory a2,
+        G1Point memory b1,
+        G2Point memory b2
+    ) internal view returns (bool) {
+        uint256[12] memory input = [
+            a1.X, a1.Y, a2.X[0], a2.X[1], a2.Y[0], a2.Y[1],
+            b1.X, b1.Y, b2.X[0], b2.X[1], b2.Y[0], b2.Y[1]
+        ];
+        uint256[1] memory out;
         bool success;
+        // solium-disable-next-line security/no-inline-assembly
         assembly {
-            success := staticcall(gas(), 7, input, 0x60, dest, 0x40)
+            success := staticcall(gas(), 0x08, input, 0x180, out, 0x20)
         }
-        require(success);
+        require(success, "pairing-failed");
+        return out[0] == 1;
     }
 
-    function pairing(G1Point[] memory p1, G2Point[] memory p2)
-    internal view returns (bool)
-    {
-        require(p1.length == p2.length);
-        uint elements = p1.length;
-        uint inputSize = elements * 6;
-        uint[] memory input = new uint[](inputSize);
-        for (uint i = 0; i < elements; i++)
-        {
-            input[i * 6 + 0] = p1[i].X;
-            input[i * 6 + 1] = p1[i].Y;
-            input[i * 6 + 2] = p2[i].X[0];
-            input[i * 6 + 3] = p2[i].X[1];
-            input[i * 6 + 4] = p2[i].Y[0];
-            input[i * 6 + 5] = p2[i].Y[1];
-        }
-        uint[1] memory out;
+    // @return b^e mod R_MOD
+    function pow(uint256 b, uint256 e) internal view returns (uint256) {
+        uint256[6] memory input = [uint256(0x20), 0x20, 0x20, b, e, R_MOD];
+        uint256[1] memory out;
         bool success;
+        // solium-disable-next-line security/no-inline-assembly
         assembly {
-            success := staticcall(gas(), 8, add(input, 0x20), mul(inputSize, 0x20), out, 0x20)
+            success := staticcall(gas(), 0x05, input, 0xc0, out, 0x20)
         }
-        require(success);
-        return out[0] != 0;
-    }
-
-    /// Convenience method for a pairing check for two pairs.
-    function pairingProd2(G1Point memory a1, G2Point memory a2, G1Point memory b1, G2Point memory b2)
-    internal view returns (bool)
-    {
-        G1Point[] memory p1 = new G1Point[](2);
-        G2Point[] memory p2 = new G2Point[](2);
-        p1[0] = a1;
-        p1[1] = b1;
-        p2[0] = a2;
-        p2[1] = b2;
-        return pairing(p1, p2);
-    }
-}
-
-library TranscriptLibrary {
-    uint32 constant DST_0 = 0;
-    uint32 constant DST_1 = 1;
-    uint32 constant DST_CHALLENGE = 2;
-
-    struct Transcript {
-        bytes32 previous_randomness;
-        bytes bindings;
-        string name;
-        uint32 challenge_counter;
-    }
-
-    function new_transcript() internal pure returns (Transcript memory t) {
-        t.challenge_counter = 0;
-    }
-
-    function set_challenge_name(Transcript memory self, string memory name) internal pure {
-        self.name = name;
-    }
-
-    function update_with_u256(Transcript memory self, uint256 value) internal pure {
-        self.bindings = abi.encodePacked(self.bindings, value);
-    }
-
-    function update_with_fr(Transcript memory self, PairingsBn254.Fr memory value) internal pure {
-        self.bindings = abi.encodePacked(self.bindings, value.value);
+        require(success, "modexp-failed");
+        return out[0];
     }
 
-    function update_with_g1(Transcript memory self, PairingsBn254.G1Point memory p) internal pure {
-        self.bindings = abi.encodePacked(self.bindings, p.X, p.Y);
-    }
-
-    function get_encode(Transcript memory self) internal pure returns(bytes memory query) {
-        if (self.challenge_counter != 0) {
-            query = abi.encodePacked(self.name, self.previous_randomness, self.bindings);
-        } else {
-            query = abi.encodePacked(self.name, self.bindings);
-        }
-        return query;
-    }
-    function get_challenge(Transcript memory self) internal pure returns(PairingsBn254.Fr memory challenge) {
-        bytes32 query;
-        if (self.challenge_counter != 0) {
-            query = sha256(abi.encodePacked(self.name, self.previous_randomness, self.bindings));
-        } else {
-            query = sha256(abi.encodePacked(self.name, self.bindings));
-        }
-        self.challenge_counter += 1;
-        self.previous_randomness = query;
-        challenge = PairingsBn254.Fr({value: uint256(query) % PairingsBn254.r_mod});
-        self.bindings = "";
+    // @return x⁻¹ mod R_MOD
+    function inverse(uint256 x) internal view returns (uint256) {
+        require(x != 0, "inverse-of-zero");
+        return pow(x, R_MOD - 2);
     }
 }
 
 contract PlonkVerifier {
-    using PairingsBn254 for PairingsBn254.G1Point;
-    using PairingsBn254 for PairingsBn254.G2Point;
-    using PairingsBn254 for PairingsBn254.Fr;
-
-    using TranscriptLibrary for TranscriptLibrary.Transcript;
-
-    uint256 constant STATE_WIDTH = 3;
-
-    struct VerificationKey {
-        uint256 domain_size;
-        uint256 num_inputs;
-        PairingsBn254.Fr omega;                                     // w
-        PairingsBn254.G1Point[STATE_WIDTH+2] selector_commitments;  // STATE_WIDTH for witness + multiplication + constant
-        PairingsBn254.G1Point[STATE_WIDTH] permutation_commitments; // [Sσ1(x)],[Sσ2(x)],[Sσ3(x)]
-        PairingsBn254.Fr[STATE_WIDTH-1] permutation_non_residues;   // k1, k2
-        PairingsBn254.G2Point g2_x;
-    }
 
-    struct Proof {
-        uint256[] input_values;
-        PairingsBn254.G1Point[STATE_WIDTH] wire_commitments;  // [a(x)]/[b(x)]/[c(x)]
-        PairingsBn254.G1Point grand_product_commitment;      // [z(x)]
-        PairingsBn254.G1Point[STATE_WIDTH] quotient_poly_commitments;  // [t_lo]/[t_mid]/[t_hi]
-        PairingsBn254.Fr[STATE_WIDTH] wire_values_at_zeta;   // a(zeta)/b(zeta)/c(zeta)
-        PairingsBn254.Fr grand_product_at_zeta_omega;        // z(w*zeta)
-        PairingsBn254.Fr quotient_polynomial_at_zeta;        // t(zeta)
-        PairingsBn254.Fr linearization_polynomial_at_zeta;   // r(zeta)
-        PairingsBn254.Fr[STATE_WIDTH-1] permutation_polynomials_at_zeta;  // Sσ1(zeta),Sσ2(zeta)
-
-        PairingsBn254.G1Point opening_at_zeta_proof;            // [Wzeta]
-        PairingsBn254.G1Point opening_at_zeta_omega_proof;      // [Wzeta*omega]
-    }
-
-    struct PartialVerifierState {
-        PairingsBn254.Fr alpha;
-        PairingsBn254.Fr beta;
-        PairingsBn254.Fr gamma;
-        PairingsBn254.Fr v;
-        PairingsBn254.Fr u;
-        PairingsBn254.Fr zeta;
-        PairingsBn254.Fr[] cached_lagrange_evals;
-
-        PairingsBn254.G1Point cached_fold_quotient_ploy_commitments;
-    }
-
-    function verify_initial(
-		PartialVerifierState memory state,
-        Proof memory proof,
-        VerificationKey memory vk) internal view returns (bool) {
-
-        require(proof.input_values.length == vk.num_inputs, "not match");
-        require(vk.num_inputs >= 1, "inv input");
-        
-        TranscriptLibrary.Transcript memory t = TranscriptLibrary.new_transcript();
-        t.set_challenge_name("gamma");
-        for (uint256 i = 0; i < vk.permutation_commitments.length; i++) {
-            t.update_with_g1(vk.permutation_commitments[i]);
-        }
-        // this is gnark order: Ql, Qr, Qm, Qo, Qk
-        //
-        t.update_with_g1(vk.selector_commitments[0]);
-        t.update_with_g1(vk.selector_commitments[1]);
-        t.update_with_g1(vk.selector_commitments[3]);
-        t.update_with_g1(vk.selector_commitments[2]);
-        t.update_with_g1(vk.selector_commitments[4]);
-
-        for (uint256 i = 0; i < proof.input_values.length; i++) {
-            t.update_with_u256(proof.input_values[i]);
-        }
-        state.gamma = t.get_challenge();
-
-        t.set_challenge_name("beta");
-        state.beta = t.get_challenge();
-
-        t.set_challenge_name("alpha");
-        t.update_with_g1(proof.grand_product_commitment);
-        state.alpha = t.get_challenge();
-
-        t.set_challenge_name("zeta");
-        for (uint256 i = 0; i < proof.quotient_poly_commitments.length; i++) {
-            t.update_with_g1(proof.quotient_poly_commitments[i]);
-        }
-        state.zeta = t.get_challenge();
-
-        uint256[] memory lagrange_poly_numbers = new uint256[](vk.num_inputs);
-        for (uint256 i = 0; i < lagrange_poly_numbers.length; i++) {
-            lagrange_poly_numbers[i] = i;
+    uint256 constant P_MOD = 21888242871839275222246405745257275088696311157297823662689037894645226208583;
+    uint256 constant R_MOD = 21888242871839275222246405745257275088548364400416034343698204186575808495617;
+
+    // verifying key
+    uint256 constant VK_NB_PUBLIC_INPUTS = {{.NbPublicVariables}};
+    uint256 constant VK_DOMAIN_SIZE = {{.Size}};
+    uint256 constant VK_INV_DOMAIN_SIZE = {{uint256 .SizeInv}};
+    uint256 constant VK_OMEGA = {{uint256 .Generator}};
+    uint256 constant VK_COSET_SHIFT = {{uint256 .CosetShift}};
+
+    uint256 constant VK_QL_COM_X = {{uint256 .Ql.X}};
+    uint256 constant VK_QL_COM_Y = {{uint256 .Ql.Y}};
+    uint256 constant VK_QR_COM_X = {{uint256 .Qr.X}};
+    uint256 constant VK_QR_COM_Y = {{uint256 .Qr.Y}};
+    uint256 constant VK_QM_COM_X = {{uint256 .Qm.X}};
+    uint256 constant VK_QM_COM_Y = {{uint256 .Qm.Y}};
+    uint256 constant VK_QO_COM_X = {{uint256 .Qo.X}};
+    uint256 constant VK_QO_COM_Y = {{uint256 .Qo.Y}};
+    uint256 constant VK_QK_COM_X = {{uint256 .Qk.X}};
+    uint256 constant VK_QK_COM_Y = {{uint256 .Qk.Y}};
+
+    uint256 constant VK_S1_COM_X = {{uint256 (index .S 0).X}};
+    uint256 constant VK_S1_COM_Y = {{uint256 (index .S 0).Y}};
+    uint256 constant VK_S2_COM_X = {{uint256 (index .S 1).X}};
+    uint256 constant VK_S2_COM_Y = {{uint256 (index .S 1).Y}};
+    uint256 constant VK_S3_COM_X = {{uint256 (index .S 2).X}};
+    uint256 constant VK_S3_COM_Y = {{uint256 (index .S 2).Y}};
+
+    // kzg srs: [1]₁, [1]₂, [α]₂
+    uint256 constant VK_G1_SRS_X = {{uint256 (index .KZGSRS.G1 0).X}};
+    uint256 constant VK_G1_SRS_Y = {{uint256 (index .KZGSRS.G1 0).Y}};
+    uint256 constant VK_G2_SRS_0_X_0 = {{uint256 (index .KZGSRS.G2 0).X.A1}};
+    uint256 constant VK_G2_SRS_0_X_1 = {{uint256 (index .KZGSRS.G2 0).X.A0}};
+    uint256 constant VK_G2_SRS_0_Y_0 = {{uint256 (index .KZGSRS.G2 0).Y.A1}};
+    uint256 constant VK_G2_SRS_0_Y_1 = {{uint256 (index .KZGSRS.G2 0).Y.A0}};
+    uint256 constant VK_G2_SRS_1_X_0 = {{uint256 (index .KZGSRS.G2 1).X.A1}};
+    uint256 constant VK_G2_SRS_1_X_1 = {{uint256 (index .KZGSRS.G2 1).X.A0}};
+    uint256 constant VK_G2_SRS_1_Y_0 = {{uint256 (index .KZGSRS.G2 1).Y.A1}};
+    uint256 constant VK_G2_SRS_1_Y_1 = {{uint256 (index .KZGSRS.G2 1).Y.A0}};
+
+    // layout of the serialized proof
+    uint256 constant PROOF_L_COM_X = 0;
+    uint256 constant PROOF_R_COM_X = 2;
+    uint256 constant PROOF_O_COM_X = 4;
+    uint256 constant PROOF_Z_COM_X = 6;
+    uint256 constant PROOF_H_0_COM_X = 8;
+    uint256 constant PROOF_H_1_COM_X = 10;
+    uint256 constant PROOF_H_2_COM_X = 12;
+    uint256 constant PROOF_H_AT_ZETA = 14;
+    uint256 constant PROOF_LINEARIZED_POLYNOMIAL_AT_ZETA = 15;
+    uint256 constant PROOF_L_AT_ZETA = 16;
+    uint256 constant PROOF_R_AT_ZETA = 17;
+    uint256 constant PROOF_O_AT_ZETA = 18;
+    uint256 constant PROOF_S1_AT_ZETA = 19;
+    uint256 constant PROOF_S2_AT_ZETA = 20;
+    uint256 constant PROOF_Z_AT_ZETA_OMEGA = 21;
+    uint256 constant PROOF_BATCH_OPENING_AT_ZETA_X = 22;
+    uint256 constant PROOF_OPENING_AT_ZETA_OMEGA_X = 24;
+    uint256 constant PROOF_LENGTH = 26;
+
+    struct State {
+        // challenges
+        uint256 gamma;
+        uint256 beta;
+        uint256 alpha;
+        uint256 zeta;
+
+        uint256 zeta_power_n_minus_one; // ζⁿ-1
+        uint256 lagrange_0;             // L₁(ζ)
+        uint256 pi;                     // ∑ᵢwᵢLᵢ(ζ)
+        uint256 alpha_square_lagrange_0;// α²L₁(ζ)
+
+        Bn254.G1Point folded_h;              // [h₁]₁ + ζⁿ⁺²[h₂]₁ + ζ²⁽ⁿ⁺²⁾[h₃]₁
+        Bn254.G1Point linearized_polynomial; // commitment to the linearized polynomial
+
+        // batch opening at ζ, folded with the kzg challenge
+        Bn254.G1Point folded_digests;
+        uint256 folded_evals;
+    }
+
+    /*
+     * @returns Whether the proof is valid given the hardcoded verifying key above
+     *          and the public inputs. serialized_proof is the output of MarshalSolidity
+     *          in gnark.
+     */
+    function verify_serialized_proof(
+        uint256[] calldata public_inputs,
+        uint256[] calldata serialized_proof
+    ) public view returns (bool) {
+        require(public_inputs.length == VK_NB_PUBLIC_INPUTS, "wrong-number-of-public-inputs");
+        require(serialized_proof.length == PROOF_LENGTH, "wrong-proof-length");
+        check_inputs(public_inputs, serialized_proof);
+
+        State memory state;
+        derive_challenges(state, public_inputs, serialized_proof);
+        compute_public_inputs(state, public_inputs);
+        if (!verify_quotient(state, serialized_proof)) {
+            return false;
         }
-        state.cached_lagrange_evals = batch_evaluate_lagrange_poly_out_of_domain(
-            lagrange_poly_numbers,
-            vk.domain_size,
-            vk.omega, state.zeta
-        );
-
-        bool valid = verify_quotient_poly_eval_at_zeta(state, proof, vk);
-        return valid;
+        fold_h(state, serialized_proof);
+        compute_linearized_polynomial(state, serialized_proof);
+        fold_proof(state, serialized_proof);
+        return batch_verify_multi_points(state, serialized_proof);
     }
 
-    function verify_commitments(
-        PartialVerifierState memory state,
-        Proof memory proof,
-        VerificationKey memory vk
-    ) internal view returns (bool) {
-        PairingsBn254.G1Point memory d = reconstruct_d(state, proof, vk);
-
-        PairingsBn254.G1Point memory tmp_g1 = PairingsBn254.P1();
-
-        PairingsBn254.Fr memory aggregation_challenge = PairingsBn254.new_fr(1);
-
-        PairingsBn254.G1Point memory commitment_aggregation = PairingsBn254.copy_g1(state.cached_fold_quotient_ploy_commitments);
-        PairingsBn254.Fr memory tmp_fr = PairingsBn254.new_fr(1);
-
-        aggregation_challenge.mul_assign(state.v);
-        commitment_aggregation.point_add_assign(d);
-
-        for (uint i = 0; i < proof.wire_commitments.length; i++) {
-            aggregation_challenge.mul_assign(state.v);
-            tmp_g1 = proof.wire_commitments[i].point_mul(aggregation_challenge);
-            commitment_aggregation.point_add_assign(tmp_g1);
+    // check_inputs ensures that the scalars are less than R_MOD and the coordinates less than P_MOD.
+    // The points are checked to be on the curve by the precompiles.
+    function check_inputs(uint256[] calldata public_inputs, uint256[] calldata proof) internal pure {
+        for (uint256 i = 0; i < public_inputs.length; i++) {
+            require(public_inputs[i] < R_MOD, "public-input-gte-r");
         }
-
-        for (uint i = 0; i < vk.permutation_commitments.length - 1; i++) {
-            aggregation_challenge.mul_assign(state.v);
-            tmp_g1 = vk.permutation_commitments[i].point_mul(aggregation_challenge);
-            commitment_aggregation.point_add_assign(tmp_g1);
+        for (uint256 i = 0; i < PROOF_H_AT_ZETA; i++) {
+            require(proof[i] < P_MOD, "proof-coordinate-gte-p");
         }
-
-        // collect opening values
-        aggregation_challenge = PairingsBn254.new_fr(1);
-
-        PairingsBn254.Fr memory aggregated_value = PairingsBn254.copy(proof.quotient_polynomial_at_zeta);
-
-        aggregation_challenge.mul_assign(state.v);
-
-        tmp_fr.assign(proof.linearization_polynomial_at_zeta);
-        tmp_fr.mul_assign(aggregation_challenge);
-        aggregated_value.add_assign(tmp_fr);
-
-        for (uint i = 0; i < proof.wire_values_at_zeta.length; i++) {
-            aggregation_challenge.mul_assign(state.v);
-
-            tmp_fr.assign(proof.wire_values_at_zeta[i]);
-            tmp_fr.mul_assign(aggregation_challenge);
-            aggregated_value.add_assign(tmp_fr);
+        for (uint256 i = PROOF_H_AT_ZETA; i < PROOF_BATCH_OPENING_AT_ZETA_X; i++) {
+            require(proof[i] < R_MOD, "proof-scalar-gte-r");
         }
-
-        for (uint i = 0; i < proof.permutation_polynomials_at_zeta.length; i++) {
-            aggregation_challenge.mul_assign(state.v);
-
-            tmp_fr.assign(proof.permutation_polynomials_at_zeta[i]);
-            tmp_fr.mul_assign(aggregation_challenge);
-            aggregated_value.add_assign(tmp_fr);
+        for (uint256 i = PROOF_BATCH_OPENING_AT_ZETA_X; i < PROOF_LENGTH; i++) {
+            require(proof[i] < P_MOD, "proof-coordinate-gte-p");
         }
-        tmp_fr.assign(proof.grand_product_at_zeta_omega);
-        tmp_fr.mul_assign(state.u);
-        aggregated_value.add_assign(tmp_fr);
-
-        commitment_aggregation.point_sub_assign(PairingsBn254.P1().point_mul(aggregated_value));
-
-        PairingsBn254.G1Point memory pair_with_generator = commitment_aggregation;
-        pair_with_generator.point_add_assign(proof.opening_at_zeta_proof.point_mul(state.zeta));
-
-        tmp_fr.assign(state.zeta);
-        tmp_fr.mul_assign(vk.omega);
-        tmp_fr.mul_assign(state.u);
-        pair_with_generator.point_add_assign(proof.opening_at_zeta_omega_proof.point_mul(tmp_fr));
-
-        PairingsBn254.G1Point memory pair_with_x = proof.opening_at_zeta_omega_proof.point_mul(state.u);
-        pair_with_x.point_add_assign(proof.opening_at_zeta_proof);
-        pair_with_x.negate();
-
-        return PairingsBn254.pairingProd2(pair_with_generator, PairingsBn254.P2(), pair_with_x, vk.g2_x);
     }
 
-    function reconstruct_d(
-        PartialVerifierState memory state,
-        Proof memory proof,
-        VerificationKey memory vk
-    ) internal view returns (PairingsBn254.G1Point memory res) {
-        res = PairingsBn254.copy_g1(vk.selector_commitments[STATE_WIDTH + 1]);
-
-        PairingsBn254.G1Point memory tmp_g1 = PairingsBn254.P1();
-        PairingsBn254.Fr memory tmp_fr = PairingsBn254.new_fr(0);
-
-        // addition gates
-        for (uint256 i = 0; i < STATE_WIDTH; i++) {
-            tmp_g1 = vk.selector_commitments[i].point_mul(proof.wire_values_at_zeta[i]);
-            res.point_add_assign(tmp_g1);
-        }
-
-        // multiplication gate
-        tmp_fr.assign(proof.wire_values_at_zeta[0]);
-        tmp_fr.mul_assign(proof.wire_values_at_zeta[1]);
-        tmp_g1 = vk.selector_commitments[STATE_WIDTH].point_mul(tmp_fr);
-        res.point_add_assign(tmp_g1);
-
-        // z * non_res * beta + gamma + a
-        PairingsBn254.Fr memory grand_product_part_at_z = PairingsBn254.copy(state.zeta);
-        grand_product_part_at_z.mul_assign(state.beta);
-        grand_product_part_at_z.add_assign(proof.wire_values_at_zeta[0]);
-        grand_product_part_at_z.add_assign(state.gamma);
-        for (uint256 i = 0; i < vk.permutation_non_residues.length; i++) {
-            tmp_fr.assign(state.zeta);
-            tmp_fr.mul_assign(vk.permutation_non_residues[i]);
-            tmp_fr.mul_assign(state.beta);
-            tmp_fr.add_assign(state.gamma);
-            tmp_fr.add_assign(proof.wire_values_at_zeta[i+1]);
-
-            grand_product_part_at_z.mul_assign(tmp_fr);
-        }
-
-        grand_product_part_at_z.mul_assign(state.alpha);
-
-        tmp_fr.assign(state.cached_lagrange_evals[0]);
-        tmp_fr.mul_assign(state.alpha);
-        tmp_fr.mul_assign(state.alpha);
-        // NOTICE
-        grand_product_part_at_z.sub_assign(tmp_fr);
-        PairingsBn254.Fr memory last_permutation_part_at_z = PairingsBn254.new_fr(1);
-        for (uint256 i = 0; i < proof.permutation_polynomials_at_zeta.length; i++) {
-            tmp_fr.assign(state.beta);
-            tmp_fr.mul_assign(proof.permutation_polynomials_at_zeta[i]);
-            tmp_fr.add_assign(state.gamma);
-            tmp_fr.add_assign(proof.wire_values_at_zeta[i]);
-
-            last_permutation_part_at_z.mul_assign(tmp_fr);
-        }
-
-        last_permutation_part_at_z.mul_assign(state.beta);
-        last_permutation_part_at_z.mul_assign(proof.grand_product_at_zeta_omega);
-        last_permutation_part_at_z.mul_assign(state.alpha);
-
-        // gnark implementation: add third part and sub second second part
-        // plonk paper implementation: add second part and sub third part
-        /*
-        tmp_g1 = proof.grand_product_commitment.point_mul(grand_product_part_at_z);
-        tmp_g1.point_sub_assign(vk.permutation_commitments[STATE_WIDTH - 1].point_mul(last_permutation_part_at_z));
-        */
-        // add to the linearization
-
-        tmp_g1 = vk.permutation_commitments[STATE_WIDTH - 1].point_mul(last_permutation_part_at_z);
-        tmp_g1.point_sub_assign(proof.grand_product_commitment.point_mul(grand_product_part_at_z));
-        res.point_add_assign(tmp_g1);
-
-        generate_uv_challenge(state, proof, vk, res);
-
-        res.point_mul_assign(state.v);
-        res.point_add_assign(proof.grand_product_commitment.point_mul(state.u));
+    function g1(uint256[] calldata proof, uint256 i) internal pure returns (Bn254.G1Point memory) {
+        return Bn254.G1Point(proof[i], proof[i + 1]);
     }
 
-    // gnark v generation process:
-    // sha256(zeta, proof.quotient_poly_commitments, linearizedPolynomialDigest, proof.wire_commitments, vk.permutation_commitments[0..1], )
-    // NOTICE: gnark use "gamma" name for v, it's not reasonable
-    // NOTICE: gnark use zeta^(n+2) which is a bit different with plonk paper
-    // generate_v_challenge();
-    function generate_uv_challenge(
-        PartialVerifierState memory state,
-        Proof memory proof,
-        VerificationKey memory vk,
-        PairingsBn254.G1Point memory linearization_point) view internal {
-        TranscriptLibrary.Transcript memory transcript = TranscriptLibrary.new_transcript();
-        transcript.set_challenge_name("gamma");
-        transcript.update_with_fr(state.zeta);
-        PairingsBn254.Fr memory zeta_plus_two = PairingsBn254.copy(state.zeta);
-        PairingsBn254.Fr memory n_plus_two = PairingsBn254.new_fr(vk.domain_size);
-        n_plus_two.add_assign(PairingsBn254.new_fr(2));
-        zeta_plus_two = zeta_plus_two.pow(n_plus_two.value);
-        state.cached_fold_quotient_ploy_commitments = PairingsBn254.copy_g1(proof.quotient_poly_commitments[STATE_WIDTH-1]);
-        for (uint256 i = 0; i < STATE_WIDTH - 1; i++) {
-            state.cached_fold_quotient_ploy_commitments.point_mul_assign(zeta_plus_two);
-            state.cached_fold_quotient_ploy_commitments.point_add_assign(proof.quotient_poly_commitments[STATE_WIDTH - 2 - i]);
-        }
-        transcript.update_with_g1(state.cached_fold_quotient_ploy_commitments);
-        transcript.update_with_g1(linearization_point);
-
-        for (uint256 i = 0; i < proof.wire_commitments.length; i++) {
-            transcript.update_with_g1(proof.wire_commitments[i]);
-        }
-        for (uint256 i = 0; i < vk.permutation_commitments.length - 1; i++) {
-            transcript.update_with_g1(vk.permutation_commitments[i]);
-        }
-        state.v = transcript.get_challenge();
-        // gnark use local randomness to generate u
-        // we use opening_at_zeta_proof and opening_at_zeta_omega_proof
-        transcript.set_challenge_name("u");
-        transcript.update_with_g1(proof.opening_at_zeta_proof);
-        transcript.update_with_g1(proof.opening_at_zeta_omega_proof);
-        state.u = transcript.get_challenge();
+    function encode_inputs(uint256[] calldata public_inputs) internal pure returns (bytes memory) {
+        uint256[] memory inputs = public_inputs;
+        return abi.encodePacked(inputs);
     }
 
-    function batch_evaluate_lagrange_poly_out_of_domain(
-        uint256[] memory poly_nums,
-        uint256 domain_size,
-        PairingsBn254.Fr memory omega,
-        PairingsBn254.Fr memory at
-    ) internal view returns (PairingsBn254.Fr[] memory res) {
-        PairingsBn254.Fr memory one = PairingsBn254.new_fr(1);
-        PairingsBn254.Fr memory tmp_1 = PairingsBn254.new_fr(0);
-        PairingsBn254.Fr memory tmp_2 = PairingsBn254.new_fr(domain_size);
-        PairingsBn254.Fr memory vanishing_at_zeta = at.pow(domain_size);
-        vanishing_at_zeta.sub_assign(one);
-        // we can not have random point z be in domain
-        require(vanishing_at_zeta.value != 0);
-        PairingsBn254.Fr[] memory nums = new PairingsBn254.Fr[](poly_nums.length);
-        PairingsBn254.Fr[] memory dens = new PairingsBn254.Fr[](poly_nums.length);
-        // numerators in a form omega^i * (z^n - 1)
-        // denoms in a form (z - omega^i) * N
-        for (uint i = 0; i < poly_nums.length; i++) {
-            tmp_1 = omega.pow(poly_nums[i]); // power of omega
-            nums[i].assign(vanishing_at_zeta);
-            nums[i].mul_assign(tmp_1);
-
-            dens[i].assign(at); // (X - omega^i) * N
-            dens[i].sub_assign(tmp_1);
-            dens[i].mul_assign(tmp_2); // mul by domain size
-        }
-
-        PairingsBn254.Fr[] memory partial_products = new PairingsBn254.Fr[](poly_nums.length);
-        partial_products[0].assign(PairingsBn254.new_fr(1));
-        for (uint i = 1; i < dens.length; i++) {
-            partial_products[i].assign(dens[i-1]);
-            partial_products[i].mul_assign(partial_products[i-1]);
-        }
+    // derive_challenges replays the Fiat-Shamir transcript of the prover:
+    // each challenge is sha256(name || previous challenge || bound values), reduced modulo R_MOD.
+    function derive_challenges(
+        State memory state,
+        uint256[] calldata public_inputs,
+        uint256[] calldata proof
+    ) internal pure {
+        // gamma is bound to the verifying key, the public inputs and [l]₁, [r]₁, [o]₁
+        bytes memory message = abi.encodePacked("gamma", VK_S1_COM_X, VK_S1_COM_Y, VK_S2_COM_X, VK_S2_COM_Y);
+        message = abi.encodePacked(message, VK_S3_COM_X, VK_S3_COM_Y, VK_QL_COM_X, VK_QL_COM_Y);
+        message = abi.encodePacked(message, VK_QR_COM_X, VK_QR_COM_Y, VK_QM_COM_X, VK_QM_COM_Y);
+        message = abi.encodePacked(message, VK_QO_COM_X, VK_QO_COM_Y, VK_QK_COM_X, VK_QK_COM_Y);
+        message = abi.encodePacked(message, encode_inputs(public_inputs));
+        message = abi.encodePacked(message, proof[PROOF_L_COM_X], proof[PROOF_L_COM_X + 1]);
+        message = abi.encodePacked(message, proof[PROOF_R_COM_X], proof[PROOF_R_COM_X + 1]);
+        message = abi.encodePacked(message, proof[PROOF_O_COM_X], proof[PROOF_O_COM_X + 1]);
+        bytes32 challenge = sha256(message);
+        state.gamma = uint256(challenge) % R_MOD;
 
-        tmp_2.assign(partial_products[partial_products.length - 1]);
-        tmp_2.mul_assign(dens[dens.length - 1]);
-        tmp_2 = tmp_2.inverse(); // tmp_2 contains a^-1 * b^-1 (with! the last one)
+        // beta
+        challenge = sha256(abi.encodePacked("beta", challenge));
+        state.beta = uint256(challenge) % R_MOD;
 
-        for (uint i = dens.length; i > 0; i--) {
-            tmp_1.assign(tmp_2); // all inversed
-            tmp_1.mul_assign(partial_products[i-1]); // clear lowest terms
-            tmp_2.mul_assign(dens[i-1]);
-            dens[i-1].assign(tmp_1);
-        }
+        // alpha is bound to [Z]₁
+        challenge = sha256(abi.encodePacked("alpha", challenge, proof[PROOF_Z_COM_X], proof[PROOF_Z_COM_X + 1]));
+        state.alpha = uint256(challenge) % R_MOD;
 
-        for (uint i = 0; i < nums.length; i++) {
-            nums[i].mul_assign(dens[i]);
-        }
-
-        return nums;
+        // zeta is bound to [h₁]₁, [h₂]₁, [h₃]₁
+        message = abi.encodePacked("zeta", challenge, proof[PROOF_H_0_COM_X], proof[PROOF_H_0_COM_X + 1]);
+        message = abi.encodePacked(message, proof[PROOF_H_1_COM_X], proof[PROOF_H_1_COM_X + 1]);
+        message = abi.encodePacked(message, proof[PROOF_H_2_COM_X], proof[PROOF_H_2_COM_X + 1]);
+        challenge = sha256(message);
+        state.zeta = uint256(challenge) % R_MOD;
     }
 
-    // plonk paper verify process step8: Compute quotient polynomial evaluation
-    function verify_quotient_poly_eval_at_zeta(
-        PartialVerifierState memory state,
-        Proof memory proof,
-        VerificationKey memory vk
-    ) internal view returns (bool) {
-        PairingsBn254.Fr memory lhs = evaluate_vanishing(vk.domain_size, state.zeta);
-        require(lhs.value != 0); // we can not check a polynomial relationship if point z is in the domain
-        lhs.mul_assign(proof.quotient_polynomial_at_zeta);
-
-        PairingsBn254.Fr memory quotient_challenge = PairingsBn254.new_fr(1);
-        PairingsBn254.Fr memory rhs = PairingsBn254.copy(proof.linearization_polynomial_at_zeta);
-
-        // public inputs
-        PairingsBn254.Fr memory tmp = PairingsBn254.new_fr(0);
-        for (uint256 i = 0; i < proof.input_values.length; i++) {
-            tmp.assign(state.cached_lagrange_evals[i]);
-            tmp.mul_assign(PairingsBn254.new_fr(proof.input_values[i]));
-            rhs.add_assign(tmp);
+    // batch_invert replaces each element of a by its inverse
+    function batch_invert(uint256[] memory a) internal view {
+        uint256[] memory partial_products = new uint256[](a.length);
+        uint256 acc = 1;
+        for (uint256 i = 0; i < a.length; i++) {
+            partial_products[i] = acc;
+            acc = mulmod(acc, a[i], R_MOD);
         }
-
-        quotient_challenge.mul_assign(state.alpha);
-
-        PairingsBn254.Fr memory z_part = PairingsBn254.copy(proof.grand_product_at_zeta_omega);
-        for (uint256 i = 0; i < proof.permutation_polynomials_at_zeta.length; i++) {
-            tmp.assign(proof.permutation_polynomials_at_zeta[i]);
-            tmp.mul_assign(state.beta);
-            tmp.add_assign(state.gamma);
-            tmp.add_assign(proof.wire_values_at_zeta[i]);
-
-            z_part.mul_assign(tmp);
+        acc = Bn254.inverse(acc);
+        for (uint256 i = a.length; i > 0; i--) {
+            uint256 tmp = mulmod(acc, partial_products[i - 1], R_MOD);
+            acc = mulmod(acc, a[i - 1], R_MOD);
+            a[i - 1] = tmp;
         }
-
-        tmp.assign(state.gamma);
-        // we need a wire value of the last polynomial in enumeration
-        tmp.add_assign(proof.wire_values_at_zeta[STATE_WIDTH - 1]);
-
-        z_part.mul_assign(tmp);
-        z_part.mul_assign(quotient_challenge);
-
-        // NOTICE: this is different with plonk paper
-        // plonk paper should be: rhs.sub_assign(z_part);
-        rhs.add_assign(z_part);
-
-        quotient_challenge.mul_assign(state.alpha);
-
-        tmp.assign(state.cached_lagrange_evals[0]);
-        tmp.mul_assign(quotient_challenge);
-
-        rhs.sub_assign(tmp);
-
-        return lhs.value == rhs.value;
-    }
-
-    function evaluate_vanishing(
-        uint256 domain_size,
-        PairingsBn254.Fr memory at
-    ) internal view returns (PairingsBn254.Fr memory res) {
-        res = at.pow(domain_size);
-        res.sub_assign(PairingsBn254.new_fr(1));
-    }
-
-	// This verifier is for a PLONK with a state width 3
-    // and main gate equation
-    // q_a(X) * a(X) + 
-    // q_b(X) * b(X) + 
-    // q_c(X) * c(X) +
-    // q_m(X) * a(X) * b(X) + 
-    // q_constants(X)+
-    // where q_{}(X) are selectors a, b, c - state (witness) polynomials
-    
-    function verify(Proof memory proof, VerificationKey memory vk) internal view returns (bool) {
-        PartialVerifierState memory state;
-        
-        bool valid = verify_initial(state, proof, vk);
-        
-        if (valid == false) {
-            return false;
-        }
-        
-        valid = verify_commitments(state, proof, vk);
-        
-        return valid;
     }
-}
-
-contract KeyedPlonkVerifier is PlonkVerifier {
-    uint256 constant SERIALIZED_PROOF_LENGTH = 26;
-	using PairingsBn254 for PairingsBn254.Fr;
-    function get_verification_key() internal pure returns(VerificationKey memory vk) {
-        vk.domain_size = {{.Size}};
-        vk.num_inputs = {{.NbPublicVariables}};
-        vk.omega = PairingsBn254.new_fr(uint256({{.Generator.String}}));
-        vk.selector_commitments[0] = PairingsBn254.new_g1(
-        	uint256({{.Ql.X.String}}),
-        	uint256({{.Ql.Y.String}})
-        );
-        vk.selector_commitments[1] = PairingsBn254.new_g1(
-			uint256({{.Qr.X.String}}),
-			uint256({{.Qr.Y.String}})
-        );
-        vk.selector_commitments[2] = PairingsBn254.new_g1(
-			uint256({{.Qo.X.String}}),
-			uint256({{.Qo.Y.String}})
-        );
-        vk.selector_commitments[3] = PairingsBn254.new_g1(
-			uint256({{.Qm.X.String}}),
-			uint256({{.Qm.Y.String}})
-        );
-        vk.selector_commitments[4] = PairingsBn254.new_g1(
-			uint256({{.Qk.X.String}}),
-			uint256({{.Qk.Y.String}})
-        );
-
-        vk.permutation_commitments[0] = PairingsBn254.new_g1(
-        	uint256({{(index .S 0).X.String}}),
-			uint256({{(index .S 0).Y.String}})
-        );
-        vk.permutation_commitments[1] = PairingsBn254.new_g1(
-			uint256({{(index .S 1).X.String}}),
-			uint256({{(index .S 1).Y.String}})
-        );
-        vk.permutation_commitments[2] = PairingsBn254.new_g1(
-			uint256({{(index .S 2).X.String}}),
-			uint256({{(index .S 2).Y.String}})
-        );
-
-        vk.permutation_non_residues[0] = PairingsBn254.new_fr(
-        	uint256({{.CosetShift.String}})
-        );
-        vk.permutation_non_residues[1] = PairingsBn254.copy(
-			vk.permutation_non_residues[0]
-        );
-		vk.permutation_non_residues[1].mul_assign(vk.permutation_non_residues[0]);
-
-        vk.g2_x = PairingsBn254.new_g2(
-			[uint256({{(index .KZGSRS.G2 1).X.A1.String}}),
-			uint256({{(index .KZGSRS.G2 1).X.A0.String}})],
-			[uint256({{(index .KZGSRS.G2 1).Y.A1.String}}),
-			uint256({{(index .KZGSRS.G2 1).Y.A0.String}})]
-        );
-    }
-
 
-    function deserialize_proof(
-        uint256[] memory public_inputs,
-        uint256[] memory serialized_proof
-    ) internal pure returns(Proof memory proof) {
-        require(serialized_proof.length == SERIALIZED_PROOF_LENGTH);
-        proof.input_values = new uint256[](public_inputs.length);
-        for (uint256 i = 0; i < public_inputs.length; i++) {
-            proof.input_values[i] = public_inputs[i];
-        }
+    // compute_public_inputs computes ζⁿ-1, L₁(ζ) and PI(ζ) = ∑ᵢwᵢLᵢ(ζ)
+    // where Lᵢ(ζ) = ωⁱ/n * (ζⁿ-1)/(ζ-ωⁱ)
+    function compute_public_inputs(State memory state, uint256[] calldata public_inputs) internal view {
+        state.zeta_power_n_minus_one = addmod(Bn254.pow(state.zeta, VK_DOMAIN_SIZE), R_MOD - 1, R_MOD);
+        require(state.zeta_power_n_minus_one != 0, "zeta-in-domain");
 
-        uint256 j = 0;
-        for (uint256 i = 0; i < STATE_WIDTH; i++) {
-            proof.wire_commitments[i] = PairingsBn254.new_g1_checked(
-                serialized_proof[j],
-                serialized_proof[j+1]
-            );
+        uint256 n = public_inputs.length > 0 ? public_inputs.length : 1;
+        uint256[] memory lagrange = new uint256[](n);
 
-            j += 2;
+        // ζ-ωⁱ
+        uint256 w = 1;
+        for (uint256 i = 0; i < n; i++) {
+            lagrange[i] = addmod(state.zeta, R_MOD - w, R_MOD);
+            w = mulmod(w, VK_OMEGA, R_MOD);
         }
+        batch_invert(lagrange);
 
-        proof.grand_product_commitment = PairingsBn254.new_g1_checked(
-            serialized_proof[j],
-            serialized_proof[j+1]
-        );
-        j += 2;
-
-        for (uint256 i = 0; i < STATE_WIDTH; i++) {
-            proof.quotient_poly_commitments[i] = PairingsBn254.new_g1_checked(
-                serialized_proof[j],
-                serialized_proof[j+1]
-            );
-
-            j += 2;
+        uint256 c = mulmod(state.zeta_power_n_minus_one, VK_INV_DOMAIN_SIZE, R_MOD);
+        w = 1;
+        for (uint256 i = 0; i < n; i++) {
+            lagrange[i] = mulmod(mulmod(c, w, R_MOD), lagrange[i], R_MOD);
+            w = mulmod(w, VK_OMEGA, R_MOD);
         }
 
-        for (uint256 i = 0; i < STATE_WIDTH; i++) {
-            proof.wire_values_at_zeta[i] = PairingsBn254.new_fr(
-                serialized_proof[j]
-            );
-
-            j += 1;
-        }
-
-        proof.grand_product_at_zeta_omega = PairingsBn254.new_fr(
-            serialized_proof[j]
-        );
-
-        j += 1;
-
-        proof.quotient_polynomial_at_zeta = PairingsBn254.new_fr(
-            serialized_proof[j]
-        );
-
-        j += 1;
-
-        proof.linearization_polynomial_at_zeta = PairingsBn254.new_fr(
-            serialized_proof[j]
-        );
-
-        j += 1;
-
-        for (uint256 i = 0; i < proof.permutation_polynomials_at_zeta.length; i++) {
-            proof.permutation_polynomials_at_zeta[i] = PairingsBn254.new_fr(
-                serialized_proof[j]
-            );
-
-            j += 1;
-        }
-
-        proof.opening_at_zeta_proof = PairingsBn254.new_g1_checked(
-            serialized_proof[j],
-            serialized_proof[j+1]
+        state.lagrange_0 = lagrange[0];
+        for (uint256 i = 0; i < public_inputs.length; i++) {
+            state.pi = addmod(state.pi, mulmod(lagrange[i], public_inputs[i], R_MOD), R_MOD);
+        }
+    }
+
+    // verify_quotient checks that h(ζ)(ζⁿ-1) equals
+    // linearizedPolynomial(ζ) + PI(ζ) + α*Z(μζ)*(l(ζ)+β*s₁(ζ)+γ)*(r(ζ)+β*s₂(ζ)+γ)*(o(ζ)+γ) - α²*L₁(ζ)
+    function verify_quotient(State memory state, uint256[] calldata proof) internal pure returns (bool) {
+        uint256 s1 = addmod(addmod(mulmod(proof[PROOF_S1_AT_ZETA], state.beta, R_MOD), proof[PROOF_L_AT_ZETA], R_MOD), state.gamma, R_MOD);
+        uint256 s2 = addmod(addmod(mulmod(proof[PROOF_S2_AT_ZETA], state.beta, R_MOD), proof[PROOF_R_AT_ZETA], R_MOD), state.gamma, R_MOD);
+        s1 = mulmod(s1, s2, R_MOD);
+        s1 = mulmod(s1, addmod(proof[PROOF_O_AT_ZETA], state.gamma, R_MOD), R_MOD);
+        s1 = mulmod(s1, state.alpha, R_MOD);
+        s1 = mulmod(s1, proof[PROOF_Z_AT_ZETA_OMEGA], R_MOD);
+
+        state.alpha_square_lagrange_0 = mulmod(mulmod(state.lagrange_0, state.alpha, R_MOD), state.alpha, R_MOD);
+
+        uint256 lhs = addmod(proof[PROOF_LINEARIZED_POLYNOMIAL_AT_ZETA], state.pi, R_MOD);
+        lhs = addmod(lhs, s1, R_MOD);
+        lhs = addmod(lhs, R_MOD - state.alpha_square_lagrange_0, R_MOD);
+
+        uint256 rhs = mulmod(proof[PROOF_H_AT_ZETA], state.zeta_power_n_minus_one, R_MOD);
+        return lhs == rhs;
+    }
+
+    // fold_h computes [h₁]₁ + ζⁿ⁺²[h₂]₁ + ζ²⁽ⁿ⁺²⁾[h₃]₁
+    function fold_h(State memory state, uint256[] calldata proof) internal view {
+        uint256 zeta_power_n_plus_two = Bn254.pow(state.zeta, VK_DOMAIN_SIZE + 2);
+        Bn254.G1Point memory folded = g1(proof, PROOF_H_2_COM_X);
+        folded = Bn254.add(Bn254.mul(folded, zeta_power_n_plus_two), g1(proof, PROOF_H_1_COM_X));
+        folded = Bn254.add(Bn254.mul(folded, zeta_power_n_plus_two), g1(proof, PROOF_H_0_COM_X));
+        state.folded_h = folded;
+    }
+
+    // compute_linearized_polynomial computes the commitment to the linearized polynomial
+    // l(ζ)*[ql]₁ + r(ζ)*[qr]₁ + r(ζ)l(ζ)*[qm]₁ + o(ζ)*[qo]₁ + [qk]₁ +
+    // α*Z(μζ)*β*(l(ζ)+β*s₁(ζ)+γ)*(r(ζ)+β*s₂(ζ)+γ)*[s₃]₁ +
+    // (-α*(l(ζ)+β*ζ+γ)*(r(ζ)+β*μ*ζ+γ)*(o(ζ)+β*μ²*ζ+γ) + α²*L₁(ζ))*[Z]₁
+    function compute_linearized_polynomial(State memory state, uint256[] calldata proof) internal view {
+        uint256 s1 = mulmod(proof[PROOF_Z_AT_ZETA_OMEGA], state.beta, R_MOD);
+        s1 = mulmod(s1, addmod(addmod(mulmod(state.beta, proof[PROOF_S1_AT_ZETA], R_MOD), proof[PROOF_L_AT_ZETA], R_MOD), state.gamma, R_MOD), R_MOD);
+        s1 = mulmod(s1, addmod(addmod(mulmod(state.beta, proof[PROOF_S2_AT_ZETA], R_MOD), proof[PROOF_R_AT_ZETA], R_MOD), state.gamma, R_MOD), R_MOD);
+        s1 = mulmod(s1, state.alpha, R_MOD);
+
+        uint256 beta_zeta = mulmod(state.beta, state.zeta, R_MOD);
+        uint256 s2 = addmod(addmod(beta_zeta, proof[PROOF_L_AT_ZETA], R_MOD), state.gamma, R_MOD);
+        beta_zeta = mulmod(beta_zeta, VK_COSET_SHIFT, R_MOD);
+        s2 = mulmod(s2, addmod(addmod(beta_zeta, proof[PROOF_R_AT_ZETA], R_MOD), state.gamma, R_MOD), R_MOD);
+        beta_zeta = mulmod(beta_zeta, VK_COSET_SHIFT, R_MOD);
+        s2 = mulmod(s2, addmod(addmod(beta_zeta, proof[PROOF_O_AT_ZETA], R_MOD), state.gamma, R_MOD), R_MOD);
+        s2 = mulmod(s2, state.alpha, R_MOD);
+        s2 = addmod(R_MOD - s2, state.alpha_square_lagrange_0, R_MOD);
+
+        Bn254.G1Point memory res = Bn254.G1Point(VK_QK_COM_X, VK_QK_COM_Y);
+        res = Bn254.add(res, Bn254.mul(Bn254.G1Point(VK_QL_COM_X, VK_QL_COM_Y), proof[PROOF_L_AT_ZETA]));
+        res = Bn254.add(res, Bn254.mul(Bn254.G1Point(VK_QR_COM_X, VK_QR_COM_Y), proof[PROOF_R_AT_ZETA]));
+        res = Bn254.add(res, Bn254.mul(Bn254.G1Point(VK_QM_COM_X, VK_QM_COM_Y), mulmod(proof[PROOF_L_AT_ZETA], proof[PROOF_R_AT_ZETA], R_MOD)));
+        res = Bn254.add(res, Bn254.mul(Bn254.G1Point(VK_QO_COM_X, VK_QO_COM_Y), proof[PROOF_O_AT_ZETA]));
+        res = Bn254.add(res, Bn254.mul(Bn254.G1Point(VK_S3_COM_X, VK_S3_COM_Y), s1));
+        res = Bn254.add(res, Bn254.mul(g1(proof, PROOF_Z_COM_X), s2));
+        state.linearized_polynomial = res;
+    }
+
+    // accumulate adds c*p to the folded digests and c*claim to the folded evaluations
+    function accumulate(State memory state, Bn254.G1Point memory p, uint256 claim, uint256 c) internal view {
+        state.folded_digests = Bn254.add(state.folded_digests, Bn254.mul(p, c));
+        state.folded_evals = addmod(state.folded_evals, mulmod(claim, c, R_MOD), R_MOD);
+    }
+
+    // fold_proof folds the batch opening proof at ζ, as kzg.FoldProof does: the digests
+    // and the claimed values are combined with the powers of a challenge bound to ζ and the digests.
+    function fold_proof(State memory state, uint256[] calldata proof) internal view {
+        bytes memory message = abi.encodePacked("gamma", state.zeta, state.folded_h.X, state.folded_h.Y);
+        message = abi.encodePacked(message, state.linearized_polynomial.X, state.linearized_polynomial.Y);
+        message = abi.encodePacked(message, proof[PROOF_L_COM_X], proof[PROOF_L_COM_X + 1]);
+        message = abi.encodePacked(message, proof[PROOF_R_COM_X], proof[PROOF_R_COM_X + 1]);
+        message = abi.encodePacked(message, proof[PROOF_O_COM_X], proof[PROOF_O_COM_X + 1]);
+        message = abi.encodePacked(message, VK_S1_COM_X, VK_S1_COM_Y, VK_S2_COM_X, VK_S2_COM_Y);
+        uint256 gamma = uint256(sha256(message)) % R_MOD;
+
+        state.folded_digests = state.folded_h;
+        state.folded_evals = proof[PROOF_H_AT_ZETA];
+
+        uint256 c = gamma;
+        accumulate(state, state.linearized_polynomial, proof[PROOF_LINEARIZED_POLYNOMIAL_AT_ZETA], c);
+        c = mulmod(c, gamma, R_MOD);
+        accumulate(state, g1(proof, PROOF_L_COM_X), proof[PROOF_L_AT_ZETA], c);
+        c = mulmod(c, gamma, R_MOD);
+        accumulate(state, g1(proof, PROOF_R_COM_X), proof[PROOF_R_AT_ZETA], c);
+        c = mulmod(c, gamma, R_MOD);
+        accumulate(state, g1(proof, PROOF_O_COM_X), proof[PROOF_O_AT_ZETA], c);
+        c = mulmod(c, gamma, R_MOD);
+        accumulate(state, Bn254.G1Point(VK_S1_COM_X, VK_S1_COM_Y), proof[PROOF_S1_AT_ZETA], c);
+        c = mulmod(c, gamma, R_MOD);
+        accumulate(state, Bn254.G1Point(VK_S2_COM_X, VK_S2_COM_Y), proof[PROOF_S2_AT_ZETA], c);
+    }
+
+    // batch_verify_multi_points verifies the folded opening at ζ and the opening of Z at μζ
+    // with a single pairing check, the two claims being combined with a random λ:
+    // e(∑ᵢλᵢ([fᵢ]₁ - [fᵢ(pᵢ)]₁ + pᵢ[Wᵢ]₁), [1]₂) * e(-∑ᵢλᵢ[Wᵢ]₁, [α]₂) == 1
+    function batch_verify_multi_points(State memory state, uint256[] calldata proof) internal view returns (bool) {
+        bytes memory message = abi.encodePacked(state.folded_digests.X, state.folded_digests.Y, state.folded_evals, state.zeta);
+        message = abi.encodePacked(message, proof[PROOF_Z_COM_X], proof[PROOF_Z_COM_X + 1], proof[PROOF_Z_AT_ZETA_OMEGA]);
+        message = abi.encodePacked(message, proof[PROOF_BATCH_OPENING_AT_ZETA_X], proof[PROOF_BATCH_OPENING_AT_ZETA_X + 1]);
+        message = abi.encodePacked(message, proof[PROOF_OPENING_AT_ZETA_OMEGA_X], proof[PROOF_OPENING_AT_ZETA_OMEGA_X + 1]);
+        uint256 lambda = uint256(sha256(message)) % R_MOD;
+
+        Bn254.G1Point memory digests = Bn254.add(state.folded_digests, Bn254.mul(g1(proof, PROOF_Z_COM_X), lambda));
+        uint256 evals = addmod(state.folded_evals, mulmod(proof[PROOF_Z_AT_ZETA_OMEGA], lambda, R_MOD), R_MOD);
+        digests = Bn254.add(digests, Bn254.mul(Bn254.G1Point(VK_G1_SRS_X, VK_G1_SRS_Y), R_MOD - evals));
+
+        digests = Bn254.add(digests, Bn254.mul(g1(proof, PROOF_BATCH_OPENING_AT_ZETA_X), state.zeta));
+        uint256 shifted_zeta = mulmod(mulmod(state.zeta, VK_OMEGA, R_MOD), lambda, R_MOD);
+        digests = Bn254.add(digests, Bn254.mul(g1(proof, PROOF_OPENING_AT_ZETA_OMEGA_X), shifted_zeta));
+
+        Bn254.G1Point memory quotients = Bn254.add(
+            g1(proof, PROOF_BATCH_OPENING_AT_ZETA_X),
+            Bn254.mul(g1(proof, PROOF_OPENING_AT_ZETA_OMEGA_X), lambda)
         );
-        j += 2;
 
-        proof.opening_at_zeta_omega_proof = PairingsBn254.new_g1_checked(
-            serialized_proof[j],
-            serialized_proof[j+1]
+        return Bn254.pairing2(
+            digests,
+            Bn254.G2Point([VK_G2_SRS_0_X_0, VK_G2_SRS_0_X_1], [VK_G2_SRS_0_Y_0, VK_G2_SRS_0_Y_1]),
+            Bn254.neg(quotients),
+            Bn254.G2Point([VK_G2_SRS_1_X_0, VK_G2_SRS_1_X_1], [VK_G2_SRS_1_Y_0, VK_G2_SRS_1_Y_1])
         );
     }
-
-    function verify_serialized_proof(
-        uint256[] memory public_inputs,
-        uint256[] memory serialized_proof
-    ) public view returns (bool) {
-        VerificationKey memory vk = get_verification_key();
-        require(vk.num_inputs == public_inputs.length);
-        Proof memory proof = deserialize_proof(public_inputs, serialized_proof);
-        bool valid = verify(proof, vk);
-        return valid;
-    }
 }
 `
@@ -0,0 +1,191 @@
+// Copyright 2020 ConsenSys Software Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plonk_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fp"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/backend/plonk"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/scs"
+	plonk_bn254 "github.com/consensys/gnark/internal/backend/bn254/plonk"
+	"github.com/consensys/gnark/internal/evm"
+	"github.com/consensys/gnark/test"
+	"github.com/stretchr/testify/require"
+)
+
+var generateFixtures = flag.Bool("solidity.generate", false, "compile the solidity verifiers with solc and write the test fixtures in testdata/solidity")
+
+type noPublicInputCircuit struct {
+	X, Y frontend.Variable
+}
+
+func (c *noPublicInputCircuit) Define(api frontend.API) error {
+	api.AssertIsEqual(api.Mul(c.X, c.X), c.Y)
+	return nil
+}
+
+type publicInputsCircuit struct {
+	X    frontend.Variable
+	Y, Z frontend.Variable `gnark:",public"`
+}
+
+func (c *publicInputsCircuit) Define(api frontend.API) error {
+	x3 := api.Mul(c.X, c.X, c.X)
+	api.AssertIsEqual(api.Add(x3, c.X, 5), c.Y)
+	api.AssertIsDifferent(c.Z, 0)
+	return nil
+}
+
+// solidityFixture is a verifier compiled by solc and a valid proof for it, such that the
+// tests run the verifiers without solc. The fixtures are written by go generate.
+type solidityFixture struct {
+	VerifyingKey  *plonk_bn254.VerifyingKey
+	Proof         *plonk_bn254.Proof
+	PublicWitness fr.Vector
+	SourceHash    string // sha256 of the contract compiled to Bytecode
+	Bytecode      string
+}
+
+// writeSolidityFixture proves assignment, compiles the verifier with solc and writes the fixture
+func writeSolidityFixture(t *testing.T, path string, circuit, assignment frontend.Circuit) {
+	ccs, err := frontend.Compile(ecc.BN254.ScalarField(), scs.NewBuilder, circuit)
+	require.NoError(t, err)
+	srs, err := test.NewKZGSRS(ccs)
+	require.NoError(t, err)
+	pk, vk, err := plonk.Setup(ccs, srs)
+	require.NoError(t, err)
+
+	fullWitness, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField())
+	require.NoError(t, err)
+	public, err := fullWitness.Public()
+	require.NoError(t, err)
+	proof, err := plonk.Prove(ccs, pk, fullWitness)
+	require.NoError(t, err)
+	require.NoError(t, plonk.Verify(proof, vk, public))
+
+	var buf bytes.Buffer
+	require.NoError(t, vk.ExportSolidity(&buf))
+	code, err := evm.CompileSolidity(buf.Bytes(), "PlonkVerifier")
+	require.NoError(t, err)
+	hash := sha256.Sum256(buf.Bytes())
+
+	// the verifier only uses the first point of the SRS in G1, the others are not written
+	_vk := *vk.(*plonk_bn254.VerifyingKey)
+	srsG1 := *_vk.KZGSRS
+	srsG1.G1 = srsG1.G1[:1]
+	_vk.KZGSRS = &srsG1
+
+	fixture := solidityFixture{
+		VerifyingKey:  &_vk,
+		Proof:         proof.(*plonk_bn254.Proof),
+		PublicWitness: public.Vector().(fr.Vector),
+		SourceHash:    hex.EncodeToString(hash[:]),
+		Bytecode:      hex.EncodeToString(code),
+	}
+	data, err := json.MarshalIndent(&fixture, "", "\t")
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, data, 0o644))
+}
+
+// testSolidity deploys the verifier of the fixture in the embedded evm and checks that it
+// accepts the proof of assignment, and rejects it once the proof is tampered with.
+func testSolidity(t *testing.T, circuit, assignment frontend.Circuit, nbPublic int) {
+	path := filepath.Join("testdata", "solidity", t.Name()+".json")
+	if *generateFixtures {
+		writeSolidityFixture(t, path, circuit, assignment)
+	}
+	data, err := os.ReadFile(path)
+	require.NoError(t, err, "missing fixture, run go generate (requires solc)")
+	var fixture solidityFixture
+	require.NoError(t, json.Unmarshal(data, &fixture))
+
+	// the fixture is compiled from the contract exported by the current template
+	var buf bytes.Buffer
+	require.NoError(t, fixture.VerifyingKey.ExportSolidity(&buf))
+	hash := sha256.Sum256(buf.Bytes())
+	require.Equal(t, fixture.SourceHash, hex.EncodeToString(hash[:]), "%s is out of date, run go generate (requires solc)", path)
+
+	calldata, err := plonk_bn254.SolidityCalldata(fixture.Proof, fixture.VerifyingKey, fixture.PublicWitness)
+	require.NoError(t, err)
+	require.Equal(t, 4+32*(2+1+nbPublic+1+26), len(calldata))
+
+	code, err := hex.DecodeString(fixture.Bytecode)
+	require.NoError(t, err)
+	vm := evm.New()
+	addr, err := vm.Deploy(code)
+	require.NoError(t, err)
+
+	out, err := vm.StaticCall(addr, calldata)
+	require.NoError(t, err)
+	require.Equal(t, "1", new(big.Int).SetBytes(out).String())
+
+	// offset of the serialized proof, after the selector, the two offsets and the public inputs
+	proofOffset := 4 + 32*(2+1+nbPublic+1)
+
+	// [l]₁.x out of the base field
+	tampered := append([]byte(nil), calldata...)
+	fp.Modulus().FillBytes(tampered[proofOffset : proofOffset+32])
+	_, err = vm.StaticCall(addr, tampered)
+	var revertErr *evm.RevertError
+	require.ErrorAs(t, err, &revertErr)
+	require.Equal(t, "execution reverted: proof-coordinate-gte-p", err.Error())
+
+	// -[l]₁ instead of [l]₁, still on the curve
+	tampered = append([]byte(nil), calldata...)
+	var y fp.Element
+	y.SetBytes(tampered[proofOffset+32 : proofOffset+64])
+	y.Neg(&y)
+	yBytes := y.Bytes()
+	copy(tampered[proofOffset+32:proofOffset+64], yBytes[:])
+	out, err = vm.StaticCall(addr, tampered)
+	require.NoError(t, err)
+	require.Equal(t, "0", new(big.Int).SetBytes(out).String())
+
+	if nbPublic == 0 {
+		return
+	}
+
+	// first public input incremented, after the selector, the two offsets and the length
+	tampered = append([]byte(nil), calldata...)
+	var x fr.Element
+	x.SetBytes(tampered[4+32*3 : 4+32*4])
+	x.Add(&x, new(fr.Element).SetOne())
+	xBytes := x.Bytes()
+	copy(tampered[4+32*3:4+32*4], xBytes[:])
+	out, err = vm.StaticCall(addr, tampered)
+	require.NoError(t, err)
+	require.Equal(t, "0", new(big.Int).SetBytes(out).String())
+}
+
+func TestSolidityNoPublicInput(t *testing.T) {
+	testSolidity(t, &noPublicInputCircuit{}, &noPublicInputCircuit{X: 3, Y: 9}, 0)
+}
+
+func TestSolidityPublicInputs(t *testing.T) {
+	testSolidity(t, &publicInputsCircuit{}, &publicInputsCircuit{X: 3, Y: 35, Z: 1}, 2)
+}
@@ -305,11 +305,18 @@ func deriveRandomness(fs *fiatshamir.Transcript, challenge string, points ...*cu
 
 // ExportSolidity exports the verifying key to a solidity smart contract.
 //
+// The generated verify_serialized_proof method takes the public inputs and the proof
+// encoded with MarshalSolidity. See SolidityCalldata to encode a call to it.
+//
 // See https://github.com/ConsenSys/gnark-tests for example usage.
 //
 // Code has not been audited and is provided as-is, we make no guarantees or warranties to its safety and reliability.
 func (vk *VerifyingKey) ExportSolidity(w io.Writer) error {
-	tmpl, err := template.New("").Parse(solidityTemplate)
+	helpers := template.FuncMap{
+		"uint256": solidityUint256,
+	}
+
+	tmpl, err := template.New("").Funcs(helpers).Parse(solidityTemplate)
 	if err != nil {
 		return err
 	}
@@ -19,7 +19,6 @@ package plonk
 import (
 	"crypto/sha256"
 	"math/big"
-	"runtime"
 	"sync"
 	"time"
 
@@ -36,7 +35,6 @@ import (
 
 	"github.com/consensys/gnark-crypto/fiat-shamir"
 	"github.com/consensys/gnark/backend"
-	"github.com/consensys/gnark/internal/utils"
 	"github.com/consensys/gnark/logger"
 )
 
@@ -75,7 +73,12 @@ func Prove(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness fr.Vector, opt backen
 	// compute the constraint system solution
 	var solution []fr.Element
 	var err error
-	if solution, err = spr.Solve(fullWitness, opt); err != nil {
+	if err = opt.BeginStage(backend.StageSolve); err != nil {
+		return nil, err
+	}
+	solution, err = spr.SolveWires(fullWitness, opt)
+	opt.EndStage(backend.StageSolve)
+	if err != nil {
 		if !opt.Force {
 			return nil, err
 		} else {
@@ -89,6 +92,10 @@ func Prove(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness fr.Vector, opt backen
 		}
 	}
 
+	if err := opt.BeginStage(backend.StageRound1); err != nil {
+		return nil, err
+	}
+
 	// query l, r, o in Lagrange basis, not blinded
 	evaluationLDomainSmall, evaluationRDomainSmall, evaluationODomainSmall := evaluateLROSmallDomain(spr, pk, solution)
 
@@ -108,7 +115,11 @@ func Prove(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness fr.Vector, opt backen
 	bwliop := wliop.Clone(int(pk.Domain[1].Cardinality)).Blind(1)
 	bwriop := wriop.Clone(int(pk.Domain[1].Cardinality)).Blind(1)
 	bwoiop := woiop.Clone(int(pk.Domain[1].Cardinality)).Blind(1)
-	if err := commitToLRO(bwliop.Coefficients(), bwriop.Coefficients(), bwoiop.Coefficients(), proof, pk.Vk.KZGSRS); err != nil {
+	if err := commitToLRO(bwliop.Coefficients(), bwriop.Coefficients(), bwoiop.Coefficients(), proof, pk.Vk.KZGSRS, opt.Workers()); err != nil {
+		return nil, err
+	}
+	opt.EndStage(backend.StageRound1)
+	if err := opt.BeginStage(backend.StageRound2); err != nil {
 		return nil, err
 	}
 
@@ -154,10 +165,13 @@ func Prove(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness fr.Vector, opt backen
 	// commit to the blinded version of z
 	bwziop := ziop // iop.NewWrappedPolynomial(&ziop)
 	bwziop.Blind(2)
-	proof.Z, err = kzg.Commit(bwziop.Coefficients(), pk.Vk.KZGSRS, runtime.NumCPU()*2)
-	if err != nil {
+	if proof.Z, err = commit(bwziop.Coefficients(), pk.Vk.KZGSRS, opt.Workers(), opt.Workers().NbWorkers()); err != nil {
 		return proof, err
 	}
+	opt.EndStage(backend.StageRound2)
+	if err := opt.BeginStage(backend.StageRound3); err != nil {
+		return nil, err
+	}
 
 	// derive alpha from the Comm(l), Comm(r), Comm(o), Com(Z)
 	alpha, err := deriveRandomness(&fs, "alpha", &proof.Z)
@@ -304,7 +318,11 @@ func Prove(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness fr.Vector, opt backen
 		h.Coefficients()[:pk.Domain[0].Cardinality+2],
 		h.Coefficients()[pk.Domain[0].Cardinality+2:2*(pk.Domain[0].Cardinality+2)],
 		h.Coefficients()[2*(pk.Domain[0].Cardinality+2):3*(pk.Domain[0].Cardinality+2)],
-		proof, pk.Vk.KZGSRS); err != nil {
+		proof, pk.Vk.KZGSRS, opt.Workers()); err != nil {
+		return nil, err
+	}
+	opt.EndStage(backend.StageRound3)
+	if err := opt.BeginStage(backend.StageRound4); err != nil {
 		return nil, err
 	}
 
@@ -375,11 +393,16 @@ func Prove(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness fr.Vector, opt backen
 		bzuzeta,
 		bwziop.Coefficients()[:bwziop.BlindedSize()],
 		pk,
+		opt.Workers(),
 	)
 
 	// TODO this commitment is only necessary to derive the challenge, we should
 	// be able to avoid doing it and get the challenge in another way
-	linearizedPolynomialDigest, errLPoly = kzg.Commit(linearizedPolynomialCanonical, pk.Vk.KZGSRS)
+	linearizedPolynomialDigest, errLPoly = commit(linearizedPolynomialCanonical, pk.Vk.KZGSRS, opt.Workers(), opt.Workers().NbWorkers())
+	opt.EndStage(backend.StageRound4)
+	if err := opt.BeginStage(backend.StageRound5); err != nil {
+		return nil, err
+	}
 
 	// foldedHDigest = Comm(h1) + ζᵐ⁺²*Comm(h2) + ζ²⁽ᵐ⁺²⁾*Comm(h3)
 	var bZetaPowerm, bSize big.Int
@@ -397,7 +420,7 @@ func Prove(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness fr.Vector, opt backen
 	foldedH := h.Coefficients()[2*(pk.Domain[0].Cardinality+2) : 3*(pk.Domain[0].Cardinality+2)]
 	h2 := h.Coefficients()[pk.Domain[0].Cardinality+2 : 2*(pk.Domain[0].Cardinality+2)]
 	h1 := h.Coefficients()[:pk.Domain[0].Cardinality+2]
-	utils.Parallelize(len(foldedH), func(start, end int) {
+	opt.Workers().Parallelize(len(foldedH), 1, func(start, end int) {
 		for i := start; i < end; i++ {
 			foldedH[i].Mul(&foldedH[i], &zetaPowerm) // ζᵐ⁺²*h3
 			foldedH[i].Add(&foldedH[i], &h2[i])      // ζ^{m+2)*h3+h2
@@ -440,26 +463,27 @@ func Prove(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness fr.Vector, opt backen
 	if err != nil {
 		return nil, err
 	}
+	opt.EndStage(backend.StageRound5)
 
 	return proof, nil
 
 }
 
 // fills proof.LRO with kzg commits of bcl, bcr and bco
-func commitToLRO(bcl, bcr, bco []fr.Element, proof *Proof, srs *kzg.SRS) error {
-	n := runtime.NumCPU() / 2
+func commitToLRO(bcl, bcr, bco []fr.Element, proof *Proof, srs *kzg.SRS, pool *backend.WorkerPool) error {
+	n := pool.NbWorkers() / 2
 	var err0, err1, err2 error
 	chCommit0 := make(chan struct{}, 1)
 	chCommit1 := make(chan struct{}, 1)
 	go func() {
-		proof.LRO[0], err0 = kzg.Commit(bcl, srs, n)
+		proof.LRO[0], err0 = commit(bcl, srs, pool, n)
 		close(chCommit0)
 	}()
 	go func() {
-		proof.LRO[1], err1 = kzg.Commit(bcr, srs, n)
+		proof.LRO[1], err1 = commit(bcr, srs, pool, n)
 		close(chCommit1)
 	}()
-	if proof.LRO[2], err2 = kzg.Commit(bco, srs, n); err2 != nil {
+	if proof.LRO[2], err2 = commit(bco, srs, pool, n); err2 != nil {
 		return err2
 	}
 	<-chCommit0
@@ -472,20 +496,20 @@ func commitToLRO(bcl, bcr, bco []fr.Element, proof *Proof, srs *kzg.SRS) error {
 	return err1
 }
 
-func commitToQuotient(h1, h2, h3 []fr.Element, proof *Proof, srs *kzg.SRS) error {
-	n := runtime.NumCPU() / 2
+func commitToQuotient(h1, h2, h3 []fr.Element, proof *Proof, srs *kzg.SRS, pool *backend.WorkerPool) error {
+	n := pool.NbWorkers() / 2
 	var err0, err1, err2 error
 	chCommit0 := make(chan struct{}, 1)
 	chCommit1 := make(chan struct{}, 1)
 	go func() {
-		proof.H[0], err0 = kzg.Commit(h1, srs, n)
+		proof.H[0], err0 = commit(h1, srs, pool, n)
 		close(chCommit0)
 	}()
 	go func() {
-		proof.H[1], err1 = kzg.Commit(h2, srs, n)
+		proof.H[1], err1 = commit(h2, srs, pool, n)
 		close(chCommit1)
 	}()
-	if proof.H[2], err2 = kzg.Commit(h3, srs, n); err2 != nil {
+	if proof.H[2], err2 = commit(h3, srs, pool, n); err2 != nil {
 		return err2
 	}
 	<-chCommit0
@@ -498,6 +522,14 @@ func commitToQuotient(h1, h2, h3 []fr.Element, proof *Proof, srs *kzg.SRS) error
 	return err1
 }
 
+// commit returns the kzg commitment of p, computed on up to nbTasks workers of the pool
+func commit(p []fr.Element, srs *kzg.SRS, pool *backend.WorkerPool, nbTasks int) (digest kzg.Digest, err error) {
+	pool.Run(nbTasks, func(nbTasks int) {
+		digest, err = kzg.Commit(p, srs, nbTasks)
+	})
+	return
+}
+
 // evaluateLROSmallDomain extracts the solution l, r, o, and returns it in lagrange form.
 // solution = [ public | secret | internal ]
 func evaluateLROSmallDomain(spr *cs.SparseR1CS, pk *ProvingKey, solution []fr.Element) ([]fr.Element, []fr.Element, []fr.Element) {
@@ -544,7 +576,7 @@ func evaluateLROSmallDomain(spr *cs.SparseR1CS, pk *ProvingKey, solution []fr.El
 // α²*L₁(ζ)*Z(X)
 // + α*( (l(ζ)+β*s1(ζ)+γ)*(r(ζ)+β*s2(ζ)+γ)*Z(μζ)*s3(X) - Z(X)*(l(ζ)+β*id1(ζ)+γ)*(r(ζ)+β*id2(ζ)+γ)*(o(ζ)+β*id3(ζ)+γ))
 // + l(ζ)*Ql(X) + l(ζ)r(ζ)*Qm(X) + r(ζ)*Qr(X) + o(ζ)*Qo(X) + Qk(X)
-func computeLinearizedPolynomial(lZeta, rZeta, oZeta, alpha, beta, gamma, zeta, zu fr.Element, blindedZCanonical []fr.Element, pk *ProvingKey) []fr.Element {
+func computeLinearizedPolynomial(lZeta, rZeta, oZeta, alpha, beta, gamma, zeta, zu fr.Element, blindedZCanonical []fr.Element, pk *ProvingKey, pool *backend.WorkerPool) []fr.Element {
 
 	// first part: individual constraints
 	var rl fr.Element
@@ -595,7 +627,7 @@ func computeLinearizedPolynomial(lZeta, rZeta, oZeta, alpha, beta, gamma, zeta,
 	linPol := make([]fr.Element, len(blindedZCanonical))
 	copy(linPol, blindedZCanonical)
 
-	utils.Parallelize(len(linPol), func(start, end int) {
+	pool.Parallelize(len(linPol), 1, func(start, end int) {
 
 		var t0, t1 fr.Element
 
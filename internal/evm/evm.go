@@ -0,0 +1,592 @@
+// Copyright 2020 ConsenSys Software Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package evm implements a minimal Ethereum Virtual Machine interpreter.
+//
+// It is not a full node implementation: there is no gas accounting, no value
+// transfer and no block context. It supports the instruction set emitted by
+// solc for the verifier contracts exported by gnark, as well as the SHA256,
+// identity, modexp and BN254 (ecAdd, ecMul, ecPairing) precompiles, which
+// is enough to deploy and execute these contracts in tests.
+package evm
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// Address of a contract deployed in the EVM
+type Address [20]byte
+
+var (
+	errStackUnderflow  = errors.New("stack underflow")
+	errStackOverflow   = errors.New("stack overflow")
+	errInvalidJump     = errors.New("invalid jump destination")
+	errInvalidOpcode   = errors.New("invalid opcode")
+	errWriteProtection = errors.New("write protection")
+	errMaxCallDepth    = errors.New("max call depth exceeded")
+	errMemoryLimit     = errors.New("memory limit exceeded")
+)
+
+const (
+	maxStackSize = 1024
+	maxCallDepth = 1024
+	maxMemory    = 1 << 28
+
+	// gasLeft is the value returned by the GAS opcode; gas is not metered.
+	gasLeft = 1 << 40
+)
+
+// RevertError is returned when the execution ended with a REVERT instruction.
+type RevertError struct {
+	Data []byte
+}
+
+func (e *RevertError) Error() string {
+	if reason, ok := decodeRevertReason(e.Data); ok {
+		return "execution reverted: " + reason
+	}
+	return fmt.Sprintf("execution reverted (%x)", e.Data)
+}
+
+// decodeRevertReason decodes the ABI encoding of Error(string)
+func decodeRevertReason(data []byte) (string, bool) {
+	if len(data) < 68 || binary.BigEndian.Uint32(data) != 0x08c379a0 {
+		return "", false
+	}
+	length := new(big.Int).SetBytes(data[36:68])
+	if !length.IsUint64() || uint64(len(data)-68) < length.Uint64() {
+		return "", false
+	}
+	return string(data[68 : 68+length.Uint64()]), true
+}
+
+// EVM holds the state of the deployed contracts.
+type EVM struct {
+	code    map[Address][]byte
+	storage map[Address]map[string]*big.Int
+	nonce   uint64
+}
+
+// New returns an empty EVM
+func New() *EVM {
+	return &EVM{
+		code:    make(map[Address][]byte),
+		storage: make(map[Address]map[string]*big.Int),
+	}
+}
+
+// Deploy executes initCode (contract creation bytecode, followed by the ABI encoded
+// constructor arguments if any) and stores the returned runtime code at a new address.
+func (evm *EVM) Deploy(initCode []byte) (Address, error) {
+	evm.nonce++
+	var addr Address
+	addr[0] = 0xc0
+	binary.BigEndian.PutUint64(addr[12:], evm.nonce)
+
+	evm.storage[addr] = make(map[string]*big.Int)
+	runtime, err := evm.run(addr, initCode, nil, false, 0)
+	if err != nil {
+		delete(evm.storage, addr)
+		return Address{}, err
+	}
+	evm.code[addr] = runtime
+	return addr, nil
+}
+
+// Call executes the code deployed at to with input as calldata and returns the output.
+func (evm *EVM) Call(to Address, input []byte) ([]byte, error) {
+	return evm.call(to, input, false, 0)
+}
+
+// StaticCall behaves like Call but fails if the execution tries to modify the state.
+func (evm *EVM) StaticCall(to Address, input []byte) ([]byte, error) {
+	return evm.call(to, input, true, 0)
+}
+
+func (evm *EVM) call(to Address, input []byte, static bool, depth int) ([]byte, error) {
+	if depth > maxCallDepth {
+		return nil, errMaxCallDepth
+	}
+	if p, ok := precompile(to); ok {
+		return p(input)
+	}
+	code, ok := evm.code[to]
+	if !ok {
+		// calling an account without code succeeds
+		return nil, nil
+	}
+	return evm.run(to, code, input, static, depth)
+}
+
+// frame is the execution context of a single call
+type frame struct {
+	evm        *EVM
+	address    Address
+	code       []byte
+	input      []byte
+	static     bool
+	depth      int
+	stack      []*big.Int
+	memory     []byte
+	returnData []byte
+	jumpDests  []bool
+}
+
+var (
+	two256    = new(big.Int).Lsh(big.NewInt(1), 256)
+	mask256   = new(big.Int).Sub(two256, big.NewInt(1))
+	two255    = new(big.Int).Lsh(big.NewInt(1), 255)
+	bigOne    = big.NewInt(1)
+	bigTwo256 = big.NewInt(256)
+)
+
+func (evm *EVM) run(address Address, code, input []byte, static bool, depth int) ([]byte, error) {
+	f := &frame{
+		evm:       evm,
+		address:   address,
+		code:      code,
+		input:     input,
+		static:    static,
+		depth:     depth,
+		jumpDests: analyzeJumpDests(code),
+	}
+	return f.execute()
+}
+
+// analyzeJumpDests marks the valid JUMPDEST positions, skipping PUSH data
+func analyzeJumpDests(code []byte) []bool {
+	res := make([]bool, len(code))
+	for pc := 0; pc < len(code); pc++ {
+		op := code[pc]
+		if op == opJUMPDEST {
+			res[pc] = true
+		} else if op >= opPUSH1 && op <= opPUSH32 {
+			pc += int(op - opPUSH1 + 1)
+		}
+	}
+	return res
+}
+
+func (f *frame) push(v *big.Int) error {
+	if len(f.stack) >= maxStackSize {
+		return errStackOverflow
+	}
+	f.stack = append(f.stack, v)
+	return nil
+}
+
+// pop pops n values from the stack; res[0] is the top of the stack
+func (f *frame) pop(n int) ([]*big.Int, error) {
+	if len(f.stack) < n {
+		return nil, errStackUnderflow
+	}
+	res := make([]*big.Int, n)
+	for i := 0; i < n; i++ {
+		res[i] = f.stack[len(f.stack)-1-i]
+	}
+	f.stack = f.stack[:len(f.stack)-n]
+	return res, nil
+}
+
+// toSigned interprets x as a two's complement 256 bits integer
+func toSigned(x *big.Int) *big.Int {
+	if x.Cmp(two255) < 0 {
+		return new(big.Int).Set(x)
+	}
+	return new(big.Int).Sub(x, two256)
+}
+
+// toUnsigned reduces x modulo 2²⁵⁶
+func toUnsigned(x *big.Int) *big.Int {
+	return x.And(x, mask256)
+}
+
+func boolToBig(b bool) *big.Int {
+	if b {
+		return big.NewInt(1)
+	}
+	return big.NewInt(0)
+}
+
+// toInt converts x to an int used as memory offset or size
+func toInt(x *big.Int) (int, error) {
+	if !x.IsUint64() || x.Uint64() > maxMemory {
+		return 0, errMemoryLimit
+	}
+	return int(x.Uint64()), nil
+}
+
+// expand grows the memory so that [offset, offset+size) is addressable
+func (f *frame) expand(offset, size *big.Int) (int, int, error) {
+	s, err := toInt(size)
+	if err != nil {
+		return 0, 0, err
+	}
+	if s == 0 {
+		return 0, 0, nil
+	}
+	o, err := toInt(offset)
+	if err != nil {
+		return 0, 0, err
+	}
+	end := o + s
+	if end > maxMemory {
+		return 0, 0, errMemoryLimit
+	}
+	if end > len(f.memory) {
+		newSize := (end + 31) / 32 * 32
+		f.memory = append(f.memory, make([]byte, newSize-len(f.memory))...)
+	}
+	return o, s, nil
+}
+
+// readPadded returns data[offset:offset+size], right padded with zeroes
+func readPadded(data []byte, offset *big.Int, size int) []byte {
+	res := make([]byte, size)
+	if !offset.IsUint64() || offset.Uint64() >= uint64(len(data)) {
+		return res
+	}
+	copy(res, data[offset.Uint64():])
+	return res
+}
+
+func (f *frame) execute() ([]byte, error) {
+	pc := 0
+	for pc < len(f.code) {
+		op := f.code[pc]
+
+		switch {
+		case op == opPUSH0:
+			if err := f.push(new(big.Int)); err != nil {
+				return nil, err
+			}
+			pc++
+			continue
+		case op >= opPUSH1 && op <= opPUSH32:
+			n := int(op - opPUSH1 + 1)
+			v := new(big.Int).SetBytes(readPadded(f.code, big.NewInt(int64(pc+1)), n))
+			if err := f.push(v); err != nil {
+				return nil, err
+			}
+			pc += n + 1
+			continue
+		case op >= opDUP1 && op <= opDUP16:
+			n := int(op - opDUP1 + 1)
+			if len(f.stack) < n {
+				return nil, errStackUnderflow
+			}
+			if err := f.push(new(big.Int).Set(f.stack[len(f.stack)-n])); err != nil {
+				return nil, err
+			}
+			pc++
+			continue
+		case op >= opSWAP1 && op <= opSWAP16:
+			n := int(op - opSWAP1 + 1)
+			if len(f.stack) < n+1 {
+				return nil, errStackUnderflow
+			}
+			top := len(f.stack) - 1
+			f.stack[top], f.stack[top-n] = f.stack[top-n], f.stack[top]
+			pc++
+			continue
+		case op >= opLOG0 && op <= opLOG4:
+			if f.static {
+				return nil, errWriteProtection
+			}
+			// logs are discarded
+			if _, err := f.pop(2 + int(op-opLOG0)); err != nil {
+				return nil, err
+			}
+			pc++
+			continue
+		}
+
+		nbIn, ok := nbInputs[op]
+		if !ok {
+			return nil, fmt.Errorf("%w 0x%02x at pc %d", errInvalidOpcode, op, pc)
+		}
+		in, err := f.pop(nbIn)
+		if err != nil {
+			return nil, err
+		}
+
+		var res *big.Int
+		switch op {
+		case opSTOP:
+			return nil, nil
+		case opADD:
+			res = toUnsigned(new(big.Int).Add(in[0], in[1]))
+		case opMUL:
+			res = toUnsigned(new(big.Int).Mul(in[0], in[1]))
+		case opSUB:
+			res = toUnsigned(new(big.Int).Sub(in[0], in[1]))
+		case opDIV:
+			res = new(big.Int)
+			if in[1].Sign() != 0 {
+				res.Div(in[0], in[1])
+			}
+		case opSDIV:
+			res = new(big.Int)
+			if in[1].Sign() != 0 {
+				// Quo truncates towards zero, as required
+				res = toUnsigned(res.Quo(toSigned(in[0]), toSigned(in[1])))
+			}
+		case opMOD:
+			res = new(big.Int)
+			if in[1].Sign() != 0 {
+				res.Mod(in[0], in[1])
+			}
+		case opSMOD:
+			res = new(big.Int)
+			if in[1].Sign() != 0 {
+				// Rem has the sign of the dividend, as required
+				res = toUnsigned(res.Rem(toSigned(in[0]), toSigned(in[1])))
+			}
+		case opADDMOD:
+			res = new(big.Int)
+			if in[2].Sign() != 0 {
+				res.Add(in[0], in[1]).Mod(res, in[2])
+			}
+		case opMULMOD:
+			res = new(big.Int)
+			if in[2].Sign() != 0 {
+				res.Mul(in[0], in[1]).Mod(res, in[2])
+			}
+		case opEXP:
+			res = new(big.Int).Exp(in[0], in[1], two256)
+		case opSIGNEXTEND:
+			res = new(big.Int).Set(in[1])
+			if in[0].Cmp(big.NewInt(31)) < 0 {
+				bit := uint(in[0].Uint64()*8 + 7)
+				m := new(big.Int).Sub(new(big.Int).Lsh(bigOne, bit), bigOne)
+				if in[1].Bit(int(bit)) == 1 {
+					res.Or(res, new(big.Int).Xor(mask256, m))
+				} else {
+					res.And(res, m)
+				}
+			}
+		case opLT:
+			res = boolToBig(in[0].Cmp(in[1]) < 0)
+		case opGT:
+			res = boolToBig(in[0].Cmp(in[1]) > 0)
+		case opSLT:
+			res = boolToBig(toSigned(in[0]).Cmp(toSigned(in[1])) < 0)
+		case opSGT:
+			res = boolToBig(toSigned(in[0]).Cmp(toSigned(in[1])) > 0)
+		case opEQ:
+			res = boolToBig(in[0].Cmp(in[1]) == 0)
+		case opISZERO:
+			res = boolToBig(in[0].Sign() == 0)
+		case opAND:
+			res = new(big.Int).And(in[0], in[1])
+		case opOR:
+			res = new(big.Int).Or(in[0], in[1])
+		case opXOR:
+			res = new(big.Int).Xor(in[0], in[1])
+		case opNOT:
+			res = new(big.Int).Xor(in[0], mask256)
+		case opBYTE:
+			res = new(big.Int)
+			if in[0].Cmp(big.NewInt(32)) < 0 {
+				var buf [32]byte
+				in[1].FillBytes(buf[:])
+				res.SetUint64(uint64(buf[in[0].Uint64()]))
+			}
+		case opSHL:
+			res = new(big.Int)
+			if in[0].Cmp(bigTwo256) < 0 {
+				res = toUnsigned(res.Lsh(in[1], uint(in[0].Uint64())))
+			}
+		case opSHR:
+			res = new(big.Int)
+			if in[0].Cmp(bigTwo256) < 0 {
+				res.Rsh(in[1], uint(in[0].Uint64()))
+			}
+		case opSAR:
+			shift := uint(255)
+			if in[0].Cmp(bigTwo256) < 0 && in[0].Uint64() < 255 {
+				shift = uint(in[0].Uint64())
+			}
+			// Rsh on negative numbers rounds towards -∞, as required
+			res = toUnsigned(new(big.Int).Rsh(toSigned(in[1]), shift))
+		case opKECCAK256:
+			o, s, err := f.expand(in[0], in[1])
+			if err != nil {
+				return nil, err
+			}
+			h := sha3.NewLegacyKeccak256()
+			h.Write(f.memory[o : o+s])
+			res = new(big.Int).SetBytes(h.Sum(nil))
+		case opADDRESS:
+			res = new(big.Int).SetBytes(f.address[:])
+		case opBALANCE, opSELFBALANCE, opCALLVALUE, opGASPRICE, opBLOCKHASH, opCOINBASE,
+			opTIMESTAMP, opNUMBER, opDIFFICULTY, opBASEFEE:
+			res = new(big.Int)
+		case opORIGIN, opCALLER:
+			res = new(big.Int)
+		case opCHAINID:
+			res = big.NewInt(1)
+		case opGASLIMIT, opGAS:
+			res = big.NewInt(gasLeft)
+		case opCALLDATALOAD:
+			res = new(big.Int).SetBytes(readPadded(f.input, in[0], 32))
+		case opCALLDATASIZE:
+			res = big.NewInt(int64(len(f.input)))
+		case opCODESIZE:
+			res = big.NewInt(int64(len(f.code)))
+		case opRETURNDATASIZE:
+			res = big.NewInt(int64(len(f.returnData)))
+		case opEXTCODESIZE:
+			var addr Address
+			in[0].FillBytes(addr[:])
+			res = big.NewInt(int64(len(f.evm.code[addr])))
+		case opCALLDATACOPY, opCODECOPY, opRETURNDATACOPY:
+			o, s, err := f.expand(in[0], in[2])
+			if err != nil {
+				return nil, err
+			}
+			src := f.input
+			if op == opCODECOPY {
+				src = f.code
+			} else if op == opRETURNDATACOPY {
+				src = f.returnData
+				if !in[1].IsUint64() || in[1].Uint64()+uint64(s) > uint64(len(src)) {
+					return nil, errors.New("return data out of bounds")
+				}
+			}
+			copy(f.memory[o:o+s], readPadded(src, in[1], s))
+		case opMCOPY:
+			d, s, err := f.expand(in[0], in[2])
+			if err != nil {
+				return nil, err
+			}
+			o, _, err := f.expand(in[1], in[2])
+			if err != nil {
+				return nil, err
+			}
+			copy(f.memory[d:d+s], f.memory[o:o+s])
+		case opPOP:
+		case opMLOAD:
+			o, _, err := f.expand(in[0], big.NewInt(32))
+			if err != nil {
+				return nil, err
+			}
+			res = new(big.Int).SetBytes(f.memory[o : o+32])
+		case opMSTORE:
+			o, _, err := f.expand(in[0], big.NewInt(32))
+			if err != nil {
+				return nil, err
+			}
+			in[1].FillBytes(f.memory[o : o+32])
+		case opMSTORE8:
+			o, _, err := f.expand(in[0], bigOne)
+			if err != nil {
+				return nil, err
+			}
+			f.memory[o] = byte(in[1].Uint64())
+		case opSLOAD:
+			res = new(big.Int)
+			if v, ok := f.evm.storage[f.address][in[0].String()]; ok {
+				res.Set(v)
+			}
+		case opSSTORE:
+			if f.static {
+				return nil, errWriteProtection
+			}
+			f.evm.storage[f.address][in[0].String()] = in[1]
+		case opJUMP:
+			if !in[0].IsUint64() || in[0].Uint64() >= uint64(len(f.code)) || !f.jumpDests[in[0].Uint64()] {
+				return nil, errInvalidJump
+			}
+			pc = int(in[0].Uint64())
+			continue
+		case opJUMPI:
+			if in[1].Sign() != 0 {
+				if !in[0].IsUint64() || in[0].Uint64() >= uint64(len(f.code)) || !f.jumpDests[in[0].Uint64()] {
+					return nil, errInvalidJump
+				}
+				pc = int(in[0].Uint64())
+				continue
+			}
+		case opPC:
+			res = big.NewInt(int64(pc))
+		case opMSIZE:
+			res = big.NewInt(int64(len(f.memory)))
+		case opJUMPDEST:
+		case opCALL, opSTATICCALL:
+			// CALL:       gas, addr, value, argsOffset, argsSize, retOffset, retSize
+			// STATICCALL: gas, addr, argsOffset, argsSize, retOffset, retSize
+			args := in[2:]
+			static := f.static || op == opSTATICCALL
+			if op == opCALL {
+				if in[2].Sign() != 0 {
+					return nil, errors.New("value transfer not supported")
+				}
+				args = in[3:]
+			}
+			ao, as, err := f.expand(args[0], args[1])
+			if err != nil {
+				return nil, err
+			}
+			ro, rs, err := f.expand(args[2], args[3])
+			if err != nil {
+				return nil, err
+			}
+			var to Address
+			new(big.Int).And(in[1], new(big.Int).Sub(new(big.Int).Lsh(bigOne, 160), bigOne)).FillBytes(to[:])
+			input := make([]byte, as)
+			copy(input, f.memory[ao:ao+as])
+
+			out, err := f.evm.call(to, input, static, f.depth+1)
+			res = boolToBig(err == nil)
+			var revertErr *RevertError
+			if err == nil {
+				f.returnData = out
+			} else if errors.As(err, &revertErr) {
+				f.returnData = revertErr.Data
+			} else {
+				f.returnData = nil
+			}
+			copy(f.memory[ro:ro+rs], f.returnData)
+		case opRETURN, opREVERT:
+			o, s, err := f.expand(in[0], in[1])
+			if err != nil {
+				return nil, err
+			}
+			out := make([]byte, s)
+			copy(out, f.memory[o:o+s])
+			if op == opREVERT {
+				return nil, &RevertError{Data: out}
+			}
+			return out, nil
+		case opINVALID:
+			return nil, fmt.Errorf("%w 0xfe at pc %d", errInvalidOpcode, pc)
+		case opSELFDESTRUCT, opCREATE, opCREATE2, opDELEGATECALL, opCALLCODE, opEXTCODECOPY, opEXTCODEHASH:
+			return nil, fmt.Errorf("opcode 0x%02x not supported", op)
+		}
+
+		if res != nil {
+			if err := f.push(res); err != nil {
+				return nil, err
+			}
+		}
+		pc++
+	}
+	return nil, nil
+}
@@ -0,0 +1,239 @@
+// Copyright 2020 ConsenSys Software Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package evm
+
+import (
+	"crypto/sha256"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/stretchr/testify/require"
+)
+
+// program is a tiny assembler for hand written test bytecode
+type program []byte
+
+func (p program) op(ops ...byte) program {
+	return append(p, ops...)
+}
+
+func (p program) push(v *big.Int) program {
+	b := v.Bytes()
+	if len(b) == 0 {
+		return append(p, opPUSH0)
+	}
+	p = append(p, opPUSH1+byte(len(b)-1))
+	return append(p, b...)
+}
+
+func (p program) pushInt(v int64) program {
+	return p.push(big.NewInt(v))
+}
+
+// returnTop stores the top of the stack at memory 0 and returns it
+func (p program) returnTop() program {
+	return p.pushInt(0).op(opMSTORE).pushInt(32).pushInt(0).op(opRETURN)
+}
+
+// deployer returns the init code deploying runtime
+func deployer(runtime []byte) []byte {
+	var init program
+	// CODECOPY(0, len(init), len(runtime)); RETURN(0, len(runtime))
+	// the init code has a fixed size of 12 bytes
+	init = init.pushInt(int64(len(runtime))).op(opDUP1).pushInt(12).pushInt(0).op(opCODECOPY)
+	init = init.pushInt(0).op(opRETURN)
+	for len(init) < 12 {
+		init = init.op(opJUMPDEST)
+	}
+	return append(init, runtime...)
+}
+
+func run(t *testing.T, code program, input []byte) ([]byte, error) {
+	t.Helper()
+	evm := New()
+	addr, err := evm.Deploy(deployer(code))
+	require.NoError(t, err)
+	return evm.Call(addr, input)
+}
+
+func requireWord(t *testing.T, expected *big.Int, out []byte) {
+	t.Helper()
+	require.Equal(t, expected.String(), new(big.Int).SetBytes(out).String())
+}
+
+func TestArithmetic(t *testing.T) {
+	minusOne := new(big.Int).Set(mask256)
+	minusSeven := new(big.Int).Sub(two256, big.NewInt(7))
+
+	for _, tc := range []struct {
+		name     string
+		code     program
+		expected *big.Int
+	}{
+		{"add", program{}.pushInt(3).pushInt(2).op(opADD), big.NewInt(5)},
+		{"sub", program{}.pushInt(3).pushInt(2).op(opSUB), minusOne},
+		{"mul overflow", program{}.pushInt(2).push(two255).op(opMUL), big.NewInt(0)},
+		{"div by zero", program{}.pushInt(0).pushInt(2).op(opDIV), big.NewInt(0)},
+		{"sdiv", program{}.pushInt(2).push(minusSeven).op(opSDIV), new(big.Int).Sub(two256, big.NewInt(3))},
+		{"smod", program{}.pushInt(2).push(minusSeven).op(opSMOD), minusOne},
+		{"addmod", program{}.pushInt(5).push(minusOne).push(minusOne).op(opADDMOD), big.NewInt(0)},
+		{"mulmod", program{}.pushInt(7).pushInt(4).pushInt(5).op(opMULMOD), big.NewInt(6)},
+		{"exp", program{}.pushInt(10).pushInt(2).op(opEXP), big.NewInt(1024)},
+		{"signextend", program{}.pushInt(0xff).pushInt(0).op(opSIGNEXTEND), minusOne},
+		{"slt", program{}.pushInt(1).push(minusOne).op(opSLT), big.NewInt(1)},
+		{"sar", program{}.push(minusSeven).pushInt(1).op(opSAR), new(big.Int).Sub(two256, big.NewInt(4))},
+		{"shl", program{}.pushInt(1).pushInt(8).op(opSHL), big.NewInt(256)},
+		{"byte", program{}.pushInt(0xabcd).pushInt(30).op(opBYTE), big.NewInt(0xab)},
+		{"jumpi", program{}.pushInt(1).pushInt(6).op(opJUMPI).op(opINVALID).op(opJUMPDEST).pushInt(42), big.NewInt(42)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := run(t, tc.code.returnTop(), nil)
+			require.NoError(t, err)
+			requireWord(t, tc.expected, out)
+		})
+	}
+}
+
+func TestCalldata(t *testing.T) {
+	// returns calldata[0] + calldata[1]
+	code := program{}.pushInt(0).op(opCALLDATALOAD).pushInt(32).op(opCALLDATALOAD).op(opADD).returnTop()
+	input := make([]byte, 64)
+	input[31] = 40
+	input[63] = 2
+	out, err := run(t, code, input)
+	require.NoError(t, err)
+	requireWord(t, big.NewInt(42), out)
+}
+
+func TestInvalidJump(t *testing.T) {
+	// jumps inside the push data of a PUSH2
+	code := program{}.pushInt(4).op(opJUMP, opPUSH1+1, opJUMPDEST, opJUMPDEST)
+	_, err := run(t, code, nil)
+	require.ErrorIs(t, err, errInvalidJump)
+}
+
+func TestRevertReason(t *testing.T) {
+	// Error("ko")
+	data := make([]byte, 4+3*32)
+	copy(data, []byte{0x08, 0xc3, 0x79, 0xa0})
+	data[4+31] = 32
+	data[4+63] = 2
+	copy(data[4+64:], "ko")
+
+	var code program
+	for i := 0; i < len(data); i += 32 {
+		code = code.push(new(big.Int).SetBytes(readPadded(data, big.NewInt(int64(i)), 32))).pushInt(int64(i)).op(opMSTORE)
+	}
+	code = code.pushInt(int64(len(data))).pushInt(0).op(opREVERT)
+
+	_, err := run(t, code, nil)
+	var revertErr *RevertError
+	require.True(t, errors.As(err, &revertErr))
+	require.Equal(t, data, revertErr.Data)
+	require.Equal(t, "execution reverted: ko", err.Error())
+}
+
+// callPrecompile returns the code calling the precompile at addr with the
+// calldata as input, and returning its output of size outSize
+func callPrecompile(addr, outSize int64) program {
+	var code program
+	code = code.op(opCALLDATASIZE).pushInt(0).pushInt(0).op(opCALLDATACOPY)
+	code = code.pushInt(outSize).pushInt(0).op(opCALLDATASIZE).pushInt(0).pushInt(addr).op(opGAS).op(opSTATICCALL)
+	// revert if the call failed
+	code = code.op(opISZERO).pushInt(int64(len(code) + 8)).op(opJUMPI)
+	code = code.pushInt(outSize).pushInt(0).op(opRETURN)
+	code = code.op(opJUMPDEST).pushInt(0).pushInt(0).op(opREVERT)
+	return code
+}
+
+func TestSha256(t *testing.T) {
+	out, err := run(t, callPrecompile(2, 32), []byte("gnark"))
+	require.NoError(t, err)
+	expected := sha256.Sum256([]byte("gnark"))
+	require.Equal(t, expected[:], out)
+}
+
+func TestModExp(t *testing.T) {
+	input := make([]byte, 96+3)
+	input[31], input[63], input[95] = 1, 1, 1
+	input[96], input[97], input[98] = 3, 5, 7
+	out, err := run(t, callPrecompile(5, 1), input)
+	require.NoError(t, err)
+	require.Equal(t, []byte{5}, out) // 3⁵ mod 7
+}
+
+func TestEcAddEcMul(t *testing.T) {
+	_, _, g1, _ := bn254.Generators()
+	var expected bn254.G1Affine
+	expected.ScalarMultiplication(&g1, big.NewInt(3))
+
+	input := append(writeG1(&g1), writeG1(&g1)...)
+	double, err := run(t, callPrecompile(6, 64), input)
+	require.NoError(t, err)
+
+	input = append(double, writeG1(&g1)...)
+	out, err := run(t, callPrecompile(6, 64), input)
+	require.NoError(t, err)
+	require.Equal(t, writeG1(&expected), out)
+
+	input = append(writeG1(&g1), make([]byte, 32)...)
+	input[len(input)-1] = 3
+	out, err = run(t, callPrecompile(7, 64), input)
+	require.NoError(t, err)
+	require.Equal(t, writeG1(&expected), out)
+
+	// point not on curve
+	input = append(writeG1(&g1), writeG1(&g1)...)
+	input[63] ^= 1
+	_, err = run(t, callPrecompile(6, 64), input)
+	require.Error(t, err)
+}
+
+func writeG2(p *bn254.G2Affine) []byte {
+	var res []byte
+	for _, e := range [...]func() [32]byte{p.X.A1.Bytes, p.X.A0.Bytes, p.Y.A1.Bytes, p.Y.A0.Bytes} {
+		b := e()
+		res = append(res, b[:]...)
+	}
+	return res
+}
+
+func TestEcPairing(t *testing.T) {
+	_, _, g1, g2 := bn254.Generators()
+	var g1Neg, g1Double bn254.G1Affine
+	g1Neg.Neg(&g1)
+	g1Double.ScalarMultiplication(&g1, big.NewInt(2))
+
+	// e(g1, g2) * e(-g1, g2) == 1
+	input := append(writeG1(&g1), writeG2(&g2)...)
+	input = append(input, writeG1(&g1Neg)...)
+	input = append(input, writeG2(&g2)...)
+	out, err := run(t, callPrecompile(8, 32), input)
+	require.NoError(t, err)
+	requireWord(t, big.NewInt(1), out)
+
+	// e(2*g1, g2) * e(-g1, g2) != 1
+	copy(input, writeG1(&g1Double))
+	out, err = run(t, callPrecompile(8, 32), input)
+	require.NoError(t, err)
+	requireWord(t, big.NewInt(0), out)
+
+	// empty input
+	out, err = run(t, callPrecompile(8, 32), nil)
+	require.NoError(t, err)
+	requireWord(t, big.NewInt(1), out)
+}
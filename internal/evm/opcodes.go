@@ -0,0 +1,127 @@
+// Copyright 2020 ConsenSys Software Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package evm
+
+const (
+	opSTOP       byte = 0x00
+	opADD        byte = 0x01
+	opMUL        byte = 0x02
+	opSUB        byte = 0x03
+	opDIV        byte = 0x04
+	opSDIV       byte = 0x05
+	opMOD        byte = 0x06
+	opSMOD       byte = 0x07
+	opADDMOD     byte = 0x08
+	opMULMOD     byte = 0x09
+	opEXP        byte = 0x0a
+	opSIGNEXTEND byte = 0x0b
+
+	opLT     byte = 0x10
+	opGT     byte = 0x11
+	opSLT    byte = 0x12
+	opSGT    byte = 0x13
+	opEQ     byte = 0x14
+	opISZERO byte = 0x15
+	opAND    byte = 0x16
+	opOR     byte = 0x17
+	opXOR    byte = 0x18
+	opNOT    byte = 0x19
+	opBYTE   byte = 0x1a
+	opSHL    byte = 0x1b
+	opSHR    byte = 0x1c
+	opSAR    byte = 0x1d
+
+	opKECCAK256 byte = 0x20
+
+	opADDRESS        byte = 0x30
+	opBALANCE        byte = 0x31
+	opORIGIN         byte = 0x32
+	opCALLER         byte = 0x33
+	opCALLVALUE      byte = 0x34
+	opCALLDATALOAD   byte = 0x35
+	opCALLDATASIZE   byte = 0x36
+	opCALLDATACOPY   byte = 0x37
+	opCODESIZE       byte = 0x38
+	opCODECOPY       byte = 0x39
+	opGASPRICE       byte = 0x3a
+	opEXTCODESIZE    byte = 0x3b
+	opEXTCODECOPY    byte = 0x3c
+	opRETURNDATASIZE byte = 0x3d
+	opRETURNDATACOPY byte = 0x3e
+	opEXTCODEHASH    byte = 0x3f
+
+	opBLOCKHASH   byte = 0x40
+	opCOINBASE    byte = 0x41
+	opTIMESTAMP   byte = 0x42
+	opNUMBER      byte = 0x43
+	opDIFFICULTY  byte = 0x44
+	opGASLIMIT    byte = 0x45
+	opCHAINID     byte = 0x46
+	opSELFBALANCE byte = 0x47
+	opBASEFEE     byte = 0x48
+
+	opPOP      byte = 0x50
+	opMLOAD    byte = 0x51
+	opMSTORE   byte = 0x52
+	opMSTORE8  byte = 0x53
+	opSLOAD    byte = 0x54
+	opSSTORE   byte = 0x55
+	opJUMP     byte = 0x56
+	opJUMPI    byte = 0x57
+	opPC       byte = 0x58
+	opMSIZE    byte = 0x59
+	opGAS      byte = 0x5a
+	opJUMPDEST byte = 0x5b
+	opMCOPY    byte = 0x5e
+	opPUSH0    byte = 0x5f
+	opPUSH1    byte = 0x60
+	opPUSH32   byte = 0x7f
+	opDUP1     byte = 0x80
+	opDUP16    byte = 0x8f
+	opSWAP1    byte = 0x90
+	opSWAP16   byte = 0x9f
+	opLOG0     byte = 0xa0
+	opLOG4     byte = 0xa4
+
+	opCREATE       byte = 0xf0
+	opCALL         byte = 0xf1
+	opCALLCODE     byte = 0xf2
+	opRETURN       byte = 0xf3
+	opDELEGATECALL byte = 0xf4
+	opCREATE2      byte = 0xf5
+	opSTATICCALL   byte = 0xfa
+	opREVERT       byte = 0xfd
+	opINVALID      byte = 0xfe
+	opSELFDESTRUCT byte = 0xff
+)
+
+// nbInputs is the number of stack items consumed by the opcodes which are not
+// PUSHx, DUPx, SWAPx or LOGx
+var nbInputs = map[byte]int{
+	opSTOP: 0, opADD: 2, opMUL: 2, opSUB: 2, opDIV: 2, opSDIV: 2, opMOD: 2, opSMOD: 2,
+	opADDMOD: 3, opMULMOD: 3, opEXP: 2, opSIGNEXTEND: 2,
+	opLT: 2, opGT: 2, opSLT: 2, opSGT: 2, opEQ: 2, opISZERO: 1, opAND: 2, opOR: 2, opXOR: 2,
+	opNOT: 1, opBYTE: 2, opSHL: 2, opSHR: 2, opSAR: 2,
+	opKECCAK256: 2,
+	opADDRESS:   0, opBALANCE: 1, opORIGIN: 0, opCALLER: 0, opCALLVALUE: 0, opCALLDATALOAD: 1,
+	opCALLDATASIZE: 0, opCALLDATACOPY: 3, opCODESIZE: 0, opCODECOPY: 3, opGASPRICE: 0,
+	opEXTCODESIZE: 1, opEXTCODECOPY: 4, opRETURNDATASIZE: 0, opRETURNDATACOPY: 3, opEXTCODEHASH: 1,
+	opBLOCKHASH: 1, opCOINBASE: 0, opTIMESTAMP: 0, opNUMBER: 0, opDIFFICULTY: 0, opGASLIMIT: 0,
+	opCHAINID: 0, opSELFBALANCE: 0, opBASEFEE: 0,
+	opPOP: 1, opMLOAD: 1, opMSTORE: 2, opMSTORE8: 2, opSLOAD: 1, opSSTORE: 2, opJUMP: 1, opJUMPI: 2,
+	opPC: 0, opMSIZE: 0, opGAS: 0, opJUMPDEST: 0, opMCOPY: 3,
+	opCREATE: 3, opCALL: 7, opCALLCODE: 7, opRETURN: 2, opDELEGATECALL: 6, opCREATE2: 4,
+	opSTATICCALL: 6, opREVERT: 2, opINVALID: 0, opSELFDESTRUCT: 1,
+}
@@ -0,0 +1,221 @@
+// Copyright 2020 ConsenSys Software Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package evm
+
+import (
+	"crypto/sha256"
+	"errors"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fp"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+var (
+	errInvalidPoint       = errors.New("invalid point")
+	errInvalidInputLength = errors.New("invalid input length")
+)
+
+type precompiledContract func(input []byte) ([]byte, error)
+
+// precompile returns the precompiled contract at addr, if any
+func precompile(addr Address) (precompiledContract, bool) {
+	for i := 0; i < 19; i++ {
+		if addr[i] != 0 {
+			return nil, false
+		}
+	}
+	switch addr[19] {
+	case 2:
+		return sha256Hash, true
+	case 4:
+		return identity, true
+	case 5:
+		return modExp, true
+	case 6:
+		return ecAdd, true
+	case 7:
+		return ecMul, true
+	case 8:
+		return ecPairing, true
+	}
+	return nil, false
+}
+
+func sha256Hash(input []byte) ([]byte, error) {
+	h := sha256.Sum256(input)
+	return h[:], nil
+}
+
+func identity(input []byte) ([]byte, error) {
+	return append([]byte{}, input...), nil
+}
+
+// modExp implements EIP-198
+func modExp(input []byte) ([]byte, error) {
+	zero := big.NewInt(0)
+	header := readPadded(input, zero, 96)
+	var lengths [3]int
+	for i := range lengths {
+		l, err := toInt(new(big.Int).SetBytes(header[i*32 : (i+1)*32]))
+		if err != nil {
+			return nil, err
+		}
+		lengths[i] = l
+	}
+	offset := 96
+	base := new(big.Int).SetBytes(readPadded(input, big.NewInt(int64(offset)), lengths[0]))
+	offset += lengths[0]
+	exp := new(big.Int).SetBytes(readPadded(input, big.NewInt(int64(offset)), lengths[1]))
+	offset += lengths[1]
+	mod := new(big.Int).SetBytes(readPadded(input, big.NewInt(int64(offset)), lengths[2]))
+
+	res := make([]byte, lengths[2])
+	if mod.Sign() == 0 {
+		return res, nil
+	}
+	new(big.Int).Exp(base, exp, mod).FillBytes(res)
+	return res, nil
+}
+
+// readFp reads a base field element, which must be in canonical form
+func readFp(buf []byte) (fp.Element, error) {
+	var e fp.Element
+	if new(big.Int).SetBytes(buf).Cmp(fp.Modulus()) >= 0 {
+		return e, errInvalidPoint
+	}
+	e.SetBytes(buf)
+	return e, nil
+}
+
+// readG1 reads an uncompressed G1 point, (0, 0) being the point at infinity
+func readG1(buf []byte) (bn254.G1Affine, error) {
+	var p bn254.G1Affine
+	var err error
+	if p.X, err = readFp(buf[:32]); err != nil {
+		return p, err
+	}
+	if p.Y, err = readFp(buf[32:64]); err != nil {
+		return p, err
+	}
+	if !p.IsInfinity() && !p.IsOnCurve() {
+		return p, errInvalidPoint
+	}
+	return p, nil
+}
+
+// readG2 reads an uncompressed G2 point encoded as x.A1, x.A0, y.A1, y.A0
+func readG2(buf []byte) (bn254.G2Affine, error) {
+	var p bn254.G2Affine
+	var err error
+	if p.X.A1, err = readFp(buf[:32]); err != nil {
+		return p, err
+	}
+	if p.X.A0, err = readFp(buf[32:64]); err != nil {
+		return p, err
+	}
+	if p.Y.A1, err = readFp(buf[64:96]); err != nil {
+		return p, err
+	}
+	if p.Y.A0, err = readFp(buf[96:128]); err != nil {
+		return p, err
+	}
+	if !p.IsInfinity() && (!p.IsOnCurve() || !p.IsInSubGroup()) {
+		return p, errInvalidPoint
+	}
+	return p, nil
+}
+
+func writeG1(p *bn254.G1Affine) []byte {
+	res := make([]byte, 64)
+	if p.IsInfinity() {
+		return res
+	}
+	xb := p.X.Bytes()
+	yb := p.Y.Bytes()
+	copy(res[:32], xb[:])
+	copy(res[32:], yb[:])
+	return res
+}
+
+func ecAdd(input []byte) ([]byte, error) {
+	buf := readPadded(input, big.NewInt(0), 128)
+	p, err := readG1(buf[:64])
+	if err != nil {
+		return nil, err
+	}
+	q, err := readG1(buf[64:])
+	if err != nil {
+		return nil, err
+	}
+	var pj, qj bn254.G1Jac
+	pj.FromAffine(&p)
+	qj.FromAffine(&q)
+	pj.AddAssign(&qj)
+	p.FromJacobian(&pj)
+	return writeG1(&p), nil
+}
+
+func ecMul(input []byte) ([]byte, error) {
+	buf := readPadded(input, big.NewInt(0), 96)
+	p, err := readG1(buf[:64])
+	if err != nil {
+		return nil, err
+	}
+	// G1 has prime order r
+	s := new(big.Int).SetBytes(buf[64:])
+	s.Mod(s, fr.Modulus())
+	p.ScalarMultiplication(&p, s)
+	return writeG1(&p), nil
+}
+
+func ecPairing(input []byte) ([]byte, error) {
+	if len(input)%192 != 0 {
+		return nil, errInvalidInputLength
+	}
+	var ps []bn254.G1Affine
+	var qs []bn254.G2Affine
+	for i := 0; i < len(input); i += 192 {
+		p, err := readG1(input[i : i+64])
+		if err != nil {
+			return nil, err
+		}
+		q, err := readG2(input[i+64 : i+192])
+		if err != nil {
+			return nil, err
+		}
+		// e(O, Q) = e(P, O) = 1
+		if p.IsInfinity() || q.IsInfinity() {
+			continue
+		}
+		ps = append(ps, p)
+		qs = append(qs, q)
+	}
+
+	res := make([]byte, 32)
+	if len(ps) == 0 {
+		res[31] = 1
+		return res, nil
+	}
+	ok, err := bn254.PairingCheck(ps, qs)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		res[31] = 1
+	}
+	return res, nil
+}
@@ -0,0 +1,61 @@
+// Copyright 2020 ConsenSys Software Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package evm
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// ErrSolcNotFound is returned by CompileSolidity when no solc binary is found in PATH
+var ErrSolcNotFound = errors.New("solc not found in PATH")
+
+// CompileSolidity compiles source with the solc binary found in PATH and returns
+// the creation bytecode of the contract with the given name.
+func CompileSolidity(source []byte, contract string) ([]byte, error) {
+	solc, err := exec.LookPath("solc")
+	if err != nil {
+		return nil, ErrSolcNotFound
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(solc, "--optimize", "--combined-json", "bin", "-")
+	cmd.Stdin = bytes.NewReader(source)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("solc: %w: %s", err, stderr.String())
+	}
+
+	var output struct {
+		Contracts map[string]struct {
+			Bin string `json:"bin"`
+		} `json:"contracts"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &output); err != nil {
+		return nil, err
+	}
+	for name, c := range output.Contracts {
+		if strings.HasSuffix(name, ":"+contract) {
+			return hex.DecodeString(c.Bin)
+		}
+	}
+	return nil, fmt.Errorf("contract %s not found in solc output", contract)
+}
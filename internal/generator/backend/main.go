@@ -154,6 +154,8 @@ func main() {
 				{File: filepath.Join(groth16Dir, "commitment.go"), Templates: []string{"groth16/groth16.commitment.go.tmpl", importCurve}},
 				{File: filepath.Join(groth16Dir, "marshal.go"), Templates: []string{"groth16/groth16.marshal.go.tmpl", importCurve}},
 				{File: filepath.Join(groth16Dir, "marshal_test.go"), Templates: []string{"groth16/tests/groth16.marshal.go.tmpl", importCurve}},
+				{File: filepath.Join(groth16Dir, "distributed.go"), Templates: []string{"groth16/groth16.distributed.go.tmpl", importCurve}},
+				{File: filepath.Join(groth16Dir, "distributed_test.go"), Templates: []string{"groth16/tests/groth16.distributed.go.tmpl", importCurve}},
 			}
 			if err := bgen.Generate(d, "groth16", "./template/zkpschemes/", entries...); err != nil {
 				panic(err) // TODO handle
@@ -185,6 +187,10 @@ func main() {
 				{File: filepath.Join(plonkFriDir, "verify.go"), Templates: []string{"plonkfri/plonk.verify.go.tmpl", importCurve}},
 				{File: filepath.Join(plonkFriDir, "prove.go"), Templates: []string{"plonkfri/plonk.prove.go.tmpl", importCurve}},
 				{File: filepath.Join(plonkFriDir, "setup.go"), Templates: []string{"plonkfri/plonk.setup.go.tmpl", importCurve}},
+				{File: filepath.Join(plonkFriDir, "fri.go"), Templates: []string{"plonkfri/plonk.fri.go.tmpl", importCurve}},
+				{File: filepath.Join(plonkFriDir, "marshal.go"), Templates: []string{"plonkfri/plonk.marshal.go.tmpl", importCurve}},
+				{File: filepath.Join(plonkFriDir, "marshal_test.go"), Templates: []string{"plonkfri/tests/marshal.go.tmpl", importCurve}},
+				{File: filepath.Join(plonkFriDir, "fri_test.go"), Templates: []string{"plonkfri/tests/fri.go.tmpl", importCurve}},
 			}
 			if err := bgen.Generate(d, "plonkfri", "./template/zkpschemes/", entries...); err != nil {
 				panic(err)
@@ -0,0 +1,24 @@
+// Package mmap provides read-only memory mapping of files.
+//
+// On platforms without mmap support, the file is read in memory instead.
+package mmap
+
+// File is a read-only mapping of a file in memory
+type File struct {
+	// Data is the content of the file. It must not be modified, and must not be
+	// accessed after Close.
+	Data []byte
+
+	unmap func() error
+}
+
+// Close releases the mapping
+func (f *File) Close() error {
+	if f.unmap == nil {
+		return nil
+	}
+	err := f.unmap()
+	f.unmap = nil
+	f.Data = nil
+	return err
+}
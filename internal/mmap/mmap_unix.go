@@ -0,0 +1,38 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+// +build linux darwin freebsd netbsd openbsd
+
+package mmap
+
+import (
+	"os"
+	"syscall"
+)
+
+// Open maps the file at path in memory. Pages are loaded by the OS on first access
+// and can be evicted under memory pressure.
+func Open(path string) (*File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if fi.Size() == 0 {
+		return &File{Data: []byte{}}, nil
+	}
+
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(fi.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, err
+	}
+	return &File{
+		Data: data,
+		unmap: func() error {
+			return syscall.Munmap(data)
+		},
+	}, nil
+}
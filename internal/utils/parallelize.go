@@ -11,6 +11,9 @@ func Parallelize(nbIterations int, work func(int, int), maxCpus ...int) {
 	nbTasks := runtime.NumCPU()
 	if len(maxCpus) == 1 {
 		nbTasks = maxCpus[0]
+		if nbTasks < 1 {
+			nbTasks = 1
+		}
 	}
 	nbIterationsPerCpus := nbIterations / nbTasks
 
@@ -36,5 +36,5 @@ func Example() {
 	// Showing nodes accounting for 2, 100% of 2 total
 	//       flat  flat%   sum%        cum   cum%
 	//          1 50.00% 50.00%          2   100%  profile_test.(*Circuit).Define profile/profile_test.go:19
-	//          1 50.00%   100%          1 50.00%  r1cs.(*builder).AssertIsEqual frontend/cs/r1cs/api_assertions.go:37
+	//          1 50.00%   100%          1 50.00%  r1cs.(*builder).AssertIsEqual frontend/cs/r1cs/api_assertions.go:38
 }
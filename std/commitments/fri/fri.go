@@ -1,6 +1,7 @@
 package fri
 
 import (
+	"errors"
 	"fmt"
 	"math/big"
 	"math/bits"
@@ -17,14 +18,20 @@ import (
 // same constant as in gnark-crypto
 const rho = 8
 const logRho = 3
-const nbRounds = 1
+
+var (
+	errFoldingFactor = errors.New("fri: the folding factor must be a power of 2 greater than 1")
+	errNbRounds      = errors.New("fri: the number of rounds must be positive")
+	errProofShape    = errors.New("fri: the proof doesn't match the parameters of the verifier")
+)
 
 // Round a single round of interactions between prover and verifier for fri.
 type Round struct {
 
-	// interactions series of queries from the verifier, each query is answered with a
-	// Merkle proof.
-	Interactions [][2]merkle.MerkleProof
+	// interactions series of queries from the verifier, each query is answered with the
+	// Merkle proofs of the entries of a fiber of x -> x^{foldingFactor}. With the default
+	// folding factor of 2, the interactions are pairs of Merkle proofs (cf gnark-crypto).
+	Interactions [][]merkle.MerkleProof
 
 	// evaluation stores the evaluation of the fully folded polynomial.
 	// The fully folded polynomial is constant, and is evaluated on a
@@ -44,6 +51,38 @@ type ProofOfProximity struct {
 	Rounds []Round
 }
 
+// BatchedProofOfProximity proof of proximity of several committed functions, attesting
+// that a random linear combination ∑ᵢ λⁱfᵢ is d-close to a low degree polynomial.
+type BatchedProofOfProximity struct {
+
+	// proof of proximity of ∑ᵢ λⁱfᵢ
+	ProofOfProximity
+
+	// Openings[r][i] are the Merkle proofs of the entries of fᵢ queried during the first
+	// interaction of the r-th round.
+	Openings [][][]merkle.MerkleProof
+}
+
+// Option configures the verifier of the proofs of proximity.
+type Option func(*RadixTwoFri)
+
+// WithNbRounds sets the number of rounds of the proofs, each round querying the
+// committed function at a fresh position. The default is 1, as in gnark-crypto.
+func WithNbRounds(nbRounds int) Option {
+	return func(s *RadixTwoFri) {
+		s.nbRounds = nbRounds
+	}
+}
+
+// WithFoldingFactor sets the number of entries folded at each interaction, it must be a
+// power of 2. The default is 2, as in gnark-crypto. If the folding factor doesn't divide
+// the size of the polynomial, the last interaction folds the remaining entries.
+func WithFoldingFactor(foldingFactor int) Option {
+	return func(s *RadixTwoFri) {
+		s.foldingFactor = foldingFactor
+	}
+}
+
 // radixTwoFri empty structs implementing compressionFunction for
 // the squaring function.
 type RadixTwoFri struct {
@@ -55,10 +94,22 @@ type RadixTwoFri struct {
 	// nbSteps number of interactions between the prover and the verifier
 	nbSteps int
 
+	// steps log₂ of the number of entries folded at each interaction
+	steps []int
+
+	// nbRounds number of rounds of the proofs
+	nbRounds int
+
+	// foldingFactor number of entries folded at each interaction
+	foldingFactor int
+
 	// Size of the polynomial. The size of the evaluation domain will be
 	// \rho * size.
 	size uint64
 
+	// logSize log₂ of the size of the evaluation domain
+	logSize int
+
 	// rootDomain generator of the cyclic group of unity of size \rho * size
 	genInv big.Int
 }
@@ -66,15 +117,31 @@ type RadixTwoFri struct {
 // NewRadixTwoFri creates an FFT-like oracle proof of proximity.
 // * h is the hash function that is used for the Merkle proofs
 // * gen is the generator of the cyclic group of unity of size \rho * size
-func NewRadixTwoFri(size uint64, h hash.Hash, gen big.Int) RadixTwoFri {
+func NewRadixTwoFri(size uint64, h hash.Hash, gen big.Int, opts ...Option) RadixTwoFri {
 
 	var res RadixTwoFri
+	res.nbRounds = 1
+	res.foldingFactor = 2
+	for _, opt := range opts {
+		opt(&res)
+	}
 
 	// computing the number of steps
 	n := ecc.NextPowerOfTwo(size)
-	nbSteps := bits.TrailingZeros(uint(n))
-	res.nbSteps = nbSteps
+	logN := bits.TrailingZeros(uint(n))
 	res.size = size
+	res.logSize = logN + logRho
+	if res.foldingFactor > 1 && bits.OnesCount(uint(res.foldingFactor)) == 1 {
+		logFoldingFactor := bits.TrailingZeros(uint(res.foldingFactor))
+		for r := logN; r > 0; r -= logFoldingFactor {
+			if r < logFoldingFactor {
+				res.steps = append(res.steps, r)
+			} else {
+				res.steps = append(res.steps, logFoldingFactor)
+			}
+		}
+	}
+	res.nbSteps = len(res.steps)
 
 	// hash function
 	res.h = h
@@ -85,8 +152,30 @@ func NewRadixTwoFri(size uint64, h hash.Hash, gen big.Int) RadixTwoFri {
 	return res
 }
 
+// check returns an error if the options of the verifier are invalid
+func (s RadixTwoFri) check() error {
+	if s.foldingFactor < 2 || bits.OnesCount(uint(s.foldingFactor)) != 1 {
+		return errFoldingFactor
+	}
+	if s.nbRounds < 1 {
+		return errNbRounds
+	}
+	return nil
+}
+
 // verifyProofOfProximitySingleRound verifies the proof of proximity (see gnark-crypto).
-func (s RadixTwoFri) verifyProofOfProximitySingleRound(api frontend.API, salt frontend.Variable, proof Round) error {
+// It returns the bits of the position queried in the first interaction, in the sorted
+// evaluations of the committed function.
+func (s RadixTwoFri) verifyProofOfProximitySingleRound(api frontend.API, salt frontend.Variable, proof Round) ([]frontend.Variable, error) {
+
+	if len(proof.Interactions) != s.nbSteps {
+		return nil, errProofShape
+	}
+	for i := 0; i < s.nbSteps; i++ {
+		if len(proof.Interactions[i]) != 1<<s.steps[i] {
+			return nil, errProofShape
+		}
+	}
 
 	// Fiat Shamir transcript to derive the challenges
 	// We take care that the namings fit on frSize bytes, to be consistent
@@ -105,17 +194,17 @@ func (s RadixTwoFri) verifyProofOfProximitySingleRound(api frontend.API, salt fr
 	// are different at each round.
 	err := fs.Bind(xis[0], []frontend.Variable{salt})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	for i := 0; i < s.nbSteps; i++ {
 		err := fs.Bind(xis[i], []frontend.Variable{proof.Interactions[i][0].RootHash})
 		if err != nil {
-			return err
+			return nil, err
 		}
 		xi[i], err = fs.ComputeChallenge(xis[i])
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
@@ -124,114 +213,145 @@ func (s RadixTwoFri) verifyProofOfProximitySingleRound(api frontend.API, salt fr
 	// query position.
 	err = fs.Bind(xis[s.nbSteps], []frontend.Variable{proof.Evaluation})
 	if err != nil {
-		return err
+		return nil, err
 	}
 	binSeed, err := fs.ComputeChallenge(xis[s.nbSteps])
 	if err != nil {
-		return err
+		return nil, err
 	}
 	bin := api.ToBinary(binSeed)
-	bPos := api.FromBinary(bin[:logRho+s.nbSteps]...)
 
-	si, err := api.NewHint(DeriveQueriesPositions, s.nbSteps, bPos, rho*s.size, s.nbSteps)
-	if err != nil {
-		return err
-	}
+	// position is the query in the sorted evaluations of the current function. The
+	// evaluations are sorted such that the fibers of x -> x^{a}, where a is the number of
+	// entries folded, are contiguous: the i-th entry of the fiber j is p(g^{j+i*n/a}), at
+	// position a*j+i. The least significant bits of position are the index in the fiber,
+	// the others are the index of the fiber.
+	position := bin[:s.logSize]
+	firstPosition := position
 
-	// prepare some data for the round checks...
 	var accGInv big.Int
 	accGInv.Set(&s.genInv)
-	even := make([]frontend.Variable, s.nbSteps)
-	odd := make([]frontend.Variable, s.nbSteps)
-	c := make([]frontend.Variable, s.nbSteps)
-	bsi := make([][]frontend.Variable, s.nbSteps)
-	for i := 0; i < s.nbSteps; i++ {
-		bsi[i] = api.ToBinary(si[i])
-		c[i] = bsi[i][0]
-		even[i] = api.Sub(si[i], c[i])
-		odd[i] = api.Add(si[i], api.Sub(1, c[i]))
-	}
-
-	// constrain the query positions: si[i]/2 = f(si[i-1])
-	// where f is the permutation sorted -> canonical
-	curSize := s.size * rho / 2
-	for i := 0; i < s.nbSteps-1; i++ {
-
-		// s <- s_{i}/2
-		s := api.FromBinary(bsi[i][1:]...)
-
-		// a <- s_{i+1}/2
-		a := api.FromBinary(bsi[i+1][1:]...)
-
-		// b <- f^{-1}(f(s_{i+1})/2) where f : i -> curSize-1-i (it flips the order of the slice [x ... x] of size curSize)
-		b := api.Sub(curSize-1, si[i+1])
-		cc := api.ToBinary(b)
-		b = api.FromBinary(cc[1:]...)
-		b = api.Sub(curSize-1, b)
-		u := api.Select(bsi[i+1][0], b, a)
-
-		api.AssertIsEqual(u, s)
-		curSize = curSize / 2
-	}
+	logSize := s.logSize
+	var expected frontend.Variable
+	for i, logFolding := range s.steps {
+
+		interaction := proof.Interactions[i]
+		fiber := position[logFolding:]
+		bFiber := api.FromBinary(fiber...)
+
+		// Merkle proofs of the entries of the fiber, which must all be against the root
+		// hash used in the transcript
+		values := make([]frontend.Variable, len(interaction))
+		for k := range interaction {
+			api.AssertIsEqual(interaction[k].RootHash, interaction[0].RootHash)
+			interaction[k].VerifyProof(api, s.h, api.Add(api.Mul(bFiber, len(interaction)), k))
+			values[k] = interaction[k].Path[0]
+		}
 
-	// for each round check the Merkle proof and the correctness of the folding
-	for i := 0; i < s.nbSteps; i++ {
+		// correctness of the folding of the previous interaction
+		if i > 0 {
+			api.AssertIsEqual(mux(api, position[:logFolding], values), expected)
+		}
 
-		// Merkle proofs
-		proof.Interactions[i][0].VerifyProof(api, s.h, even[i])
-		proof.Interactions[i][1].VerifyProof(api, s.h, odd[i])
+		// fold the fiber, g <- g^{fiber}
+		g := exp(api, accGInv, fiber)
+		expected = fold(api, values, accGInv, logSize, g, xi[i])
 
-		// correctness of the folding
+		// the fiber is the position of the folded evaluation in the canonical evaluations
+		// of the next function, we convert it to the position in the sorted ones.
 		if i < s.nbSteps-1 {
+			next := len(fiber) - s.steps[i+1]
+			position = append(append([]frontend.Variable{}, fiber[next:]...), fiber[:next]...)
+		}
 
-			// g <- g^{si/2}
-			g := exp(api, accGInv, bsi[i][1:])
-
-			// solve the system...
-			l := proof.Interactions[i][0].Path[0]
-			r := proof.Interactions[i][1].Path[0]
-			fe := api.Add(l, r)
-			fo := api.Mul(api.Sub(l, r), g)
-			fo = api.Div(api.Add(api.Mul(fo, xi[i]), fe), 2)
-
-			// compute the folding
-			ln := proof.Interactions[i+1][0].Path[0]
-			rn := proof.Interactions[i+1][1].Path[0]
-			fn := api.Select(c[i+1], rn, ln)
-			api.AssertIsEqual(fn, fo)
-
-			// accGinv <- accGinv^{2}
+		// accGinv <- accGinv^{a}
+		for k := 0; k < logFolding; k++ {
 			accGInv.Mul(&accGInv, &accGInv).
 				Mod(&accGInv, api.Compiler().Field())
 		}
+		logSize -= logFolding
 	}
 
-	// last transition
-	l := proof.Interactions[s.nbSteps-1][0].Path[0]
-	r := proof.Interactions[s.nbSteps-1][1].Path[0]
-
-	// g <- g^{si/2}
-	g := exp(api, accGInv, bsi[s.nbSteps-1][1:])
-
-	// solve the system and compute the last folding
-	fe := api.Add(l, r)
-	fo := api.Mul(api.Sub(l, r), g)
-	fo = api.Mul(fo, xi[s.nbSteps-1])
-	fo = api.Div(api.Add(fo, fe), 2)
+	// the fully folded polynomial is constant
+	api.AssertIsEqual(expected, proof.Evaluation)
 
-	api.AssertIsEqual(fo, proof.Evaluation)
-
-	return nil
+	return firstPosition, nil
 }
 
 // VerifyProofOfProximity verifies the proof, by checking each interaction one
 // by one.
 func (s RadixTwoFri) VerifyProofOfProximity(api frontend.API, proof ProofOfProximity) error {
-	for i := 0; i < nbRounds; i++ {
-		err := s.verifyProofOfProximitySingleRound(api, i, proof.Rounds[i])
+	if err := s.check(); err != nil {
+		return err
+	}
+	if len(proof.Rounds) != s.nbRounds {
+		return errProofShape
+	}
+	for i := 0; i < s.nbRounds; i++ {
+		_, err := s.verifyProofOfProximitySingleRound(api, i, proof.Rounds[i])
 		if err != nil {
 			return err
 		}
 	}
 	return nil
 }
+
+// VerifyBatchedProofOfProximity verifies the proof of proximity of the functions committed
+// with the Merkle roots roots.
+//
+// The coefficient λ of the linear combination is derived with Fiat Shamir from the roots,
+// under the challenge name "lambda". Then, at each round, the entries of the committed
+// functions opened at the positions queried in the first interaction must combine into
+// the entries of ∑ᵢ λⁱfᵢ.
+func (s RadixTwoFri) VerifyBatchedProofOfProximity(api frontend.API, roots []frontend.Variable, proof BatchedProofOfProximity) error {
+	if err := s.check(); err != nil {
+		return err
+	}
+	if len(proof.Rounds) != s.nbRounds || len(proof.Openings) != s.nbRounds {
+		return errProofShape
+	}
+	for r := range proof.Openings {
+		if len(proof.Openings[r]) != len(roots) {
+			return errProofShape
+		}
+		for i := range proof.Openings[r] {
+			if len(proof.Openings[r][i]) != 1<<s.steps[0] {
+				return errProofShape
+			}
+		}
+	}
+
+	// derive λ
+	name := paddNaming("lambda", utils.ByteLen(api.Compiler().Field()))
+	fs := fiatshamir.NewTranscript(api, s.h, name)
+	if err := fs.Bind(name, roots); err != nil {
+		return err
+	}
+	lambda, err := fs.ComputeChallenge(name)
+	if err != nil {
+		return err
+	}
+
+	for r := 0; r < s.nbRounds; r++ {
+		position, err := s.verifyProofOfProximitySingleRound(api, r, proof.Rounds[r])
+		if err != nil {
+			return err
+		}
+		bFiber := api.FromBinary(position[s.steps[0]:]...)
+
+		// ∑ᵢ λⁱfᵢ, entry by entry of the queried fiber
+		interaction := proof.Rounds[r].Interactions[0]
+		for k := range interaction {
+			var acc frontend.Variable = 0
+			for i := len(roots) - 1; i >= 0; i-- {
+				opening := &proof.Openings[r][i][k]
+				api.AssertIsEqual(opening.RootHash, roots[i])
+				opening.VerifyProof(api, s.h, api.Add(api.Mul(bFiber, len(interaction)), k))
+				acc = api.Add(api.Mul(acc, lambda), opening.Path[0])
+			}
+			api.AssertIsEqual(acc, interaction[k].Path[0])
+		}
+	}
+
+	return nil
+}
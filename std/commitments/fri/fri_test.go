@@ -1,12 +1,17 @@
 package fri
 
 import (
+	"fmt"
 	"math/big"
+	"math/bits"
 	"testing"
 
+	"github.com/consensys/gnark-crypto/accumulator/merkletree"
 	"github.com/consensys/gnark-crypto/ecc"
 	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/fft"
 	"github.com/consensys/gnark-crypto/ecc/bn254/fr/fri"
+	fiatshamir "github.com/consensys/gnark-crypto/fiat-shamir"
 	"github.com/consensys/gnark-crypto/hash"
 	"github.com/consensys/gnark/frontend"
 	"github.com/consensys/gnark/frontend/cs/r1cs"
@@ -22,6 +27,9 @@ type ProofOfProximityTest struct {
 var sizePolyTest = uint64(32)
 var nbSteps = 5
 
+// inverse of the generator of the biggest domain (of size \rho * sizePolyTest)
+var gInvTest = "14607982016670611764231825270871087984049314771307170893064215224383340934614"
+
 func (p *ProofOfProximityTest) Define(api frontend.API) error {
 
 	// creation of the hash function
@@ -32,7 +40,7 @@ func (p *ProofOfProximityTest) Define(api frontend.API) error {
 
 	// inverse of the generator of the biggest domain (of size \rho * sizePolyTest)
 	var gInv big.Int
-	gInv.SetString("14607982016670611764231825270871087984049314771307170893064215224383340934614", 10)
+	gInv.SetString(gInvTest, 10)
 
 	// oracle proof of proximity
 	opp := NewRadixTwoFri(sizePolyTest, &h, gInv)
@@ -64,12 +72,13 @@ func TestFriVerification(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	nbRounds := len(proximityProof.Rounds)
 
 	// 3 - create the circuit, allocate the slices...
 	var circuit ProofOfProximityTest
 	circuit.Proof.Rounds = make([]Round, nbRounds)
 	for i := 0; i < nbRounds; i++ {
-		circuit.Proof.Rounds[i].Interactions = make([][2]merkle.MerkleProof, nbSteps)
+		circuit.Proof.Rounds[i].Interactions = make([][]merkle.MerkleProof, nbSteps)
 		for j := 0; j < nbSteps; j++ {
 
 			// only one of the paths is filled, it is the longest of the 2.
@@ -78,6 +87,7 @@ func TestFriVerification(t *testing.T) {
 			if b > a {
 				a = b
 			}
+			circuit.Proof.Rounds[i].Interactions[j] = make([]merkle.MerkleProof, 2)
 			circuit.Proof.Rounds[i].Interactions[j][0].Path = make([]frontend.Variable, a)
 			circuit.Proof.Rounds[i].Interactions[j][1].Path = make([]frontend.Variable, a)
 		}
@@ -93,8 +103,9 @@ func TestFriVerification(t *testing.T) {
 	witness.Proof.Rounds = make([]Round, nbRounds)
 	for i := 0; i < nbRounds; i++ {
 		witness.Proof.Rounds[i].Evaluation = proximityProof.Rounds[i].Evaluation
-		witness.Proof.Rounds[i].Interactions = make([][2]merkle.MerkleProof, nbSteps)
+		witness.Proof.Rounds[i].Interactions = make([][]merkle.MerkleProof, nbSteps)
 		for j := 0; j < nbSteps; j++ {
+			witness.Proof.Rounds[i].Interactions[j] = make([]merkle.MerkleProof, 2)
 
 			// Merkle root
 			witness.Proof.Rounds[i].Interactions[j][0].RootHash = proximityProof.Rounds[i].Interactions[j][0].MerkleRoot
@@ -128,4 +139,393 @@ func TestFriVerification(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	// 6 - the reference prover below builds the same proof
+	ref := newReferenceFri(sizePolyTest, 2)
+	refProof, _ := ref.prove(t, polynomial, 1)
+	if refProof.Rounds[0].Evaluation != proximityProof.Rounds[0].Evaluation {
+		t.Fatal("reference prover doesn't match gnark-crypto")
+	}
+	for j := 0; j < nbSteps; j++ {
+		if refProof.Rounds[0].Interactions[j][0].RootHash.(*big.Int).Cmp(new(big.Int).SetBytes(proximityProof.Rounds[0].Interactions[j][0].MerkleRoot)) != 0 {
+			t.Fatal("reference prover doesn't match gnark-crypto")
+		}
+	}
+}
+
+type configurableFriCircuit struct {
+	Proof ProofOfProximity
+	opts  []Option
+}
+
+func (p *configurableFriCircuit) Define(api frontend.API) error {
+	h, err := mimc.NewMiMC(api)
+	if err != nil {
+		return err
+	}
+	var gInv big.Int
+	gInv.SetString(gInvTest, 10)
+	opp := NewRadixTwoFri(sizePolyTest, &h, gInv, p.opts...)
+	return opp.VerifyProofOfProximity(api, p.Proof)
+}
+
+func TestFriVerificationOptions(t *testing.T) {
+
+	polynomial := make([]fr.Element, sizePolyTest)
+	for i := 0; i < int(sizePolyTest); i++ {
+		polynomial[i].SetRandom()
+	}
+
+	for _, foldingFactor := range []int{2, 4, 8} {
+		for _, nbRounds := range []int{1, 3} {
+			t.Run(fmt.Sprintf("folding=%d/rounds=%d", foldingFactor, nbRounds), func(t *testing.T) {
+				ref := newReferenceFri(sizePolyTest, foldingFactor)
+				proof, _ := ref.prove(t, polynomial, nbRounds)
+				opts := []Option{WithFoldingFactor(foldingFactor), WithNbRounds(nbRounds)}
+
+				circuit := configurableFriCircuit{Proof: placeholderProofOfProximity(proof), opts: opts}
+				witness := configurableFriCircuit{Proof: proof}
+				if err := test.IsSolved(&circuit, &witness, ecc.BN254.ScalarField()); err != nil {
+					t.Fatal(err)
+				}
+
+				// the folding must be consistent between the interactions
+				tampered := witnessOf(proof)
+				tampered.Proof.Rounds[nbRounds-1].Evaluation = 42
+				if err := test.IsSolved(&circuit, &tampered, ecc.BN254.ScalarField()); err == nil {
+					t.Fatal("tampered proof verified")
+				}
+			})
+		}
+	}
+
+	// the number of rounds is enforced
+	ref := newReferenceFri(sizePolyTest, 2)
+	proof, _ := ref.prove(t, polynomial, 1)
+	circuit := configurableFriCircuit{Proof: placeholderProofOfProximity(proof), opts: []Option{WithNbRounds(2)}}
+	if _, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &circuit); err == nil {
+		t.Fatal("expected an error for a proof with too few rounds")
+	}
+	circuit.opts = []Option{WithFoldingFactor(3)}
+	if _, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &circuit); err == nil {
+		t.Fatal("expected an error for a folding factor which is not a power of 2")
+	}
+}
+
+type batchedFriCircuit struct {
+	Roots []frontend.Variable
+	Proof BatchedProofOfProximity
+	opts  []Option
+}
+
+func (p *batchedFriCircuit) Define(api frontend.API) error {
+	h, err := mimc.NewMiMC(api)
+	if err != nil {
+		return err
+	}
+	var gInv big.Int
+	gInv.SetString(gInvTest, 10)
+	opp := NewRadixTwoFri(sizePolyTest, &h, gInv, p.opts...)
+	return opp.VerifyBatchedProofOfProximity(api, p.Roots, p.Proof)
+}
+
+func TestBatchedFriVerification(t *testing.T) {
+
+	const nbPolynomials = 3
+	polynomials := make([][]fr.Element, nbPolynomials)
+	for i := range polynomials {
+		polynomials[i] = make([]fr.Element, sizePolyTest)
+		for j := range polynomials[i] {
+			polynomials[i][j].SetRandom()
+		}
+	}
+
+	for _, foldingFactor := range []int{2, 4} {
+		t.Run(fmt.Sprintf("folding=%d", foldingFactor), func(t *testing.T) {
+			const nbRounds = 2
+			ref := newReferenceFri(sizePolyTest, foldingFactor)
+			roots, proof := ref.proveBatch(t, polynomials, nbRounds)
+			opts := []Option{WithFoldingFactor(foldingFactor), WithNbRounds(nbRounds)}
+
+			circuit := batchedFriCircuit{Roots: make([]frontend.Variable, nbPolynomials), opts: opts}
+			circuit.Proof.ProofOfProximity = placeholderProofOfProximity(proof.ProofOfProximity)
+			circuit.Proof.Openings = make([][][]merkle.MerkleProof, len(proof.Openings))
+			for r := range proof.Openings {
+				circuit.Proof.Openings[r] = make([][]merkle.MerkleProof, len(proof.Openings[r]))
+				for i := range proof.Openings[r] {
+					circuit.Proof.Openings[r][i] = placeholderMerkleProofs(proof.Openings[r][i])
+				}
+			}
+
+			witness := batchedFriCircuit{Roots: roots, Proof: proof}
+			if err := test.IsSolved(&circuit, &witness, ecc.BN254.ScalarField()); err != nil {
+				t.Fatal(err)
+			}
+
+			// the openings must combine into the queried entries of the linear combination
+			witness.Proof.Openings[1][2] = append([]merkle.MerkleProof{}, proof.Openings[1][0]...)
+			if err := test.IsSolved(&circuit, &witness, ecc.BN254.ScalarField()); err == nil {
+				t.Fatal("tampered proof verified")
+			}
+		})
+	}
+}
+
+// referenceFri is a native prover of proofs of proximity with a configurable folding
+// factor. With a folding factor of 2, it builds the same proofs as gnark-crypto.
+type referenceFri struct {
+	h      hash.Hash
+	domain *fft.Domain
+	steps  []int
+}
+
+func newReferenceFri(size uint64, foldingFactor int) referenceFri {
+	var res referenceFri
+	res.h = hash.MIMC_BN254
+	n := ecc.NextPowerOfTwo(size)
+	res.domain = fft.NewDomain(n * rho)
+	logFoldingFactor := bits.TrailingZeros(uint(foldingFactor))
+	for r := bits.TrailingZeros64(n); r > 0; r -= logFoldingFactor {
+		if r < logFoldingFactor {
+			res.steps = append(res.steps, r)
+		} else {
+			res.steps = append(res.steps, logFoldingFactor)
+		}
+	}
+	return res
+}
+
+// evaluate returns the evaluations of p on the domain, in canonical order
+func (s referenceFri) evaluate(p []fr.Element) []fr.Element {
+	res := make([]fr.Element, s.domain.Cardinality)
+	copy(res, p)
+	s.domain.FFT(res, fft.DIF)
+	fft.BitReverse(res)
+	return res
+}
+
+// sortFibers orders the evaluations such that the fibers of x -> x^{2^logFolding}
+// are contiguous
+func sortFibers(evals []fr.Element, logFolding int) []fr.Element {
+	a := 1 << logFolding
+	n := len(evals) / a
+	res := make([]fr.Element, len(evals))
+	for j := 0; j < n; j++ {
+		for t := 0; t < a; t++ {
+			res[a*j+t] = evals[j+t*n]
+		}
+	}
+	return res
+}
+
+// merkleProof returns the Merkle proof of the entry at position in sorted
+func (s referenceFri) merkleProof(t *testing.T, sorted []fr.Element, position uint64) merkle.MerkleProof {
+	tree := merkletree.New(s.h.New())
+	if err := tree.SetIndex(position); err != nil {
+		t.Fatal(err)
+	}
+	for i := range sorted {
+		tree.Push(sorted[i].Marshal())
+	}
+	root, proofSet, _, _ := tree.Prove()
+	res := merkle.MerkleProof{RootHash: new(big.Int).SetBytes(root)}
+	res.Path = make([]frontend.Variable, len(proofSet))
+	for i := range proofSet {
+		res.Path[i] = new(big.Int).SetBytes(proofSet[i])
+	}
+	return res
+}
+
+func (s referenceFri) root(sorted []fr.Element) []byte {
+	tree := merkletree.New(s.h.New())
+	for i := range sorted {
+		tree.Push(sorted[i].Marshal())
+	}
+	return tree.Root()
+}
+
+// proveRound builds a round of the proof of proximity of the function of evaluations
+// evals, and returns the position queried in the first interaction
+func (s referenceFri) proveRound(t *testing.T, salt int, evals []fr.Element) (Round, uint64) {
+	var res Round
+	xis := make([]string, len(s.steps)+1)
+	for i := range s.steps {
+		xis[i] = paddNaming(fmt.Sprintf("x%d", i), fr.Bytes)
+	}
+	xis[len(s.steps)] = paddNaming("s0", fr.Bytes)
+	fs := fiatshamir.NewTranscript(s.h.New(), xis...)
+	var bSalt fr.Element
+	bSalt.SetUint64(uint64(salt))
+	if err := fs.Bind(xis[0], bSalt.Marshal()); err != nil {
+		t.Fatal(err)
+	}
+
+	// fold the evaluations
+	layers := make([][]fr.Element, len(s.steps))
+	gInv := s.domain.GeneratorInv
+	for i, logFolding := range s.steps {
+		layers[i] = sortFibers(evals, logFolding)
+		if err := fs.Bind(xis[i], s.root(layers[i])); err != nil {
+			t.Fatal(err)
+		}
+		b, err := fs.ComputeChallenge(xis[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		var x fr.Element
+		x.SetBytes(b)
+		evals = foldEvaluations(layers[i], logFolding, gInv, x)
+		for k := 0; k < logFolding; k++ {
+			gInv.Square(&gInv)
+		}
+	}
+	res.Evaluation = evals[0]
+
+	// queries
+	if err := fs.Bind(xis[len(s.steps)], evals[0].Marshal()); err != nil {
+		t.Fatal(err)
+	}
+	b, err := fs.ComputeChallenge(xis[len(s.steps)])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var bPos big.Int
+	bPos.SetBytes(b).Mod(&bPos, new(big.Int).SetUint64(s.domain.Cardinality))
+	position := bPos.Uint64()
+	first := position
+
+	logSize := bits.TrailingZeros64(s.domain.Cardinality)
+	res.Interactions = make([][]merkle.MerkleProof, len(s.steps))
+	for i, logFolding := range s.steps {
+		fiber := position >> logFolding
+		res.Interactions[i] = make([]merkle.MerkleProof, 1<<logFolding)
+		for k := range res.Interactions[i] {
+			res.Interactions[i][k] = s.merkleProof(t, layers[i], fiber<<logFolding+uint64(k))
+		}
+		logSize -= logFolding
+		if i < len(s.steps)-1 {
+			next := logSize - s.steps[i+1]
+			position = fiber>>next | (fiber&(1<<next-1))<<s.steps[i+1]
+		}
+	}
+	return res, first
+}
+
+func (s referenceFri) prove(t *testing.T, p []fr.Element, nbRounds int) (ProofOfProximity, []uint64) {
+	var res ProofOfProximity
+	evals := s.evaluate(p)
+	res.Rounds = make([]Round, nbRounds)
+	positions := make([]uint64, nbRounds)
+	for i := range res.Rounds {
+		res.Rounds[i], positions[i] = s.proveRound(t, i, evals)
+	}
+	return res, positions
+}
+
+// proveBatch commits to the polynomials and builds the proof of proximity of their
+// linear combination
+func (s referenceFri) proveBatch(t *testing.T, polynomials [][]fr.Element, nbRounds int) ([]frontend.Variable, BatchedProofOfProximity) {
+	sorted := make([][]fr.Element, len(polynomials))
+	roots := make([]frontend.Variable, len(polynomials))
+	name := paddNaming("lambda", fr.Bytes)
+	fs := fiatshamir.NewTranscript(s.h.New(), name)
+	for i := range polynomials {
+		sorted[i] = sortFibers(s.evaluate(polynomials[i]), s.steps[0])
+		root := s.root(sorted[i])
+		roots[i] = new(big.Int).SetBytes(root)
+		if err := fs.Bind(name, root); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b, err := fs.ComputeChallenge(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lambda fr.Element
+	lambda.SetBytes(b)
+
+	// ∑ᵢ λⁱpᵢ
+	combination := make([]fr.Element, len(polynomials[0]))
+	for i := len(polynomials) - 1; i >= 0; i-- {
+		for j := range combination {
+			combination[j].Mul(&combination[j], &lambda).Add(&combination[j], &polynomials[i][j])
+		}
+	}
+
+	var res BatchedProofOfProximity
+	var positions []uint64
+	res.ProofOfProximity, positions = s.prove(t, combination, nbRounds)
+	res.Openings = make([][][]merkle.MerkleProof, nbRounds)
+	for r := range res.Openings {
+		fiber := positions[r] >> s.steps[0]
+		res.Openings[r] = make([][]merkle.MerkleProof, len(polynomials))
+		for i := range polynomials {
+			res.Openings[r][i] = make([]merkle.MerkleProof, 1<<s.steps[0])
+			for k := range res.Openings[r][i] {
+				res.Openings[r][i][k] = s.merkleProof(t, sorted[i], fiber<<s.steps[0]+uint64(k))
+			}
+		}
+	}
+	return roots, res
+}
+
+// foldEvaluations folds the sorted evaluations of a function, cf fold
+func foldEvaluations(sorted []fr.Element, logFolding int, gInv, x fr.Element) []fr.Element {
+	a := 1 << logFolding
+	n := len(sorted) / a
+
+	var zetaInv, aInv fr.Element
+	zetaInv.Exp(gInv, big.NewInt(int64(n)))
+	aInv.SetUint64(uint64(a)).Inverse(&aInv)
+	zetaInvs := make([]fr.Element, a)
+	zetaInvs[0].SetOne()
+	for i := 1; i < a; i++ {
+		zetaInvs[i].Mul(&zetaInvs[i-1], &zetaInv)
+	}
+
+	res := make([]fr.Element, n)
+	var gj fr.Element
+	gj.SetOne()
+	for j := 0; j < n; j++ {
+		var y, ym fr.Element
+		y.Mul(&x, &gj)
+		ym.SetOne()
+		for m := 0; m < a; m++ {
+			var pm, tmp fr.Element
+			for t := 0; t < a; t++ {
+				tmp.Mul(&sorted[a*j+t], &zetaInvs[(t*m)%a])
+				pm.Add(&pm, &tmp)
+			}
+			pm.Mul(&pm, &aInv).Mul(&pm, &ym)
+			res[j].Add(&res[j], &pm)
+			ym.Mul(&ym, &y)
+		}
+		gj.Mul(&gj, &gInv)
+	}
+	return res
+}
+
+func placeholderMerkleProofs(proofs []merkle.MerkleProof) []merkle.MerkleProof {
+	res := make([]merkle.MerkleProof, len(proofs))
+	for i := range proofs {
+		res[i].Path = make([]frontend.Variable, len(proofs[i].Path))
+	}
+	return res
+}
+
+func placeholderProofOfProximity(proof ProofOfProximity) ProofOfProximity {
+	var res ProofOfProximity
+	res.Rounds = make([]Round, len(proof.Rounds))
+	for i := range proof.Rounds {
+		res.Rounds[i].Interactions = make([][]merkle.MerkleProof, len(proof.Rounds[i].Interactions))
+		for j := range proof.Rounds[i].Interactions {
+			res.Rounds[i].Interactions[j] = placeholderMerkleProofs(proof.Rounds[i].Interactions[j])
+		}
+	}
+	return res
+}
+
+func witnessOf(proof ProofOfProximity) configurableFriCircuit {
+	var res configurableFriCircuit
+	res.Proof.Rounds = append([]Round{}, proof.Rounds...)
+	return res
 }
@@ -2,6 +2,7 @@ package fri
 
 import (
 	"math/big"
+	"math/bits"
 
 	"github.com/consensys/gnark/backend/hint"
 	"github.com/consensys/gnark/frontend"
@@ -19,6 +20,60 @@ func exp(api frontend.API, x frontend.Variable, e []frontend.Variable) frontend.
 	return res
 }
 
+// fold returns the evaluation at x of the folding of a fiber of x -> x^{a}, where a
+// is the number of entries of the fiber. values[t] = p(g^{j+t*n/a}) where n = 2^{logSize},
+// gInv is the inverse of g, and gj = g^{-j}.
+//
+// If p = ∑ₘ Xᵐpₘ(Xᵃ), the system to solve for the pₘ(g^{a*j}) is a DFT of size a:
+// values[t] = ∑ₘ g^{jm}ζ^{tm}pₘ(g^{a*j}) where ζ = g^{n/a}, and the folded evaluation is
+// ∑ₘ xᵐpₘ(g^{a*j}).
+func fold(api frontend.API, values []frontend.Variable, gInv big.Int, logSize int, gj, x frontend.Variable) frontend.Variable {
+
+	field := api.Compiler().Field()
+	a := len(values)
+
+	// ζ^{-i}
+	zetaInv := make([]big.Int, a)
+	zetaInv[0].SetUint64(1)
+	var z big.Int
+	z.Exp(&gInv, big.NewInt(int64(1)<<(logSize-bits.TrailingZeros(uint(a)))), field)
+	for i := 1; i < a; i++ {
+		zetaInv[i].Mul(&zetaInv[i-1], &z).Mod(&zetaInv[i], field)
+	}
+	var aInv big.Int
+	aInv.SetUint64(uint64(a)).ModInverse(&aInv, field)
+
+	// ∑ₘ (x*g^{-j})ᵐ*a⁻¹*∑ₜ values[t]*ζ^{-tm}
+	y := api.Mul(x, gj)
+	var res frontend.Variable = 0
+	var ym frontend.Variable = 1
+	for m := 0; m < a; m++ {
+		var pm frontend.Variable = 0
+		for t := 0; t < a; t++ {
+			var c big.Int
+			c.Mul(&zetaInv[(t*m)%a], &aInv).Mod(&c, field)
+			pm = api.Add(pm, api.Mul(values[t], &c))
+		}
+		res = api.Add(res, api.Mul(pm, ym))
+		if m < a-1 {
+			ym = api.Mul(ym, y)
+		}
+	}
+	return res
+}
+
+// mux returns values[i], where i is given by its bits (little endian)
+func mux(api frontend.API, i []frontend.Variable, values []frontend.Variable) frontend.Variable {
+	for _, b := range i {
+		next := make([]frontend.Variable, len(values)/2)
+		for k := range next {
+			next[k] = api.Select(b, values[2*k+1], values[2*k])
+		}
+		values = next
+	}
+	return values[0]
+}
+
 // // mustBeInSameFiber ensures that {g1,g2} = f^{-1}(x) where f: x -> x^{2}
 // func mustBeInSameFiber(api frontend.API, g1, g2, x frontend.Variable) {
 // 	_g1 := api.Mul(g1, g1)
@@ -50,7 +105,7 @@ func paddNaming(s string, size int) string {
 	return string(a)
 }
 
-// deriveQueriesPositions derives the indices of the oracle
+// DeriveQueriesPositions derives the indices of the oracle
 // function that the verifier has to pick, in sorted form.
 // * pos is the initial position, i.e. the logarithm of the first challenge
 // * size is the size of the initial polynomial
@@ -65,6 +120,10 @@ func paddNaming(s string, size int) string {
 //
 // outputs:
 // * slice of positions to query during a round
+//
+// Deprecated: the verifier derives the positions from the bits of the challenges, without
+// hint. DeriveQueriesPositions is kept to solve the constraint systems compiled with
+// previous versions.
 var DeriveQueriesPositions = func(_ *big.Int, inputs []*big.Int, res []*big.Int) error {
 
 	pos := inputs[0].Uint64()
@@ -95,6 +95,13 @@ func (e *Element[T]) GnarkInitHook() {
 }
 
 // copy makes a deep copy of the element.
+// PrintLimbs implements frontend.LimbsPrinter, such that api.Println and api.Printf print the
+// element as its integer value (which is not reduced if the element overflows).
+func (e Element[T]) PrintLimbs() ([]frontend.Variable, uint) {
+	var fp T
+	return e.Limbs, fp.BitsPerLimb()
+}
+
 func (e *Element[T]) copy() *Element[T] {
 	r := Element[T]{}
 	r.Limbs = make([]frontend.Variable, len(e.Limbs))
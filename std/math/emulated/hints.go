@@ -12,10 +12,12 @@ import (
 // inside a func, then it becomes anonymous and hint identification is screwed.
 
 func init() {
+	hint.RegisterNamed(typedHintName, typedHint)
 	hint.Register(GetHints()...)
 }
 
-// GetHints returns all hint functions used in the package.
+// GetHints returns all hint functions used in the package. The typed hints (see RegisterHint)
+// are solved by a hint function registered by the package.
 func GetHints() []hint.Function {
 	return []hint.Function{
 		DivHint,
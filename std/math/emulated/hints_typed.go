@@ -0,0 +1,353 @@
+package emulated
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/consensys/gnark/backend/hint"
+	"github.com/consensys/gnark/frontend"
+)
+
+// typedHintName is the stable identifier of the hint function solving all the typed hints.
+const typedHintName = "emulated/v1/typed"
+
+var (
+	typedHints  = make(map[hint.ID]*registeredHint)
+	typedHintsM sync.RWMutex
+)
+
+// HintArgs are the values of the inputs or of the outputs of a typed hint. Each argument is a
+// slice, of the length given in the circuit: a single element is a slice of length one.
+type HintArgs struct {
+	Emulated [][]*big.Int // integer values of the emulated elements
+	Native   [][]*big.Int // values of the native variables
+}
+
+// HintFunction computes the outputs of a typed hint (see RegisterHint) from the values of its
+// inputs. mod is the modulus of the emulated field. The input elements are not necessarily
+// reduced. The outputs are initialized to zero and must be set to non-negative values: the
+// emulated ones must fit in an element, usually reduced modulo mod, and the native ones are
+// reduced in the native field.
+type HintFunction func(mod *big.Int, inputs, outputs HintArgs) error
+
+// Hint is a hint function over the emulated field T. Contrary to hint.Function, it doesn't
+// have to unpack its inputs from the limbs and the parameters of the emulated field, nor to
+// decompose its outputs into limbs: Field.CallHint does it, for emulated elements and native
+// variables, grouped in slices.
+//
+// The typed hints are all solved by a single hint function registered by this package, which
+// receives the identifier of the typed hint in its inputs. As such, the prover doesn't need to
+// provide them with backend.WithHints.
+type Hint[T FieldParams] struct {
+	h *registeredHint
+}
+
+type registeredHint struct {
+	name string
+	id   hint.ID
+	fn   HintFunction
+}
+
+// RegisterHint registers a typed hint over the emulated field T bound to the given stable
+// identifier, and returns it. As for hint.RegisterNamed, the identifier should be versioned
+// (e.g. "mygadget/v1/sqrt"), such that the compiled constraint systems don't depend on the
+// location of fn in the code. RegisterHint is typically called in a package level variable
+// declaration or in an init() method, and panics if the identifier is already registered.
+//
+// fn doesn't depend on T: gadgets generic over the emulated field get the hint over their
+// field with HintOf.
+func RegisterHint[T FieldParams](name string, fn HintFunction) *Hint[T] {
+	if name == "" {
+		panic("emulated: empty hint name")
+	}
+	h := &registeredHint{name: name, id: hint.NamedUUID(name), fn: fn}
+
+	typedHintsM.Lock()
+	defer typedHintsM.Unlock()
+	if other, ok := typedHints[h.id]; ok {
+		panic(fmt.Sprintf("emulated: hint %q already registered (%q)", name, other.name))
+	}
+	typedHints[h.id] = h
+	return &Hint[T]{h}
+}
+
+// HintOf returns the typed hint registered with the identifier name (see RegisterHint) over
+// the emulated field T. It panics if the identifier isn't registered.
+func HintOf[T FieldParams](name string) *Hint[T] {
+	typedHintsM.RLock()
+	defer typedHintsM.RUnlock()
+	h, ok := typedHints[hint.NamedUUID(name)]
+	if !ok {
+		panic(fmt.Sprintf("emulated: hint %q not registered", name))
+	}
+	return &Hint[T]{h}
+}
+
+// String returns the identifier of the hint
+func (h *Hint[T]) String() string {
+	return h.h.name
+}
+
+// HintInputs are the inputs of a typed hint in the circuit, see HintArgs
+type HintInputs[T FieldParams] struct {
+	Emulated [][]*Element[T]
+	Native   [][]frontend.Variable
+}
+
+// HintOutputs are the outputs of a typed hint in the circuit, see HintArgs
+type HintOutputs[T FieldParams] struct {
+	Emulated [][]*Element[T]
+	Native   [][]frontend.Variable
+}
+
+// NewHint calls the typed hint h on the elements inputs, given as the single emulated
+// argument, and returns nbOutputs elements, the single emulated output argument. See CallHint.
+func (f *Field[T]) NewHint(h *Hint[T], nbOutputs int, inputs ...*Element[T]) ([]*Element[T], error) {
+	res, err := f.CallHint(h, HintInputs[T]{Emulated: [][]*Element[T]{inputs}}, []int{nbOutputs}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return res.Emulated[0], nil
+}
+
+// CallHint calls the typed hint h on the inputs, and returns outputs whose emulated and
+// native arguments are of the lengths nbEmulated and nbNative.
+//
+// The inputs may have any number of limbs. The limbs of the emulated outputs are constrained
+// to be within bounds of the field parameters, the outputs are not constrained otherwise: it
+// is the caller responsibility to verify them.
+func (f *Field[T]) CallHint(h *Hint[T], inputs HintInputs[T], nbEmulated, nbNative []int) (HintOutputs[T], error) {
+	var res HintOutputs[T]
+	nbLimbs := int(f.fParams.NbLimbs())
+	nbOutputs := 0
+	for _, n := range nbEmulated {
+		if n < 0 {
+			return res, fmt.Errorf("hint %s: negative number of outputs", h)
+		}
+		nbOutputs += n * nbLimbs
+	}
+	for _, n := range nbNative {
+		if n < 0 {
+			return res, fmt.Errorf("hint %s: negative number of outputs", h)
+		}
+		nbOutputs += n
+	}
+	if nbOutputs == 0 {
+		return res, fmt.Errorf("hint %s must return at least one output", h)
+	}
+
+	// id, nbBits, nbLimbs, modulus limbs,
+	// nbEmulated, [len, [nbLimbs(input), input limbs]...]...,
+	// nbNative, [len, inputs...]...,
+	// nbEmulated outputs, [len]..., nbNative outputs, [len]...
+	p := f.Modulus()
+	hintInputs := []frontend.Variable{
+		uint64(h.h.id),
+		f.fParams.BitsPerLimb(),
+		nbLimbs,
+	}
+	hintInputs = append(hintInputs, p.Limbs...)
+	hintInputs = append(hintInputs, len(inputs.Emulated))
+	for _, arg := range inputs.Emulated {
+		hintInputs = append(hintInputs, len(arg))
+		for _, in := range arg {
+			hintInputs = append(hintInputs, len(in.Limbs))
+			hintInputs = append(hintInputs, in.Limbs...)
+		}
+	}
+	hintInputs = append(hintInputs, len(inputs.Native))
+	for _, arg := range inputs.Native {
+		hintInputs = append(hintInputs, len(arg))
+		hintInputs = append(hintInputs, arg...)
+	}
+	for _, lengths := range [][]int{nbEmulated, nbNative} {
+		hintInputs = append(hintInputs, len(lengths))
+		for _, n := range lengths {
+			hintInputs = append(hintInputs, n)
+		}
+	}
+
+	outputs, err := f.api.NewHint(typedHint, nbOutputs, hintInputs...)
+	if err != nil {
+		return res, err
+	}
+	res.Emulated = make([][]*Element[T], len(nbEmulated))
+	for i, n := range nbEmulated {
+		res.Emulated[i] = make([]*Element[T], n)
+		for j := range res.Emulated[i] {
+			res.Emulated[i][j] = f.packLimbs(outputs[:nbLimbs], true)
+			outputs = outputs[nbLimbs:]
+		}
+	}
+	res.Native = make([][]frontend.Variable, len(nbNative))
+	for i, n := range nbNative {
+		res.Native[i], outputs = outputs[:n], outputs[n:]
+	}
+	return res, nil
+}
+
+// hintReader reads the inputs packed by Field.CallHint
+type hintReader struct {
+	name   string
+	inputs []*big.Int
+}
+
+// next returns the next n inputs
+func (r *hintReader) next(n int) ([]*big.Int, error) {
+	if n < 0 || len(r.inputs) < n {
+		return nil, fmt.Errorf("%s: inputs missing", r.name)
+	}
+	res := r.inputs[:n]
+	r.inputs = r.inputs[n:]
+	return res, nil
+}
+
+// length returns the next input as a length, at most max
+func (r *hintReader) length(max int) (int, error) {
+	v, err := r.next(1)
+	if err != nil {
+		return 0, err
+	}
+	if !v[0].IsInt64() || v[0].Int64() < 0 || v[0].Int64() > int64(max) {
+		return 0, fmt.Errorf("%s: invalid length", r.name)
+	}
+	return int(v[0].Int64()), nil
+}
+
+// lengths returns the lengths of the arguments of a kind, at most max each
+func (r *hintReader) lengths(max int) ([]int, error) {
+	n, err := r.length(len(r.inputs))
+	if err != nil {
+		return nil, err
+	}
+	res := make([]int, n)
+	for i := range res {
+		if res[i], err = r.length(max); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// newArgs returns arguments of the given lengths, initialized to zero
+func newArgs(lengths []int) [][]*big.Int {
+	res := make([][]*big.Int, len(lengths))
+	for i, n := range lengths {
+		res[i] = make([]*big.Int, n)
+		for j := range res[i] {
+			res[i][j] = new(big.Int)
+		}
+	}
+	return res
+}
+
+// typedHint unpacks the inputs packed by Field.CallHint, calls the typed hint they identify and
+// decomposes its outputs into limbs.
+func typedHint(_ *big.Int, inputs []*big.Int, outputs []*big.Int) error {
+	if len(inputs) < 3 {
+		return fmt.Errorf("input must be at least three elements")
+	}
+	typedHintsM.RLock()
+	h, ok := typedHints[hint.ID(inputs[0].Uint64())]
+	typedHintsM.RUnlock()
+	if !ok {
+		return fmt.Errorf("unknown typed hint %d", inputs[0].Uint64())
+	}
+
+	nbBits := uint(inputs[1].Uint64())
+	nbLimbs := int(inputs[2].Int64())
+	if nbLimbs <= 0 {
+		return fmt.Errorf("%s: invalid number of limbs", h.name)
+	}
+	r := hintReader{name: h.name, inputs: inputs[3:]}
+	limbs, err := r.next(nbLimbs)
+	if err != nil {
+		return err
+	}
+	p := new(big.Int)
+	if err := recompose(limbs, nbBits, p); err != nil {
+		return fmt.Errorf("%s: recompose emulated order: %w", h.name, err)
+	}
+
+	var in, out HintArgs
+	emulated, err := r.lengths(len(r.inputs))
+	if err != nil {
+		return err
+	}
+	in.Emulated = newArgs(emulated)
+	for i := range in.Emulated {
+		for j := range in.Emulated[i] {
+			n, err := r.length(len(r.inputs))
+			if err != nil {
+				return err
+			}
+			if limbs, err = r.next(n); err != nil {
+				return err
+			}
+			if n != 0 {
+				if err := recompose(limbs, nbBits, in.Emulated[i][j]); err != nil {
+					return fmt.Errorf("%s: recompose input %d.%d: %w", h.name, i, j, err)
+				}
+			}
+		}
+	}
+	native, err := r.lengths(len(r.inputs))
+	if err != nil {
+		return err
+	}
+	in.Native = make([][]*big.Int, len(native))
+	for i, n := range native {
+		if in.Native[i], err = r.next(n); err != nil {
+			return err
+		}
+	}
+
+	nbEmulated, err := r.lengths(len(outputs) / nbLimbs)
+	if err != nil {
+		return err
+	}
+	nbNative, err := r.lengths(len(outputs))
+	if err != nil {
+		return err
+	}
+	if len(r.inputs) != 0 {
+		return fmt.Errorf("%s: input length mismatch", h.name)
+	}
+	nbOutputs := 0
+	for _, n := range nbEmulated {
+		nbOutputs += n * nbLimbs
+	}
+	for _, n := range nbNative {
+		nbOutputs += n
+	}
+	if len(outputs) != nbOutputs {
+		return fmt.Errorf("%s: output length mismatch", h.name)
+	}
+	out.Emulated, out.Native = newArgs(nbEmulated), newArgs(nbNative)
+
+	if err := h.fn(p, in, out); err != nil {
+		return fmt.Errorf("%s: %w", h.name, err)
+	}
+	for i := range out.Emulated {
+		for j, o := range out.Emulated[i] {
+			if o.Sign() < 0 {
+				return fmt.Errorf("%s: output %d.%d is negative", h.name, i, j)
+			}
+			if err := decompose(o, nbBits, outputs[:nbLimbs]); err != nil {
+				return fmt.Errorf("%s: decompose output %d.%d: %w", h.name, i, j, err)
+			}
+			outputs = outputs[nbLimbs:]
+		}
+	}
+	for i := range out.Native {
+		for j, o := range out.Native[i] {
+			if o.Sign() < 0 {
+				return fmt.Errorf("%s: native output %d.%d is negative", h.name, i, j)
+			}
+			outputs[0].Set(o)
+			outputs = outputs[1:]
+		}
+	}
+	return nil
+}
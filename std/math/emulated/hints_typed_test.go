@@ -0,0 +1,187 @@
+package emulated
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/backend/hint"
+	cs_bn254 "github.com/consensys/gnark/constraint/bn254"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/test"
+)
+
+// sumProductHint returns the sum and the product of its inputs
+var sumProductHint = RegisterHint[BN254Fp]("emulated/test/v1/sum-product", func(p *big.Int, inputs, outputs HintArgs) error {
+	if len(inputs.Emulated) != 1 || len(outputs.Emulated) != 1 || len(outputs.Emulated[0]) != 2 {
+		return fmt.Errorf("expecting two outputs")
+	}
+	sum, prod := outputs.Emulated[0][0], outputs.Emulated[0][1]
+	prod.SetUint64(1)
+	for _, in := range inputs.Emulated[0] {
+		sum.Add(sum, in)
+		prod.Mul(prod, in)
+	}
+	sum.Mod(sum, p)
+	prod.Mod(prod, p)
+	return nil
+})
+
+// scaleHint returns k⋅xᵢ for the emulated elements xᵢ and the native k, and the native k⋅n
+// where n is the number of elements
+var _ = RegisterHint[BN254Fp]("emulated/test/v1/scale", func(p *big.Int, inputs, outputs HintArgs) error {
+	if len(inputs.Emulated) != 1 || len(inputs.Native) != 1 || len(inputs.Native[0]) != 1 {
+		return fmt.Errorf("expecting elements and a native input")
+	}
+	xs, k := inputs.Emulated[0], inputs.Native[0][0]
+	if len(outputs.Emulated) != 1 || len(outputs.Emulated[0]) != len(xs) || len(outputs.Native) != 1 || len(outputs.Native[0]) != 1 {
+		return fmt.Errorf("expecting %d elements and a native output", len(xs))
+	}
+	for i, x := range xs {
+		outputs.Emulated[0][i].Mul(x, k).Mod(outputs.Emulated[0][i], p)
+	}
+	outputs.Native[0][0].Mul(k, big.NewInt(int64(len(xs))))
+	return nil
+})
+
+type TypedHintCircuit[T FieldParams] struct {
+	A, B, C   Element[T]
+	Sum, Prod Element[T]
+}
+
+func (c *TypedHintCircuit[T]) Define(api frontend.API) error {
+	f, err := NewField[T](api)
+	if err != nil {
+		return err
+	}
+	// the product of A and B is not reduced, and has more limbs than C
+	ab := f.Mul(&c.A, &c.B)
+	res, err := f.NewHint(HintOf[T]("emulated/test/v1/sum-product"), 2, ab, &c.C)
+	if err != nil {
+		return err
+	}
+	f.AssertIsEqual(res[0], &c.Sum)
+	f.AssertIsEqual(res[1], &c.Prod)
+	f.AssertIsEqual(f.Mul(ab, &c.C), res[1])
+	return nil
+}
+
+type TypedHintArgsCircuit[T FieldParams] struct {
+	X, Y, Z Element[T]
+	K       frontend.Variable
+	KE      Element[T]
+}
+
+func (c *TypedHintArgsCircuit[T]) Define(api frontend.API) error {
+	f, err := NewField[T](api)
+	if err != nil {
+		return err
+	}
+	res, err := f.CallHint(HintOf[T]("emulated/test/v1/scale"), HintInputs[T]{
+		Emulated: [][]*Element[T]{{&c.X, &c.Y, &c.Z}},
+		Native:   [][]frontend.Variable{{c.K}},
+	}, []int{3}, []int{1})
+	if err != nil {
+		return err
+	}
+	for i, x := range []*Element[T]{&c.X, &c.Y, &c.Z} {
+		f.AssertIsEqual(res.Emulated[0][i], f.Mul(x, &c.KE))
+	}
+	api.AssertIsEqual(res.Native[0][0], api.Mul(c.K, 3))
+	return nil
+}
+
+func TestTypedHint(t *testing.T) {
+	testTypedHint[Goldilocks](t)
+	testTypedHint[Secp256k1Fp](t)
+	testTypedHint[BN254Fp](t)
+}
+
+func testTypedHint[T FieldParams](t *testing.T) {
+	var fp T
+	assert := test.NewAssert(t)
+	assert.Run(func(assert *test.Assert) {
+		var circuit, witness TypedHintCircuit[T]
+		p := fp.Modulus()
+		a, _ := rand.Int(rand.Reader, p)
+		b, _ := rand.Int(rand.Reader, p)
+		c, _ := rand.Int(rand.Reader, p)
+		ab := new(big.Int).Mul(a, b)
+		sum := new(big.Int).Add(ab, c)
+		prod := new(big.Int).Mul(ab, c)
+		witness.A = ValueOf[T](a)
+		witness.B = ValueOf[T](b)
+		witness.C = ValueOf[T](c)
+		witness.Sum = ValueOf[T](sum.Mod(sum, p))
+		witness.Prod = ValueOf[T](prod.Mod(prod, p))
+		assert.ProverSucceeded(&circuit, &witness, test.WithCurves(testCurve), test.NoSerialization(), test.WithBackends(backend.GROTH16, backend.PLONK))
+	}, testName[T]())
+	assert.Run(func(assert *test.Assert) {
+		var circuit, witness TypedHintArgsCircuit[T]
+		p := fp.Modulus()
+		for _, x := range []*Element[T]{&witness.X, &witness.Y, &witness.Z} {
+			v, _ := rand.Int(rand.Reader, p)
+			*x = ValueOf[T](v)
+		}
+		witness.K = 12345
+		witness.KE = ValueOf[T](12345)
+		assert.SolvingSucceeded(&circuit, &witness, test.WithCurves(testCurve), test.NoSerialization(), test.WithBackends(backend.GROTH16, backend.PLONK))
+	}, "args", testName[T]())
+}
+
+func TestRegisterHint(t *testing.T) {
+	assert := test.NewAssert(t)
+	assert.Equal("emulated/test/v1/sum-product", HintOf[Secp256k1Fp]("emulated/test/v1/sum-product").String())
+	assert.Panics(func() { RegisterHint[Secp256k1Fp]("emulated/test/v1/sum-product", sumProductHint.h.fn) })
+	assert.Panics(func() { HintOf[Secp256k1Fp]("emulated/test/v1/unknown") })
+
+	// outputs which don't fit in an element are rejected
+	var fp Secp256k1Fp
+	tooLarge := RegisterHint[Secp256k1Fp]("emulated/test/v1/too-large", func(_ *big.Int, _, outputs HintArgs) error {
+		outputs.Emulated[0][0].Lsh(big.NewInt(1), fp.BitsPerLimb()*fp.NbLimbs())
+		return nil
+	})
+	inputs := []*big.Int{big.NewInt(int64(tooLarge.h.id)), big.NewInt(int64(fp.BitsPerLimb())), big.NewInt(int64(fp.NbLimbs()))}
+	for i := 0; i < int(fp.NbLimbs()); i++ {
+		inputs = append(inputs, big.NewInt(0))
+	}
+	// no inputs, one emulated output
+	for _, v := range []int64{0, 0, 1, 1, 0} {
+		inputs = append(inputs, big.NewInt(v))
+	}
+	outputs := make([]*big.Int, fp.NbLimbs())
+	for i := range outputs {
+		outputs[i] = new(big.Int)
+	}
+	assert.Error(typedHint(nil, inputs, outputs))
+}
+
+type divInverseCircuit[T FieldParams] struct {
+	A, B Element[T]
+}
+
+func (c *divInverseCircuit[T]) Define(api frontend.API) error {
+	f, err := NewField[T](api)
+	if err != nil {
+		return err
+	}
+	f.Div(&c.A, &c.B)
+	f.Inverse(&c.B)
+	return nil
+}
+
+// TestDivInverseHints checks that Div and Inverse don't use the typed hints, such that the
+// constraint systems compiled before the typed hints are unchanged
+func TestDivInverseHints(t *testing.T) {
+	assert := test.NewAssert(t)
+	ccs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &divInverseCircuit[Secp256k1Fp]{})
+	assert.NoError(err)
+	dependencies := ccs.(*cs_bn254.R1CS).MHintsDependencies
+	assert.Contains(dependencies, hint.UUID(DivHint))
+	assert.Contains(dependencies, hint.UUID(InverseHint))
+	assert.NotContains(dependencies, hint.NamedUUID(typedHintName))
+}
@@ -0,0 +1,184 @@
+/*
+Copyright © 2020 ConsenSys
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plonkfri
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/consensys/gnark/backend/plonkfri"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/internal/utils"
+)
+
+// Assign sets the values of the proof from a native PLONK-FRI proof (see plonkfri.Prove).
+//
+// The native proof being curve specific, it is copied field by field.
+func (p *Proof) Assign(proof plonkfri.Proof) error {
+	return assign(reflect.ValueOf(p).Elem(), reflect.ValueOf(proof))
+}
+
+// Assign sets the verifying key from a native PLONK-FRI verifying key (see
+// plonkfri.Setup). It must be called before the outer circuit is compiled.
+func (vk *VerifyingKey) Assign(_vk plonkfri.VerifyingKey) error {
+	src := reflect.ValueOf(_vk)
+	if src.Kind() == reflect.Pointer {
+		src = src.Elem()
+	}
+	if src.Kind() != reflect.Struct {
+		return fmt.Errorf("unsupported verifying key type %T", _vk)
+	}
+
+	vk.Size = src.FieldByName("Size").Uint()
+	vk.NbPublicVariables = src.FieldByName("NbPublicVariables").Uint()
+	vk.SizeInv = utils.FromInterface(src.FieldByName("SizeInv").Addr().Interface())
+	vk.Generator = utils.FromInterface(src.FieldByName("Generator").Addr().Interface())
+	vk.GenOpening = utils.FromInterface(src.FieldByName("GenOpening").Addr().Interface())
+
+	params := src.FieldByName("Iopp").MethodByName("Params").Call(nil)[0]
+	if err := assign(reflect.ValueOf(&vk.Params).Elem(), params); err != nil {
+		return err
+	}
+	if !vk.Params.MiMC {
+		return errors.New("the verifying key must be set up with MiMC (see plonkfri.WithMiMC)")
+	}
+
+	for i := range vk.Qpp {
+		vk.Qpp[i] = utils.FromInterface(src.FieldByName("Qpp").Index(i).FieldByName("ID").Interface())
+	}
+	for i := range vk.Spp {
+		vk.Spp[i] = utils.FromInterface(src.FieldByName("Spp").Index(i).FieldByName("ID").Interface())
+	}
+	for i := range vk.Idpp {
+		vk.Idpp[i] = utils.FromInterface(src.FieldByName("Idpp").Index(i).FieldByName("ID").Interface())
+	}
+	return nil
+}
+
+// PlaceholderProof returns a proof with the shape of the proofs verified with vk, to be
+// used in the outer circuit definition when it is compiled
+func PlaceholderProof(vk VerifyingKey) Proof {
+	var proof Proof
+	steps, logSize := friSteps(vk)
+
+	logArity := steps[0]
+	placeholderMerkleProof := func(logSize, logArity int) MerkleProof {
+		return MerkleProof{
+			Leaf: make([]frontend.Variable, 1<<logArity),
+			Path: make([]frontend.Variable, logSize-logArity),
+		}
+	}
+	placeholderProofOfProximity := func() ProofOfProximity {
+		pp := ProofOfProximity{
+			Roots:   make([]frontend.Variable, len(steps)-1),
+			Queries: make([][]MerkleProof, vk.Params.NbQueries),
+		}
+		for i := range pp.Queries {
+			pp.Queries[i] = make([]MerkleProof, len(steps))
+			logSize := logSize
+			for j, logArity := range steps {
+				pp.Queries[i][j] = placeholderMerkleProof(logSize, logArity)
+				logSize -= logArity
+			}
+		}
+		return pp
+	}
+
+	for i := range proof.LROpp {
+		proof.LROpp[i] = placeholderProofOfProximity()
+	}
+	proof.Zpp = placeholderProofOfProximity()
+	for i := range proof.Hpp {
+		proof.Hpp[i] = placeholderProofOfProximity()
+	}
+	for _, openings := range [][]OpeningProof{
+		proof.OpeningsLROmp[:],
+		proof.OpeningsZmp[:],
+		proof.OpeningsHmp[:],
+		proof.OpeningsQlQrQmQoQkincompletemp[:],
+		proof.OpeningsS1S2S3mp[:],
+		proof.OpeningsId1Id2Id3mp[:],
+	} {
+		for i := range openings {
+			openings[i].MerkleProof = placeholderMerkleProof(logSize, logArity)
+		}
+	}
+	return proof
+}
+
+// assign copies src in dst, matching struct fields by name. Field elements, byte slices
+// (hashes) and integers are copied in frontend.Variable as big.Int.
+func assign(dst, src reflect.Value) error {
+	if src.Kind() == reflect.Pointer || src.Kind() == reflect.Interface {
+		if src.IsNil() {
+			return errors.New("can't assign a nil value")
+		}
+		return assign(dst, src.Elem())
+	}
+
+	switch dst.Kind() {
+	case reflect.Interface:
+		v := src.Interface()
+		if src.CanAddr() && src.Kind() == reflect.Array {
+			// field elements implement the conversion to big.Int on pointers
+			v = src.Addr().Interface()
+		}
+		dst.Set(reflect.ValueOf(utils.FromInterface(v)))
+	case reflect.Struct:
+		if src.Kind() != reflect.Struct {
+			return fmt.Errorf("can't assign %s to %s", src.Type(), dst.Type())
+		}
+		for i := 0; i < dst.NumField(); i++ {
+			f := dst.Type().Field(i)
+			sf := src.FieldByName(f.Name)
+			if !sf.IsValid() {
+				return fmt.Errorf("missing field %s in %s", f.Name, src.Type())
+			}
+			if err := assign(dst.Field(i), sf); err != nil {
+				return fmt.Errorf("%s: %w", f.Name, err)
+			}
+		}
+	case reflect.Array:
+		if src.Kind() != reflect.Array || src.Len() != dst.Len() {
+			return fmt.Errorf("can't assign %s to %s", src.Type(), dst.Type())
+		}
+		for i := 0; i < dst.Len(); i++ {
+			if err := assign(dst.Index(i), src.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice:
+		if src.Kind() != reflect.Slice {
+			return fmt.Errorf("can't assign %s to %s", src.Type(), dst.Type())
+		}
+		dst.Set(reflect.MakeSlice(dst.Type(), src.Len(), src.Len()))
+		for i := 0; i < src.Len(); i++ {
+			if err := assign(dst.Index(i), src.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Int, reflect.Bool:
+		if src.Kind() != dst.Kind() {
+			return fmt.Errorf("can't assign %s to %s", src.Type(), dst.Type())
+		}
+		dst.Set(src)
+	default:
+		return fmt.Errorf("unsupported type %s", dst.Type())
+	}
+	return nil
+}
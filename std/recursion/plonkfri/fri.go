@@ -0,0 +1,294 @@
+/*
+Copyright © 2020 ConsenSys
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plonkfri
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"math/bits"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/internal/utils"
+	fiatshamir "github.com/consensys/gnark/std/fiat-shamir"
+	"github.com/consensys/gnark/std/hash"
+)
+
+var errProofShape = errors.New("fri: proof doesn't match the FRI parameters")
+
+// iopp verifies the FRI proofs of the backend/plonkfri prover in a circuit
+type iopp struct {
+	api     frontend.API
+	h       hash.Hash
+	params  FRIParams
+	size    uint64 // size of the evaluation domain
+	logSize int
+	steps   []int // log₂ of the arity of each folding step
+	gen     big.Int
+	genInv  big.Int
+}
+
+// newIopp mirrors the Iopp instantiated by the native Setup, for polynomials of size vk.Size+2
+func newIopp(api frontend.API, h hash.Hash, vk VerifyingKey) *iopp {
+	s := &iopp{
+		api:    api,
+		h:      h,
+		params: vk.Params,
+	}
+	s.steps, s.logSize = friSteps(vk)
+	s.size = 1 << s.logSize
+	s.gen.Set(&vk.GenOpening)
+	s.genInv.ModInverse(&s.gen, api.Compiler().Field())
+	return s
+}
+
+// friSteps returns the log₂ of the arities of the folding steps and the log₂ of the size
+// of the evaluation domain of the FRI scheme of vk
+func friSteps(vk VerifyingKey) (steps []int, logSize int) {
+	n := ecc.NextPowerOfTwo(vk.Size + 2)
+	logN := bits.TrailingZeros64(n)
+	logArity := bits.TrailingZeros(uint(vk.Params.FoldingArity))
+	for r := logN; r > 0; r -= logArity {
+		if r < logArity {
+			steps = append(steps, r)
+		} else {
+			steps = append(steps, logArity)
+		}
+	}
+	return steps, logN + bits.TrailingZeros(uint(vk.Params.BlowupFactor))
+}
+
+// verifyProofOfProximity mirrors the native Iopp.VerifyProofOfProximity
+func (s *iopp) verifyProofOfProximity(pp *ProofOfProximity) error {
+	api := s.api
+	if len(pp.Roots) != len(s.steps)-1 || len(pp.Queries) != s.params.NbQueries {
+		return errProofShape
+	}
+
+	// Fiat Shamir, the names are padded to the size of a field element as in the native prover
+	frSize := utils.ByteLen(api.Compiler().Field())
+	names := make([]string, len(s.steps)+1)
+	for i := range s.steps {
+		names[i] = paddNaming(fmt.Sprintf("x%d", i), frSize)
+	}
+	names[len(s.steps)] = paddNaming("q", frSize)
+	fs := fiatshamir.NewTranscript(api, s.h, names...)
+
+	roots := append([]frontend.Variable{pp.ID}, pp.Roots...)
+	x := make([]frontend.Variable, len(s.steps))
+	for i := range s.steps {
+		if err := fs.Bind(names[i], []frontend.Variable{roots[i]}); err != nil {
+			return err
+		}
+		var err error
+		if x[i], err = fs.ComputeChallenge(names[i]); err != nil {
+			return err
+		}
+	}
+	if err := fs.Bind(names[len(s.steps)], []frontend.Variable{pp.Final}); err != nil {
+		return err
+	}
+	seed, err := fs.ComputeChallenge(names[len(s.steps)])
+	if err != nil {
+		return err
+	}
+
+	// proof of work: H(seed, nonce) has GrindingBits leading zeros
+	if s.params.GrindingBits == 0 {
+		api.AssertIsEqual(pp.Nonce, 0)
+	} else {
+		pow := s.canonicalBits(s.hash(seed, pp.Nonce))
+		for _, b := range pow[len(pow)-s.params.GrindingBits:] {
+			api.AssertIsEqual(b, 0)
+		}
+	}
+
+	for i := range pp.Queries {
+		if len(pp.Queries[i]) != len(s.steps) {
+			return errProofShape
+		}
+		position := s.lowBits(s.hash(seed, pp.Nonce, i), s.logSize)
+
+		logSize := s.logSize
+		var gInv big.Int
+		gInv.Set(&s.genInv)
+		var expected frontend.Variable
+		for j, logArity := range s.steps {
+			mp := &pp.Queries[i][j]
+			if len(mp.Leaf) != 1<<logArity {
+				return errProofShape
+			}
+			leafBits := position[:logSize-logArity]
+			if err := s.verifyMerklePath(mp, leafBits, roots[j]); err != nil {
+				return err
+			}
+			if j > 0 {
+				api.AssertIsEqual(mux(api, position[logSize-logArity:logSize], mp.Leaf), expected)
+			}
+
+			// y = x·g⁻ʲ, where g is the generator of the current layer domain
+			y := api.Mul(x[j], s.expConstant(&gInv, leafBits))
+			expected = s.foldCoset(mp.Leaf, &gInv, logSize, logArity, y)
+
+			for k := 0; k < logArity; k++ {
+				gInv.Mul(&gInv, &gInv).Mod(&gInv, api.Compiler().Field())
+			}
+			logSize -= logArity
+		}
+		api.AssertIsEqual(expected, pp.Final)
+	}
+
+	return nil
+}
+
+// verifyOpening mirrors the native Iopp.VerifyOpening, position being given by its
+// logSize bits
+func (s *iopp) verifyOpening(position []frontend.Variable, op *OpeningProof, root frontend.Variable) error {
+	logArity := s.steps[0]
+	if len(op.Leaf) != 1<<logArity {
+		return errProofShape
+	}
+	s.api.AssertIsEqual(mux(s.api, position[s.logSize-logArity:], op.Leaf), op.ClaimedValue)
+	return s.verifyMerklePath(&op.MerkleProof, position[:s.logSize-logArity], root)
+}
+
+// verifyMerklePath checks that mp opens the leaf whose index bits are given, in the tree of root root
+func (s *iopp) verifyMerklePath(mp *MerkleProof, index []frontend.Variable, root frontend.Variable) error {
+	if len(mp.Path) != len(index) {
+		return errProofShape
+	}
+	node := s.hash(append([]frontend.Variable{merkleLeafTag}, mp.Leaf...)...)
+	for i, sibling := range mp.Path {
+		left := s.api.Select(index[i], sibling, node)
+		right := s.api.Select(index[i], node, sibling)
+		node = s.hash(merkleNodeTag, left, right)
+	}
+	s.api.AssertIsEqual(node, root)
+	return nil
+}
+
+// foldCoset returns ∑ₘ uₘ·yᵐ, where u is the inverse DFT of the evaluations v of a
+// polynomial on a coset of the domain of size 2^{logSize}, of generator g = gInv⁻¹
+func (s *iopp) foldCoset(v []frontend.Variable, gInv *big.Int, logSize, logArity int, y frontend.Variable) frontend.Variable {
+	api := s.api
+	field := api.Compiler().Field()
+	arity := len(v)
+
+	// ζ⁻ⁱ, where ζ = g^{size/arity}
+	zetaInv := make([]big.Int, arity)
+	zetaInv[0].SetUint64(1)
+	var z big.Int
+	z.Exp(gInv, big.NewInt(1<<(logSize-logArity)), field)
+	for i := 1; i < arity; i++ {
+		zetaInv[i].Mul(&zetaInv[i-1], &z).Mod(&zetaInv[i], field)
+	}
+	var arityInv big.Int
+	arityInv.SetUint64(uint64(arity)).ModInverse(&arityInv, field)
+
+	var res frontend.Variable = 0
+	var ym frontend.Variable = 1
+	var c big.Int
+	for m := 0; m < arity; m++ {
+		var u frontend.Variable = 0
+		for t := 0; t < arity; t++ {
+			c.Mul(&zetaInv[(t*m)%arity], &arityInv).Mod(&c, field)
+			u = api.Add(u, api.Mul(v[t], &c))
+		}
+		res = api.Add(res, api.Mul(u, ym))
+		if m < arity-1 {
+			ym = api.Mul(ym, y)
+		}
+	}
+	return res
+}
+
+// expGenerator returns g^{e}, g being the generator of the evaluation domain
+func (s *iopp) expGenerator(e []frontend.Variable) frontend.Variable {
+	return s.expConstant(&s.gen, e)
+}
+
+// expConstant returns x^{e} for a constant x, e being given by its bits
+func (s *iopp) expConstant(x *big.Int, e []frontend.Variable) frontend.Variable {
+	field := s.api.Compiler().Field()
+	var res frontend.Variable = 1
+	var acc big.Int
+	acc.Set(x)
+	for i := range e {
+		res = s.api.Mul(res, s.api.Select(e[i], new(big.Int).Set(&acc), 1))
+		acc.Mul(&acc, &acc).Mod(&acc, field)
+	}
+	return res
+}
+
+// hash returns H(data...), hashing the field elements as in the native prover
+// domain separation tags of the merkle tree hashes, as in internal/backend/*/plonkfri
+const (
+	merkleLeafTag = 0
+	merkleNodeTag = 1
+)
+
+func (s *iopp) hash(data ...frontend.Variable) frontend.Variable {
+	s.h.Reset()
+	s.h.Write(data...)
+	return s.h.Sum()
+}
+
+// lowBits returns the n least significant bits of the canonical representation of v
+func (s *iopp) lowBits(v frontend.Variable, n int) []frontend.Variable {
+	return s.canonicalBits(v)[:n]
+}
+
+// canonicalBits returns the binary decomposition of v, constrained to be the one of the
+// integer in [0, r) representing v
+func (s *iopp) canonicalBits(v frontend.Variable) []frontend.Variable {
+	api := s.api
+	b := api.ToBinary(v)
+
+	// b ⩽ r-1, bit by bit from the most significant one. t is 1 as long as the bits
+	// of b are equal to the ones of r-1.
+	var bound big.Int
+	bound.Sub(api.Compiler().Field(), big.NewInt(1))
+	var t frontend.Variable = 1
+	for i := len(b) - 1; i >= 0; i-- {
+		if bound.Bit(i) == 0 {
+			api.AssertIsEqual(api.Mul(t, b[i]), 0)
+		} else {
+			t = api.Mul(t, b[i])
+		}
+	}
+	return b
+}
+
+// mux returns values[i], i being given by its bits (little endian)
+func mux(api frontend.API, bits []frontend.Variable, values []frontend.Variable) frontend.Variable {
+	for _, b := range bits {
+		next := make([]frontend.Variable, len(values)/2)
+		for i := range next {
+			next[i] = api.Select(b, values[2*i+1], values[2*i])
+		}
+		values = next
+	}
+	return values[0]
+}
+
+// paddNaming pads a challenge name to the size of a field element, as in the native prover
+func paddNaming(s string, size int) string {
+	a := make([]byte, size)
+	copy(a, s)
+	return string(a)
+}
@@ -0,0 +1,286 @@
+/*
+Copyright © 2020 ConsenSys
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package plonkfri provides a ZKP-circuit function to verify PLONK-FRI proofs (see
+// backend/plonkfri) inside a circuit.
+//
+// The inner proof must be generated with keys set up with plonkfri.WithMiMC, and the
+// circuit verifying it must be defined over the scalar field of the same curve.
+package plonkfri
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark/frontend"
+	fiatshamir "github.com/consensys/gnark/std/fiat-shamir"
+	"github.com/consensys/gnark/std/hash/mimc"
+)
+
+var errPublicInputs = errors.New("invalid number of public inputs")
+
+// FRIParams are the parameters of the FRI commitment scheme of the inner proof
+// (see plonkfri.SetupConfig)
+type FRIParams struct {
+	BlowupFactor int
+	NbQueries    int
+	FoldingArity int
+	GrindingBits int
+	MiMC         bool
+}
+
+// MerkleProof is the opening of a leaf of a FRI layer
+type MerkleProof struct {
+	Leaf []frontend.Variable // evaluations on a coset of the domain of the layer
+	Path []frontend.Variable // hashes of the siblings, from the leaf up to the root
+}
+
+// ProofOfProximity is a FRI proof that a committed polynomial is of bounded degree
+type ProofOfProximity struct {
+	ID      frontend.Variable // commitment to the polynomial
+	Roots   []frontend.Variable
+	Final   frontend.Variable
+	Nonce   frontend.Variable
+	Queries [][]MerkleProof
+}
+
+// OpeningProof proves the evaluation of a committed polynomial
+type OpeningProof struct {
+	MerkleProof
+	ClaimedValue frontend.Variable
+}
+
+// Proof is a PLONK-FRI proof, as generated by plonkfri.Prove
+type Proof struct {
+	LROpp                          [3]ProofOfProximity
+	Zpp                            ProofOfProximity
+	Hpp                            [3]ProofOfProximity
+	OpeningsLROmp                  [3]OpeningProof
+	OpeningsZmp                    [2]OpeningProof
+	OpeningsHmp                    [3]OpeningProof
+	OpeningsQlQrQmQoQkincompletemp [5]OpeningProof
+	OpeningsS1S2S3mp               [3]OpeningProof
+	OpeningsId1Id2Id3mp            [3]OpeningProof
+}
+
+// VerifyingKey is the PLONK-FRI verifying key of the inner circuit.
+//
+// It is a constant of the circuit, set by Assign before it is compiled, such that the
+// outer circuit only accepts proofs of this inner circuit. The proofs of proximity of the
+// commitments to the circuit polynomials are not checked in the circuit, the verifying
+// key is trusted.
+type VerifyingKey struct {
+	Size              uint64    `gnark:"-"`
+	NbPublicVariables uint64    `gnark:"-"`
+	Params            FRIParams `gnark:"-"`
+	SizeInv           big.Int   `gnark:"-"`
+	Generator         big.Int   `gnark:"-"` // generator of the domain of size Size
+	GenOpening        big.Int   `gnark:"-"` // generator of the FRI evaluation domain
+
+	Qpp  [5]big.Int `gnark:"-"` // commitments to ql, qr, qm, qo, qk
+	Spp  [3]big.Int `gnark:"-"` // commitments to s1, s2, s3
+	Idpp [3]big.Int `gnark:"-"` // commitments to id1, id2, id3
+}
+
+// Verify checks that proof is a valid PLONK-FRI proof for the inner circuit of verifying
+// key vk and public inputs publicInputs.
+//
+// It replays the Fiat Shamir transcript of the prover, checks the FRI proofs of
+// proximity of the committed polynomials, the openings at the challenge ζ against the
+// commitments, and the gate and permutation identities at ζ.
+func Verify(api frontend.API, vk VerifyingKey, proof Proof, publicInputs []frontend.Variable) error {
+	if uint64(len(publicInputs)) != vk.NbPublicVariables {
+		return fmt.Errorf("%w: expected %d, got %d", errPublicInputs, vk.NbPublicVariables, len(publicInputs))
+	}
+	if !vk.Params.MiMC {
+		return errors.New("the inner proof must be generated with MiMC (see plonkfri.WithMiMC)")
+	}
+	h, err := mimc.NewMiMC(api)
+	if err != nil {
+		return err
+	}
+	iopp := newIopp(api, &h, vk)
+
+	// 0 - derive the challenges with Fiat Shamir
+	fs := fiatshamir.NewTranscript(api, &h, "gamma", "beta", "alpha", "zeta")
+	bindings := append(append([]frontend.Variable{}, publicInputs...), proof.LROpp[0].ID, proof.LROpp[1].ID, proof.LROpp[2].ID)
+	if err := fs.Bind("gamma", bindings); err != nil {
+		return err
+	}
+	beta, err := fs.ComputeChallenge("gamma")
+	if err != nil {
+		return err
+	}
+	gamma, err := fs.ComputeChallenge("beta")
+	if err != nil {
+		return err
+	}
+	if err := fs.Bind("alpha", []frontend.Variable{proof.Zpp.ID}); err != nil {
+		return err
+	}
+	alpha, err := fs.ComputeChallenge("alpha")
+	if err != nil {
+		return err
+	}
+	if err := fs.Bind("zeta", []frontend.Variable{proof.Hpp[0].ID, proof.Hpp[1].ID, proof.Hpp[2].ID}); err != nil {
+		return err
+	}
+	frOpeningPosition, err := fs.ComputeChallenge("zeta")
+	if err != nil {
+		return err
+	}
+
+	// the opening position is the challenge reduced modulo the size of the FRI domain,
+	// and ζ = genOpening^{position}
+	position := iopp.lowBits(frOpeningPosition, iopp.logSize)
+
+	// the shifted position, for z(μζ), is position + friSize/n mod friSize
+	shifted := api.Add(api.FromBinary(position...), iopp.size/vk.Size)
+	shiftedPosition := api.ToBinary(shifted, iopp.logSize+1)[:iopp.logSize]
+
+	// 1 - verify that the committed polynomials are of bounded degree
+	for _, pp := range []*ProofOfProximity{&proof.LROpp[0], &proof.LROpp[1], &proof.LROpp[2], &proof.Zpp, &proof.Hpp[0], &proof.Hpp[1], &proof.Hpp[2]} {
+		if err := iopp.verifyProofOfProximity(pp); err != nil {
+			return err
+		}
+	}
+
+	// 2 - verify the openings
+	openings := []struct {
+		op       *OpeningProof
+		root     frontend.Variable
+		position []frontend.Variable
+	}{
+		{&proof.OpeningsQlQrQmQoQkincompletemp[0], &vk.Qpp[0], position},
+		{&proof.OpeningsQlQrQmQoQkincompletemp[1], &vk.Qpp[1], position},
+		{&proof.OpeningsQlQrQmQoQkincompletemp[2], &vk.Qpp[2], position},
+		{&proof.OpeningsQlQrQmQoQkincompletemp[3], &vk.Qpp[3], position},
+		{&proof.OpeningsQlQrQmQoQkincompletemp[4], &vk.Qpp[4], position},
+		{&proof.OpeningsLROmp[0], proof.LROpp[0].ID, position},
+		{&proof.OpeningsLROmp[1], proof.LROpp[1].ID, position},
+		{&proof.OpeningsLROmp[2], proof.LROpp[2].ID, position},
+		{&proof.OpeningsHmp[0], proof.Hpp[0].ID, position},
+		{&proof.OpeningsHmp[1], proof.Hpp[1].ID, position},
+		{&proof.OpeningsHmp[2], proof.Hpp[2].ID, position},
+		{&proof.OpeningsS1S2S3mp[0], &vk.Spp[0], position},
+		{&proof.OpeningsS1S2S3mp[1], &vk.Spp[1], position},
+		{&proof.OpeningsS1S2S3mp[2], &vk.Spp[2], position},
+		{&proof.OpeningsId1Id2Id3mp[0], &vk.Idpp[0], position},
+		{&proof.OpeningsId1Id2Id3mp[1], &vk.Idpp[1], position},
+		{&proof.OpeningsId1Id2Id3mp[2], &vk.Idpp[2], position},
+		{&proof.OpeningsZmp[0], proof.Zpp.ID, position},
+		{&proof.OpeningsZmp[1], proof.Zpp.ID, shiftedPosition},
+	}
+	for _, o := range openings {
+		if err := iopp.verifyOpening(o.position, o.op, o.root); err != nil {
+			return err
+		}
+	}
+
+	// 3 - verify the algebraic relation at ζ
+	zeta := iopp.expGenerator(position)
+
+	ql := proof.OpeningsQlQrQmQoQkincompletemp[0].ClaimedValue
+	qr := proof.OpeningsQlQrQmQoQkincompletemp[1].ClaimedValue
+	qm := proof.OpeningsQlQrQmQoQkincompletemp[2].ClaimedValue
+	qo := proof.OpeningsQlQrQmQoQkincompletemp[3].ClaimedValue
+	qk := proof.OpeningsQlQrQmQoQkincompletemp[4].ClaimedValue
+	l := proof.OpeningsLROmp[0].ClaimedValue
+	r := proof.OpeningsLROmp[1].ClaimedValue
+	o := proof.OpeningsLROmp[2].ClaimedValue
+	h1 := proof.OpeningsHmp[0].ClaimedValue
+	h2 := proof.OpeningsHmp[1].ClaimedValue
+	h3 := proof.OpeningsHmp[2].ClaimedValue
+	s1 := proof.OpeningsS1S2S3mp[0].ClaimedValue
+	s2 := proof.OpeningsS1S2S3mp[1].ClaimedValue
+	s3 := proof.OpeningsS1S2S3mp[2].ClaimedValue
+	id1 := proof.OpeningsId1Id2Id3mp[0].ClaimedValue
+	id2 := proof.OpeningsId1Id2Id3mp[1].ClaimedValue
+	id3 := proof.OpeningsId1Id2Id3mp[2].ClaimedValue
+	z := proof.OpeningsZmp[0].ClaimedValue
+	zshift := proof.OpeningsZmp[1].ClaimedValue
+
+	// ζⁿ, n being a power of 2
+	zetaN := zeta
+	for i := uint64(1); i < vk.Size; i <<= 1 {
+		zetaN = api.Mul(zetaN, zetaN)
+	}
+	vanishing := api.Sub(zetaN, 1)
+
+	// 3.1 ql*l+qr*r+qm*l*r+qo*o+qk
+	t1 := api.Add(
+		api.Mul(l, ql),
+		api.Mul(r, qr),
+		api.Mul(qm, l, r),
+		api.Mul(o, qo),
+		qk,
+		completeQk(api, vk, publicInputs, zeta, vanishing),
+	)
+
+	// 3.2 z(μζ)*(l+β*s1+γ)*(r+β*s2+γ)*(o+β*s3+γ)-z*(l+β*id1+γ)*(r+β*id2+γ)*(o+β*id3+γ)
+	t2 := api.Mul(
+		api.Add(l, api.Mul(beta, s1), gamma),
+		api.Add(r, api.Mul(beta, s2), gamma),
+		api.Add(o, api.Mul(beta, s3), gamma),
+		zshift,
+	)
+	t2 = api.Sub(t2, api.Mul(
+		api.Add(l, api.Mul(beta, id1), gamma),
+		api.Add(r, api.Mul(beta, id2), gamma),
+		api.Add(o, api.Mul(beta, id3), gamma),
+		z,
+	))
+
+	// 3.3 (z-1)*L₀(ζ), where L₀(ζ) = (ζⁿ-1)/(n(ζ-1)), set to 0 when ζ=1 as in the native verifier
+	zetaIsOne := api.IsZero(api.Sub(zeta, 1))
+	l0 := api.Div(api.Mul(vanishing, &vk.SizeInv), api.Select(zetaIsOne, 1, api.Sub(zeta, 1)))
+	l0 = api.Select(zetaIsOne, 0, l0)
+	t3 := api.Mul(api.Sub(z, 1), l0)
+
+	// 3.4 lhs = t1 + α*t2 + α²*t3
+	lhs := api.Add(api.Mul(api.Add(api.Mul(t3, alpha), t2), alpha), t1)
+
+	// 4 - rhs = (h1 + ζⁿ⁺²*h2 + ζ²⁽ⁿ⁺²⁾*h3)*(ζⁿ-1)
+	zetaN2 := api.Mul(zetaN, zeta, zeta)
+	rhs := api.Add(api.Mul(api.Add(api.Mul(h3, zetaN2), h2), zetaN2), h1)
+	rhs = api.Mul(rhs, vanishing)
+
+	api.AssertIsEqual(lhs, rhs)
+
+	return nil
+}
+
+// completeQk returns ∑_{i<nb_public_inputs}w_i*L_i(ζ), where L_i(ζ) = ωⁱ(ζⁿ-1)/(n(ζ-ωⁱ)),
+// and L_i(ζ) = 1 if ζ=ωⁱ
+func completeQk(api frontend.API, vk VerifyingKey, publicInputs []frontend.Variable, zeta, vanishing frontend.Variable) frontend.Variable {
+	field := api.Compiler().Field()
+	var res frontend.Variable = 0
+	omegaI := big.NewInt(1) // ωⁱ
+	var c big.Int
+	for i := range publicInputs {
+		den := api.Sub(zeta, omegaI)
+		isZero := api.IsZero(den)
+		c.Mul(omegaI, &vk.SizeInv).Mod(&c, field)
+		li := api.Div(api.Mul(vanishing, &c), api.Select(isZero, 1, den))
+		li = api.Select(isZero, 1, li)
+		res = api.Add(res, api.Mul(li, publicInputs[i]))
+
+		omegaI = new(big.Int).Mul(omegaI, &vk.Generator)
+		omegaI.Mod(omegaI, field)
+	}
+	return res
+}
@@ -0,0 +1,170 @@
+/*
+Copyright © 2020 ConsenSys
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plonkfri
+
+import (
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/backend/plonkfri"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/scs"
+	"github.com/consensys/gnark/test"
+)
+
+type innerCircuit struct {
+	X frontend.Variable
+	Y frontend.Variable `gnark:",public"`
+}
+
+func (circuit *innerCircuit) Define(api frontend.API) error {
+	x := circuit.X
+	for i := 0; i < 4; i++ {
+		x = api.Mul(x, x)
+	}
+	api.AssertIsEqual(api.Add(x, circuit.X), circuit.Y)
+	return nil
+}
+
+// otherCircuit has the shape of innerCircuit, with a different constraint
+type otherCircuit struct {
+	X frontend.Variable
+	Y frontend.Variable `gnark:",public"`
+}
+
+func (circuit *otherCircuit) Define(api frontend.API) error {
+	x := circuit.X
+	for i := 0; i < 4; i++ {
+		x = api.Mul(x, x)
+	}
+	api.AssertIsEqual(api.Sub(x, circuit.X), circuit.Y)
+	return nil
+}
+
+type verifierCircuit struct {
+	Proof        Proof
+	VerifyingKey VerifyingKey
+	PublicInputs [1]frontend.Variable `gnark:",public"`
+}
+
+func (circuit *verifierCircuit) Define(api frontend.API) error {
+	return Verify(api, circuit.VerifyingKey, circuit.Proof, circuit.PublicInputs[:])
+}
+
+func generateInnerProof(t *testing.T, opts ...plonkfri.SetupOption) (plonkfri.Proof, plonkfri.VerifyingKey) {
+	// 3¹⁶ + 3
+	return generateProof(t, &innerCircuit{}, &innerCircuit{X: 3, Y: 43046724}, opts...)
+}
+
+func generateProof(t *testing.T, circuit, assignment frontend.Circuit, opts ...plonkfri.SetupOption) (plonkfri.Proof, plonkfri.VerifyingKey) {
+	ccs, err := frontend.Compile(ecc.BN254.ScalarField(), scs.NewBuilder, circuit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk, vk, err := plonkfri.Setup(ccs, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField())
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof, err := plonkfri.Prove(ccs, pk, w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicWitness, err := w.Public()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := plonkfri.Verify(proof, vk, publicWitness); err != nil {
+		t.Fatal(err)
+	}
+	return proof, vk
+}
+
+func TestVerifier(t *testing.T) {
+	assert := test.NewAssert(t)
+
+	for _, opts := range [][]plonkfri.SetupOption{
+		{plonkfri.WithBlowupFactor(2), plonkfri.WithNbQueries(1), plonkfri.WithFoldingArity(2)},
+		{plonkfri.WithBlowupFactor(4), plonkfri.WithNbQueries(2), plonkfri.WithFoldingArity(4), plonkfri.WithGrindingBits(2)},
+	} {
+		innerProof, innerVk := generateInnerProof(t, append(opts, plonkfri.WithMiMC())...)
+
+		var circuit, assignment verifierCircuit
+		assert.NoError(circuit.VerifyingKey.Assign(innerVk))
+		circuit.Proof = PlaceholderProof(circuit.VerifyingKey)
+
+		assignment.VerifyingKey = circuit.VerifyingKey
+		assert.NoError(assignment.Proof.Assign(innerProof))
+		assignment.PublicInputs[0] = 43046724
+
+		assert.NoError(test.IsSolved(&circuit, &assignment, ecc.BN254.ScalarField()))
+
+		assignment.PublicInputs[0] = 43046725
+		assert.Error(test.IsSolved(&circuit, &assignment, ecc.BN254.ScalarField()))
+	}
+}
+
+func TestVerifierOtherCircuit(t *testing.T) {
+	assert := test.NewAssert(t)
+
+	opts := []plonkfri.SetupOption{plonkfri.WithBlowupFactor(2), plonkfri.WithNbQueries(2), plonkfri.WithFoldingArity(2), plonkfri.WithMiMC()}
+	_, innerVk := generateInnerProof(t, opts...)
+	// 3¹⁶ - 3, a valid proof with the verifying key of another circuit
+	otherProof, otherVk := generateProof(t, &otherCircuit{}, &otherCircuit{X: 3, Y: 43046718}, opts...)
+
+	var circuit, assignment verifierCircuit
+	assert.NoError(circuit.VerifyingKey.Assign(innerVk))
+	circuit.Proof = PlaceholderProof(circuit.VerifyingKey)
+	assert.NoError(assignment.VerifyingKey.Assign(otherVk))
+	assert.NoError(assignment.Proof.Assign(otherProof))
+	assignment.PublicInputs[0] = 43046718
+
+	assert.Error(test.IsSolved(&circuit, &assignment, ecc.BN254.ScalarField()))
+
+	// sanity check: the proof is accepted by the circuit of its own verifying key
+	circuit.VerifyingKey = assignment.VerifyingKey
+	assert.NoError(test.IsSolved(&circuit, &assignment, ecc.BN254.ScalarField()))
+}
+
+func TestVerifierCompile(t *testing.T) {
+	assert := test.NewAssert(t)
+
+	innerProof, innerVk := generateInnerProof(t,
+		plonkfri.WithBlowupFactor(2), plonkfri.WithNbQueries(1), plonkfri.WithFoldingArity(4), plonkfri.WithMiMC())
+
+	var circuit, assignment verifierCircuit
+	assert.NoError(circuit.VerifyingKey.Assign(innerVk))
+	circuit.Proof = PlaceholderProof(circuit.VerifyingKey)
+	assignment.VerifyingKey = circuit.VerifyingKey
+	assert.NoError(assignment.Proof.Assign(innerProof))
+	assignment.PublicInputs[0] = 43046724
+
+	assert.SolvingSucceeded(&circuit, &assignment, test.WithCurves(ecc.BN254), test.WithBackends(backend.GROTH16))
+}
+
+func TestVerifyingKeyWithoutMiMC(t *testing.T) {
+	_, innerVk := generateInnerProof(t, plonkfri.WithBlowupFactor(2), plonkfri.WithNbQueries(1))
+	var vk VerifyingKey
+	if err := vk.Assign(innerVk); err == nil {
+		t.Fatal("expected an error for a verifying key without MiMC")
+	}
+}
@@ -17,8 +17,10 @@ limitations under the License.
 package test
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"reflect"
 	"strings"
 	"testing"
@@ -35,6 +37,7 @@ import (
 	"github.com/consensys/gnark/frontend/cs/r1cs"
 	"github.com/consensys/gnark/frontend/cs/scs"
 	"github.com/consensys/gnark/frontend/schema"
+	gnarkio "github.com/consensys/gnark/io"
 	"github.com/stretchr/testify/require"
 )
 
@@ -181,6 +184,18 @@ func (assert *Assert) ProverSucceeded(circuit frontend.Circuit, validAssignment
 					err = plonkfri.Verify(correctProof, vk, validPublicWitness)
 					checkError(err)
 
+					if opt.witnessSerialization {
+						// the deserialized keys and proof must verify
+						_vk := plonkfri.NewVerifyingKey(curve)
+						_proof := plonkfri.NewProof(curve)
+						assert.marshalRoundTrip(pk, plonkfri.NewProvingKey(curve))
+						assert.marshalRoundTrip(vk, _vk)
+						assert.marshalRoundTrip(correctProof, _proof)
+
+						err = plonkfri.Verify(_proof, _vk, validPublicWitness)
+						checkError(err)
+					}
+
 				default:
 					panic("backend not implemented")
 				}
@@ -558,6 +573,33 @@ func (assert *Assert) marshalWitness(w witness.Witness, curveID ecc.ID, publicOn
 	assert.True(witnessMatch, "round trip marshaling failed")
 }
 
+// marshalRoundTrip serializes from, with and without point compression, and deserializes
+// it in to. to must then serialize to the same bytes.
+func (assert *Assert) marshalRoundTrip(from io.WriterTo, to interface {
+	io.WriterTo
+	io.ReaderFrom
+}) {
+	var buf, reBuf bytes.Buffer
+	if raw, ok := from.(gnarkio.WriterRawTo); ok {
+		_, err := raw.WriteRawTo(&buf)
+		assert.NoError(err)
+		_, err = to.ReadFrom(&buf)
+		assert.NoError(err)
+		buf.Reset()
+	}
+
+	written, err := from.WriteTo(&buf)
+	assert.NoError(err)
+	data := append([]byte(nil), buf.Bytes()...)
+	read, err := to.ReadFrom(&buf)
+	assert.NoError(err)
+	assert.Equal(written, read, "bytes written / read don't match")
+
+	_, err = to.WriteTo(&reBuf)
+	assert.NoError(err)
+	assert.True(bytes.Equal(data, reBuf.Bytes()), "round trip marshaling failed")
+}
+
 func (assert *Assert) marshalWitnessJSON(w witness.Witness, s *schema.Schema, curveID ecc.ID, publicOnly bool) {
 	var err error
 	if publicOnly {
@@ -23,7 +23,6 @@ import (
 	"reflect"
 	"runtime"
 	"strconv"
-	"strings"
 
 	"github.com/consensys/gnark/debug"
 	"github.com/consensys/gnark/frontend/schema"
@@ -33,7 +32,9 @@ import (
 	"github.com/consensys/gnark-crypto/field/pool"
 	"github.com/consensys/gnark/backend"
 	"github.com/consensys/gnark/backend/hint"
+	"github.com/consensys/gnark/constraint"
 	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs"
 	"github.com/consensys/gnark/internal/utils"
 )
 
@@ -370,7 +371,8 @@ func (e *engine) Cmp(i1, i2 frontend.Variable) frontend.Variable {
 	return res
 }
 
-func (e *engine) AssertIsEqual(i1, i2 frontend.Variable) {
+func (e *engine) AssertIsEqual(i1, i2 frontend.Variable, opts ...frontend.AssertOption) {
+	defer e.assertMessage(opts)
 	cptAssertIsEqual++
 	b1, b2 := e.toBigInt(i1), e.toBigInt(i2)
 	if b1.Cmp(b2) != 0 {
@@ -378,20 +380,22 @@ func (e *engine) AssertIsEqual(i1, i2 frontend.Variable) {
 	}
 }
 
-func (e *engine) AssertIsDifferent(i1, i2 frontend.Variable) {
+func (e *engine) AssertIsDifferent(i1, i2 frontend.Variable, opts ...frontend.AssertOption) {
+	defer e.assertMessage(opts)
 	b1, b2 := e.toBigInt(i1), e.toBigInt(i2)
 	if b1.Cmp(b2) == 0 {
 		panic(fmt.Sprintf("[assertIsDifferent] %s != %s", b1.String(), b2.String()))
 	}
 }
 
-func (e *engine) AssertIsBoolean(i1 frontend.Variable) {
+func (e *engine) AssertIsBoolean(i1 frontend.Variable, opts ...frontend.AssertOption) {
+	defer e.assertMessage(opts)
 	b1 := e.toBigInt(i1)
 	e.mustBeBoolean(b1)
 }
 
-func (e *engine) AssertIsLessOrEqual(v frontend.Variable, bound frontend.Variable) {
-
+func (e *engine) AssertIsLessOrEqual(v frontend.Variable, bound frontend.Variable, opts ...frontend.AssertOption) {
+	defer e.assertMessage(opts)
 	bValue := e.toBigInt(bound)
 
 	if bValue.Sign() == -1 {
@@ -405,47 +409,76 @@ func (e *engine) AssertIsLessOrEqual(v frontend.Variable, bound frontend.Variabl
 }
 
 func (e *engine) Println(a ...frontend.Variable) {
-	var sbb strings.Builder
-	sbb.WriteString("(test.engine) ")
+	e.printf(cs.PrintlnFormat(len(a)), a)
+}
+
+func (e *engine) Printf(format string, a ...frontend.Variable) {
+	e.printf(format, a)
+}
+
+func (e *engine) PrintlnIf(cond frontend.Variable, a ...frontend.Variable) {
+	if e.toBigInt(cond).Sign() == 0 {
+		return
+	}
+	e.printf(cs.PrintlnFormat(len(a)), a)
+}
+
+// assertMessage prefixes the panic of a failed assertion with its message, if any (see
+// frontend.WithMessage)
+func (e *engine) assertMessage(opts []frontend.AssertOption) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	if opt := frontend.NewAssertConfig(opts...); opt.Label != "" {
+		r = fmt.Sprintf("%s: %v", e.sprintf(opt.Label, opt.Args), r)
+	}
+	panic(r)
+}
+
+// printf formats the log as the solver does (see cs.ParseLog), and prints it to the circuit log
+// sink if set (see backend.WithCircuitLogSink), to stdout otherwise
+func (e *engine) printf(format string, a []frontend.Variable) {
+	msg := e.sprintf(format, a)
 
 	// prefix log line with file.go:line
-	if _, file, line, ok := runtime.Caller(1); ok {
-		sbb.WriteString(filepath.Base(file))
-		sbb.WriteByte(':')
-		sbb.WriteString(strconv.Itoa(line))
-		sbb.WriteByte(' ')
-	}
-
-	for i := 0; i < len(a); i++ {
-		e.print(&sbb, a[i])
-		sbb.WriteByte(' ')
-	}
-	fmt.Println(sbb.String())
-}
-
-func (e *engine) print(sbb *strings.Builder, x interface{}) {
-	switch v := x.(type) {
-	case string:
-		sbb.WriteString(v)
-	case []frontend.Variable:
-		sbb.WriteRune('[')
-		for i := range v {
-			e.print(sbb, v[i])
-			if i+1 != len(v) {
-				sbb.WriteRune(',')
-			}
+	var caller string
+	if _, file, line, ok := runtime.Caller(2); ok {
+		caller = filepath.Base(file) + ":" + strconv.Itoa(line)
+	}
+	if e.opt.CircuitLogSink != nil {
+		e.opt.CircuitLogSink(caller, msg)
+		return
+	}
+	fmt.Println("(test.engine)", caller, msg)
+}
+
+// sprintf formats the values as the solver does, see cs.ParseLog
+func (e *engine) sprintf(format string, a []frontend.Variable) string {
+	format, logArgs := cs.ParseLog(format, a, isValue)
+	args := make([]interface{}, len(logArgs))
+	for i, arg := range logArgs {
+		if arg.BitsPerLimb == 0 {
+			args[i] = constraint.LogValue{Value: e.toBigInt(arg.Variables[0]), Field: e.q}
+			continue
 		}
-		sbb.WriteRune(']')
-	default:
-		i := e.toBigInt(v)
-		var iAsNeg big.Int
-		iAsNeg.Sub(i, e.q)
-		if iAsNeg.IsInt64() {
-			sbb.WriteString(strconv.FormatInt(iAsNeg.Int64(), 10))
-		} else {
-			sbb.WriteString(i.String())
+		value := new(big.Int)
+		for j := len(arg.Variables) - 1; j >= 0; j-- {
+			value.Lsh(value, arg.BitsPerLimb).Add(value, e.toBigInt(arg.Variables[j]))
 		}
+		args[i] = constraint.LogValue{Value: value}
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// isValue returns true if v is a value of the engine, false for the arguments of the logs
+// which are not numbers (e.g. strings)
+func isValue(v frontend.Variable) bool {
+	switch v.(type) {
+	case big.Int, *big.Int, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return true
 	}
+	return false
 }
 
 func (e *engine) NewHint(f hint.Function, nbOutputs int, inputs ...frontend.Variable) ([]frontend.Variable, error) {
@@ -129,7 +129,7 @@ func (p *permutter) solveSCS() error {
 		return err
 	}
 
-	_, err = p.scs.Solve(p.witness, opt)
+	_, err = p.scs.SolveWires(p.witness, opt)
 	return err
 }
 
@@ -144,7 +144,7 @@ func (p *permutter) solveR1CS() error {
 		p.b[i].SetZero()
 		p.c[i].SetZero()
 	}
-	_, err = p.r1cs.Solve(p.witness, p.a, p.b, p.c, opt)
+	_, err = p.r1cs.SolveWires(p.witness, p.a, p.b, p.c, opt)
 	return err
 }
 